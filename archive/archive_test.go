@@ -4,11 +4,17 @@ import (
 	"archive/tar"
 	"bytes"
 	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
+	"io"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/cruciblehq/spec/reference"
 	"github.com/klauspost/compress/zstd"
 )
 
@@ -589,8 +595,12 @@ func TestDetect(t *testing.T) {
 		{"archive.tgz", Gzip, false},
 		{"ARCHIVE.TAR.ZST", Zstd, false},
 		{"ARCHIVE.TAR.GZ", Gzip, false},
-		{"archive.zip", 0, true},
-		{"archive.tar", 0, true},
+		{"archive.tar", Tar, false},
+		{"archive.tar.xz", Xz, false},
+		{"archive.txz", Xz, false},
+		{"archive.tar.bz2", Bzip2, false},
+		{"archive.tbz2", Bzip2, false},
+		{"archive.zip", Zip, false},
 		{"archive.rar", 0, true},
 		{"noextension", 0, true},
 	}
@@ -619,7 +629,7 @@ func TestFormatString(t *testing.T) {
 
 func TestCreateUnsupportedFormat(t *testing.T) {
 	srcDir := t.TempDir()
-	archivePath := filepath.Join(t.TempDir(), "test.zip")
+	archivePath := filepath.Join(t.TempDir(), "test.rar")
 	err := Create(srcDir, archivePath)
 	if err == nil {
 		t.Fatal("expected error for unsupported format")
@@ -630,7 +640,7 @@ func TestCreateUnsupportedFormat(t *testing.T) {
 }
 
 func TestExtractUnsupportedFormat(t *testing.T) {
-	archivePath := filepath.Join(t.TempDir(), "test.zip")
+	archivePath := filepath.Join(t.TempDir(), "test.rar")
 	if err := os.WriteFile(archivePath, []byte("fake"), 0644); err != nil {
 		t.Fatal(err)
 	}
@@ -850,3 +860,324 @@ func TestExtractHardLinkAbsolutePath(t *testing.T) {
 		t.Fatalf("expected ErrInvalidPath, got: %v", err)
 	}
 }
+
+func TestCreateReproducible(t *testing.T) {
+	for _, ext := range []string{".tar.zst", ".tar.gz", ".tar"} {
+		t.Run(ext, func(t *testing.T) {
+			t.Setenv("SOURCE_DATE_EPOCH", "")
+			opts := CreateOptions{Reproducible: true}
+
+			first := t.TempDir()
+			createTestFiles(t, first)
+			second := t.TempDir()
+			createTestFiles(t, second)
+			past := time.Date(2001, 2, 3, 4, 5, 6, 0, time.UTC)
+			if err := os.Chtimes(filepath.Join(second, "file.txt"), past, past); err != nil {
+				t.Fatal(err)
+			}
+
+			a := filepath.Join(t.TempDir(), "a"+ext)
+			b := filepath.Join(t.TempDir(), "b"+ext)
+			if err := CreateWithOptions(first, a, opts); err != nil {
+				t.Fatalf("CreateWithOptions failed: %v", err)
+			}
+			if err := CreateWithOptions(second, b, opts); err != nil {
+				t.Fatalf("CreateWithOptions failed: %v", err)
+			}
+
+			dataA, err := os.ReadFile(a)
+			if err != nil {
+				t.Fatal(err)
+			}
+			dataB, err := os.ReadFile(b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(dataA, dataB) {
+				t.Fatal("archives of identical trees differ")
+			}
+		})
+	}
+}
+
+func TestCreateReproducibleHeaders(t *testing.T) {
+	t.Setenv("SOURCE_DATE_EPOCH", "1700000000")
+
+	srcDir := t.TempDir()
+	createTestFiles(t, srcDir)
+
+	archivePath := filepath.Join(t.TempDir(), "test.tar")
+	if err := CreateWithOptions(srcDir, archivePath, CreateOptions{Reproducible: true}); err != nil {
+		t.Fatalf("CreateWithOptions failed: %v", err)
+	}
+
+	f, err := os.Open(archivePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var names []string
+	tr := tar.NewReader(f)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, header.Name)
+		if header.ModTime.Unix() != 1700000000 {
+			t.Errorf("%s: mtime = %v", header.Name, header.ModTime)
+		}
+		if header.Uid != 0 || header.Gid != 0 || header.Uname != "" || header.Gname != "" {
+			t.Errorf("%s: ownership not cleared", header.Name)
+		}
+	}
+
+	want := []string{"emptydir", "file.txt", "subdir", "subdir/nested.txt"}
+	if strings.Join(names, ",") != strings.Join(want, ",") {
+		t.Fatalf("entries = %v, want %v", names, want)
+	}
+}
+
+func TestCreateReproducibleModTime(t *testing.T) {
+	t.Setenv("SOURCE_DATE_EPOCH", "not-a-number")
+
+	srcDir := t.TempDir()
+	createTestFiles(t, srcDir)
+
+	err := CreateWithOptions(srcDir, filepath.Join(t.TempDir(), "test.tar"), CreateOptions{Reproducible: true})
+	if !errors.Is(err, ErrSourceDateEpoch) {
+		t.Fatalf("expected ErrSourceDateEpoch, got: %v", err)
+	}
+
+	modTime := time.Unix(1234567890, 0)
+	archivePath := filepath.Join(t.TempDir(), "test.tar")
+	if err := CreateWithOptions(srcDir, archivePath, CreateOptions{Reproducible: true, ModTime: modTime}); err != nil {
+		t.Fatalf("explicit ModTime should override SOURCE_DATE_EPOCH: %v", err)
+	}
+}
+
+func TestCreateWithOptionsFilters(t *testing.T) {
+	srcDir := t.TempDir()
+	createTestFiles(t, srcDir)
+	files := map[string]string{
+		IgnoreFileName:                  "node_modules/\n*.log\n!keep.log\n",
+		"node_modules/dep/index.js":     "dep",
+		"web/node_modules/dep/index.js": "dep",
+		"web/app.js":                    "app",
+		"web/debug.log":                 "debug",
+		"web/keep.log":                  "keep",
+		"web/tmp/scratch.txt":           "scratch",
+	}
+	for name, content := range files {
+		path := filepath.Join(srcDir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	archivePath := filepath.Join(t.TempDir(), "test.tar")
+	opts := CreateOptions{
+		IgnoreFile: IgnoreFileName,
+		Include:    []string{"web/"},
+		Exclude:    []string{"tmp/"},
+	}
+	if err := CreateWithOptions(srcDir, archivePath, opts); err != nil {
+		t.Fatalf("CreateWithOptions failed: %v", err)
+	}
+
+	want := []string{"web", "web/app.js", "web/keep.log"}
+	if got := readNames(t, archivePath); strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("entries = %v, want %v", got, want)
+	}
+}
+
+func TestCreateWithOptionsIncludeKeepsParents(t *testing.T) {
+	srcDir := t.TempDir()
+	createTestFiles(t, srcDir)
+
+	archivePath := filepath.Join(t.TempDir(), "test.tar")
+	if err := CreateWithOptions(srcDir, archivePath, CreateOptions{Include: []string{"*.txt"}}); err != nil {
+		t.Fatalf("CreateWithOptions failed: %v", err)
+	}
+
+	want := []string{"file.txt", "subdir", "subdir/nested.txt"}
+	if got := readNames(t, archivePath); strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("entries = %v, want %v", got, want)
+	}
+}
+
+func TestCreateWithOptionsMissingIgnoreFile(t *testing.T) {
+	srcDir := t.TempDir()
+	createTestFiles(t, srcDir)
+
+	archivePath := filepath.Join(t.TempDir(), "test.tar")
+	if err := CreateWithOptions(srcDir, archivePath, CreateOptions{IgnoreFile: IgnoreFileName}); err != nil {
+		t.Fatalf("CreateWithOptions failed: %v", err)
+	}
+	if got := readNames(t, archivePath); len(got) != 4 {
+		t.Fatalf("entries = %v, want all 4", got)
+	}
+}
+
+func TestCreateWithOptionsSymlinks(t *testing.T) {
+	srcDir := t.TempDir()
+	createTestFiles(t, srcDir)
+	if err := os.Symlink("../file.txt", filepath.Join(srcDir, "subdir", "link.txt")); err != nil {
+		t.Fatal(err)
+	}
+
+	archivePath := filepath.Join(t.TempDir(), "test.tar.zst")
+	if err := CreateWithOptions(srcDir, archivePath, CreateOptions{Symlinks: true}); err != nil {
+		t.Fatalf("CreateWithOptions failed: %v", err)
+	}
+
+	destDir := filepath.Join(t.TempDir(), "extracted")
+	if err := Extract(archivePath, destDir); err != nil {
+		t.Fatalf("Extract failed: %v", err)
+	}
+	target, err := os.Readlink(filepath.Join(destDir, "subdir", "link.txt"))
+	if err != nil {
+		t.Fatalf("symlink not extracted: %v", err)
+	}
+	if target != "../file.txt" {
+		t.Fatalf("symlink target = %q, want %q", target, "../file.txt")
+	}
+}
+
+func TestCreateWithOptionsSymlinkEscape(t *testing.T) {
+	for _, target := range []string{"../../outside", "/etc/passwd"} {
+		t.Run(target, func(t *testing.T) {
+			srcDir := t.TempDir()
+			createTestFiles(t, srcDir)
+			if err := os.Symlink(target, filepath.Join(srcDir, "subdir", "escape")); err != nil {
+				t.Fatal(err)
+			}
+
+			archivePath := filepath.Join(t.TempDir(), "test.tar")
+			err := CreateWithOptions(srcDir, archivePath, CreateOptions{Symlinks: true})
+			if !errors.Is(err, ErrInvalidPath) {
+				t.Fatalf("expected ErrInvalidPath, got: %v", err)
+			}
+		})
+	}
+}
+
+func TestCreateWithOptionsHardlinks(t *testing.T) {
+	srcDir := t.TempDir()
+	createTestFiles(t, srcDir)
+	if err := os.Link(filepath.Join(srcDir, "file.txt"), filepath.Join(srcDir, "subdir", "same.txt")); err != nil {
+		t.Skipf("hard links not supported: %v", err)
+	}
+
+	archivePath := filepath.Join(t.TempDir(), "test.tar")
+	if err := CreateWithOptions(srcDir, archivePath, CreateOptions{Hardlinks: true}); err != nil {
+		t.Fatalf("CreateWithOptions failed: %v", err)
+	}
+
+	f, err := os.Open(archivePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	tr := tar.NewReader(f)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			t.Fatal("hard link entry not found")
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if header.Name == "subdir/same.txt" {
+			if header.Typeflag != tar.TypeLink || header.Linkname != "file.txt" {
+				t.Fatalf("got type %c link %q, want hard link to file.txt", header.Typeflag, header.Linkname)
+			}
+			return
+		}
+	}
+}
+
+func readNames(t *testing.T, archivePath string) []string {
+	t.Helper()
+
+	f, err := os.Open(archivePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var names []string
+	tr := tar.NewReader(f)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return names
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, header.Name)
+	}
+}
+
+func TestCreateToDigest(t *testing.T) {
+	srcDir := t.TempDir()
+	createTestFiles(t, srcDir)
+
+	var buf bytes.Buffer
+	digest, size, err := CreateTo(&buf, srcDir, Zstd, CreateOptions{})
+	if err != nil {
+		t.Fatalf("CreateTo failed: %v", err)
+	}
+
+	sum := sha256.Sum256(buf.Bytes())
+	if digest.Algorithm != "sha256" || digest.Hash != hex.EncodeToString(sum[:]) {
+		t.Fatalf("digest = %s, want sha256:%x", digest.String(), sum)
+	}
+	if size != int64(buf.Len()) {
+		t.Fatalf("size = %d, want %d", size, buf.Len())
+	}
+
+	destDir := filepath.Join(t.TempDir(), "extracted")
+	if err := ExtractFrom(bytes.NewReader(buf.Bytes()), destDir, Zstd, ExtractOptions{Digest: &digest}); err != nil {
+		t.Fatalf("ExtractFrom failed: %v", err)
+	}
+	assertFileContent(t, filepath.Join(destDir, "subdir", "nested.txt"), "nested")
+}
+
+func TestExtractFromDigestMismatch(t *testing.T) {
+	srcDir := t.TempDir()
+	createTestFiles(t, srcDir)
+
+	var buf bytes.Buffer
+	digest, _, err := CreateTo(&buf, srcDir, Gzip, CreateOptions{})
+	if err != nil {
+		t.Fatalf("CreateTo failed: %v", err)
+	}
+	digest.Hash = strings.Repeat("0", 64)
+
+	destDir := filepath.Join(t.TempDir(), "extracted")
+	err = ExtractFrom(bytes.NewReader(buf.Bytes()), destDir, Gzip, ExtractOptions{Digest: &digest})
+	if !errors.Is(err, ErrDigestMismatch) {
+		t.Fatalf("expected ErrDigestMismatch, got: %v", err)
+	}
+	if _, err := os.Stat(destDir); !os.IsNotExist(err) {
+		t.Fatal("destination should be removed after a digest mismatch")
+	}
+}
+
+func TestExtractFromUnsupportedDigest(t *testing.T) {
+	destDir := filepath.Join(t.TempDir(), "extracted")
+	err := ExtractFrom(bytes.NewReader(nil), destDir, Tar, ExtractOptions{Digest: &reference.Digest{Algorithm: "md5", Hash: "00"}})
+	if !errors.Is(err, ErrUnsupportedDigest) {
+		t.Fatalf("expected ErrUnsupportedDigest, got: %v", err)
+	}
+}
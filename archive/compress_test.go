@@ -0,0 +1,245 @@
+package archive
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Compressible pseudo-random data of the given size.
+func testData(size int, seed uint64) []byte {
+	r := rand.New(rand.NewPCG(seed, seed))
+	words := []string{"crucible ", "archive ", "resource ", "machine ", "image ", "layer "}
+	var buf bytes.Buffer
+	for buf.Len() < size {
+		buf.WriteString(words[r.IntN(len(words))])
+	}
+	return buf.Bytes()[:size]
+}
+
+// Creates a tree of small files and one large file for concurrency tests and
+// benchmarks.
+func createTree(tb testing.TB, dir string, files, fileSize int) {
+	tb.Helper()
+
+	for i := range files {
+		sub := filepath.Join(dir, fmt.Sprintf("dir%02d", i%10))
+		if err := os.MkdirAll(sub, 0755); err != nil {
+			tb.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(sub, fmt.Sprintf("file%04d.txt", i)), testData(fileSize, uint64(i)), 0644); err != nil {
+			tb.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, "large.bin"), testData(3*gzipBlockSize+12345, 99), 0644); err != nil {
+		tb.Fatal(err)
+	}
+}
+
+// Fails unless two trees hold the same regular files.
+func assertSameTree(t *testing.T, want, got string) {
+	t.Helper()
+
+	err := filepath.WalkDir(want, func(path string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, _ := filepath.Rel(want, path)
+		a, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		b, err := os.ReadFile(filepath.Join(got, rel))
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(a, b) {
+			return fmt.Errorf("%s differs", rel)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestParallelGzipWriter(t *testing.T) {
+	for _, size := range []int{0, 100, gzipBlockSize, 3*gzipBlockSize + 777} {
+		t.Run(fmt.Sprint(size), func(t *testing.T) {
+			data := testData(size, 1)
+
+			var outputs [][]byte
+			for _, concurrency := range []int{2, 8} {
+				var buf bytes.Buffer
+				zw := newParallelGzipWriter(&buf, gzip.DefaultCompression, concurrency)
+				// Write in uneven chunks to cross block boundaries.
+				for p := data; len(p) > 0; {
+					n := min(len(p), 70000)
+					if _, err := zw.Write(p[:n]); err != nil {
+						t.Fatal(err)
+					}
+					p = p[n:]
+				}
+				if err := zw.Close(); err != nil {
+					t.Fatal(err)
+				}
+				outputs = append(outputs, buf.Bytes())
+			}
+
+			if !bytes.Equal(outputs[0], outputs[1]) {
+				t.Fatal("output depends on concurrency")
+			}
+
+			zr, err := gzip.NewReader(bytes.NewReader(outputs[0]))
+			if err != nil {
+				t.Fatal(err)
+			}
+			zr.Multistream(false)
+			got, err := io.ReadAll(zr)
+			if err != nil {
+				t.Fatalf("decompression failed: %v", err)
+			}
+			if !bytes.Equal(got, data) {
+				t.Fatal("round trip mismatch")
+			}
+		})
+	}
+}
+
+func TestCreateCompressionOptions(t *testing.T) {
+	srcDir := t.TempDir()
+	createTree(t, srcDir, 20, 4096)
+
+	for _, name := range []string{"test.tar.zst", "test.tar.gz", "test.zip"} {
+		t.Run(name, func(t *testing.T) {
+			opts := CreateOptions{Level: 9, Concurrency: 4, Reproducible: true}
+			if name == "test.tar.zst" {
+				opts.WindowSize = 1 << 20
+			}
+
+			var digests []string
+			for range 2 {
+				archivePath := filepath.Join(t.TempDir(), name)
+				if err := CreateWithOptions(srcDir, archivePath, opts); err != nil {
+					t.Fatalf("CreateWithOptions failed: %v", err)
+				}
+				data, err := os.ReadFile(archivePath)
+				if err != nil {
+					t.Fatal(err)
+				}
+				digests = append(digests, string(data))
+
+				destDir := filepath.Join(t.TempDir(), "extracted")
+				if err := Extract(archivePath, destDir); err != nil {
+					t.Fatalf("Extract failed: %v", err)
+				}
+				assertSameTree(t, srcDir, destDir)
+			}
+			if digests[0] != digests[1] {
+				t.Fatal("reproducible output differs between runs")
+			}
+		})
+	}
+}
+
+func TestCreateInvalidCompressionOptions(t *testing.T) {
+	srcDir := t.TempDir()
+	createTestFiles(t, srcDir)
+
+	tests := []struct {
+		name string
+		opts CreateOptions
+		want error
+	}{
+		{"test.tar.zst", CreateOptions{Level: 23}, ErrInvalidLevel},
+		{"test.tar.gz", CreateOptions{Level: 10}, ErrInvalidLevel},
+		{"test.zip", CreateOptions{Level: -3}, ErrInvalidLevel},
+		{"test.tar.zst", CreateOptions{WindowSize: 1000}, ErrInvalidWindowSize},
+	}
+
+	for _, tt := range tests {
+		err := CreateWithOptions(srcDir, filepath.Join(t.TempDir(), tt.name), tt.opts)
+		if !errors.Is(err, tt.want) {
+			t.Errorf("%s %+v: expected %v, got: %v", tt.name, tt.opts, tt.want, err)
+		}
+	}
+}
+
+func TestExtractConcurrent(t *testing.T) {
+	srcDir := t.TempDir()
+	createTree(t, srcDir, 100, 8192)
+	if err := os.Link(filepath.Join(srcDir, "dir00", "file0000.txt"), filepath.Join(srcDir, "hard.txt")); err != nil {
+		t.Fatal(err)
+	}
+
+	archivePath := filepath.Join(t.TempDir(), "test.tar.gz")
+	if err := CreateWithOptions(srcDir, archivePath, CreateOptions{Hardlinks: true, Concurrency: 4}); err != nil {
+		t.Fatalf("CreateWithOptions failed: %v", err)
+	}
+
+	for _, mode := range []ExtractMode{ExtractFail, ExtractMirror} {
+		destDir := filepath.Join(t.TempDir(), "extracted")
+		opts := ExtractOptions{Concurrency: 8, Mode: mode, PreserveTimes: true}
+		if err := ExtractWithOptions(archivePath, destDir, opts); err != nil {
+			t.Fatalf("mode %d: ExtractWithOptions failed: %v", mode, err)
+		}
+		assertSameTree(t, srcDir, destDir)
+	}
+}
+
+func BenchmarkCreateGzip(b *testing.B) {
+	srcDir := b.TempDir()
+	createTree(b, srcDir, 200, 64<<10)
+
+	for _, concurrency := range []int{1, 4, 8} {
+		b.Run(fmt.Sprintf("concurrency=%d", concurrency), func(b *testing.B) {
+			for b.Loop() {
+				if _, _, err := CreateTo(io.Discard, srcDir, Gzip, CreateOptions{Concurrency: concurrency}); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkCreateZstd(b *testing.B) {
+	srcDir := b.TempDir()
+	createTree(b, srcDir, 200, 64<<10)
+
+	for _, concurrency := range []int{1, 4} {
+		b.Run(fmt.Sprintf("concurrency=%d", concurrency), func(b *testing.B) {
+			for b.Loop() {
+				if _, _, err := CreateTo(io.Discard, srcDir, Zstd, CreateOptions{Concurrency: concurrency}); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkExtract(b *testing.B) {
+	srcDir := b.TempDir()
+	createTree(b, srcDir, 500, 16<<10)
+
+	archivePath := filepath.Join(b.TempDir(), "bench.tar.zst")
+	if err := Create(srcDir, archivePath); err != nil {
+		b.Fatal(err)
+	}
+
+	for _, concurrency := range []int{1, 4, 8} {
+		b.Run(fmt.Sprintf("concurrency=%d", concurrency), func(b *testing.B) {
+			for b.Loop() {
+				destDir := filepath.Join(b.TempDir(), "extracted")
+				if err := ExtractWithOptions(archivePath, destDir, ExtractOptions{Concurrency: concurrency}); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
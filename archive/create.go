@@ -2,24 +2,133 @@ package archive
 
 import (
 	"archive/tar"
+	"context"
 	"io"
 	"io/fs"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/cruciblehq/crex"
+	"github.com/cruciblehq/spec/reference"
 )
 
+// Options controlling archive creation.
+//
+// The zero value creates an archive with the metadata of the source files, as
+// [Create] does.
+type CreateOptions struct {
+	Reproducible bool      // Normalize entry metadata so that the output depends only on paths, contents, and permissions.
+	ModTime      time.Time // Modification time of every entry in reproducible mode (zero to use SOURCE_DATE_EPOCH).
+	IgnoreFile   string    // Ignore file in src, in gitignore syntax (empty for none; see [IgnoreFileName]).
+	Include      []string  // Patterns of paths to archive (empty to archive everything).
+	Exclude      []string  // Patterns of paths to leave out.
+	Symlinks     bool      // Archive symlinks instead of rejecting them.
+	Hardlinks    bool      // Store files with several links to them once, as hard links.
+	SpecialBits  bool      // Keep setuid, setgid, and sticky bits in entry modes.
+	Devices      bool      // Archive device files and named pipes instead of rejecting them.
+	Xattrs       bool      // Capture extended attributes as PAX records (Linux only).
+	Level        int       // Compression level (0 for the format's default; see [CreateWithOptions]).
+	Concurrency  int       // Number of goroutines compressing in parallel (0 for one, or GOMAXPROCS for non-reproducible Zstandard).
+	WindowSize   int       // Zstandard window size in bytes, a power of two (0 for the encoder's default).
+
+	Progress ProgressFunc // Receives progress reports, counting file contents read (nil for none).
+}
+
 // Creates a compressed tar archive from a directory.
 //
-// The compression format is detected from the dest filename extension (see
-// [Format]). The archive contains all files and directories under src with
-// paths stored relative to src. Paths in the archive use forward slashes
-// regardless of the host operating system. Only regular files and directories
-// are archivable; symlinks and other special file types such as devices and
-// sockets will cause the function to return [ErrUnsupportedFileType]. If
-// creation fails, the partially written archive is removed.
-func Create(src, dest string) (err error) {
+// The format is detected from the dest filename extension (see [Format]);
+// formats that cannot be written return [ErrUnsupportedFormat]. The archive
+// contains all files and directories under src with paths stored relative to
+// src. Paths in the archive use forward slashes regardless of the host
+// operating system. Only regular files and directories are archivable;
+// symlinks and other special file types such as devices and sockets will
+// cause the function to return [ErrUnsupportedFileType]. If creation fails,
+// the partially written archive is removed.
+func Create(src, dest string) error {
+	return CreateWithOptions(src, dest, CreateOptions{})
+}
+
+// Creates a compressed tar archive from a directory with options.
+//
+// Behaves like [Create]. Entries are always written in lexical path order.
+//
+// The paths to archive are selected with the ignore file, then the Exclude
+// patterns, then the Include patterns, all relative to src and using the
+// gitignore syntax (see [IgnoreFileName]). A path matched by the ignore file
+// or by an Exclude pattern is left out, and so is everything beneath an
+// excluded directory. When Include is set, only paths matching one of its
+// patterns, or lying beneath a matching directory, are archived, along with
+// the directories leading to them. A missing ignore file is not an error.
+// Malformed patterns return [ErrInvalidPattern].
+//
+// With Symlinks set, symlinks are archived as such rather than followed; a
+// symlink whose target is absolute or resolves outside src returns
+// [ErrInvalidPath]. With Hardlinks set, a file reached through several hard
+// links is stored in full under the first path in lexical order and as a
+// hard link under the others. Hard links are only detected on Unix systems,
+// and zip archives, which cannot represent them, store every file in full.
+//
+// Zstandard archives are written in a seekable layout: each entry is
+// compressed as a separate frame, followed by a skippable frame indexing
+// them, so [OpenFS] can read an entry without decompressing the ones before
+// it. Other Zstandard tools ignore the index and see an ordinary archive.
+//
+// Unless Reproducible is set, entries record the numeric owner and group IDs
+// and names, and modification times, of the source files. With SpecialBits
+// set, setuid, setgid, and sticky bits are kept. With Devices set, character
+// and block devices and named pipes are archived with their device numbers;
+// sockets are still rejected. With Xattrs set, the extended attributes of
+// files and directories, including security.capability, are stored as PAX
+// records in the form "SCHILY.xattr.<name>"; symlink attributes are not
+// captured, and no attributes are captured on platforms other than Linux.
+// These are restored by extraction with the matching [ExtractOptions].
+// Zip archives hold none of this metadata.
+//
+// Compression is tuned with Level, Concurrency, and WindowSize. Zstandard
+// levels range from 1 to 22 as on the zstd command line, and gzip and zip
+// levels from 1 to 9; other levels return [ErrInvalidLevel]. Plain tar
+// archives ignore the level. With Concurrency above one, Zstandard encodes
+// blocks of large entries in parallel, and gzip archives are compressed in
+// parallel blocks of 1 MiB, each primed with the end of the previous one,
+// which yields a standard gzip stream that is slightly larger than a
+// sequential one. WindowSize sets the Zstandard window, which decoders must
+// be able to allocate; an invalid size returns [ErrInvalidWindowSize].
+//
+// With Reproducible set, creating an archive from the same tree twice yields
+// identical bytes, and therefore the same digest, on any host:
+//
+//   - Every entry's modification time is opts.ModTime, truncated to seconds.
+//     If it is zero, the SOURCE_DATE_EPOCH environment variable is used, and
+//     the Unix epoch if that is unset. A malformed SOURCE_DATE_EPOCH returns
+//     [ErrSourceDateEpoch].
+//   - Owner and group IDs are zero and owner and group names are empty.
+//   - Headers use the PAX format without access or change times, so long
+//     names are encoded the same way everywhere.
+//   - Compressor parameters are pinned: Zstandard and gzip at their default
+//     levels unless Level is set, Zstandard with one goroutine unless
+//     Concurrency is set, and gzip with an empty header.
+//
+// The output then depends only on the paths, contents, and permissions of
+// the files, on the compression options, and on the version of the
+// compression libraries.
+//
+// If opts.Progress is set, it receives the number of entries written and of
+// bytes of file contents read so far. The total is not known in advance and
+// is reported as -1.
+func CreateWithOptions(src, dest string, opts CreateOptions) error {
+	return CreateContext(context.Background(), src, dest, opts)
+}
+
+// Creates a compressed tar archive from a directory with a context.
+//
+// Behaves like [CreateWithOptions], and stops once ctx is cancelled, between
+// entries or while file contents are being copied. The partially written
+// archive is then removed, as on any other error, and the returned error
+// wraps the context's error.
+func CreateContext(ctx context.Context, src, dest string, opts CreateOptions) (err error) {
 	fmt, err := detect(dest)
 	if err != nil {
 		return crex.Wrap(ErrCreateFailed, err)
@@ -29,39 +138,239 @@ func Create(src, dest string) (err error) {
 	if err != nil {
 		return crex.Wrap(ErrCreateFailed, err)
 	}
-	defer file.Close()
-
-	cw, err := newCompressWriter(file, fmt)
-	if err != nil {
-		os.Remove(dest)
-		return crex.Wrap(ErrCreateFailed, err)
-	}
 	defer func() {
-		cw.Close()
+		file.Close()
 		if err != nil {
 			os.Remove(dest)
 		}
 	}()
 
-	tw := tar.NewWriter(cw)
-	defer tw.Close()
-
-	if err = writeTar(tw, src); err != nil {
+	if err = writeArchive(ctx, file, src, fmt, opts); err != nil {
 		return crex.Wrap(ErrCreateFailed, err)
 	}
 
 	return nil
 }
 
-// Writes directory contents to a tar writer.
+// Streams a compressed tar archive of a directory to a writer.
+//
+// Behaves like [CreateWithOptions], writing to w in the given format instead
+// of a named file. Returns the SHA-256 digest and size in bytes of the
+// compressed output, computed as it is written, so the archive can be
+// uploaded without being stored and hashed separately. Nothing is written to
+// w after an error, but what was written before it is not retracted.
+func CreateTo(w io.Writer, src string, f Format, opts CreateOptions) (reference.Digest, int64, error) {
+	return CreateToContext(context.Background(), w, src, f, opts)
+}
+
+// Streams a compressed tar archive of a directory to a writer with a context.
+//
+// Behaves like [CreateTo], and stops once ctx is cancelled, as
+// [CreateContext] does. What was written to w before cancellation is not
+// retracted.
+func CreateToContext(ctx context.Context, w io.Writer, src string, f Format, opts CreateOptions) (reference.Digest, int64, error) {
+	dw := newDigestWriter(w)
+	if err := writeArchive(ctx, dw, src, f, opts); err != nil {
+		return reference.Digest{}, 0, crex.Wrap(ErrCreateFailed, err)
+	}
+	return dw.digest(), dw.n, nil
+}
+
+// Writes a compressed tar archive of a directory to w.
 //
-// Walks src directory recursively and writes each entry to tw. Paths in the
-// archive are relative to src and use forward slashes.
-func writeTar(tw *tar.Writer, src string) error {
+// The tar and compression streams are closed before returning, so w holds
+// the complete archive on success. Final progress is reported once they are.
+func writeArchive(ctx context.Context, w io.Writer, src string, f Format, opts CreateOptions) error {
+	if !f.Writable() {
+		return ErrUnsupportedFormat
+	}
+
+	hdr, err := newHeaderFunc(opts)
+	if err != nil {
+		return err
+	}
+
+	t := newTracker(ctx, opts.Progress, -1)
+	if err := writeEntries(w, src, f, hdr, opts, t); err != nil {
+		return err
+	}
+
+	t.done()
+	return nil
+}
+
+// Writes the entries of a directory to w in format f, closing the tar and
+// compression streams.
+func writeEntries(w io.Writer, src string, f Format, hdr headerFunc, opts CreateOptions, t *tracker) error {
+	if f == Zip {
+		opts.Hardlinks = false
+		zw, err := newZipWriter(w, opts.Level)
+		if err != nil {
+			return err
+		}
+		if err := writeTar(zw, src, hdr, opts, t); err != nil {
+			zw.Close()
+			return err
+		}
+		return zw.Close()
+	}
+
+	if f == Zstd {
+		sw, err := newSeekableWriter(w, opts)
+		if err != nil {
+			return err
+		}
+		if err := writeTar(sw, src, hdr, opts, t); err != nil {
+			sw.Close()
+			return err
+		}
+		return sw.Close()
+	}
+
+	cw, err := newCompressWriter(w, f, opts)
+	if err != nil {
+		return err
+	}
+
+	tw := tar.NewWriter(cw)
+	if err := writeTar(tw, src, hdr, opts, t); err != nil {
+		tw.Close()
+		cw.Close()
+		return err
+	}
+	if err := tw.Close(); err != nil {
+		cw.Close()
+		return err
+	}
+	return cw.Close()
+}
+
+// Builds the tar header of an entry from its file info, archive path, and
+// symlink target.
+type headerFunc func(info fs.FileInfo, name, link string) (*tar.Header, error)
+
+// Returns the header function for the given options.
+func newHeaderFunc(opts CreateOptions) (headerFunc, error) {
+	if !opts.Reproducible {
+		return fileHeader, nil
+	}
+
+	modTime := opts.ModTime
+	if modTime.IsZero() {
+		epoch, err := sourceDateEpoch()
+		if err != nil {
+			return nil, err
+		}
+		modTime = epoch
+	}
+	modTime = modTime.Truncate(time.Second)
+
+	return func(info fs.FileInfo, name, link string) (*tar.Header, error) {
+		return reproducibleHeader(info, name, link, modTime), nil
+	}, nil
+}
+
+// Builds a header carrying the source file's metadata.
+//
+// Special bits (setuid, setgid, sticky) are stripped from the mode.
+func fileHeader(info fs.FileInfo, name, link string) (*tar.Header, error) {
+	header, err := tar.FileInfoHeader(info, link)
+	if err != nil {
+		return nil, err
+	}
+	header.Name = name
+	header.Mode = int64(info.Mode().Perm())
+	return header, nil
+}
+
+// Builds a header that depends only on the entry's path, type, size, link
+// target, and permissions.
+func reproducibleHeader(info fs.FileInfo, name, link string, modTime time.Time) *tar.Header {
+	header := &tar.Header{
+		Name:    name,
+		Mode:    int64(info.Mode().Perm()),
+		ModTime: modTime,
+		Format:  tar.FormatPAX,
+	}
+	switch {
+	case info.IsDir():
+		header.Typeflag = tar.TypeDir
+	case info.Mode()&os.ModeSymlink != 0:
+		header.Typeflag = tar.TypeSymlink
+		header.Linkname = link
+	case info.Mode()&(os.ModeDevice|os.ModeNamedPipe) != 0:
+		// Device numbers come from the platform-specific stat data.
+		if fh, err := tar.FileInfoHeader(info, ""); err == nil {
+			header.Typeflag, header.Devmajor, header.Devminor = fh.Typeflag, fh.Devmajor, fh.Devminor
+		}
+	default:
+		header.Typeflag = tar.TypeReg
+		header.Size = info.Size()
+	}
+	return header
+}
+
+// Timestamp given by the SOURCE_DATE_EPOCH environment variable.
+//
+// Returns the Unix epoch if the variable is unset or empty, and
+// [ErrSourceDateEpoch] if it is not a non-negative integer.
+func sourceDateEpoch() (time.Time, error) {
+	v := os.Getenv("SOURCE_DATE_EPOCH")
+	if v == "" {
+		return time.Unix(0, 0), nil
+	}
+	sec, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || sec < 0 {
+		return time.Time{}, ErrSourceDateEpoch
+	}
+	return time.Unix(sec, 0), nil
+}
+
+// State of a walk writing a directory tree to a tar writer.
+type tarWalker struct {
+	tw       entryWriter
+	src      string
+	hdr      headerFunc
+	filter   *filter           // Paths to archive (nil to archive everything).
+	symlinks bool              // Whether symlinks are archived rather than rejected.
+	special  bool              // Whether setuid, setgid, and sticky bits are kept.
+	devices  bool              // Whether devices and named pipes are archived rather than rejected.
+	xattrs   bool              // Whether extended attributes are captured.
+	links    map[fileID]string // Archive paths of multiply linked files (nil to store every file in full).
+	pending  []pendingDir      // Directories awaiting an included descendant, outermost first.
+	t        *tracker
+}
+
+// Directory whose entry is written only if something beneath it is.
+type pendingDir struct {
+	name string
+	info fs.FileInfo
+}
+
+// Writes directory contents to an archive.
+//
+// Walks src directory recursively in lexical order and writes each entry to
+// tw. Paths in the archive are relative to src and use forward slashes. The
+// walk stops with the context's error once the tracker's context is
+// cancelled.
+func writeTar(tw entryWriter, src string, hdr headerFunc, opts CreateOptions, t *tracker) error {
+	f, err := newFilter(src, opts)
+	if err != nil {
+		return err
+	}
+
+	w := &tarWalker{tw: tw, src: src, hdr: hdr, filter: f, symlinks: opts.Symlinks, special: opts.SpecialBits, devices: opts.Devices, xattrs: opts.Xattrs, t: t}
+	if opts.Hardlinks {
+		w.links = make(map[fileID]string)
+	}
+
 	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
 		if err != nil {
 			return err
 		}
+		if err := t.check(); err != nil {
+			return err
+		}
 
 		relPath, err := filepath.Rel(src, path)
 		if err != nil {
@@ -72,16 +381,67 @@ func writeTar(tw *tar.Writer, src string) error {
 			return nil
 		}
 
-		return writeEntry(tw, path, relPath, d)
+		return w.visit(path, filepath.ToSlash(relPath), d)
 	})
 }
 
+// Decides whether an entry is archived, and writes it if so.
+//
+// Excluded directories are skipped along with their contents. When an include
+// filter is set, directories that do not match it are held back until an
+// included entry beneath them is written, so that only the directories
+// leading to included entries are archived.
+func (w *tarWalker) visit(path, name string, d fs.DirEntry) error {
+	if w.filter == nil {
+		return w.writeEntry(path, name, d)
+	}
+
+	for len(w.pending) > 0 && !strings.HasPrefix(name, w.pending[len(w.pending)-1].name+"/") {
+		w.pending = w.pending[:len(w.pending)-1]
+	}
+
+	if w.filter.excluded(name, d.IsDir()) {
+		if d.IsDir() {
+			return fs.SkipDir
+		}
+		return nil
+	}
+
+	if !w.filter.included(name, d.IsDir()) {
+		if d.IsDir() {
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			w.pending = append(w.pending, pendingDir{name, info})
+		}
+		return nil
+	}
+
+	for _, dir := range w.pending {
+		header, err := w.header(filepath.Join(w.src, filepath.FromSlash(dir.name)), dir.name, "", dir.info)
+		if err != nil {
+			return err
+		}
+		if err := w.tw.WriteHeader(header); err != nil {
+			return err
+		}
+		w.t.entry()
+	}
+	w.pending = w.pending[:0]
+
+	return w.writeEntry(path, name, d)
+}
+
 // Writes a single entry to the tar writer.
 //
 // Validates file type, creates tar header with normalized path and permissions,
-// and writes file contents for regular files. Returns [ErrUnsupportedFileType]
-// for symlinks and special files.
-func writeEntry(tw *tar.Writer, path, relPath string, d fs.DirEntry) error {
+// and writes file contents for regular files. Symlinks are archived when
+// enabled, after checking that their target stays within the source tree, and
+// rejected with [ErrUnsupportedFileType] otherwise. Files already archived
+// under another name are stored as hard links when enabled. Special files
+// return [ErrUnsupportedFileType].
+func (w *tarWalker) writeEntry(path, name string, d fs.DirEntry) error {
 	info, err := d.Info()
 	if err != nil {
 		return err
@@ -89,38 +449,113 @@ func writeEntry(tw *tar.Writer, path, relPath string, d fs.DirEntry) error {
 
 	mode := info.Mode()
 
-	if mode&os.ModeSymlink != 0 || (!mode.IsRegular() && !mode.IsDir()) {
+	var link string
+	switch {
+	case mode&os.ModeSymlink != 0:
+		if !w.symlinks {
+			return ErrUnsupportedFileType
+		}
+		if link, err = readSymlink(path, name); err != nil {
+			return err
+		}
+	case mode&(os.ModeDevice|os.ModeNamedPipe) != 0:
+		if !w.devices {
+			return ErrUnsupportedFileType
+		}
+	case !mode.IsRegular() && !mode.IsDir():
 		return ErrUnsupportedFileType
 	}
 
-	header, err := tar.FileInfoHeader(info, "")
+	header, err := w.header(path, name, link, info)
 	if err != nil {
 		return err
 	}
 
-	// Override name and strip special bits (setuid, setgid, sticky)
-	header.Name = filepath.ToSlash(relPath)
-	header.Mode = int64(info.Mode().Perm())
+	if w.links != nil && mode.IsRegular() {
+		if id, ok := linkID(info); ok {
+			if first, seen := w.links[id]; seen {
+				header.Typeflag = tar.TypeLink
+				header.Linkname = first
+				header.Size = 0
+			} else {
+				w.links[id] = name
+			}
+		}
+	}
 
-	if err := tw.WriteHeader(header); err != nil {
+	if err := w.tw.WriteHeader(header); err != nil {
 		return err
 	}
 
-	if mode.IsRegular() {
-		return copyFile(tw, path)
+	if header.Typeflag == tar.TypeReg {
+		if err := copyFile(w.tw, path, header.Size, w.t); err != nil {
+			return err
+		}
 	}
 
+	w.t.entry()
 	return nil
 }
 
-// Copies file contents from path to w.
-func copyFile(w io.Writer, path string) error {
+// Builds the header of an entry, adding the metadata selected by the options.
+func (w *tarWalker) header(path, name, link string, info fs.FileInfo) (*tar.Header, error) {
+	header, err := w.hdr(info, name, link)
+	if err != nil {
+		return nil, err
+	}
+
+	if w.special {
+		header.Mode |= specialBits(info.Mode())
+	}
+
+	if w.xattrs && info.Mode()&os.ModeSymlink == 0 {
+		attrs, err := readXattrs(path)
+		if err != nil {
+			return nil, err
+		}
+		for attr, value := range attrs {
+			if header.PAXRecords == nil {
+				header.PAXRecords = make(map[string]string)
+			}
+			header.PAXRecords[paxXattr+attr] = value
+		}
+	}
+
+	return header, nil
+}
+
+// Reads the target of a symlink being archived.
+//
+// The target is returned with forward slashes. Absolute targets, and
+// relative targets that resolve outside the source tree, return
+// [ErrInvalidPath], mirroring the checks applied on extraction.
+func readSymlink(path, name string) (string, error) {
+	target, err := os.Readlink(path)
+	if err != nil {
+		return "", err
+	}
+	if filepath.IsAbs(target) || strings.HasPrefix(filepath.ToSlash(target), "/") {
+		return "", ErrInvalidPath
+	}
+	resolved := filepath.Join(filepath.Dir(filepath.FromSlash(name)), target)
+	if resolved != "." && !filepath.IsLocal(resolved) {
+		return "", ErrInvalidPath
+	}
+	return filepath.ToSlash(target), nil
+}
+
+// Copies size bytes of file contents from path to w.
+//
+// Copying exactly the size recorded in the header keeps the archive valid if
+// the file grows while it is being read. The bytes are counted by t, and the
+// copy stops once its context is cancelled.
+func copyFile(w io.Writer, path string, size int64, t *tracker) error {
 	f, err := os.Open(path)
 	if err != nil {
 		return err
 	}
 	defer f.Close()
 
-	_, err = io.Copy(w, f)
+	_, err = io.CopyN(w, t.reader(f), size)
 	return err
 }
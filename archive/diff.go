@@ -0,0 +1,340 @@
+package archive
+
+import (
+	"archive/tar"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/cruciblehq/crex"
+)
+
+// Kind of change between two trees.
+type ChangeKind string
+
+const (
+	ChangeAdded    ChangeKind = "added"    // Entry only present in the new tree.
+	ChangeRemoved  ChangeKind = "removed"  // Entry only present in the old tree.
+	ChangeModified ChangeKind = "modified" // Entry present in both trees with different attributes.
+)
+
+// Attribute of an entry compared by [Diff].
+type DiffField string
+
+const (
+	FieldType    DiffField = "type"    // Entry type.
+	FieldMode    DiffField = "mode"    // Permission and special bits.
+	FieldSize    DiffField = "size"    // Size of a regular file.
+	FieldContent DiffField = "content" // Digest of a regular file's contents.
+	FieldLink    DiffField = "link"    // Target of a symlink.
+)
+
+// Type of an entry compared by [Diff].
+type EntryType string
+
+const (
+	EntryFile    EntryType = "file"    // Regular file.
+	EntryDir     EntryType = "dir"     // Directory.
+	EntrySymlink EntryType = "symlink" // Symbolic link.
+	EntryLink    EntryType = "link"    // Hard link whose target is not in the tree.
+	EntryDevice  EntryType = "device"  // Character or block device.
+	EntryFifo    EntryType = "fifo"    // Named pipe.
+	EntryOther   EntryType = "other"   // Any other entry type.
+)
+
+// Attributes of an entry compared by [Diff].
+type DiffEntry struct {
+	Type   EntryType `json:"type"`             // Entry type.
+	Mode   int64     `json:"mode"`             // Permission and special bits, as in tar headers.
+	Size   int64     `json:"size,omitempty"`   // Size of a regular file in bytes.
+	Digest string    `json:"digest,omitempty"` // Digest of a regular file's contents, as "sha256:<hex>".
+	Link   string    `json:"link,omitempty"`   // Target of a symlink.
+}
+
+// Difference of one entry between two trees.
+//
+// Old is nil for added entries and New for removed ones. Fields lists the
+// attributes that differ between modified entries.
+type Change struct {
+	Path   string      `json:"path"`             // Slash-separated path relative to the root.
+	Kind   ChangeKind  `json:"kind"`             // Kind of change.
+	Old    *DiffEntry  `json:"old,omitempty"`    // Entry in the old tree.
+	New    *DiffEntry  `json:"new,omitempty"`    // Entry in the new tree.
+	Fields []DiffField `json:"fields,omitempty"` // Attributes that differ.
+}
+
+// Compares two archives, or an archive and a directory.
+//
+// Each of oldSrc and newSrc is a directory or an archive file in any format
+// [Extract] accepts. Entries are matched by path, after stripping a leading
+// "./" and trailing slashes, and compared by type, permission and special
+// bits, size and SHA-256 digest of regular file contents, and symlink
+// target. Times, ownership, and extended attributes are not compared. A hard
+// link is compared as the file it links to, so an archive created with
+// [CreateOptions].Hardlinks matches the directory it was created from. If an
+// archive holds several entries with the same path, the last one counts.
+//
+// Neither input is extracted: archives are streamed and file contents hashed
+// as they are read, so memory grows with the number of entries rather than
+// their size. Changes are returned sorted by path, and the result is empty
+// if the trees are the same. The returned changes marshal to JSON as they
+// are, and [WriteDiff] renders them as text. Failures to read either input
+// return [ErrReadFailed].
+func Diff(oldSrc, newSrc string) ([]Change, error) {
+	oldTree, err := readTree(oldSrc)
+	if err != nil {
+		return nil, err
+	}
+	newTree, err := readTree(newSrc)
+	if err != nil {
+		return nil, err
+	}
+	return compareTrees(oldTree, newTree), nil
+}
+
+// Writes changes as text, one line per change.
+//
+// Added entries are prefixed with "+", removed ones with "-", and modified
+// ones with "~" followed by the old and new values of the attributes that
+// differ:
+//
+//	~ bin/config.yaml: mode 0644 -> 0600, size 12 -> 14, content sha256:1a… -> sha256:9f…
+//	+ bin/tool (file)
+//	- old.txt (file)
+func WriteDiff(w io.Writer, changes []Change) error {
+	for _, c := range changes {
+		if _, err := fmt.Fprintln(w, c.String()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Renders the change as a line of text, as [WriteDiff] does.
+func (c Change) String() string {
+	switch c.Kind {
+	case ChangeAdded:
+		return fmt.Sprintf("+ %s (%s)", c.Path, c.New.Type)
+	case ChangeRemoved:
+		return fmt.Sprintf("- %s (%s)", c.Path, c.Old.Type)
+	}
+
+	parts := make([]string, 0, len(c.Fields))
+	for _, field := range c.Fields {
+		var before, after string
+		switch field {
+		case FieldType:
+			before, after = string(c.Old.Type), string(c.New.Type)
+		case FieldMode:
+			before, after = fmt.Sprintf("%04o", c.Old.Mode), fmt.Sprintf("%04o", c.New.Mode)
+		case FieldSize:
+			before, after = fmt.Sprint(c.Old.Size), fmt.Sprint(c.New.Size)
+		case FieldContent:
+			before, after = c.Old.Digest, c.New.Digest
+		case FieldLink:
+			before, after = c.Old.Link, c.New.Link
+		}
+		parts = append(parts, fmt.Sprintf("%s %s -> %s", field, before, after))
+	}
+	return fmt.Sprintf("~ %s: %s", c.Path, strings.Join(parts, ", "))
+}
+
+// Compares the entries of two trees keyed by path.
+func compareTrees(oldTree, newTree map[string]*DiffEntry) []Change {
+	changes := []Change{}
+
+	for name, before := range oldTree {
+		after, ok := newTree[name]
+		if !ok {
+			changes = append(changes, Change{Path: name, Kind: ChangeRemoved, Old: before})
+			continue
+		}
+		if fields := compareEntries(before, after); len(fields) > 0 {
+			changes = append(changes, Change{Path: name, Kind: ChangeModified, Old: before, New: after, Fields: fields})
+		}
+	}
+	for name, after := range newTree {
+		if _, ok := oldTree[name]; !ok {
+			changes = append(changes, Change{Path: name, Kind: ChangeAdded, New: after})
+		}
+	}
+
+	slices.SortFunc(changes, func(a, b Change) int {
+		return strings.Compare(a.Path, b.Path)
+	})
+	return changes
+}
+
+// Attributes that differ between two entries at the same path.
+//
+// The size and content of regular files, and the target of symlinks, are
+// only compared between entries of the same type.
+func compareEntries(before, after *DiffEntry) []DiffField {
+	var fields []DiffField
+	if before.Type != after.Type {
+		fields = append(fields, FieldType)
+	}
+	if before.Mode != after.Mode {
+		fields = append(fields, FieldMode)
+	}
+	if before.Type != after.Type {
+		return fields
+	}
+	if before.Size != after.Size {
+		fields = append(fields, FieldSize)
+	}
+	if before.Digest != after.Digest {
+		fields = append(fields, FieldContent)
+	}
+	if before.Link != after.Link {
+		fields = append(fields, FieldLink)
+	}
+	return fields
+}
+
+// Reads the entries of a directory or archive file, keyed by path.
+func readTree(src string) (map[string]*DiffEntry, error) {
+	info, err := os.Stat(src)
+	if err != nil {
+		return nil, crex.Wrap(ErrReadFailed, err)
+	}
+	if info.IsDir() {
+		tree, err := readDirTree(src)
+		if err != nil {
+			return nil, crex.Wrap(ErrReadFailed, err)
+		}
+		return tree, nil
+	}
+	return readArchiveTree(src)
+}
+
+// Reads the entries of an archive file, hashing file contents as they are
+// streamed.
+func readArchiveTree(src string) (map[string]*DiffEntry, error) {
+	tree := make(map[string]*DiffEntry)
+	err := walkArchive(src, func(header *tar.Header, r io.Reader) (bool, error) {
+		name := entryName(header.Name)
+		if name == "" || name == "." {
+			return false, nil
+		}
+
+		e := &DiffEntry{Mode: header.Mode & 0o7777}
+		switch header.Typeflag {
+		case tar.TypeReg:
+			digest, err := hashContents(r)
+			if err != nil {
+				return false, err
+			}
+			e.Type, e.Size, e.Digest = EntryFile, header.Size, digest
+		case tar.TypeDir:
+			e.Type = EntryDir
+		case tar.TypeSymlink:
+			e.Type, e.Link = EntrySymlink, header.Linkname
+		case tar.TypeLink:
+			target, ok := tree[entryName(header.Linkname)]
+			if !ok {
+				e.Type, e.Link = EntryLink, header.Linkname
+				break
+			}
+			linked := *target
+			e = &linked
+		case tar.TypeChar, tar.TypeBlock:
+			e.Type = EntryDevice
+		case tar.TypeFifo:
+			e.Type = EntryFifo
+		case tar.TypeXHeader, tar.TypeXGlobalHeader, tar.TypeGNULongName, tar.TypeGNULongLink:
+			return false, nil
+		default:
+			e.Type = EntryOther
+		}
+
+		tree[name] = e
+		return false, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return tree, nil
+}
+
+// Reads the entries of a directory, hashing the contents of regular files.
+//
+// Symlinks are read rather than followed.
+func readDirTree(src string) (map[string]*DiffEntry, error) {
+	tree := make(map[string]*DiffEntry)
+	err := filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		if rel == "." {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		mode := info.Mode()
+		e := &DiffEntry{Mode: int64(mode.Perm()) | specialBits(mode)}
+		switch {
+		case mode.IsRegular():
+			digest, err := hashFile(path)
+			if err != nil {
+				return err
+			}
+			e.Type, e.Size, e.Digest = EntryFile, info.Size(), digest
+		case mode.IsDir():
+			e.Type = EntryDir
+		case mode&os.ModeSymlink != 0:
+			target, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			e.Type, e.Link = EntrySymlink, filepath.ToSlash(target)
+		case mode&os.ModeDevice != 0:
+			e.Type = EntryDevice
+		case mode&os.ModeNamedPipe != 0:
+			e.Type = EntryFifo
+		default:
+			e.Type = EntryOther
+		}
+
+		tree[filepath.ToSlash(rel)] = e
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return tree, nil
+}
+
+// Digest of the contents of a file.
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	return hashContents(f)
+}
+
+// Digest of the bytes read from r.
+func hashContents(r io.Reader) (string, error) {
+	dw := newDigestWriter(io.Discard)
+	if _, err := io.Copy(dw, r); err != nil {
+		return "", err
+	}
+	d := dw.digest()
+	return d.String(), nil
+}
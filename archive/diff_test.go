@@ -0,0 +1,162 @@
+package archive
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// Creates two versions of a tree exercising every kind of change.
+func createDiffTrees(t *testing.T) (string, string) {
+	t.Helper()
+
+	v1, v2 := t.TempDir(), t.TempDir()
+	for _, dir := range []string{v1, v2} {
+		if err := os.MkdirAll(filepath.Join(dir, "bin"), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "same.txt"), []byte("unchanged"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write := func(dir, name, content string, mode os.FileMode) {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), mode); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(v1, "removed.txt", "gone", 0644)
+	write(v2, "bin/added", "new", 0755)
+	write(v1, "content.txt", "aaaa", 0644)
+	write(v2, "content.txt", "bbbb", 0644)
+	write(v1, "mode.sh", "echo", 0644)
+	write(v2, "mode.sh", "echo", 0755)
+	write(v1, "typed", "file", 0644)
+	if err := os.Mkdir(filepath.Join(v2, "typed"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.Symlink("same.txt", filepath.Join(v1, "link")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("content.txt", filepath.Join(v2, "link")); err != nil {
+		t.Fatal(err)
+	}
+
+	return v1, v2
+}
+
+func TestDiff(t *testing.T) {
+	v1, v2 := createDiffTrees(t)
+
+	v1Archive := filepath.Join(t.TempDir(), "v1.tar.gz")
+	v2Archive := filepath.Join(t.TempDir(), "v2.tar.zst")
+	for _, c := range []struct{ src, dest string }{{v1, v1Archive}, {v2, v2Archive}} {
+		if err := CreateWithOptions(c.src, c.dest, CreateOptions{Symlinks: true}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want := []string{
+		"bin/added added",
+		"content.txt modified content",
+		"link modified link",
+		"mode.sh modified mode",
+		"removed.txt removed",
+		"typed modified type,mode",
+	}
+
+	inputs := []struct{ name, old, new string }{
+		{"archives", v1Archive, v2Archive},
+		{"directories", v1, v2},
+		{"archive and directory", v1Archive, v2},
+	}
+	for _, in := range inputs {
+		t.Run(in.name, func(t *testing.T) {
+			changes, err := Diff(in.old, in.new)
+			if err != nil {
+				t.Fatalf("Diff failed: %v", err)
+			}
+
+			var got []string
+			for _, c := range changes {
+				var fields []string
+				for _, f := range c.Fields {
+					fields = append(fields, string(f))
+				}
+				got = append(got, strings.TrimSpace(c.Path+" "+string(c.Kind)+" "+strings.Join(fields, ",")))
+			}
+			if !slices.Equal(got, want) {
+				t.Errorf("expected changes:\n%s\ngot:\n%s", strings.Join(want, "\n"), strings.Join(got, "\n"))
+			}
+		})
+	}
+}
+
+func TestDiffIdentical(t *testing.T) {
+	srcDir := t.TempDir()
+	createTestFiles(t, srcDir)
+	if err := os.Link(filepath.Join(srcDir, "file.txt"), filepath.Join(srcDir, "hard.txt")); err != nil {
+		t.Fatal(err)
+	}
+
+	archivePath := filepath.Join(t.TempDir(), "test.tar.gz")
+	if err := CreateWithOptions(srcDir, archivePath, CreateOptions{Hardlinks: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	changes, err := Diff(archivePath, srcDir)
+	if err != nil {
+		t.Fatalf("Diff failed: %v", err)
+	}
+	if len(changes) != 0 {
+		t.Errorf("expected no changes, got %v", changes)
+	}
+}
+
+func TestDiffMissingInput(t *testing.T) {
+	_, err := Diff(filepath.Join(t.TempDir(), "missing.tar.gz"), t.TempDir())
+	if !errors.Is(err, ErrReadFailed) {
+		t.Errorf("expected ErrReadFailed, got: %v", err)
+	}
+}
+
+func TestWriteDiff(t *testing.T) {
+	changes := []Change{
+		{Path: "a", Kind: ChangeModified, Fields: []DiffField{FieldMode, FieldSize},
+			Old: &DiffEntry{Type: EntryFile, Mode: 0o644, Size: 1}, New: &DiffEntry{Type: EntryFile, Mode: 0o4755, Size: 2}},
+		{Path: "b", Kind: ChangeAdded, New: &DiffEntry{Type: EntryDir, Mode: 0o755}},
+		{Path: "c", Kind: ChangeModified, Fields: []DiffField{FieldLink},
+			Old: &DiffEntry{Type: EntrySymlink, Link: "x"}, New: &DiffEntry{Type: EntrySymlink, Link: "y"}},
+		{Path: "d", Kind: ChangeRemoved, Old: &DiffEntry{Type: EntrySymlink}},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteDiff(&buf, changes); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "~ a: mode 0644 -> 4755, size 1 -> 2\n+ b (dir)\n~ c: link x -> y\n- d (symlink)\n"
+	if buf.String() != want {
+		t.Errorf("expected:\n%s\ngot:\n%s", want, buf.String())
+	}
+}
+
+func TestChangeJSON(t *testing.T) {
+	c := Change{Path: "a", Kind: ChangeAdded, New: &DiffEntry{Type: EntryFile, Mode: 0o644, Size: 3, Digest: "sha256:00"}}
+
+	data, err := json.Marshal(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `{"path":"a","kind":"added","new":{"type":"file","mode":420,"size":3,"digest":"sha256:00"}}`
+	if string(data) != want {
+		t.Errorf("expected %s, got %s", want, data)
+	}
+}
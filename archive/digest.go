@@ -0,0 +1,41 @@
+package archive
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"io"
+
+	"github.com/cruciblehq/spec/reference"
+)
+
+// Digest algorithm of archives streamed by [CreateTo] and verified by
+// [ExtractFrom].
+const DigestAlgorithm = "sha256"
+
+// Writer that hashes and counts the bytes written through it.
+type digestWriter struct {
+	w io.Writer
+	h hash.Hash
+	n int64
+}
+
+// Wraps w in a digest writer.
+func newDigestWriter(w io.Writer) *digestWriter {
+	return &digestWriter{w: w, h: sha256.New()}
+}
+
+// Implements [io.Writer].
+//
+// Only the bytes accepted by the underlying writer are hashed.
+func (d *digestWriter) Write(p []byte) (int, error) {
+	n, err := d.w.Write(p)
+	d.h.Write(p[:n])
+	d.n += int64(n)
+	return n, err
+}
+
+// Digest of the bytes written so far.
+func (d *digestWriter) digest() reference.Digest {
+	return reference.Digest{Algorithm: DigestAlgorithm, Hash: hex.EncodeToString(d.h.Sum(nil))}
+}
@@ -1,13 +1,20 @@
 // Package archive provides functions for creating and extracting compressed tar
 // archives.
 //
-// Supported formats are Zstandard (.tar.zst) and Gzip (.tar.gz, .tgz). The
-// compression format is detected automatically from the file extension by
-// [Create] and [Extract], or supplied explicitly to [ExtractFromReader]. Only
-// regular files and directories are archivable; symlinks and special files
-// (devices, sockets, named pipes) are rejected with [ErrUnsupportedFileType]
-// during creation. Extraction additionally supports symlinks and hard links,
-// both validated against directory escape. Path traversal attacks and absolute
+// Supported formats are Zstandard (.tar.zst), Gzip (.tar.gz, .tgz), plain tar
+// (.tar), and zip (.zip), all of which can be created and extracted, and xz
+// (.tar.xz, .txz) and bzip2 (.tar.bz2, .tbz2), which can only be extracted.
+// [Create] picks the format from the destination extension. [Extract] detects
+// it from the leading bytes of the archive (see [DetectFormat]), falling back
+// to the extension, so mislabelled files are still read correctly; it is
+// supplied explicitly to [ExtractFromReader]. By default only regular files
+// and directories are archivable; symlinks and special files (devices,
+// sockets, named pipes) are rejected with [ErrUnsupportedFileType] during
+// creation. [CreateWithOptions] can archive symlinks that stay within the
+// source tree, store hard-linked files once, and select the paths to archive
+// with a .crucibleignore file in gitignore syntax and include and exclude
+// patterns. Extraction additionally supports symlinks and hard links, both
+// validated against directory escape. Path traversal attacks and absolute
 // paths are detected and rejected with [ErrInvalidPath].
 //
 // Created archives preserve source file permissions with special bits (setuid,
@@ -15,6 +22,20 @@
 // archive headers with the same masking. Intermediate directories that have no
 // archive entry are created with [os.ModePerm], subject to the process umask.
 //
+// [CreateWithOptions] can instead create reproducible archives, whose bytes
+// depend only on the paths, contents, and permissions of the files: entries
+// are written in lexical order with a fixed timestamp (SOURCE_DATE_EPOCH by
+// default), no ownership, and PAX headers, and compressor parameters are
+// pinned. Digests of published archives can then be re-derived independently
+// from the source tree.
+//
+// [CreateTo] streams an archive to any [io.Writer], such as an upload request
+// body, returning its digest and size computed in the same pass.
+// [ExtractFrom] can verify an expected digest while extracting, and enforces
+// [Limits] on total and per-file size, entry count, path depth, and
+// compression ratio, so that untrusted archives cannot exhaust resources. The
+// extracted files are removed if the archive is rejected.
+//
 // Creating an archive from a directory:
 //
 //	err := archive.Create("mydir", "output.tar.zst")
@@ -38,6 +59,51 @@
 //		log.Fatal(err)
 //	}
 //
+// Archives can be inspected without extracting them. [List] returns the entry
+// headers of an archive file, [Stat] the header of a single entry, and
+// [FindAll] reads the regular files whose names match a glob pattern. Each
+// detects the format and decompresses the archive itself. [Diff] compares
+// two archives, or an archive and a directory, reporting added, removed, and
+// modified entries as [Change] values that marshal to JSON or render as text
+// with [WriteDiff].
+//
+// Root file system archives need more metadata than permissions. Creation can
+// keep setuid, setgid, and sticky bits, archive devices and named pipes, and
+// capture extended attributes; extraction can restore times, numeric
+// ownership (remapped with [IDMapping] for rootless use), those bits,
+// extended attributes, and devices. All of these are opt-in.
+//
+// By default extraction refuses an existing destination. [ExtractOptions]
+// can instead overwrite it, keep the files it already has, mirror the archive
+// by deleting files the archive lacks, or extract to a temporary sibling
+// directory that is renamed into place once complete (see [ExtractMode]).
+//
+// Compression level, Zstandard window size, and the number of compressing
+// goroutines are set through [CreateOptions]; gzip output is the same at any
+// concurrency. [ExtractOptions].Concurrency writes small files on a pool of
+// goroutines, which helps most with archives of many small files.
+//
+// [CreateContext], [CreateToContext], [ExtractContext], and
+// [ExtractFromContext] stop when their context is cancelled, cleaning up as
+// they do on any other error. A [ProgressFunc] set in the options reports
+// entries and bytes processed as the operation runs.
+//
+// [OpenFS] exposes an archive as a read-only [io/fs.FS], so it can be
+// consumed directly by [io/fs.WalkDir], [io/fs.ReadFile], or
+// [html/template.ParseFS]. Zstandard archives created by this package carry
+// an index of per-entry frames, making random access cheap; they remain
+// readable by ordinary zstd tools.
+//
+// Reading the readme files of an archive:
+//
+//	files, err := archive.FindAll("output.tar.zst", "README*")
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	for _, f := range files {
+//		fmt.Println(f.Header.Name, len(f.Data))
+//	}
+//
 // Reading a single file from a tar stream:
 //
 //	tr := tar.NewReader(r)
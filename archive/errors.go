@@ -9,4 +9,23 @@ var (
 	ErrInvalidPath         = errors.New("invalid path")
 	ErrUnsupportedFileType = errors.New("unsupported file type")
 	ErrUnsupportedFormat   = errors.New("unsupported archive format")
+	ErrSourceDateEpoch     = errors.New("SOURCE_DATE_EPOCH must be a non-negative integer")
+	ErrInvalidPattern      = errors.New("invalid pattern")
+	ErrUnsupportedDigest   = errors.New("unsupported digest algorithm")
+	ErrDigestMismatch      = errors.New("archive digest mismatch")
+	ErrEntryNotFound       = errors.New("archive entry not found")
+	ErrInvalidIndex        = errors.New("invalid archive index")
+	ErrNotDir              = errors.New("not a directory")
+	ErrInvalidExtractMode  = errors.New("invalid extract mode")
+	ErrInvalidLevel        = errors.New("invalid compression level")
+	ErrInvalidWindowSize   = errors.New("invalid compression window size")
+	ErrIDNotMapped         = errors.New("ID not covered by the ID mapping")
+
+	// Extraction limit errors (see [Limits]).
+
+	ErrTotalSizeExceeded        = errors.New("archive content exceeds the total size limit")
+	ErrFileSizeExceeded         = errors.New("archive file exceeds the file size limit")
+	ErrEntryCountExceeded       = errors.New("archive exceeds the entry count limit")
+	ErrPathDepthExceeded        = errors.New("archive path exceeds the depth limit")
+	ErrCompressionRatioExceeded = errors.New("archive exceeds the compression ratio limit")
 )
@@ -2,6 +2,8 @@ package archive
 
 import (
 	"archive/tar"
+	"bytes"
+	"context"
 	"errors"
 	"io"
 	"os"
@@ -9,11 +11,141 @@ import (
 	"strings"
 
 	"github.com/cruciblehq/crex"
+	"github.com/cruciblehq/spec/reference"
+)
+
+// Options controlling archive extraction.
+//
+// The zero value extracts the archive without verification or limits, as
+// [Extract] does. By default only permissions are restored from entry
+// headers; the preservation options restore more, as needed for root file
+// systems. Hard links share the metadata of their target, and failures to
+// restore metadata fail the extraction.
+type ExtractOptions struct {
+
+	// Expected digest of the compressed archive (nil to skip verification).
+	//
+	// The digest of the compressed stream is computed while extracting,
+	// including any bytes that follow the end of the archive, and compared
+	// once the stream is exhausted, returning [ErrDigestMismatch] if they
+	// differ. The digest must use [DigestAlgorithm], or
+	// [ErrUnsupportedDigest] is returned before anything is read.
+	Digest *reference.Digest
+
+	// Resource limits (zero fields are unlimited).
+	//
+	// Entries are checked before their contents are written, and the
+	// compression ratio as the archive is decompressed. An archive exceeding
+	// a limit fails with the limit's sentinel error (see [Limits]).
+	Limits Limits
+
+	// Treatment of an existing destination (see [ExtractMode]).
+	Mode ExtractMode
+
+	// Restore modification and access times.
+	//
+	// Times of symlinks are not restored. Directory times are set once all
+	// entries are extracted.
+	PreserveTimes bool
+
+	// Restore numeric owner and group IDs, mapped by UIDMap and GIDMap.
+	//
+	// Owner and group names are ignored. Changing ownership to other users
+	// requires privileges.
+	PreserveOwnership bool
+
+	// Mapping of archive user IDs to host IDs (empty for the identity).
+	//
+	// Lets a rootless process map IDs into the range delegated to it. An ID
+	// outside a non-empty mapping returns [ErrIDNotMapped].
+	UIDMap []IDMapping
+
+	// Mapping of archive group IDs to host IDs (empty for the identity).
+	//
+	// Applied as UIDMap is.
+	GIDMap []IDMapping
+
+	// Restore setuid, setgid, and sticky bits, after ownership.
+	SpecialBits bool
+
+	// Restore extended attributes from PAX records (Linux only).
+	//
+	// Attributes are read from "SCHILY.xattr.<name>" records and set after
+	// ownership, so that security.capability survives. Setting security and
+	// trusted attributes requires privileges.
+	Xattrs bool
+
+	// Create device and named pipe entries (Linux only).
+	//
+	// Without it, such entries return [ErrUnsupportedFileType]. Creating
+	// devices requires root.
+	Devices bool
+
+	// Number of files written concurrently (0 or 1 to write them in order).
+	//
+	// Above one, regular files of up to 4 MiB are read into memory and
+	// written by a pool of that many goroutines while the archive continues
+	// to be decompressed. Larger files, and all other entries, are written as
+	// they are read. Hard links, and entries replacing a file still being
+	// written, wait for pending writes first.
+	Concurrency int
+
+	// Receives progress reports, counting compressed bytes read (nil for
+	// none).
+	//
+	// The total is the size of the archive file when extracting from a path,
+	// and -1 when extracting from a reader.
+	Progress ProgressFunc
+}
+
+// How extraction treats an existing destination directory.
+//
+// In every mode, symlinks and hard links are validated against escaping the
+// destination, as they are by [Extract]. In the overwrite, skip-existing, and
+// mirror modes, entries beneath a symlink that was not extracted from the
+// same archive return [ErrInvalidPath], so existing links cannot redirect
+// writes. These modes modify dest in place: if extraction fails, an existing
+// dest may be left partially updated, and a dest created by the extraction is
+// removed. An unknown mode returns [ErrInvalidExtractMode].
+type ExtractMode int
+
+const (
+
+	// Fail if dest exists.
+	//
+	// Returns [ErrExtractFailed] wrapping [os.ErrExist], and removes dest if
+	// extraction fails, so a rejected archive leaves no files behind.
+	ExtractFail ExtractMode = iota
+
+	// Extract into dest, replacing the paths the archive contains.
+	//
+	// Whatever was at a path is removed first, so an existing symlink is
+	// replaced rather than written through. Existing directories are kept and
+	// take the permissions of the archive entry.
+	ExtractOverwrite
+
+	// Extract into dest, keeping the paths that already exist.
+	//
+	// Only the paths missing from dest are extracted.
+	ExtractSkipExisting
+
+	// Extract into dest as with [ExtractOverwrite], then delete the paths the
+	// archive does not contain, so dest ends up matching the archive.
+	ExtractMirror
+
+	// Extract to a temporary sibling of dest, then rename it into place.
+	//
+	// The rename happens once extraction and digest verification have
+	// succeeded. An existing dest is renamed out of the way first and then
+	// deleted, so dest briefly does not exist, but never holds a partial
+	// tree. On failure dest is left as it was.
+	ExtractAtomic
 )
 
 // Extracts a compressed tar archive to a directory.
 //
-// The compression format is detected from the src filename extension (see
+// The format is detected from the content of src (see [DetectFormat]), or
+// from its filename extension if the content is not recognised (see
 // [Format]). Permissions are preserved from the archive headers with special
 // bits stripped. Intermediate directories that have no archive entry are
 // created with [os.ModePerm], subject to the process umask. Returns
@@ -24,29 +156,35 @@ import (
 // devices and sockets return [ErrUnsupportedFileType]. Absolute paths and path
 // traversal attempts (e.g., "../etc/passwd") return [ErrInvalidPath]. If
 // extraction fails, the destination directory and its contents are removed.
-func Extract(src, dest string) (err error) {
-	fmt, err := detect(src)
-	if err != nil {
-		return crex.Wrap(ErrExtractFailed, err)
-	}
+func Extract(src, dest string) error {
+	return ExtractWithOptions(src, dest, ExtractOptions{})
+}
 
-	if _, statErr := os.Stat(dest); statErr == nil {
-		return crex.Wrap(ErrExtractFailed, os.ErrExist)
-	}
+// Extracts a compressed tar archive to a directory with options.
+//
+// Behaves like [Extract], and like [ExtractFrom] with respect to the options.
+// Progress reports carry the size of src as their total.
+func ExtractWithOptions(src, dest string, opts ExtractOptions) error {
+	return ExtractContext(context.Background(), src, dest, opts)
+}
 
-	file, err := os.Open(src)
+// Extracts a compressed tar archive to a directory with a context.
+//
+// Behaves like [ExtractWithOptions], and stops once ctx is cancelled, as
+// [ExtractFromContext] does.
+func ExtractContext(ctx context.Context, src, dest string, opts ExtractOptions) error {
+	file, r, fmt, err := openArchive(src)
 	if err != nil {
 		return crex.Wrap(ErrExtractFailed, err)
 	}
 	defer file.Close()
 
-	defer func() {
-		if err != nil {
-			os.RemoveAll(dest)
-		}
-	}()
+	info, err := file.Stat()
+	if err != nil {
+		return crex.Wrap(ErrExtractFailed, err)
+	}
 
-	return ExtractFromReader(file, dest, fmt)
+	return extractFrom(ctx, r, dest, fmt, opts, info.Size())
 }
 
 // Extracts a compressed tar archive from a reader to a directory.
@@ -59,31 +197,226 @@ func Extract(src, dest string) (err error) {
 // same entry types as [Extract]: regular files, directories, symlinks, and
 // hard links (all validated against directory escape). PAX headers are
 // skipped. Unlike [Extract], this function does not check whether dest
-// already exists and does not clean up on failure.
+// already exists and does not clean up on failure. Use [ExtractFrom] for
+// untrusted input, and [ExtractFromContext] to cancel extraction or report
+// its progress.
 func ExtractFromReader(r io.Reader, dest string, f Format) error {
-	dr, err := newDecompressReader(r, f)
-	if err != nil {
+	t := newTracker(context.Background(), nil, -1)
+	if err := extractFromReader(r, dest, f, ExtractOptions{}, t); err != nil {
 		return crex.Wrap(ErrExtractFailed, err)
 	}
-	defer dr.Close()
+	return nil
+}
 
-	if err := os.MkdirAll(dest, os.ModePerm); err != nil {
-		return crex.Wrap(ErrExtractFailed, err)
+// Extracts a compressed tar archive from a reader with options.
+//
+// Behaves like [ExtractFromReader], except that by default, like [Extract],
+// it returns [ErrExtractFailed] wrapping [os.ErrExist] if dest already
+// exists, and removes dest if extraction fails, so a rejected archive leaves
+// no files behind; other modes accept an existing dest (see [ExtractMode]).
+// The options can also verify the digest of r, enforce [Limits], restore
+// metadata beyond permissions, write files concurrently, and report
+// progress, as described on each field of [ExtractOptions].
+func ExtractFrom(r io.Reader, dest string, f Format, opts ExtractOptions) error {
+	return extractFrom(context.Background(), r, dest, f, opts, -1)
+}
+
+// Extracts a compressed tar archive from a reader with a context.
+//
+// Behaves like [ExtractFrom], and stops once ctx is cancelled, between
+// entries or while reading from r. Files are then removed as on any other
+// error, and the returned error wraps the context's error. With
+// [ExtractOverwrite], this can replace [ExtractFromReader] where
+// cancellation is needed.
+func ExtractFromContext(ctx context.Context, r io.Reader, dest string, f Format, opts ExtractOptions) error {
+	return extractFrom(ctx, r, dest, f, opts, -1)
+}
+
+// Extracts an archive from r as [ExtractFromContext] does, reporting total as
+// the size of the compressed stream.
+func extractFrom(ctx context.Context, r io.Reader, dest string, f Format, opts ExtractOptions, total int64) (err error) {
+	if opts.Digest != nil && !strings.EqualFold(opts.Digest.Algorithm, DigestAlgorithm) {
+		return crex.Wrap(ErrExtractFailed, ErrUnsupportedDigest)
 	}
 
-	if err := readTar(tar.NewReader(dr), dest); err != nil {
+	_, statErr := os.Stat(dest)
+	existed := statErr == nil
+	target := dest
+	var staging string
+
+	switch opts.Mode {
+	case ExtractFail:
+		if existed {
+			return crex.Wrap(ErrExtractFailed, os.ErrExist)
+		}
+	case ExtractOverwrite, ExtractSkipExisting, ExtractMirror:
+	case ExtractAtomic:
+		staging, err = os.MkdirTemp(filepath.Dir(dest), "."+filepath.Base(dest)+"-*")
+		if err != nil {
+			return crex.Wrap(ErrExtractFailed, err)
+		}
+		target = filepath.Join(staging, "new")
+		existed = false
+		opts.Mode = ExtractFail
+	default:
+		return crex.Wrap(ErrExtractFailed, ErrInvalidExtractMode)
+	}
+
+	defer func() {
+		if staging != "" {
+			os.RemoveAll(staging)
+		} else if err != nil && !existed {
+			os.RemoveAll(dest)
+		}
+	}()
+
+	t := newTracker(ctx, opts.Progress, total)
+	r = t.reader(r)
+
+	var dw *digestWriter
+	if opts.Digest != nil {
+		dw = newDigestWriter(io.Discard)
+		r = io.TeeReader(r, dw)
+	}
+
+	if err := extractFromReader(r, target, f, opts, t); err != nil {
 		return crex.Wrap(ErrExtractFailed, err)
 	}
 
+	if dw != nil {
+		if _, err := io.Copy(io.Discard, r); err != nil {
+			return crex.Wrap(ErrExtractFailed, err)
+		}
+		if !strings.EqualFold(dw.digest().Hash, opts.Digest.Hash) {
+			return crex.Wrap(ErrExtractFailed, ErrDigestMismatch)
+		}
+	}
+
+	if staging != "" {
+		if err := swapInto(target, dest, staging); err != nil {
+			return crex.Wrap(ErrExtractFailed, err)
+		}
+	}
+
+	t.done()
 	return nil
 }
 
-// Reads tar entries and extracts them to dest.
+// Decompresses r and extracts its entries to dest within the limits.
 //
-// Validates each entry path for security before extraction. Returns the first
-// error encountered or nil on successful completion.
-func readTar(tr *tar.Reader, dest string) error {
+// Entries are merged into an existing dest according to the mode, which
+// must not be [ExtractAtomic]. Entries are counted by t, and extraction stops
+// between entries once its context is cancelled.
+func extractFromReader(r io.Reader, dest string, f Format, opts ExtractOptions, t *tracker) error {
+	lim := &limiter{limits: opts.Limits}
+	er, closeFn, err := openEntries(r, f, lim)
+	if err != nil {
+		return err
+	}
+	defer closeFn()
+
+	if err := os.MkdirAll(dest, os.ModePerm); err != nil {
+		return err
+	}
+
+	x := &extractor{
+		dest: dest,
+		lim:  lim,
+		m:    newMerger(dest, opts.Mode),
+		rs:   newRestorer(opts),
+		pool: newWriterPool(opts.Concurrency),
+		t:    t,
+	}
+	err = x.read(er)
+	if x.pool != nil {
+		if waitErr := x.pool.wait(); err == nil {
+			err = waitErr
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	if x.m != nil && x.m.mode == ExtractMirror {
+		if err := x.m.prune(); err != nil {
+			return err
+		}
+	}
+	if x.rs != nil {
+		return x.rs.finish()
+	}
+	return nil
+}
+
+// Opens the entries of an archive in format f read from r.
+//
+// Decompressed data is passed through the limiter, whose compressed count is
+// set to track r. The returned function releases the decompressor, and any
+// temporary file used to spool a zip archive.
+func openEntries(r io.Reader, f Format, lim *limiter) (entryReader, func(), error) {
+	cr := &countingReader{r: r}
+	lim.compressed = cr
+
+	if f == Zip {
+		zr, cleanup, err := openZip(cr, lim.reader)
+		if err != nil {
+			return nil, nil, err
+		}
+		return zr, func() {
+			zr.Close()
+			cleanup()
+		}, nil
+	}
+
+	dr, err := newDecompressReader(cr, f)
+	if err != nil {
+		return nil, nil, err
+	}
+	return tar.NewReader(lim.reader(dr)), func() { dr.Close() }, nil
+}
+
+// Opens an archive file, detecting its format.
+//
+// The format is detected from the content of the file, falling back to its
+// filename extension, as [Extract] does. The caller must close the file.
+func openArchive(src string) (*os.File, io.Reader, Format, error) {
+	file, err := os.Open(src)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+
+	f, r, err := DetectFormat(file)
+	if errors.Is(err, ErrUnsupportedFormat) {
+		f, err = detect(src)
+	}
+	if err != nil {
+		file.Close()
+		return nil, nil, 0, err
+	}
+
+	return file, r, f, nil
+}
+
+// State of one extraction.
+type extractor struct {
+	dest string
+	lim  *limiter
+	m    *merger     // Merges entries with existing files (nil for a fresh dest).
+	rs   *restorer   // Restores metadata beyond permissions (nil for none).
+	pool *writerPool // Writes files concurrently (nil to write them in order).
+	t    *tracker
+}
+
+// Reads archive entries and extracts them to dest.
+//
+// Returns the first error encountered or nil on successful completion. Files
+// handed to the pool may still be being written when it returns.
+func (x *extractor) read(tr entryReader) error {
 	for {
+		if err := x.t.check(); err != nil {
+			return err
+		}
+
 		header, err := tr.Next()
 		if errors.Is(err, io.EOF) {
 			return nil
@@ -92,19 +425,76 @@ func readTar(tr *tar.Reader, dest string) error {
 			return err
 		}
 
-		target, err := validateAndJoinPath(dest, header.Name)
+		if err := x.next(tr, header); err != nil {
+			return err
+		}
+		x.t.entry()
+	}
+}
+
+// Extracts the entry whose header was just read from tr.
+//
+// Validates the entry path for security, and the header against the limits,
+// before extraction.
+func (x *extractor) next(tr entryReader, header *tar.Header) error {
+	if err := x.lim.check(header); err != nil {
+		return err
+	}
+
+	target, err := validateAndJoinPath(x.dest, header.Name)
+	if err != nil {
+		return err
+	}
+
+	if target == "" {
+		return nil
+	}
+
+	if x.pool != nil && (header.Typeflag == tar.TypeLink || x.pool.busy(target)) {
+		if err := x.pool.wait(); err != nil {
+			return err
+		}
+	}
+
+	if x.m != nil {
+		skip, err := x.m.prepare(header, target)
 		if err != nil {
 			return err
 		}
+		if skip {
+			return nil
+		}
+	}
 
-		if target == "" {
-			continue
+	if x.pool != nil && header.Typeflag == tar.TypeReg && header.Size <= pooledFileSize {
+		data := make([]byte, header.Size)
+		if _, err := io.ReadFull(tr, data); err != nil {
+			return err
 		}
+		return x.pool.submit(target, func() error {
+			return x.extract(header, bytes.NewReader(data), target)
+		})
+	}
+
+	return x.extract(header, tr, target)
+}
+
+// Extracts a single entry and restores its metadata.
+func (x *extractor) extract(header *tar.Header, r io.Reader, target string) error {
+	if x.rs == nil {
+		return extractEntry(header, r, x.dest, target)
+	}
 
-		if err := extractEntry(header, tr, dest, target); err != nil {
+	handled, err := x.rs.special(header, target)
+	if err != nil {
+		return err
+	}
+	if !handled {
+		if err := extractEntry(header, r, x.dest, target); err != nil {
 			return err
 		}
 	}
+	return x.rs.restore(header, target)
 }
 
 // Validates and joins an archive path with the destination directory.
@@ -141,13 +531,13 @@ func validateAndJoinPath(dest, name string) (string, error) {
 // Handles directories, regular files, symlinks, and hard links. PAX extended
 // headers are skipped. Returns [ErrUnsupportedFileType] for all other entry
 // types (devices, FIFOs, etc.).
-func extractEntry(header *tar.Header, tr *tar.Reader, dest, target string) error {
+func extractEntry(header *tar.Header, r io.Reader, dest, target string) error {
 	switch header.Typeflag {
 	case tar.TypeDir:
 		return os.MkdirAll(target, os.FileMode(header.Mode)&os.ModePerm)
 
 	case tar.TypeReg:
-		return extractFile(tr, target, os.FileMode(header.Mode)&os.ModePerm)
+		return extractFile(r, target, os.FileMode(header.Mode)&os.ModePerm)
 
 	case tar.TypeSymlink:
 		if err := validateSymlink(dest, target, header.Linkname); err != nil {
@@ -2,11 +2,20 @@ package archive
 
 import (
 	"archive/tar"
+	"errors"
 	"io"
+	"path"
+	"strings"
 
 	"github.com/cruciblehq/crex"
 )
 
+// Regular file read from an archive.
+type File struct {
+	Header *tar.Header // Entry header.
+	Data   []byte      // File contents.
+}
+
 // Reads a named file from a tar archive.
 //
 // Scans the tar reader sequentially until filename is found or the archive is
@@ -32,3 +41,121 @@ func Find(tr *tar.Reader, filename string) ([]byte, error) {
 		}
 	}
 }
+
+// Lists the entries of an archive file without extracting it.
+//
+// The format is detected as by [Extract], and any supported format is
+// accepted. Headers are returned in archive order, including those of
+// directories, links, and PAX headers as they appear in the stream. Entries
+// of zip archives are converted to equivalent tar headers.
+func List(src string) ([]*tar.Header, error) {
+	var headers []*tar.Header
+	err := walkArchive(src, func(header *tar.Header, _ io.Reader) (bool, error) {
+		headers = append(headers, header)
+		return false, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return headers, nil
+}
+
+// Returns the header of a named entry of an archive file.
+//
+// Names are compared after stripping a leading "./" and trailing slashes, so
+// "subdir" finds the entry "./subdir/". Returns [ErrEntryNotFound] if no
+// entry has the name. Scanning stops at the first match.
+func Stat(src, name string) (*tar.Header, error) {
+	name = entryName(name)
+
+	var found *tar.Header
+	err := walkArchive(src, func(header *tar.Header, _ io.Reader) (bool, error) {
+		if entryName(header.Name) == name {
+			found = header
+			return true, nil
+		}
+		return false, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if found == nil {
+		return nil, crex.Wrap(ErrReadFailed, ErrEntryNotFound)
+	}
+	return found, nil
+}
+
+// Reads the regular files of an archive file whose names match a pattern.
+//
+// The pattern uses the syntax of [path.Match] and is matched against the
+// whole entry name, after stripping a leading "./"; "*" does not cross "/",
+// so "README*" only matches at the root. Files are returned in archive order
+// with their contents read into memory, so callers handling untrusted
+// archives should check the sizes with [List] first. Returns an empty slice
+// if nothing matches, and [ErrInvalidPattern] if the pattern is malformed.
+func FindAll(src, pattern string) ([]File, error) {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, crex.Wrap(ErrReadFailed, ErrInvalidPattern)
+	}
+
+	files := []File{}
+	err := walkArchive(src, func(header *tar.Header, r io.Reader) (bool, error) {
+		if header.Typeflag != tar.TypeReg {
+			return false, nil
+		}
+		if ok, _ := path.Match(pattern, entryName(header.Name)); !ok {
+			return false, nil
+		}
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return false, err
+		}
+		files = append(files, File{Header: header, Data: data})
+		return false, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// Calls fn for each entry of an archive file.
+//
+// The reader passed to fn yields the contents of the entry and is only valid
+// until fn returns. Scanning stops when fn returns true or an error.
+func walkArchive(src string, fn func(header *tar.Header, r io.Reader) (bool, error)) error {
+	file, r, f, err := openArchive(src)
+	if err != nil {
+		return crex.Wrap(ErrReadFailed, err)
+	}
+	defer file.Close()
+
+	er, closeFn, err := openEntries(r, f, &limiter{})
+	if err != nil {
+		return crex.Wrap(ErrReadFailed, err)
+	}
+	defer closeFn()
+
+	for {
+		header, err := er.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return crex.Wrap(ErrReadFailed, err)
+		}
+
+		stop, err := fn(header, er)
+		if err != nil {
+			return crex.Wrap(ErrReadFailed, err)
+		}
+		if stop {
+			return nil
+		}
+	}
+}
+
+// Normalises an entry name for comparison.
+func entryName(name string) string {
+	return strings.TrimRight(strings.TrimPrefix(name, "./"), "/")
+}
@@ -0,0 +1,104 @@
+package archive
+
+import (
+	"archive/tar"
+	"errors"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// Creates an archive of the test files in the given format and returns its path.
+func createTestArchive(t *testing.T, name string) string {
+	t.Helper()
+
+	srcDir := t.TempDir()
+	createTestFiles(t, srcDir)
+
+	archivePath := filepath.Join(t.TempDir(), name)
+	if err := Create(srcDir, archivePath); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	return archivePath
+}
+
+func TestList(t *testing.T) {
+	for _, name := range []string{"test.tar.zst", "test.tar.gz", "test.tar", "test.zip"} {
+		t.Run(name, func(t *testing.T) {
+			headers, err := List(createTestArchive(t, name))
+			if err != nil {
+				t.Fatalf("List failed: %v", err)
+			}
+
+			var names []string
+			for _, h := range headers {
+				names = append(names, entryName(h.Name))
+			}
+			for _, want := range []string{"file.txt", "subdir", "subdir/nested.txt", "emptydir"} {
+				if !slices.Contains(names, want) {
+					t.Errorf("List() = %v, missing %q", names, want)
+				}
+			}
+		})
+	}
+}
+
+func TestStat(t *testing.T) {
+	archivePath := createTestArchive(t, "test.tar.zst")
+
+	header, err := Stat(archivePath, "subdir/nested.txt")
+	if err != nil {
+		t.Fatalf("Stat failed: %v", err)
+	}
+	if header.Typeflag != tar.TypeReg || header.Size != int64(len("nested")) {
+		t.Fatalf("unexpected header: %+v", header)
+	}
+
+	header, err = Stat(archivePath, "./subdir/")
+	if err != nil {
+		t.Fatalf("Stat failed: %v", err)
+	}
+	if header.Typeflag != tar.TypeDir {
+		t.Fatalf("typeflag = %c, want directory", header.Typeflag)
+	}
+
+	if _, err := Stat(archivePath, "missing.txt"); !errors.Is(err, ErrEntryNotFound) || !errors.Is(err, ErrReadFailed) {
+		t.Fatalf("expected ErrEntryNotFound, got: %v", err)
+	}
+}
+
+func TestFindAll(t *testing.T) {
+	archivePath := createTestArchive(t, "test.zip")
+
+	files, err := FindAll(archivePath, "*.txt")
+	if err != nil {
+		t.Fatalf("FindAll failed: %v", err)
+	}
+	if len(files) != 1 || entryName(files[0].Header.Name) != "file.txt" || string(files[0].Data) != "hello" {
+		t.Fatalf("FindAll(*.txt) = %+v", files)
+	}
+
+	files, err = FindAll(archivePath, "*/*.txt")
+	if err != nil {
+		t.Fatalf("FindAll failed: %v", err)
+	}
+	if len(files) != 1 || string(files[0].Data) != "nested" {
+		t.Fatalf("FindAll(*/*.txt) = %+v", files)
+	}
+
+	files, err = FindAll(archivePath, "LICENSE*")
+	if err != nil || len(files) != 0 {
+		t.Fatalf("FindAll(LICENSE*) = %+v, %v", files, err)
+	}
+
+	if _, err := FindAll(archivePath, "[a-"); !errors.Is(err, ErrInvalidPattern) {
+		t.Fatalf("expected ErrInvalidPattern, got: %v", err)
+	}
+}
+
+func TestListMissingFile(t *testing.T) {
+	_, err := List(filepath.Join(t.TempDir(), "missing.tar.zst"))
+	if !errors.Is(err, ErrReadFailed) {
+		t.Fatalf("expected ErrReadFailed, got: %v", err)
+	}
+}
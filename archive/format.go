@@ -1,33 +1,69 @@
 package archive
 
 import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
 	"compress/gzip"
+	"errors"
 	"io"
 	"strings"
 
 	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
 )
 
-// Supported archive compression formats.
+// Supported archive formats.
 //
-// Each format corresponds to a tar archive compressed with a specific
-// algorithm. The format is inferred from the file extension by [Create] and
-// [Extract], or supplied explicitly to [ExtractFromReader].
+// Most formats correspond to a tar archive compressed with a specific
+// algorithm; [Zip] is a zip archive. The format is inferred from the content
+// by [DetectFormat], from the file extension by [Create], from both by
+// [Extract] (content first), or supplied explicitly to [ExtractFromReader].
+// [Xz] and [Bzip2] archives can be read but not created.
 type Format int
 
 const (
-	Zstd Format = iota // Zstandard compression (.tar.zst).
-	Gzip               // Gzip compression (.tar.gz, .tgz).
-	Tar                // Plain tar, no compression (.tar).
+	Zstd  Format = iota // Zstandard compression (.tar.zst).
+	Gzip                // Gzip compression (.tar.gz, .tgz).
+	Tar                 // Plain tar, no compression (.tar).
+	Xz                  // XZ compression (.tar.xz, .txz), read only.
+	Bzip2               // Bzip2 compression (.tar.bz2, .tbz2), read only.
+	Zip                 // Zip archive (.zip).
 )
 
 const (
-	extZstd = ".tar.zst" // File extension for Zstandard-compressed tar archives.
-	extGzip = ".tar.gz"  // File extension for Gzip-compressed tar archives.
-	extTgz  = ".tgz"     // Alternate file extension for Gzip-compressed tar archives.
-	extTar  = ".tar"     // File extension for plain tar archives.
+	extZstd  = ".tar.zst" // File extension for Zstandard-compressed tar archives.
+	extGzip  = ".tar.gz"  // File extension for Gzip-compressed tar archives.
+	extTgz   = ".tgz"     // Alternate file extension for Gzip-compressed tar archives.
+	extTar   = ".tar"     // File extension for plain tar archives.
+	extXz    = ".tar.xz"  // File extension for XZ-compressed tar archives.
+	extTxz   = ".txz"     // Alternate file extension for XZ-compressed tar archives.
+	extBzip2 = ".tar.bz2" // File extension for Bzip2-compressed tar archives.
+	extTbz2  = ".tbz2"    // Alternate file extension for Bzip2-compressed tar archives.
+	extZip   = ".zip"     // File extension for zip archives.
 )
 
+// Magic numbers identifying each format, and the offset at which they occur.
+//
+// Tar is checked first because a plain tar archive starts with an entry name,
+// which could begin with any of the other magic numbers.
+var magics = []struct {
+	format Format
+	offset int
+	magic  []byte
+}{
+	{Tar, 257, []byte("ustar")},
+	{Zstd, 0, []byte{0x28, 0xb5, 0x2f, 0xfd}},
+	{Gzip, 0, []byte{0x1f, 0x8b}},
+	{Xz, 0, []byte{0xfd, '7', 'z', 'X', 'Z', 0x00}},
+	{Bzip2, 0, []byte{'B', 'Z', 'h'}},
+	{Zip, 0, []byte{'P', 'K', 0x03, 0x04}},
+	{Zip, 0, []byte{'P', 'K', 0x05, 0x06}},
+}
+
+// Number of leading bytes needed to recognize every format.
+const sniffLen = 262
+
 // String returns the canonical file extension for the format.
 func (f Format) String() string {
 	switch f {
@@ -37,11 +73,27 @@ func (f Format) String() string {
 		return extGzip
 	case Tar:
 		return extTar
+	case Xz:
+		return extXz
+	case Bzip2:
+		return extBzip2
+	case Zip:
+		return extZip
 	default:
 		return ""
 	}
 }
 
+// Whether archives of the format can be created.
+func (f Format) Writable() bool {
+	switch f {
+	case Zstd, Gzip, Tar, Zip:
+		return true
+	default:
+		return false
+	}
+}
+
 // Detects the archive format from a filename.
 //
 // Returns [ErrUnsupportedFormat] if the extension is not recognised.
@@ -50,10 +102,14 @@ func detect(name string) (Format, error) {
 	switch {
 	case strings.HasSuffix(lower, extZstd):
 		return Zstd, nil
-	case strings.HasSuffix(lower, extGzip):
-		return Gzip, nil
-	case strings.HasSuffix(lower, extTgz):
+	case strings.HasSuffix(lower, extGzip), strings.HasSuffix(lower, extTgz):
 		return Gzip, nil
+	case strings.HasSuffix(lower, extXz), strings.HasSuffix(lower, extTxz):
+		return Xz, nil
+	case strings.HasSuffix(lower, extBzip2), strings.HasSuffix(lower, extTbz2):
+		return Bzip2, nil
+	case strings.HasSuffix(lower, extZip):
+		return Zip, nil
 	case strings.HasSuffix(lower, extTar):
 		return Tar, nil
 	default:
@@ -61,13 +117,50 @@ func detect(name string) (Format, error) {
 	}
 }
 
+// Detects the archive format from the leading bytes of a stream.
+//
+// Recognizes the magic numbers of Zstandard, Gzip, XZ, Bzip2, and zip, and
+// the ustar magic of tar headers. Since detection consumes the leading bytes,
+// the returned reader must be used in place of r; it yields the whole stream.
+// Returns [ErrUnsupportedFormat] if the content is not recognised, including
+// tar archives in the pre-POSIX format, which carry no magic number.
+func DetectFormat(r io.Reader) (Format, io.Reader, error) {
+	br := bufio.NewReaderSize(r, sniffLen)
+	head, err := br.Peek(sniffLen)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, bufio.ErrBufferFull) {
+		return 0, br, err
+	}
+	for _, m := range magics {
+		if len(head) >= m.offset+len(m.magic) && bytes.Equal(head[m.offset:m.offset+len(m.magic)], m.magic) {
+			return m.format, br, nil
+		}
+	}
+	return 0, br, ErrUnsupportedFormat
+}
+
 // Returns a write-closer that compresses data with the given format.
-func newCompressWriter(w io.Writer, f Format) (io.WriteCloser, error) {
+//
+// Encoder parameters are pinned, apart from those set by the options, so
+// that the same input always compresses to the same bytes, which
+// reproducible archives depend on; only the Zstandard concurrency is left to
+// the library unless opts.Reproducible or opts.Concurrency is set. Gzip is
+// compressed in parallel blocks when opts.Concurrency is above one.
+func newCompressWriter(w io.Writer, f Format, opts CreateOptions) (io.WriteCloser, error) {
 	switch f {
 	case Zstd:
-		return zstd.NewWriter(w)
+		return newZstdEncoder(w, opts)
 	case Gzip:
-		return gzip.NewWriter(w), nil
+		level := gzip.DefaultCompression
+		if opts.Level != 0 {
+			if opts.Level < gzip.BestSpeed || opts.Level > gzip.BestCompression {
+				return nil, ErrInvalidLevel
+			}
+			level = opts.Level
+		}
+		if opts.Concurrency > 1 {
+			return newParallelGzipWriter(w, level, opts.Concurrency), nil
+		}
+		return gzip.NewWriterLevel(w, level)
 	case Tar:
 		return nopWriteCloser{w}, nil
 	default:
@@ -75,6 +168,39 @@ func newCompressWriter(w io.Writer, f Format) (io.WriteCloser, error) {
 	}
 }
 
+// Returns a Zstandard encoder with pinned parameters.
+//
+// The level, concurrency, and window size are taken from the options when
+// set. Without a concurrency, reproducible archives are encoded by a single
+// goroutine, and others with the library default of GOMAXPROCS. Levels
+// follow the zstd command line scale, from 1 to 22, and are mapped to the
+// nearest level the encoder implements.
+func newZstdEncoder(w io.Writer, opts CreateOptions) (*zstd.Encoder, error) {
+	level := zstd.SpeedDefault
+	if opts.Level != 0 {
+		if opts.Level < 1 || opts.Level > 22 {
+			return nil, ErrInvalidLevel
+		}
+		level = zstd.EncoderLevelFromZstd(opts.Level)
+	}
+
+	eopts := []zstd.EOption{
+		zstd.WithEncoderLevel(level),
+		zstd.WithEncoderCRC(true),
+	}
+	if opts.Concurrency > 0 || opts.Reproducible {
+		eopts = append(eopts, zstd.WithEncoderConcurrency(max(opts.Concurrency, 1)))
+	}
+	if opts.WindowSize != 0 {
+		if opts.WindowSize < zstd.MinWindowSize || opts.WindowSize > zstd.MaxWindowSize || opts.WindowSize&(opts.WindowSize-1) != 0 {
+			return nil, ErrInvalidWindowSize
+		}
+		eopts = append(eopts, zstd.WithWindowSize(opts.WindowSize))
+	}
+
+	return zstd.NewWriter(w, eopts...)
+}
+
 // Returns a read-closer that decompresses data with the given format.
 func newDecompressReader(r io.Reader, f Format) (io.ReadCloser, error) {
 	switch f {
@@ -88,6 +214,14 @@ func newDecompressReader(r io.Reader, f Format) (io.ReadCloser, error) {
 		return gzip.NewReader(r)
 	case Tar:
 		return io.NopCloser(r), nil
+	case Xz:
+		xr, err := xz.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		return io.NopCloser(xr), nil
+	case Bzip2:
+		return io.NopCloser(bzip2.NewReader(r)), nil
 	default:
 		return nil, ErrUnsupportedFormat
 	}
@@ -0,0 +1,184 @@
+package archive
+
+import (
+	"archive/tar"
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ulikunitz/xz"
+)
+
+// Bzip2-compressed ustar archive holding hello.txt with content "hello".
+const bzip2Archive = "QlpoOTFBWSZTWdGSZZgAAG97gMmAAAJAAUeAAIBiRJ5ACAggAFQlKDEMBBp5BFIhoAAB93cSRDmpETDqKGV9tiCQEFMZjOBNZJXY51nZztCno1/jAYVmVoyREDIu5IpwoSGjJMsw"
+
+// Builds an uncompressed ustar archive holding hello.txt.
+func buildHelloTar(t *testing.T) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	if err := tw.WriteHeader(&tar.Header{Name: "hello.txt", Typeflag: tar.TypeReg, Mode: 0644, Size: 5, Format: tar.FormatUSTAR}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tw.Write([]byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestDetectFormat(t *testing.T) {
+	srcDir := t.TempDir()
+	createTestFiles(t, srcDir)
+
+	for _, f := range []Format{Zstd, Gzip, Tar, Zip} {
+		t.Run(f.String(), func(t *testing.T) {
+			var buf bytes.Buffer
+			if _, _, err := CreateTo(&buf, srcDir, f, CreateOptions{}); err != nil {
+				t.Fatalf("CreateTo failed: %v", err)
+			}
+			data := buf.Bytes()
+
+			got, r, err := DetectFormat(bytes.NewReader(data))
+			if err != nil {
+				t.Fatalf("DetectFormat failed: %v", err)
+			}
+			if got != f {
+				t.Fatalf("DetectFormat = %v, want %v", got, f)
+			}
+			replayed, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(replayed, data) {
+				t.Fatal("returned reader does not yield the whole stream")
+			}
+		})
+	}
+
+	if _, _, err := DetectFormat(bytes.NewReader([]byte("fake"))); !errors.Is(err, ErrUnsupportedFormat) {
+		t.Fatalf("expected ErrUnsupportedFormat, got: %v", err)
+	}
+}
+
+func TestExtractSniffsMislabelledArchive(t *testing.T) {
+	srcDir := t.TempDir()
+	createTestFiles(t, srcDir)
+
+	var buf bytes.Buffer
+	if _, _, err := CreateTo(&buf, srcDir, Gzip, CreateOptions{}); err != nil {
+		t.Fatalf("CreateTo failed: %v", err)
+	}
+	archivePath := filepath.Join(t.TempDir(), "image.tar")
+	if err := os.WriteFile(archivePath, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	destDir := filepath.Join(t.TempDir(), "extracted")
+	if err := Extract(archivePath, destDir); err != nil {
+		t.Fatalf("Extract failed: %v", err)
+	}
+	assertFileContent(t, filepath.Join(destDir, "file.txt"), "hello")
+}
+
+func TestExtractXz(t *testing.T) {
+	var buf bytes.Buffer
+	xw, err := xz.NewWriter(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := xw.Write(buildHelloTar(t)); err != nil {
+		t.Fatal(err)
+	}
+	if err := xw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if f, _, err := DetectFormat(bytes.NewReader(buf.Bytes())); err != nil || f != Xz {
+		t.Fatalf("DetectFormat = %v, %v, want Xz", f, err)
+	}
+
+	destDir := filepath.Join(t.TempDir(), "extracted")
+	if err := ExtractFromReader(&buf, destDir, Xz); err != nil {
+		t.Fatalf("ExtractFromReader failed: %v", err)
+	}
+	assertFileContent(t, filepath.Join(destDir, "hello.txt"), "hello")
+}
+
+func TestExtractBzip2(t *testing.T) {
+	data, err := base64.StdEncoding.DecodeString(bzip2Archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	archivePath := filepath.Join(t.TempDir(), "test.tar.bz2")
+	if err := os.WriteFile(archivePath, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	destDir := filepath.Join(t.TempDir(), "extracted")
+	if err := Extract(archivePath, destDir); err != nil {
+		t.Fatalf("Extract failed: %v", err)
+	}
+	assertFileContent(t, filepath.Join(destDir, "hello.txt"), "hello")
+}
+
+func TestCreateReadOnlyFormat(t *testing.T) {
+	for _, name := range []string{"test.tar.xz", "test.tar.bz2"} {
+		err := Create(t.TempDir(), filepath.Join(t.TempDir(), name))
+		if !errors.Is(err, ErrUnsupportedFormat) {
+			t.Errorf("Create(%q) error = %v, want ErrUnsupportedFormat", name, err)
+		}
+	}
+}
+
+func TestCreateAndExtractZip(t *testing.T) {
+	srcDir := t.TempDir()
+	createTestFiles(t, srcDir)
+	if err := os.Symlink("file.txt", filepath.Join(srcDir, "link.txt")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(filepath.Join(srcDir, "file.txt"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	archivePath := filepath.Join(t.TempDir(), "test.zip")
+	if err := CreateWithOptions(srcDir, archivePath, CreateOptions{Symlinks: true, Hardlinks: true}); err != nil {
+		t.Fatalf("CreateWithOptions failed: %v", err)
+	}
+
+	destDir := filepath.Join(t.TempDir(), "extracted")
+	if err := Extract(archivePath, destDir); err != nil {
+		t.Fatalf("Extract failed: %v", err)
+	}
+
+	assertFileContent(t, filepath.Join(destDir, "file.txt"), "hello")
+	assertFileContent(t, filepath.Join(destDir, "subdir", "nested.txt"), "nested")
+	assertDirExists(t, filepath.Join(destDir, "emptydir"))
+
+	info, err := os.Stat(filepath.Join(destDir, "file.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Fatalf("mode = %v, want 0600", info.Mode().Perm())
+	}
+	if target, err := os.Readlink(filepath.Join(destDir, "link.txt")); err != nil || target != "file.txt" {
+		t.Fatalf("symlink = %q, %v, want file.txt", target, err)
+	}
+}
+
+func TestFormatStringExtended(t *testing.T) {
+	for f, want := range map[Format]string{Tar: ".tar", Xz: ".tar.xz", Bzip2: ".tar.bz2", Zip: ".zip"} {
+		if got := f.String(); got != want {
+			t.Errorf("%d.String() = %q, want %q", f, got, want)
+		}
+	}
+}
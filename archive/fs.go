@@ -0,0 +1,530 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/cruciblehq/crex"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Maximum number of symlinks followed when resolving a path.
+const maxSymlinkHops = 40
+
+// Read-only file system over the entries of an archive.
+//
+// FS implements [fs.FS], [fs.ReadDirFS], [fs.StatFS], and [fs.ReadLinkFS], so
+// archives can be consumed by [fs.WalkDir], [fs.ReadFile], [fs.Glob], and
+// anything else accepting a file system. Directories that have no archive
+// entry of their own are synthesized with mode 0755. Hard links share the
+// contents of their target. Symlinks are followed by Open and Stat when they
+// are the last element of a path, and must resolve within the archive.
+//
+// How entries are located depends on the format. Zstandard archives written
+// by this package carry an index of per-entry frames (see [Create]), so an
+// entry is read by decompressing its frame alone. Plain tar and zip archives
+// are read in place. Archives in other formats, and Zstandard archives
+// without an index, are decompressed once to a temporary file, which is
+// removed by Close.
+//
+// An FS is safe for concurrent use. Files opened from it must not be used
+// after it is closed.
+type FS struct {
+	nodes   map[string]*node
+	closers []io.Closer // Released by Close, last first.
+}
+
+// Entry of an archive file system.
+type node struct {
+	header   *tar.Header                   // Entry header, with a cleaned name.
+	open     func() (io.ReadCloser, error) // Opens the contents of a regular file (nil for other types).
+	children []string                      // Names of the entries of a directory, sorted.
+}
+
+// Opens an archive file as a read-only file system.
+//
+// The format is detected as by [Extract]. The returned file system must be
+// closed to release the archive file. Returns [ErrReadFailed] if the archive
+// cannot be opened or indexed.
+func OpenFS(src string) (*FS, error) {
+	file, err := os.Open(src)
+	if err != nil {
+		return nil, crex.Wrap(ErrReadFailed, err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, crex.Wrap(ErrReadFailed, err)
+	}
+
+	f, _, err := DetectFormat(io.NewSectionReader(file, 0, info.Size()))
+	if errors.Is(err, ErrUnsupportedFormat) {
+		f, err = detect(src)
+	}
+	if err != nil {
+		file.Close()
+		return nil, crex.Wrap(ErrReadFailed, err)
+	}
+
+	fsys, err := NewFS(file, info.Size(), f)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	fsys.closers = append([]io.Closer{file}, fsys.closers...)
+	return fsys, nil
+}
+
+// Returns a read-only file system over an archive of the given size and
+// format.
+//
+// Behaves like [OpenFS], reading the archive from r, which must remain
+// readable until the file system is closed. Close does not close r.
+func NewFS(r io.ReaderAt, size int64, f Format) (*FS, error) {
+	fsys := &FS{nodes: make(map[string]*node)}
+	fsys.nodes["."] = &node{header: dirHeader(".")}
+
+	if err := fsys.load(r, size, f); err != nil {
+		fsys.Close()
+		return nil, crex.Wrap(ErrReadFailed, err)
+	}
+	if err := fsys.link(); err != nil {
+		fsys.Close()
+		return nil, crex.Wrap(ErrReadFailed, err)
+	}
+	return fsys, nil
+}
+
+// Releases the resources held by the file system.
+func (fsys *FS) Close() error {
+	var errs []error
+	for _, c := range slices.Backward(fsys.closers) {
+		errs = append(errs, c.Close())
+	}
+	fsys.closers = nil
+	return errors.Join(errs...)
+}
+
+// Implements [fs.FS].
+func (fsys *FS) Open(name string) (fs.File, error) {
+	n, err := fsys.resolve("open", name, true)
+	if err != nil {
+		return nil, err
+	}
+
+	info := n.header.FileInfo()
+	if info.IsDir() {
+		return &fsDir{info: info, entries: fsys.dirEntries(n)}, nil
+	}
+	if n.open == nil {
+		return &fsFile{info: info, r: io.NopCloser(strings.NewReader(""))}, nil
+	}
+
+	r, err := n.open()
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	if sf, ok := r.(sectionFile); ok {
+		return &fsSeekFile{fsFile: fsFile{info: info, r: r}, sr: sf.SectionReader}, nil
+	}
+	return &fsFile{info: info, r: r}, nil
+}
+
+// Implements [fs.ReadDirFS].
+func (fsys *FS) ReadDir(name string) ([]fs.DirEntry, error) {
+	n, err := fsys.resolve("readdir", name, true)
+	if err != nil {
+		return nil, err
+	}
+	if n.header.Typeflag != tar.TypeDir {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: ErrNotDir}
+	}
+	return fsys.dirEntries(n), nil
+}
+
+// Implements [fs.StatFS].
+func (fsys *FS) Stat(name string) (fs.FileInfo, error) {
+	n, err := fsys.resolve("stat", name, true)
+	if err != nil {
+		return nil, err
+	}
+	return n.header.FileInfo(), nil
+}
+
+// Implements [fs.ReadLinkFS].
+func (fsys *FS) Lstat(name string) (fs.FileInfo, error) {
+	n, err := fsys.resolve("lstat", name, false)
+	if err != nil {
+		return nil, err
+	}
+	return n.header.FileInfo(), nil
+}
+
+// Implements [fs.ReadLinkFS].
+func (fsys *FS) ReadLink(name string) (string, error) {
+	n, err := fsys.resolve("readlink", name, false)
+	if err != nil {
+		return "", err
+	}
+	if n.header.Typeflag != tar.TypeSymlink {
+		return "", &fs.PathError{Op: "readlink", Path: name, Err: fs.ErrInvalid}
+	}
+	return n.header.Linkname, nil
+}
+
+// Looks up the entry of a path, following a final symlink if requested.
+//
+// Errors are returned as [fs.PathError] values for op.
+func (fsys *FS) resolve(op, name string, follow bool) (*node, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+
+	current := name
+	for hops := 0; ; hops++ {
+		n, ok := fsys.nodes[current]
+		if !ok {
+			return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+		}
+		if !follow || n.header.Typeflag != tar.TypeSymlink {
+			return n, nil
+		}
+		if hops == maxSymlinkHops {
+			return nil, &fs.PathError{Op: op, Path: name, Err: ErrInvalidPath}
+		}
+
+		target := n.header.Linkname
+		if path.IsAbs(target) {
+			return nil, &fs.PathError{Op: op, Path: name, Err: ErrInvalidPath}
+		}
+		current = path.Join(path.Dir(current), target)
+		if !fs.ValidPath(current) {
+			return nil, &fs.PathError{Op: op, Path: name, Err: ErrInvalidPath}
+		}
+	}
+}
+
+// Directory entries of a directory node.
+func (fsys *FS) dirEntries(n *node) []fs.DirEntry {
+	entries := make([]fs.DirEntry, len(n.children))
+	for i, child := range n.children {
+		entries[i] = fs.FileInfoToDirEntry(fsys.nodes[child].header.FileInfo())
+	}
+	return entries
+}
+
+// Builds the nodes of an archive in the given format.
+func (fsys *FS) load(r io.ReaderAt, size int64, f Format) error {
+	switch f {
+	case Tar:
+		return fsys.loadTar(r, size)
+	case Zip:
+		return fsys.loadZip(r, size)
+	case Zstd:
+		index, ok, err := readIndex(r, size)
+		if err != nil {
+			return err
+		}
+		if ok {
+			return fsys.loadIndex(r, index)
+		}
+	}
+	return fsys.loadSpooled(r, size, f)
+}
+
+// Builds the nodes of a plain tar archive, reading contents in place.
+//
+// Only headers are read; the contents of each entry are skipped by seeking.
+func (fsys *FS) loadTar(r io.ReaderAt, size int64) error {
+	sr := io.NewSectionReader(r, 0, size)
+	tr := tar.NewReader(sr)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if isSparse(header) {
+			return ErrUnsupportedFileType
+		}
+
+		var open func() (io.ReadCloser, error)
+		if header.Typeflag == tar.TypeReg {
+			offset, err := sr.Seek(0, io.SeekCurrent)
+			if err != nil {
+				return err
+			}
+			length := header.Size
+			open = func() (io.ReadCloser, error) {
+				return sectionFile{io.NewSectionReader(r, offset, length)}, nil
+			}
+		}
+		if err := fsys.add(header, open); err != nil {
+			return err
+		}
+	}
+}
+
+// Builds the nodes of a zip archive.
+func (fsys *FS) loadZip(r io.ReaderAt, size int64) error {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return err
+	}
+	for _, f := range zr.File {
+		header, err := zipHeader(f)
+		if err != nil {
+			return err
+		}
+		var open func() (io.ReadCloser, error)
+		if header.Typeflag == tar.TypeReg {
+			open = f.Open
+		}
+		if err := fsys.add(header, open); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Builds the nodes of a seekable Zstandard archive from its index.
+func (fsys *FS) loadIndex(r io.ReaderAt, index []indexEntry) error {
+	for _, e := range index {
+		var open func() (io.ReadCloser, error)
+		if e.Typeflag == tar.TypeReg {
+			offset, length := e.Offset, e.Length
+			open = func() (io.ReadCloser, error) {
+				return openFrame(io.NewSectionReader(r, offset, length))
+			}
+		}
+		if err := fsys.add(e.header(), open); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Decompresses an archive to a temporary file and builds its nodes from it.
+func (fsys *FS) loadSpooled(r io.ReaderAt, size int64, f Format) error {
+	dr, err := newDecompressReader(io.NewSectionReader(r, 0, size), f)
+	if err != nil {
+		return err
+	}
+	defer dr.Close()
+
+	tmp, err := os.CreateTemp("", "archive-*.tar")
+	if err != nil {
+		return err
+	}
+	fsys.closers = append(fsys.closers, tempFile{tmp})
+
+	n, err := io.Copy(tmp, dr)
+	if err != nil {
+		return err
+	}
+	return fsys.loadTar(tmp, n)
+}
+
+// Adds an entry, and the directories leading to it.
+//
+// A later entry replaces an earlier one with the same name, as on
+// extraction. Returns [ErrInvalidPath] for names that are not valid
+// [fs.FS] paths, and for entries that would have to be both a directory and
+// another type.
+func (fsys *FS) add(header *tar.Header, open func() (io.ReadCloser, error)) error {
+	name := entryName(header.Name)
+	if name == "" || name == "." {
+		return nil
+	}
+	if !fs.ValidPath(name) {
+		return ErrInvalidPath
+	}
+
+	h := *header
+	h.Name = name
+	if existing, ok := fsys.nodes[name]; ok {
+		if len(existing.children) > 0 && h.Typeflag != tar.TypeDir {
+			return ErrInvalidPath
+		}
+		existing.header, existing.open = &h, open
+		return nil
+	}
+	fsys.nodes[name] = &node{header: &h, open: open}
+
+	for child := name; child != "."; {
+		dir := path.Dir(child)
+		parent, ok := fsys.nodes[dir]
+		if !ok {
+			parent = &node{header: dirHeader(dir)}
+			fsys.nodes[dir] = parent
+		} else if parent.header.Typeflag != tar.TypeDir {
+			return ErrInvalidPath
+		}
+		i, found := slices.BinarySearch(parent.children, child)
+		if found {
+			break
+		}
+		parent.children = slices.Insert(parent.children, i, child)
+		child = dir
+	}
+	return nil
+}
+
+// Points hard links at the contents of their targets.
+//
+// Returns [ErrInvalidPath] if a target is missing or not a regular file.
+func (fsys *FS) link() error {
+	for _, n := range fsys.nodes {
+		if n.header.Typeflag != tar.TypeLink {
+			continue
+		}
+		target, ok := fsys.nodes[entryName(n.header.Linkname)]
+		for hops := 0; ok && target.header.Typeflag == tar.TypeLink && hops < maxSymlinkHops; hops++ {
+			target, ok = fsys.nodes[entryName(target.header.Linkname)]
+		}
+		if !ok || target.header.Typeflag != tar.TypeReg {
+			return ErrInvalidPath
+		}
+		n.header.Size = target.header.Size
+		n.open = target.open
+	}
+	return nil
+}
+
+// Header of a directory that has no archive entry.
+func dirHeader(name string) *tar.Header {
+	return &tar.Header{Name: name, Typeflag: tar.TypeDir, Mode: 0755}
+}
+
+// Whether a header describes a sparse file, whose contents are not stored
+// contiguously.
+func isSparse(header *tar.Header) bool {
+	if header.Typeflag == tar.TypeGNUSparse {
+		return true
+	}
+	for key := range header.PAXRecords {
+		if strings.HasPrefix(key, "GNU.sparse.") {
+			return true
+		}
+	}
+	return false
+}
+
+// Opens the contents of the entry compressed in a single frame.
+func openFrame(r io.Reader) (io.ReadCloser, error) {
+	dec, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1))
+	if err != nil {
+		return nil, err
+	}
+	tr := tar.NewReader(dec)
+	if _, err := tr.Next(); err != nil {
+		dec.Close()
+		return nil, err
+	}
+	return &frameReader{Reader: tr, dec: dec}, nil
+}
+
+// Contents of an entry being decompressed from its frame.
+type frameReader struct {
+	io.Reader
+	dec *zstd.Decoder
+}
+
+// Releases the decoder.
+func (f *frameReader) Close() error {
+	f.dec.Close()
+	return nil
+}
+
+// Contents of an entry stored uncompressed.
+type sectionFile struct{ *io.SectionReader }
+
+// Implements [io.Closer].
+func (sectionFile) Close() error { return nil }
+
+// Temporary file removed when closed.
+type tempFile struct{ *os.File }
+
+// Closes and removes the file.
+func (t tempFile) Close() error {
+	err := t.File.Close()
+	if rmErr := os.Remove(t.Name()); err == nil {
+		err = rmErr
+	}
+	return err
+}
+
+// File opened from an [FS].
+type fsFile struct {
+	info fs.FileInfo
+	r    io.ReadCloser
+}
+
+// Implements [fs.File].
+func (f *fsFile) Stat() (fs.FileInfo, error) { return f.info, nil }
+
+// Implements [fs.File].
+func (f *fsFile) Read(p []byte) (int, error) { return f.r.Read(p) }
+
+// Implements [fs.File].
+func (f *fsFile) Close() error { return f.r.Close() }
+
+// File opened from an [FS] whose contents are stored uncompressed, and so
+// support random access.
+type fsSeekFile struct {
+	fsFile
+	sr *io.SectionReader
+}
+
+// Implements [io.Seeker].
+func (f *fsSeekFile) Seek(offset int64, whence int) (int64, error) {
+	return f.sr.Seek(offset, whence)
+}
+
+// Implements [io.ReaderAt].
+func (f *fsSeekFile) ReadAt(p []byte, off int64) (int, error) {
+	return f.sr.ReadAt(p, off)
+}
+
+// Directory opened from an [FS].
+type fsDir struct {
+	info    fs.FileInfo
+	entries []fs.DirEntry
+	offset  int
+}
+
+// Implements [fs.File].
+func (d *fsDir) Stat() (fs.FileInfo, error) { return d.info, nil }
+
+// Implements [fs.File].
+func (d *fsDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.info.Name(), Err: fs.ErrInvalid}
+}
+
+// Implements [fs.File].
+func (d *fsDir) Close() error { return nil }
+
+// Implements [fs.ReadDirFile].
+func (d *fsDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	rest := d.entries[d.offset:]
+	if n <= 0 {
+		d.offset = len(d.entries)
+		return rest, nil
+	}
+	if len(rest) == 0 {
+		return nil, io.EOF
+	}
+	n = min(n, len(rest))
+	d.offset += n
+	return rest[:n], nil
+}
@@ -0,0 +1,161 @@
+package archive
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Creates an archive of the test files, with a symlink and a hard link, and
+// returns its path.
+func createFSTestArchive(t *testing.T, name string) string {
+	t.Helper()
+
+	srcDir := t.TempDir()
+	createTestFiles(t, srcDir)
+	if err := os.Symlink("subdir/nested.txt", filepath.Join(srcDir, "link.txt")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Link(filepath.Join(srcDir, "file.txt"), filepath.Join(srcDir, "hard.txt")); err != nil {
+		t.Fatal(err)
+	}
+
+	archivePath := filepath.Join(t.TempDir(), name)
+	if err := CreateWithOptions(srcDir, archivePath, CreateOptions{Symlinks: true, Hardlinks: true}); err != nil {
+		t.Fatalf("CreateWithOptions failed: %v", err)
+	}
+	return archivePath
+}
+
+func TestOpenFS(t *testing.T) {
+	for _, name := range []string{"test.tar.zst", "test.tar.gz", "test.tar", "test.zip"} {
+		t.Run(name, func(t *testing.T) {
+			fsys, err := OpenFS(createFSTestArchive(t, name))
+			if err != nil {
+				t.Fatalf("OpenFS failed: %v", err)
+			}
+			defer fsys.Close()
+
+			if err := fstest.TestFS(fsys, "file.txt", "hard.txt", "link.txt", "subdir/nested.txt", "emptydir"); err != nil {
+				t.Fatal(err)
+			}
+
+			data, err := fs.ReadFile(fsys, "link.txt")
+			if err != nil || string(data) != "nested" {
+				t.Fatalf("ReadFile(link.txt) = %q, %v", data, err)
+			}
+			data, err = fs.ReadFile(fsys, "hard.txt")
+			if err != nil || string(data) != "hello" {
+				t.Fatalf("ReadFile(hard.txt) = %q, %v", data, err)
+			}
+			if target, err := fs.ReadLink(fsys, "link.txt"); err != nil || target != "subdir/nested.txt" {
+				t.Fatalf("ReadLink(link.txt) = %q, %v", target, err)
+			}
+			if _, err := fs.ReadDir(fsys, "file.txt"); !errors.Is(err, ErrNotDir) {
+				t.Fatalf("expected ErrNotDir, got: %v", err)
+			}
+			if _, err := fsys.Open("missing.txt"); !errors.Is(err, fs.ErrNotExist) {
+				t.Fatalf("expected fs.ErrNotExist, got: %v", err)
+			}
+		})
+	}
+}
+
+func TestSeekableZstdIndex(t *testing.T) {
+	archivePath := createFSTestArchive(t, "test.tar.zst")
+	data, err := os.ReadFile(archivePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	index, ok, err := readIndex(bytes.NewReader(data), int64(len(data)))
+	if err != nil || !ok {
+		t.Fatalf("readIndex = %v, %v", ok, err)
+	}
+
+	for _, e := range index {
+		if e.Name != "subdir/nested.txt" {
+			continue
+		}
+		r, err := openFrame(bytes.NewReader(data[e.Offset : e.Offset+e.Length]))
+		if err != nil {
+			t.Fatalf("openFrame failed: %v", err)
+		}
+		defer r.Close()
+		content, err := io.ReadAll(r)
+		if err != nil || string(content) != "nested" {
+			t.Fatalf("frame contents = %q, %v", content, err)
+		}
+		return
+	}
+	t.Fatalf("index has no entry for subdir/nested.txt: %+v", index)
+}
+
+func TestSeekableZstdIsPlainZstd(t *testing.T) {
+	archivePath := createFSTestArchive(t, "test.tar.zst")
+	data, err := os.ReadFile(archivePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dec, err := zstd.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dec.Close()
+	if _, err := io.Copy(io.Discard, dec); err != nil {
+		t.Fatalf("decoding failed: %v", err)
+	}
+
+	if _, err := exec.LookPath("zstd"); err != nil {
+		t.Skip("zstd not installed")
+	}
+	if out, err := exec.Command("zstd", "-t", archivePath).CombinedOutput(); err != nil {
+		t.Fatalf("zstd -t failed: %v\n%s", err, out)
+	}
+}
+
+func TestOpenFSWithoutIndex(t *testing.T) {
+	srcDir := t.TempDir()
+	createTestFiles(t, srcDir)
+
+	var tarBuf bytes.Buffer
+	if _, _, err := CreateTo(&tarBuf, srcDir, Tar, CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	enc, err := zstd.NewWriter(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := enc.Write(tarBuf.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+	if err := enc.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	fsys, err := NewFS(bytes.NewReader(buf.Bytes()), int64(buf.Len()), Zstd)
+	if err != nil {
+		t.Fatalf("NewFS failed: %v", err)
+	}
+	defer fsys.Close()
+
+	if err := fstest.TestFS(fsys, "file.txt", "subdir/nested.txt"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestOpenFSMissingFile(t *testing.T) {
+	if _, err := OpenFS(filepath.Join(t.TempDir(), "missing.tar.zst")); !errors.Is(err, ErrReadFailed) {
+		t.Fatalf("expected ErrReadFailed, got: %v", err)
+	}
+}
@@ -0,0 +1,254 @@
+package archive
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Default name of the ignore file read by [CreateWithOptions].
+const IgnoreFileName = ".crucibleignore"
+
+// Single pattern of an ignore file or filter list.
+type pattern struct {
+	re      *regexp.Regexp // Compiled pattern, matched against slash-separated relative paths.
+	negate  bool           // Whether the pattern re-includes paths ("!" prefix).
+	dirOnly bool           // Whether the pattern only matches directories (trailing "/").
+}
+
+// Ordered list of patterns with gitignore semantics.
+//
+// Patterns are matched against paths relative to the archive root, using
+// forward slashes. The last pattern that matches a path decides whether it is
+// matched, so later negated patterns re-include paths matched earlier.
+type patternList []pattern
+
+// Parses patterns in gitignore syntax, one per line.
+//
+// Blank lines and lines starting with "#" are skipped, and trailing spaces are
+// trimmed. A leading "!" negates the pattern, and a leading "\" escapes a "#"
+// or "!" that starts the pattern. A trailing "/" restricts the pattern to
+// directories. A pattern containing a "/" other than a trailing one is
+// anchored to the root; otherwise it matches a name at any depth. "*" matches
+// anything except "/", "?" matches one character except "/", and bracket
+// expressions match a character class. "**" matches across directories when
+// it forms a whole path segment ("**/a", "a/**", "a/**/b").
+func parsePatterns(r io.Reader) (patternList, error) {
+	var list patternList
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		p, ok, err := parsePattern(scanner.Text())
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			list = append(list, p)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// Parses a list of patterns given one per element.
+//
+// Elements follow the syntax of [parsePatterns]; blank elements and comments
+// are skipped.
+func parsePatternSlice(lines []string) (patternList, error) {
+	return parsePatterns(strings.NewReader(strings.Join(lines, "\n")))
+}
+
+// Parses a single pattern line.
+//
+// Returns false if the line holds no pattern, and [ErrInvalidPattern] if the
+// pattern is malformed.
+func parsePattern(line string) (pattern, bool, error) {
+	line = strings.TrimRight(line, " \t\r")
+	if line == "" || strings.HasPrefix(line, "#") {
+		return pattern{}, false, nil
+	}
+
+	var p pattern
+	if strings.HasPrefix(line, "!") {
+		p.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, `\#`) || strings.HasPrefix(line, `\!`) {
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		p.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if line == "" {
+		return pattern{}, false, nil
+	}
+
+	anchored := strings.Contains(line, "/")
+	line = strings.TrimPrefix(line, "/")
+
+	expr, err := patternRegexp(line)
+	if err != nil {
+		return pattern{}, false, err
+	}
+	if anchored {
+		expr = "^" + expr + "$"
+	} else {
+		expr = "^(?:.*/)?" + expr + "$"
+	}
+
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return pattern{}, false, ErrInvalidPattern
+	}
+	p.re = re
+	return p, true, nil
+}
+
+// Translates a glob into a regular expression, without anchors.
+func patternRegexp(glob string) (string, error) {
+	segments := strings.Split(glob, "/")
+	var b strings.Builder
+	for i, seg := range segments {
+		last := i == len(segments)-1
+		if seg == "**" {
+			if last {
+				b.WriteString(".*")
+			} else {
+				b.WriteString("(?:.*/)?")
+			}
+			continue
+		}
+		if err := segmentRegexp(&b, seg); err != nil {
+			return "", err
+		}
+		if !last {
+			b.WriteString("/")
+		}
+	}
+	return b.String(), nil
+}
+
+// Translates a single path segment of a glob into a regular expression.
+func segmentRegexp(b *strings.Builder, seg string) error {
+	for i := 0; i < len(seg); i++ {
+		c := seg[i]
+		switch c {
+		case '*':
+			for i+1 < len(seg) && seg[i+1] == '*' {
+				i++
+			}
+			b.WriteString("[^/]*")
+		case '?':
+			b.WriteString("[^/]")
+		case '\\':
+			i++
+			if i == len(seg) {
+				return ErrInvalidPattern
+			}
+			b.WriteString(regexp.QuoteMeta(seg[i : i+1]))
+		case '[':
+			end := strings.IndexByte(seg[i+1:], ']')
+			if end < 0 {
+				return ErrInvalidPattern
+			}
+			class := seg[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			if class == "" || class == "^" {
+				return ErrInvalidPattern
+			}
+			b.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return nil
+}
+
+// Whether the list matches a path.
+//
+// The path is relative to the archive root and uses forward slashes. The last
+// matching pattern decides; a path matched by no pattern is not matched.
+func (l patternList) match(path string, dir bool) bool {
+	matched := false
+	for _, p := range l {
+		if p.dirOnly && !dir {
+			continue
+		}
+		if p.re.MatchString(path) {
+			matched = !p.negate
+		}
+	}
+	return matched
+}
+
+// Selection of the paths to archive.
+type filter struct {
+	ignore  patternList // Patterns from the ignore file.
+	exclude patternList // Patterns of paths to leave out.
+	include patternList // Patterns of paths to archive (empty to archive everything).
+}
+
+// Builds the filter for the given options.
+//
+// Returns nil if the options select every path.
+func newFilter(src string, opts CreateOptions) (*filter, error) {
+	f := &filter{}
+
+	if opts.IgnoreFile != "" {
+		file, err := os.Open(filepath.Join(src, opts.IgnoreFile))
+		switch {
+		case err == nil:
+			f.ignore, err = parsePatterns(file)
+			file.Close()
+			if err != nil {
+				return nil, err
+			}
+		case !errors.Is(err, fs.ErrNotExist):
+			return nil, err
+		}
+	}
+
+	var err error
+	if f.exclude, err = parsePatternSlice(opts.Exclude); err != nil {
+		return nil, err
+	}
+	if f.include, err = parsePatternSlice(opts.Include); err != nil {
+		return nil, err
+	}
+
+	if len(f.ignore) == 0 && len(f.exclude) == 0 && len(f.include) == 0 {
+		return nil, nil
+	}
+	return f, nil
+}
+
+// Whether a path is left out by the ignore file or an exclude pattern.
+func (f *filter) excluded(path string, dir bool) bool {
+	return f.ignore.match(path, dir) || f.exclude.match(path, dir)
+}
+
+// Whether a path matches an include pattern or lies beneath a matching
+// directory.
+func (f *filter) included(path string, dir bool) bool {
+	if len(f.include) == 0 {
+		return true
+	}
+	if f.include.match(path, dir) {
+		return true
+	}
+	for i := strings.LastIndexByte(path, '/'); i > 0; i = strings.LastIndexByte(path[:i], '/') {
+		if f.include.match(path[:i], true) {
+			return true
+		}
+	}
+	return false
+}
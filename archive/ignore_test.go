@@ -0,0 +1,59 @@
+package archive
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestPatternListMatch(t *testing.T) {
+	tests := []struct {
+		patterns string
+		path     string
+		dir      bool
+		want     bool
+	}{
+		{"node_modules", "node_modules", true, true},
+		{"node_modules", "web/node_modules", true, true},
+		{"*.log", "logs/build.log", false, true},
+		{"*.log", "build.log.txt", false, false},
+		{"/dist", "dist", true, true},
+		{"/dist", "web/dist", true, false},
+		{"build/", "build", true, true},
+		{"build/", "build", false, false},
+		{"docs/*.md", "docs/readme.md", false, true},
+		{"docs/*.md", "docs/api/readme.md", false, false},
+		{"docs/**/*.md", "docs/api/readme.md", false, true},
+		{"docs/**/*.md", "docs/readme.md", false, true},
+		{"**/cache", "a/b/cache", true, true},
+		{"vendor/**", "vendor/x/y.go", false, true},
+		{"vendor/**", "vendor", true, false},
+		{"file?.txt", "file1.txt", false, true},
+		{"file[0-9].txt", "filea.txt", false, false},
+		{"file[!0-9].txt", "filea.txt", false, true},
+		{"*.log\n!keep.log", "keep.log", false, false},
+		{"*.log\n!keep.log", "other.log", false, true},
+		{"# comment\n\n\\#hash", "#hash", false, true},
+		{"\\!bang", "!bang", false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(strings.ReplaceAll(tt.patterns, "\n", ";")+" "+tt.path, func(t *testing.T) {
+			list, err := parsePatterns(strings.NewReader(tt.patterns))
+			if err != nil {
+				t.Fatalf("parsePatterns failed: %v", err)
+			}
+			if got := list.match(tt.path, tt.dir); got != tt.want {
+				t.Fatalf("match(%q) = %v, want %v", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParsePatternsInvalid(t *testing.T) {
+	for _, p := range []string{"file[", "trailing\\", "[]"} {
+		if _, err := parsePatterns(strings.NewReader(p)); !errors.Is(err, ErrInvalidPattern) {
+			t.Errorf("parsePatterns(%q) error = %v, want ErrInvalidPattern", p, err)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package archive
+
+import (
+	"archive/tar"
+	"io"
+	"strings"
+)
+
+// Minimum number of uncompressed bytes before the compression ratio is
+// checked, so that small archives of highly compressible headers and padding
+// are not rejected.
+const ratioGrace = 1 << 20
+
+// Resource limits enforced while extracting an archive.
+//
+// Guard against archives crafted to exhaust disk space, inodes, or memory
+// (decompression bombs). A zero field disables the corresponding limit, so the
+// zero value enforces nothing. Each limit fails with its own sentinel error.
+// See [DefaultLimits] for values suited to untrusted archives.
+type Limits struct {
+	MaxTotalSize int64   // Maximum total size of file contents in bytes ([ErrTotalSizeExceeded]).
+	MaxFileSize  int64   // Maximum size of a single file in bytes ([ErrFileSizeExceeded]).
+	MaxEntries   int     // Maximum number of entries ([ErrEntryCountExceeded]).
+	MaxDepth     int     // Maximum number of components in an entry path ([ErrPathDepthExceeded]).
+	MaxRatio     float64 // Maximum ratio of uncompressed to compressed bytes ([ErrCompressionRatioExceeded]).
+}
+
+// Limits suited to extracting untrusted archives.
+//
+// Allows 8 GiB of content, 2 GiB per file, 100,000 entries, paths 64
+// components deep, and a compression ratio of 200. The ratio is only checked
+// once 1 MiB has been decompressed.
+func DefaultLimits() Limits {
+	return Limits{
+		MaxTotalSize: 8 << 30,
+		MaxFileSize:  2 << 30,
+		MaxEntries:   100_000,
+		MaxDepth:     64,
+		MaxRatio:     200,
+	}
+}
+
+// Enforces [Limits] over the course of one extraction.
+type limiter struct {
+	limits       Limits
+	compressed   *countingReader // Compressed input, counting bytes consumed.
+	decompressed int64           // Decompressed bytes read so far.
+	total        int64           // Sum of the sizes of the entries seen so far.
+	entries      int             // Number of entries seen so far.
+}
+
+// Checks an entry header against the limits, before its contents are read.
+func (l *limiter) check(header *tar.Header) error {
+	l.entries++
+	if l.limits.MaxEntries > 0 && l.entries > l.limits.MaxEntries {
+		return ErrEntryCountExceeded
+	}
+
+	if l.limits.MaxDepth > 0 && pathDepth(header.Name) > l.limits.MaxDepth {
+		return ErrPathDepthExceeded
+	}
+
+	if header.Typeflag != tar.TypeReg {
+		return nil
+	}
+	if l.limits.MaxFileSize > 0 && header.Size > l.limits.MaxFileSize {
+		return ErrFileSizeExceeded
+	}
+	l.total += header.Size
+	if l.limits.MaxTotalSize > 0 && l.total > l.limits.MaxTotalSize {
+		return ErrTotalSizeExceeded
+	}
+	return nil
+}
+
+// Wraps a decompressed stream to enforce the compression ratio.
+//
+// Several streams may be wrapped, as with the entries of a zip archive; the
+// ratio is computed over all of them.
+func (l *limiter) reader(r io.Reader) io.Reader {
+	if l.limits.MaxRatio <= 0 {
+		return r
+	}
+	return &ratioReader{r: r, limiter: l}
+}
+
+// Number of components in a slash-separated archive path.
+func pathDepth(name string) int {
+	name = strings.Trim(strings.TrimPrefix(name, "./"), "/")
+	if name == "" || name == "." {
+		return 0
+	}
+	return strings.Count(name, "/") + 1
+}
+
+// Reader that counts the bytes read through it.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+// Implements [io.Reader].
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// Decompressed stream that fails once the compression ratio exceeds the
+// limit.
+type ratioReader struct {
+	r       io.Reader
+	limiter *limiter
+}
+
+// Implements [io.Reader].
+func (r *ratioReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	l := r.limiter
+	l.decompressed += int64(n)
+	if l.decompressed > ratioGrace {
+		compressed := max(l.compressed.n, 1)
+		if float64(l.decompressed)/float64(compressed) > l.limits.MaxRatio {
+			return n, ErrCompressionRatioExceeded
+		}
+	}
+	return n, err
+}
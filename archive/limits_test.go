@@ -0,0 +1,103 @@
+package archive
+
+import (
+	"archive/tar"
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Builds a zstd-compressed tar archive with a file per name, each holding
+// size zero bytes.
+func buildZeroArchive(t *testing.T, names []string, size int) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	zw, err := zstd.NewWriter(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tw := tar.NewWriter(zw)
+	content := make([]byte, size)
+	for _, name := range names {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Typeflag: tar.TypeReg, Mode: 0644, Size: int64(size)}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write(content); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestExtractLimits(t *testing.T) {
+	tests := []struct {
+		name   string
+		names  []string
+		size   int
+		limits Limits
+		want   error
+	}{
+		{"within limits", []string{"a", "b/c"}, 100, Limits{MaxTotalSize: 200, MaxFileSize: 100, MaxEntries: 2, MaxDepth: 2, MaxRatio: 10}, nil},
+		{"total size", []string{"a", "b"}, 100, Limits{MaxTotalSize: 150}, ErrTotalSizeExceeded},
+		{"file size", []string{"a"}, 100, Limits{MaxFileSize: 99}, ErrFileSizeExceeded},
+		{"entry count", []string{"a", "b", "c"}, 1, Limits{MaxEntries: 2}, ErrEntryCountExceeded},
+		{"path depth", []string{"a/b/c/d"}, 1, Limits{MaxDepth: 3}, ErrPathDepthExceeded},
+		{"compression ratio", []string{"zeros"}, 4 << 20, Limits{MaxRatio: 100}, ErrCompressionRatioExceeded},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := buildZeroArchive(t, tt.names, tt.size)
+			dest := filepath.Join(t.TempDir(), "extracted")
+
+			err := ExtractFrom(bytes.NewReader(data), dest, Zstd, ExtractOptions{Limits: tt.limits})
+			if tt.want == nil {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.want) || !errors.Is(err, ErrExtractFailed) {
+				t.Fatalf("got %v, want %v", err, tt.want)
+			}
+			if _, err := os.Stat(dest); !os.IsNotExist(err) {
+				t.Fatal("destination should be removed after exceeding a limit")
+			}
+		})
+	}
+}
+
+func TestDefaultLimitsAllowOrdinaryArchives(t *testing.T) {
+	srcDir := t.TempDir()
+	createTestFiles(t, srcDir)
+
+	archivePath := filepath.Join(t.TempDir(), "test.tar.zst")
+	if err := Create(srcDir, archivePath); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	dest := filepath.Join(t.TempDir(), "extracted")
+	if err := ExtractWithOptions(archivePath, dest, ExtractOptions{Limits: DefaultLimits()}); err != nil {
+		t.Fatalf("ExtractWithOptions failed: %v", err)
+	}
+	assertFileContent(t, filepath.Join(dest, "file.txt"), "hello")
+}
+
+func TestPathDepth(t *testing.T) {
+	for name, want := range map[string]int{"": 0, "./": 0, "a": 1, "./a/b/": 2, "a/b/c": 3} {
+		if got := pathDepth(name); got != want {
+			t.Errorf("pathDepth(%q) = %d, want %d", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,16 @@
+//go:build !unix
+
+package archive
+
+import "io/fs"
+
+// Identity of a file on disk, shared by all hard links to it.
+type fileID struct{}
+
+// Identity of a file with more than one hard link.
+//
+// Hard links are not detected on this platform, so every file is stored in
+// full.
+func linkID(info fs.FileInfo) (fileID, bool) {
+	return fileID{}, false
+}
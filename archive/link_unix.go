@@ -0,0 +1,25 @@
+//go:build unix
+
+package archive
+
+import (
+	"io/fs"
+	"syscall"
+)
+
+// Identity of a file on disk, shared by all hard links to it.
+type fileID struct {
+	dev uint64 // Device holding the file.
+	ino uint64 // Inode number of the file.
+}
+
+// Identity of a file with more than one hard link.
+//
+// Returns false for files with a single link, which need no tracking.
+func linkID(info fs.FileInfo) (fileID, bool) {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok || st.Nlink < 2 {
+		return fileID{}, false
+	}
+	return fileID{dev: uint64(st.Dev), ino: uint64(st.Ino)}, true
+}
@@ -0,0 +1,157 @@
+package archive
+
+import (
+	"archive/tar"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Merges archive entries into an existing destination directory.
+type merger struct {
+	dest  string
+	mode  ExtractMode
+	links map[string]bool // Symlinks extracted from the archive.
+	kept  map[string]bool // Paths extracted from the archive and their parents (mirror mode only).
+}
+
+// Returns a merger for the mode, or nil if dest is extracted fresh.
+func newMerger(dest string, mode ExtractMode) *merger {
+	switch mode {
+	case ExtractOverwrite, ExtractSkipExisting, ExtractMirror:
+	default:
+		return nil
+	}
+
+	m := &merger{dest: dest, mode: mode, links: make(map[string]bool)}
+	if mode == ExtractMirror {
+		m.kept = make(map[string]bool)
+	}
+	return m
+}
+
+// Prepares target for an entry, before it is extracted.
+//
+// Returns true if the entry is to be skipped because target exists. In the
+// other modes, whatever exists at target is removed, unless both it and the
+// entry are directories, in which case the directory takes the entry's
+// permissions. Returns [ErrInvalidPath] if target, or the target of a hard
+// link, lies beneath a symlink not extracted from the archive.
+func (m *merger) prepare(header *tar.Header, target string) (bool, error) {
+	if err := m.checkParents(target); err != nil {
+		return false, err
+	}
+	if header.Typeflag == tar.TypeLink {
+		linkTarget, err := validateHardlink(m.dest, header.Linkname)
+		if err != nil {
+			return false, err
+		}
+		if err := m.checkParents(linkTarget); err != nil {
+			return false, err
+		}
+	}
+
+	info, err := os.Lstat(target)
+	exists := err == nil
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return false, err
+	}
+
+	if exists && m.mode == ExtractSkipExisting {
+		return true, nil
+	}
+
+	m.keep(target)
+	if header.Typeflag == tar.TypeSymlink {
+		m.links[target] = true
+	} else {
+		delete(m.links, target)
+	}
+
+	if !exists {
+		return false, nil
+	}
+	if header.Typeflag == tar.TypeDir && info.IsDir() {
+		return false, os.Chmod(target, os.FileMode(header.Mode)&os.ModePerm)
+	}
+	return false, os.RemoveAll(target)
+}
+
+// Rejects targets beneath a symlink that was not extracted from the archive.
+//
+// Symlinks extracted from the archive have been validated against escaping
+// dest, but existing ones could point anywhere.
+func (m *merger) checkParents(target string) error {
+	rel, err := filepath.Rel(m.dest, filepath.Dir(target))
+	if err != nil || rel == "." {
+		return err
+	}
+
+	dir := m.dest
+	for _, part := range strings.Split(rel, string(filepath.Separator)) {
+		dir = filepath.Join(dir, part)
+		info, err := os.Lstat(dir)
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if info.Mode()&fs.ModeSymlink != 0 && !m.links[dir] {
+			return ErrInvalidPath
+		}
+	}
+	return nil
+}
+
+// Records target and its parents as extracted, in mirror mode.
+func (m *merger) keep(target string) {
+	if m.kept == nil {
+		return
+	}
+	for p := target; p != m.dest && !m.kept[p]; p = filepath.Dir(p) {
+		m.kept[p] = true
+	}
+}
+
+// Deletes the paths under dest that were not extracted from the archive.
+func (m *merger) prune() error {
+	return filepath.WalkDir(m.dest, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == m.dest || m.kept[path] {
+			return nil
+		}
+		if err := os.RemoveAll(path); err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return fs.SkipDir
+		}
+		return nil
+	})
+}
+
+// Moves a tree staged by atomic extraction into place at dest.
+//
+// An existing dest is first moved into the staging directory, from where it
+// is deleted along with it, and restored if the staged tree cannot be moved.
+func swapInto(staged, dest, staging string) error {
+	old := filepath.Join(staging, "old")
+	err := os.Rename(dest, old)
+	moved := err == nil
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	if err := os.Rename(staged, dest); err != nil {
+		if moved {
+			os.Rename(old, dest)
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,164 @@
+package archive
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Creates a destination holding a modified copy of the test files, a file
+// not in the test archive, and a symlink pointing outside the destination.
+func createExistingDest(t *testing.T) string {
+	t.Helper()
+
+	dest := filepath.Join(t.TempDir(), "dest")
+	if err := os.MkdirAll(filepath.Join(dest, "subdir"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dest, "file.txt"), []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dest, "extra.txt"), []byte("extra"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return dest
+}
+
+func TestExtractModes(t *testing.T) {
+	archivePath := createTestArchive(t, "test.tar.zst")
+
+	tests := []struct {
+		name    string
+		mode    ExtractMode
+		file    string
+		extra   bool
+		wantErr error
+	}{
+		{"fail", ExtractFail, "", true, os.ErrExist},
+		{"overwrite", ExtractOverwrite, "hello", true, nil},
+		{"skip existing", ExtractSkipExisting, "old", true, nil},
+		{"mirror", ExtractMirror, "hello", false, nil},
+		{"atomic", ExtractAtomic, "hello", false, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dest := createExistingDest(t)
+
+			err := ExtractWithOptions(archivePath, dest, ExtractOptions{Mode: tt.mode})
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("expected %v, got: %v", tt.wantErr, err)
+				}
+				assertFileContent(t, filepath.Join(dest, "file.txt"), "old")
+				return
+			}
+			if err != nil {
+				t.Fatalf("ExtractWithOptions failed: %v", err)
+			}
+
+			assertFileContent(t, filepath.Join(dest, "file.txt"), tt.file)
+			assertFileContent(t, filepath.Join(dest, "subdir", "nested.txt"), "nested")
+			assertDirExists(t, filepath.Join(dest, "emptydir"))
+
+			_, err = os.Stat(filepath.Join(dest, "extra.txt"))
+			if tt.extra != (err == nil) {
+				t.Fatalf("extra.txt exists = %v, want %v", err == nil, tt.extra)
+			}
+		})
+	}
+}
+
+func TestExtractOverwriteReplacesSymlink(t *testing.T) {
+	archivePath := createTestArchive(t, "test.tar.gz")
+
+	outside := filepath.Join(t.TempDir(), "outside.txt")
+	if err := os.WriteFile(outside, []byte("outside"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	dest := createExistingDest(t)
+	if err := os.Remove(filepath.Join(dest, "file.txt")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, filepath.Join(dest, "file.txt")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ExtractWithOptions(archivePath, dest, ExtractOptions{Mode: ExtractOverwrite}); err != nil {
+		t.Fatalf("ExtractWithOptions failed: %v", err)
+	}
+
+	assertFileContent(t, outside, "outside")
+	info, err := os.Lstat(filepath.Join(dest, "file.txt"))
+	if err != nil || info.Mode()&fs.ModeSymlink != 0 {
+		t.Fatalf("file.txt was not replaced: %v, %v", info, err)
+	}
+}
+
+func TestExtractMergeRejectsExistingSymlinkParent(t *testing.T) {
+	archivePath := createTestArchive(t, "test.tar.gz")
+
+	outside := t.TempDir()
+	dest := createExistingDest(t)
+	if err := os.RemoveAll(filepath.Join(dest, "subdir")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, filepath.Join(dest, "subdir")); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, mode := range []ExtractMode{ExtractOverwrite, ExtractSkipExisting, ExtractMirror} {
+		err := ExtractWithOptions(archivePath, dest, ExtractOptions{Mode: mode})
+		if mode == ExtractSkipExisting {
+			// The existing symlink is kept, and nothing is written beneath it.
+			if !errors.Is(err, ErrInvalidPath) {
+				t.Fatalf("mode %d: expected ErrInvalidPath, got: %v", mode, err)
+			}
+		} else if err != nil {
+			t.Fatalf("mode %d: ExtractWithOptions failed: %v", mode, err)
+		}
+
+		if _, err := os.Stat(filepath.Join(outside, "nested.txt")); err == nil {
+			t.Fatalf("mode %d: file written through existing symlink", mode)
+		}
+		if mode != ExtractSkipExisting {
+			assertFileContent(t, filepath.Join(dest, "subdir", "nested.txt"), "nested")
+			os.RemoveAll(filepath.Join(dest, "subdir"))
+			os.Symlink(outside, filepath.Join(dest, "subdir"))
+		}
+	}
+}
+
+func TestExtractAtomicFailureKeepsDest(t *testing.T) {
+	dest := createExistingDest(t)
+
+	archivePath := filepath.Join(t.TempDir(), "bad.tar.gz")
+	if err := os.WriteFile(archivePath, []byte{0x1f, 0x8b, 0, 0}, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ExtractWithOptions(archivePath, dest, ExtractOptions{Mode: ExtractAtomic}); err == nil {
+		t.Fatal("expected error")
+	}
+	assertFileContent(t, filepath.Join(dest, "file.txt"), "old")
+
+	entries, err := os.ReadDir(filepath.Dir(dest))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("staging directory left behind: %v", entries)
+	}
+}
+
+func TestExtractInvalidMode(t *testing.T) {
+	archivePath := createTestArchive(t, "test.tar.zst")
+	dest := filepath.Join(t.TempDir(), "dest")
+
+	err := ExtractWithOptions(archivePath, dest, ExtractOptions{Mode: ExtractMode(99)})
+	if !errors.Is(err, ErrInvalidExtractMode) {
+		t.Fatalf("expected ErrInvalidExtractMode, got: %v", err)
+	}
+}
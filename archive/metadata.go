@@ -0,0 +1,169 @@
+package archive
+
+import (
+	"archive/tar"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Prefix of the PAX records holding extended attributes.
+const paxXattr = "SCHILY.xattr."
+
+// Mapping of a contiguous range of user or group IDs from an archive to the
+// host.
+//
+// Rootless extraction maps the IDs of an archive into the range delegated to
+// the user, as user namespaces do. For example, {0, 100000, 65536} maps
+// archive IDs 0 through 65535 to host IDs 100000 through 165535.
+type IDMapping struct {
+	ArchiveID int // First ID of the range in the archive.
+	HostID    int // First ID of the range on the host.
+	Size      int // Number of IDs in the range.
+}
+
+// Maps an archive ID to a host ID.
+//
+// An empty mapping is the identity. Returns [ErrIDNotMapped] if no range of
+// a non-empty mapping contains id.
+func mapID(id int, mappings []IDMapping) (int, error) {
+	if len(mappings) == 0 {
+		return id, nil
+	}
+	for _, m := range mappings {
+		if id >= m.ArchiveID && id < m.ArchiveID+m.Size {
+			return m.HostID + id - m.ArchiveID, nil
+		}
+	}
+	return 0, ErrIDNotMapped
+}
+
+// Tar mode bits for the setuid, setgid, and sticky bits of a file mode.
+func specialBits(mode os.FileMode) int64 {
+	var bits int64
+	if mode&os.ModeSetuid != 0 {
+		bits |= 0o4000
+	}
+	if mode&os.ModeSetgid != 0 {
+		bits |= 0o2000
+	}
+	if mode&os.ModeSticky != 0 {
+		bits |= 0o1000
+	}
+	return bits
+}
+
+// Restores entry metadata beyond permissions, as selected by the options.
+type restorer struct {
+	opts ExtractOptions
+	dirs []dirTimes // Directories whose times are restored once their contents are extracted.
+}
+
+// Times to restore on a directory.
+type dirTimes struct {
+	path  string
+	atime time.Time
+	mtime time.Time
+}
+
+// Returns a restorer for the options, or nil if they preserve nothing.
+func newRestorer(opts ExtractOptions) *restorer {
+	if !opts.PreserveTimes && !opts.PreserveOwnership && !opts.SpecialBits && !opts.Xattrs && !opts.Devices {
+		return nil
+	}
+	return &restorer{opts: opts}
+}
+
+// Creates device and named pipe entries, if enabled.
+//
+// Returns false for other entries, which are extracted as usual.
+func (rs *restorer) special(header *tar.Header, target string) (bool, error) {
+	switch header.Typeflag {
+	case tar.TypeChar, tar.TypeBlock, tar.TypeFifo:
+	default:
+		return false, nil
+	}
+	if !rs.opts.Devices {
+		return false, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(target), os.ModePerm); err != nil {
+		return true, err
+	}
+	return true, makeDevice(target, header)
+}
+
+// Restores the metadata of an extracted entry.
+//
+// Ownership is restored first, since changing it clears the setuid and
+// setgid bits and file capabilities, then the mode, extended attributes, and
+// times. Directory times are restored by [restorer.finish], as extracting
+// their contents changes them. Hard links share the metadata of their
+// target, and symlinks only have their ownership restored.
+func (rs *restorer) restore(header *tar.Header, target string) error {
+	switch header.Typeflag {
+	case tar.TypeDir, tar.TypeReg, tar.TypeSymlink, tar.TypeChar, tar.TypeBlock, tar.TypeFifo:
+	default:
+		return nil
+	}
+
+	if rs.opts.PreserveOwnership {
+		uid, err := mapID(header.Uid, rs.opts.UIDMap)
+		if err != nil {
+			return err
+		}
+		gid, err := mapID(header.Gid, rs.opts.GIDMap)
+		if err != nil {
+			return err
+		}
+		if err := os.Lchown(target, uid, gid); err != nil {
+			return err
+		}
+	}
+
+	if header.Typeflag == tar.TypeSymlink {
+		return nil
+	}
+
+	if rs.opts.SpecialBits {
+		mode := header.FileInfo().Mode() & (os.ModePerm | os.ModeSetuid | os.ModeSetgid | os.ModeSticky)
+		if err := os.Chmod(target, mode); err != nil {
+			return err
+		}
+	}
+
+	if rs.opts.Xattrs {
+		for key, value := range header.PAXRecords {
+			if attr, ok := strings.CutPrefix(key, paxXattr); ok {
+				if err := setXattr(target, attr, value); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	if rs.opts.PreserveTimes {
+		atime := header.AccessTime
+		if atime.IsZero() {
+			atime = header.ModTime
+		}
+		if header.Typeflag == tar.TypeDir {
+			rs.dirs = append(rs.dirs, dirTimes{target, atime, header.ModTime})
+			return nil
+		}
+		return os.Chtimes(target, atime, header.ModTime)
+	}
+
+	return nil
+}
+
+// Restores the times of directories, in reverse order of extraction.
+func (rs *restorer) finish() error {
+	for i := len(rs.dirs) - 1; i >= 0; i-- {
+		d := rs.dirs[i]
+		if err := os.Chtimes(d.path, d.atime, d.mtime); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package archive
+
+import (
+	"archive/tar"
+	"bytes"
+	"errors"
+	"syscall"
+)
+
+// Reads the extended attributes of a file.
+//
+// Returns no attributes if the file system does not support them.
+func readXattrs(path string) (map[string]string, error) {
+	names, err := xattrCall(func(buf []byte) (int, error) { return syscall.Listxattr(path, buf) })
+	if errors.Is(err, syscall.ENOTSUP) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	attrs := make(map[string]string)
+	for name := range bytes.SplitSeq(names, []byte{0}) {
+		if len(name) == 0 {
+			continue
+		}
+		value, err := xattrCall(func(buf []byte) (int, error) { return syscall.Getxattr(path, string(name), buf) })
+		if errors.Is(err, syscall.ENODATA) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		attrs[string(name)] = string(value)
+	}
+	return attrs, nil
+}
+
+// Calls an extended attribute syscall with a buffer of the size it reports.
+//
+// Retries if the attribute grows between the size query and the read.
+func xattrCall(call func(buf []byte) (int, error)) ([]byte, error) {
+	for {
+		size, err := call(nil)
+		if err != nil || size == 0 {
+			return nil, err
+		}
+		buf := make([]byte, size)
+		n, err := call(buf)
+		if errors.Is(err, syscall.ERANGE) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return buf[:n], nil
+	}
+}
+
+// Sets an extended attribute of a file.
+func setXattr(path, attr, value string) error {
+	return syscall.Setxattr(path, attr, []byte(value), 0)
+}
+
+// Creates a device or named pipe for an entry.
+//
+// Creating devices requires privileges; named pipes do not.
+func makeDevice(target string, header *tar.Header) error {
+	mode := uint32(header.Mode & 0o777)
+	switch header.Typeflag {
+	case tar.TypeChar:
+		mode |= syscall.S_IFCHR
+	case tar.TypeBlock:
+		mode |= syscall.S_IFBLK
+	case tar.TypeFifo:
+		mode |= syscall.S_IFIFO
+	}
+	return syscall.Mknod(target, mode, mkdev(header.Devmajor, header.Devminor))
+}
+
+// Encodes a device number as glibc does.
+func mkdev(major, minor int64) int {
+	return int((major&0xfff)<<8 | (major&^0xfff)<<32 | minor&0xff | (minor&^0xff)<<12)
+}
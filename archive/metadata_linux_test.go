@@ -0,0 +1,145 @@
+package archive
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+// Owner and group IDs of a file.
+func fileOwner(t *testing.T, path string) (int, int) {
+	t.Helper()
+
+	info, err := os.Lstat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	st := info.Sys().(*syscall.Stat_t)
+	return int(st.Uid), int(st.Gid)
+}
+
+func TestPreserveOwnership(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("changing ownership requires root")
+	}
+
+	srcDir := t.TempDir()
+	createTestFiles(t, srcDir)
+	if err := os.Lchown(filepath.Join(srcDir, "file.txt"), 1234, 5678); err != nil {
+		t.Fatal(err)
+	}
+
+	archivePath := filepath.Join(t.TempDir(), "test.tar.gz")
+	if err := Create(srcDir, archivePath); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	destDir := filepath.Join(t.TempDir(), "extracted")
+	opts := ExtractOptions{
+		PreserveOwnership: true,
+		UIDMap:            []IDMapping{{ArchiveID: 0, HostID: 0, Size: 1}, {ArchiveID: 1234, HostID: 2000, Size: 1}},
+		GIDMap:            []IDMapping{{ArchiveID: 0, HostID: 0, Size: 1}, {ArchiveID: 5678, HostID: 3000, Size: 1}},
+	}
+	if err := ExtractWithOptions(archivePath, destDir, opts); err != nil {
+		t.Fatalf("ExtractWithOptions failed: %v", err)
+	}
+
+	uid, gid := fileOwner(t, filepath.Join(destDir, "file.txt"))
+	if uid != 2000 || gid != 3000 {
+		t.Fatalf("owner = %d:%d, want 2000:3000", uid, gid)
+	}
+
+	opts.UIDMap = []IDMapping{{ArchiveID: 0, HostID: 0, Size: 1}}
+	err := ExtractWithOptions(archivePath, filepath.Join(t.TempDir(), "extracted"), opts)
+	if !errors.Is(err, ErrIDNotMapped) {
+		t.Fatalf("expected ErrIDNotMapped, got: %v", err)
+	}
+}
+
+func TestPreserveXattrs(t *testing.T) {
+	srcDir := t.TempDir()
+	createTestFiles(t, srcDir)
+	path := filepath.Join(srcDir, "file.txt")
+	if err := syscall.Setxattr(path, "user.crucible", []byte("value"), 0); err != nil {
+		t.Skipf("extended attributes not supported: %v", err)
+	}
+
+	archivePath := filepath.Join(t.TempDir(), "test.tar.zst")
+	if err := CreateWithOptions(srcDir, archivePath, CreateOptions{Xattrs: true}); err != nil {
+		t.Fatalf("CreateWithOptions failed: %v", err)
+	}
+
+	header, err := Stat(archivePath, "file.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := header.PAXRecords["SCHILY.xattr.user.crucible"]; got != "value" {
+		t.Fatalf("PAX record = %q, want %q", got, "value")
+	}
+
+	destDir := filepath.Join(t.TempDir(), "extracted")
+	if err := ExtractWithOptions(archivePath, destDir, ExtractOptions{Xattrs: true}); err != nil {
+		t.Fatalf("ExtractWithOptions failed: %v", err)
+	}
+
+	attrs, err := readXattrs(filepath.Join(destDir, "file.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if attrs["user.crucible"] != "value" {
+		t.Fatalf("xattrs = %v", attrs)
+	}
+}
+
+func TestPreserveDevices(t *testing.T) {
+	srcDir := t.TempDir()
+	createTestFiles(t, srcDir)
+	if err := syscall.Mkfifo(filepath.Join(srcDir, "fifo"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if os.Geteuid() == 0 {
+		// /dev/null: character device 1:3.
+		if err := syscall.Mknod(filepath.Join(srcDir, "null"), syscall.S_IFCHR|0666, mkdev(1, 3)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := Create(srcDir, filepath.Join(t.TempDir(), "test.tar")); !errors.Is(err, ErrUnsupportedFileType) {
+		t.Fatalf("expected ErrUnsupportedFileType without Devices, got: %v", err)
+	}
+
+	archivePath := filepath.Join(t.TempDir(), "test.tar")
+	if err := CreateWithOptions(srcDir, archivePath, CreateOptions{Devices: true}); err != nil {
+		t.Fatalf("CreateWithOptions failed: %v", err)
+	}
+
+	if err := Extract(archivePath, filepath.Join(t.TempDir(), "extracted")); !errors.Is(err, ErrUnsupportedFileType) {
+		t.Fatalf("expected ErrUnsupportedFileType without Devices, got: %v", err)
+	}
+
+	destDir := filepath.Join(t.TempDir(), "extracted")
+	if err := ExtractWithOptions(archivePath, destDir, ExtractOptions{Devices: true}); err != nil {
+		t.Fatalf("ExtractWithOptions failed: %v", err)
+	}
+
+	info, err := os.Lstat(filepath.Join(destDir, "fifo"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode()&os.ModeNamedPipe == 0 {
+		t.Fatalf("fifo: mode = %v, want named pipe", info.Mode())
+	}
+
+	if os.Geteuid() == 0 {
+		info, err := os.Lstat(filepath.Join(destDir, "null"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		st := info.Sys().(*syscall.Stat_t)
+		if info.Mode()&os.ModeCharDevice == 0 || st.Rdev != uint64(mkdev(1, 3)) {
+			t.Fatalf("null: mode = %v, rdev = %d", info.Mode(), st.Rdev)
+		}
+	}
+}
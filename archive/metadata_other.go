@@ -0,0 +1,26 @@
+//go:build !linux
+
+package archive
+
+import "archive/tar"
+
+// Reads the extended attributes of a file.
+//
+// Extended attributes are not captured on this platform.
+func readXattrs(path string) (map[string]string, error) {
+	return nil, nil
+}
+
+// Sets an extended attribute of a file.
+//
+// Extended attributes are not restored on this platform.
+func setXattr(path, attr, value string) error {
+	return nil
+}
+
+// Creates a device or named pipe for an entry.
+//
+// Devices and named pipes are only created on Linux.
+func makeDevice(target string, header *tar.Header) error {
+	return ErrUnsupportedFileType
+}
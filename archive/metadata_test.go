@@ -0,0 +1,111 @@
+package archive
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestMapID(t *testing.T) {
+	mappings := []IDMapping{{ArchiveID: 0, HostID: 100000, Size: 65536}, {ArchiveID: 70000, HostID: 1000, Size: 1}}
+
+	tests := []struct {
+		id      int
+		want    int
+		wantErr bool
+	}{
+		{0, 100000, false},
+		{65535, 165535, false},
+		{70000, 1000, false},
+		{65536, 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := mapID(tt.id, mappings)
+		if tt.wantErr {
+			if !errors.Is(err, ErrIDNotMapped) {
+				t.Errorf("mapID(%d): expected ErrIDNotMapped, got: %v", tt.id, err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("mapID(%d) = %d, %v, want %d", tt.id, got, err, tt.want)
+		}
+	}
+
+	if got, err := mapID(42, nil); err != nil || got != 42 {
+		t.Errorf("identity mapID(42) = %d, %v", got, err)
+	}
+}
+
+func TestPreserveTimesAndSpecialBits(t *testing.T) {
+	srcDir := t.TempDir()
+	createTestFiles(t, srcDir)
+
+	mtime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	for _, p := range []string{"file.txt", "subdir/nested.txt", "subdir"} {
+		if err := os.Chtimes(filepath.Join(srcDir, p), mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Chmod(filepath.Join(srcDir, "emptydir"), 0755|os.ModeSticky); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(filepath.Join(srcDir, "file.txt"), 0755|os.ModeSetuid); err != nil {
+		t.Fatal(err)
+	}
+
+	archivePath := filepath.Join(t.TempDir(), "test.tar.zst")
+	if err := CreateWithOptions(srcDir, archivePath, CreateOptions{SpecialBits: true}); err != nil {
+		t.Fatalf("CreateWithOptions failed: %v", err)
+	}
+
+	t.Run("default", func(t *testing.T) {
+		destDir := filepath.Join(t.TempDir(), "extracted")
+		if err := Extract(archivePath, destDir); err != nil {
+			t.Fatalf("Extract failed: %v", err)
+		}
+		info, err := os.Stat(filepath.Join(destDir, "file.txt"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Mode()&os.ModeSetuid != 0 || info.ModTime().Equal(mtime) {
+			t.Fatalf("metadata restored without options: %v, %v", info.Mode(), info.ModTime())
+		}
+	})
+
+	t.Run("preserved", func(t *testing.T) {
+		destDir := filepath.Join(t.TempDir(), "extracted")
+		opts := ExtractOptions{PreserveTimes: true, SpecialBits: true}
+		if err := ExtractWithOptions(archivePath, destDir, opts); err != nil {
+			t.Fatalf("ExtractWithOptions failed: %v", err)
+		}
+
+		for _, p := range []string{"file.txt", "subdir/nested.txt", "subdir"} {
+			info, err := os.Stat(filepath.Join(destDir, p))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !info.ModTime().Equal(mtime) {
+				t.Errorf("%s: mtime = %v, want %v", p, info.ModTime(), mtime)
+			}
+		}
+
+		info, err := os.Stat(filepath.Join(destDir, "file.txt"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Mode() != 0755|os.ModeSetuid {
+			t.Errorf("file.txt: mode = %v, want setuid 0755", info.Mode())
+		}
+		info, err = os.Stat(filepath.Join(destDir, "emptydir"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Mode()&os.ModeSticky == 0 {
+			t.Errorf("emptydir: mode = %v, want sticky", info.Mode())
+		}
+	})
+}
@@ -0,0 +1,188 @@
+package archive
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"hash/crc32"
+	"io"
+	"sync"
+
+	"github.com/klauspost/compress/flate"
+)
+
+const (
+	gzipBlockSize = 1 << 20  // Uncompressed size of each block compressed in parallel.
+	gzipDictSize  = 32 << 10 // Size of the deflate window carried over between blocks.
+)
+
+// Gzip writer that compresses blocks in parallel.
+//
+// Input is split into blocks, each deflated by its own goroutine using the
+// end of the previous block as a dictionary, and ending on a byte boundary
+// with a sync flush, except for the last, which ends the stream. The blocks
+// are written in order, so the output is a single standard gzip member. The
+// output depends only on the input and level, not on the concurrency.
+type parallelGzipWriter struct {
+	w       io.Writer
+	level   int
+	block   []byte             // Block being filled.
+	dict    []byte             // End of the last submitted block.
+	crc     uint32             // CRC-32 of the input so far.
+	size    uint32             // Input size so far, modulo 2^32.
+	queue   chan chan gzipPart // Compressed blocks, in order, bounded by the concurrency.
+	done    chan struct{}      // Closed when the output goroutine exits.
+	mu      sync.Mutex
+	err     error // First error, set by the output goroutine.
+	started bool
+	closed  bool
+}
+
+// Compressed block, or the error compressing it.
+type gzipPart struct {
+	data []byte
+	err  error
+}
+
+// Returns a parallel gzip writer writing to w with up to concurrency blocks
+// in flight.
+func newParallelGzipWriter(w io.Writer, level, concurrency int) *parallelGzipWriter {
+	return &parallelGzipWriter{
+		w:     w,
+		level: level,
+		block: make([]byte, 0, gzipBlockSize),
+		queue: make(chan chan gzipPart, concurrency),
+		done:  make(chan struct{}),
+	}
+}
+
+// Implements [io.Writer].
+func (z *parallelGzipWriter) Write(p []byte) (int, error) {
+	if err := z.error(); err != nil {
+		return 0, err
+	}
+
+	n := len(p)
+	z.crc = crc32.Update(z.crc, crc32.IEEETable, p)
+	z.size += uint32(n)
+	for len(p) > 0 {
+		k := min(len(p), gzipBlockSize-len(z.block))
+		z.block = append(z.block, p[:k]...)
+		p = p[k:]
+		if len(z.block) == gzipBlockSize {
+			z.submit(false)
+		}
+	}
+	return n, nil
+}
+
+// Compresses the remaining input and writes the gzip trailer.
+func (z *parallelGzipWriter) Close() error {
+	if z.closed {
+		return z.error()
+	}
+	z.closed = true
+
+	z.submit(true)
+	close(z.queue)
+	<-z.done
+
+	if err := z.error(); err != nil {
+		return err
+	}
+	trailer := binary.LittleEndian.AppendUint32(nil, z.crc)
+	trailer = binary.LittleEndian.AppendUint32(trailer, z.size)
+	_, err := z.w.Write(trailer)
+	return err
+}
+
+// Queues the current block for compression.
+func (z *parallelGzipWriter) submit(last bool) {
+	if !z.started {
+		z.started = true
+		go z.output()
+	}
+
+	block, dict := z.block, z.dict
+	z.dict = block[max(len(block)-gzipDictSize, 0):]
+	z.block = make([]byte, 0, gzipBlockSize)
+
+	part := make(chan gzipPart, 1)
+	z.queue <- part
+	go func() {
+		data, err := deflateBlock(block, dict, z.level, last)
+		part <- gzipPart{data, err}
+	}()
+}
+
+// Writes the gzip header and the compressed blocks in order.
+//
+// After an error, the remaining blocks are drained without being written.
+func (z *parallelGzipWriter) output() {
+	defer close(z.done)
+
+	z.setError(writeGzipHeader(z.w, z.level))
+	for part := range z.queue {
+		p := <-part
+		if z.error() != nil {
+			continue
+		}
+		if p.err != nil {
+			z.setError(p.err)
+			continue
+		}
+		_, err := z.w.Write(p.data)
+		z.setError(err)
+	}
+}
+
+// First error encountered by the output goroutine.
+func (z *parallelGzipWriter) error() error {
+	z.mu.Lock()
+	defer z.mu.Unlock()
+	return z.err
+}
+
+// Records an error, keeping the first one.
+func (z *parallelGzipWriter) setError(err error) {
+	z.mu.Lock()
+	defer z.mu.Unlock()
+	if z.err == nil {
+		z.err = err
+	}
+}
+
+// Deflates a block primed with a dictionary.
+//
+// The last block ends the deflate stream; the others end with a sync flush,
+// so that the next block's output can follow it directly.
+func deflateBlock(block, dict []byte, level int, last bool) ([]byte, error) {
+	var buf bytes.Buffer
+	fw, err := flate.NewWriterDict(&buf, level, dict)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := fw.Write(block); err != nil {
+		return nil, err
+	}
+	if last {
+		err = fw.Close()
+	} else {
+		err = fw.Flush()
+	}
+	return buf.Bytes(), err
+}
+
+// Writes a gzip header without name, comment, or modification time, as
+// [gzip.Writer] does.
+func writeGzipHeader(w io.Writer, level int) error {
+	header := []byte{0x1f, 0x8b, 8, 0, 0, 0, 0, 0, 0, 255}
+	switch level {
+	case gzip.BestCompression:
+		header[8] = 2
+	case gzip.BestSpeed:
+		header[8] = 4
+	}
+	_, err := w.Write(header)
+	return err
+}
@@ -0,0 +1,82 @@
+package archive
+
+import (
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Maximum size of a file written by the pool; larger files are written as
+// they are read, so that buffered contents stay bounded.
+const pooledFileSize = 4 << 20
+
+// Bounded pool of goroutines writing extracted files.
+type writerPool struct {
+	sem     chan struct{} // Holds a token per file being written.
+	wg      sync.WaitGroup
+	mu      sync.Mutex
+	err     error           // First write error.
+	targets map[string]bool // Paths being written.
+}
+
+// Returns a pool writing up to n files at once, or nil if n is at most one.
+func newWriterPool(n int) *writerPool {
+	if n <= 1 {
+		return nil
+	}
+	return &writerPool{sem: make(chan struct{}, n), targets: make(map[string]bool)}
+}
+
+// Runs fn, which writes target, once a goroutine is free.
+//
+// Returns the first error of an earlier write, if any, without running fn.
+func (p *writerPool) submit(target string, fn func() error) error {
+	p.sem <- struct{}{}
+
+	p.mu.Lock()
+	if err := p.err; err != nil {
+		p.mu.Unlock()
+		<-p.sem
+		return err
+	}
+	p.targets[target] = true
+	p.mu.Unlock()
+
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		err := fn()
+
+		p.mu.Lock()
+		delete(p.targets, target)
+		if err != nil && p.err == nil {
+			p.err = err
+		}
+		p.mu.Unlock()
+		<-p.sem
+	}()
+	return nil
+}
+
+// Whether target, or a path beneath it, is being written.
+func (p *writerPool) busy(target string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	prefix := target + string(filepath.Separator)
+	for t := range p.targets {
+		if t == target || strings.HasPrefix(t, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Waits for pending writes, returning the first error.
+func (p *writerPool) wait() error {
+	p.wg.Wait()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.err
+}
@@ -0,0 +1,94 @@
+package archive
+
+import (
+	"context"
+	"io"
+)
+
+// Number of bytes processed between two progress reports within an entry.
+const progressInterval = 1 << 20
+
+// Progress of an archive operation.
+//
+// What Bytes counts depends on the operation: file contents read from the
+// source tree when creating an archive, and compressed bytes read from the
+// archive when extracting one.
+type Progress struct {
+	Entries int   // Number of entries processed so far.
+	Bytes   int64 // Number of bytes processed so far.
+	Total   int64 // Total number of bytes to process, or -1 if unknown.
+}
+
+// Receives progress reports.
+//
+// Reports are made from the goroutine running the operation, after each
+// entry, every MiB of data within an entry, and once more on success, so the
+// function should return quickly.
+type ProgressFunc func(Progress)
+
+// Tracks cancellation and progress over the course of one operation.
+type tracker struct {
+	ctx  context.Context
+	fn   ProgressFunc // Progress callback (nil for none).
+	p    Progress
+	next int64 // Byte count at which progress is next reported.
+}
+
+// Returns a tracker for ctx reporting to fn, with the given total.
+func newTracker(ctx context.Context, fn ProgressFunc, total int64) *tracker {
+	return &tracker{ctx: ctx, fn: fn, p: Progress{Total: total}, next: progressInterval}
+}
+
+// Returns the context's error once it is cancelled, and nil before.
+func (t *tracker) check() error {
+	return t.ctx.Err()
+}
+
+// Counts a processed entry and reports progress.
+func (t *tracker) entry() {
+	t.p.Entries++
+	t.report()
+}
+
+// Counts processed bytes, reporting progress every [progressInterval].
+func (t *tracker) add(n int) {
+	t.p.Bytes += int64(n)
+	if t.p.Bytes >= t.next {
+		t.next = t.p.Bytes + progressInterval
+		t.report()
+	}
+}
+
+// Reports the final progress of a successful operation.
+func (t *tracker) done() {
+	t.report()
+}
+
+// Calls the progress callback, if any.
+func (t *tracker) report() {
+	if t.fn != nil {
+		t.fn(t.p)
+	}
+}
+
+// Wraps r to count the bytes read through it, and to fail once the context
+// is cancelled.
+func (t *tracker) reader(r io.Reader) io.Reader {
+	return &trackingReader{r: r, t: t}
+}
+
+// Reader counting bytes for a tracker.
+type trackingReader struct {
+	r io.Reader
+	t *tracker
+}
+
+// Implements [io.Reader].
+func (r *trackingReader) Read(p []byte) (int, error) {
+	if err := r.t.check(); err != nil {
+		return 0, err
+	}
+	n, err := r.r.Read(p)
+	r.t.add(n)
+	return n, err
+}
@@ -0,0 +1,140 @@
+package archive
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Fails unless reports never go backwards.
+func assertMonotonic(t *testing.T, reports []Progress) {
+	t.Helper()
+
+	for i := 1; i < len(reports); i++ {
+		if reports[i].Entries < reports[i-1].Entries || reports[i].Bytes < reports[i-1].Bytes {
+			t.Fatalf("progress went backwards: %+v after %+v", reports[i], reports[i-1])
+		}
+	}
+}
+
+func TestCreateProgress(t *testing.T) {
+	srcDir := t.TempDir()
+	createTree(t, srcDir, 20, 1000)
+
+	var reports []Progress
+	opts := CreateOptions{Progress: func(p Progress) { reports = append(reports, p) }}
+	archivePath := filepath.Join(t.TempDir(), "test.tar.gz")
+	if err := CreateWithOptions(srcDir, archivePath, opts); err != nil {
+		t.Fatalf("CreateWithOptions failed: %v", err)
+	}
+
+	assertMonotonic(t, reports)
+	last := reports[len(reports)-1]
+	want := Progress{Entries: 10 + 20 + 1, Bytes: 20*1000 + 3*gzipBlockSize + 12345, Total: -1}
+	if last != want {
+		t.Errorf("expected final progress %+v, got %+v", want, last)
+	}
+	// The large file is reported every MiB in addition to each entry.
+	if len(reports) < want.Entries+3 {
+		t.Errorf("expected at least %d reports, got %d", want.Entries+3, len(reports))
+	}
+}
+
+func TestCreateContextCancelled(t *testing.T) {
+	srcDir := t.TempDir()
+	createTree(t, srcDir, 5, 1000)
+
+	ctx, cancel := context.WithCancel(t.Context())
+	opts := CreateOptions{Progress: func(p Progress) {
+		// Cancel in the middle of the large file.
+		if p.Bytes > 5*1000+progressInterval {
+			cancel()
+		}
+	}}
+
+	archivePath := filepath.Join(t.TempDir(), "test.tar.zst")
+	err := CreateContext(ctx, srcDir, archivePath, opts)
+	if !errors.Is(err, ErrCreateFailed) || !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected cancellation, got: %v", err)
+	}
+	if _, err := os.Stat(archivePath); !os.IsNotExist(err) {
+		t.Error("partial archive should be removed")
+	}
+}
+
+func TestExtractProgress(t *testing.T) {
+	srcDir := t.TempDir()
+	createTree(t, srcDir, 20, 1000)
+	archivePath := filepath.Join(t.TempDir(), "test.tar.gz")
+	if err := Create(srcDir, archivePath); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(archivePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var reports []Progress
+	opts := ExtractOptions{Progress: func(p Progress) { reports = append(reports, p) }}
+	if err := ExtractWithOptions(archivePath, filepath.Join(t.TempDir(), "extracted"), opts); err != nil {
+		t.Fatalf("ExtractWithOptions failed: %v", err)
+	}
+
+	assertMonotonic(t, reports)
+	last := reports[len(reports)-1]
+	want := Progress{Entries: 10 + 20 + 1, Bytes: info.Size(), Total: info.Size()}
+	if last != want {
+		t.Errorf("expected final progress %+v, got %+v", want, last)
+	}
+}
+
+func TestExtractContextCancelled(t *testing.T) {
+	srcDir := t.TempDir()
+	createTree(t, srcDir, 20, 1000)
+	archivePath := filepath.Join(t.TempDir(), "test.tar.zst")
+	if err := Create(srcDir, archivePath); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, mode := range []ExtractMode{ExtractFail, ExtractAtomic} {
+		ctx, cancel := context.WithCancel(t.Context())
+		opts := ExtractOptions{Mode: mode, Concurrency: 4, Progress: func(p Progress) {
+			if p.Entries == 5 {
+				cancel()
+			}
+		}}
+
+		destDir := filepath.Join(t.TempDir(), "extracted")
+		err := ExtractContext(ctx, archivePath, destDir, opts)
+		if !errors.Is(err, ErrExtractFailed) || !errors.Is(err, context.Canceled) {
+			t.Fatalf("mode %d: expected cancellation, got: %v", mode, err)
+		}
+		entries, _ := os.ReadDir(filepath.Dir(destDir))
+		if len(entries) != 0 {
+			t.Errorf("mode %d: expected no files left behind, got %d", mode, len(entries))
+		}
+	}
+}
+
+func TestExtractFromContextCancelled(t *testing.T) {
+	archivePath := createTestArchive(t, "test.tar.gz")
+	file, err := os.Open(archivePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+
+	destDir := filepath.Join(t.TempDir(), "extracted")
+	err = ExtractFromContext(ctx, file, destDir, Gzip, ExtractOptions{})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected cancellation, got: %v", err)
+	}
+	if _, err := os.Stat(destDir); !os.IsNotExist(err) {
+		t.Error("destination should be removed")
+	}
+}
@@ -0,0 +1,214 @@
+package archive
+
+import (
+	"archive/tar"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+const (
+	skippableMagic = 0x184d2a5e // Magic number of the zstd skippable frame holding the index.
+	indexMagic     = 0x58495243 // Magic number ending the index ("CRIX").
+	indexFooterLen = 8          // Length of the index footer: payload length and magic.
+	frameHeaderLen = 8          // Length of a skippable frame header: magic and size.
+)
+
+// Entry of the index of a seekable Zstandard archive.
+//
+// Each entry is compressed as its own frame, holding its tar header and
+// contents, so it can be read by decompressing that frame alone.
+type indexEntry struct {
+	Name     string    `json:"name"`
+	Typeflag byte      `json:"type"`
+	Mode     int64     `json:"mode"`
+	Size     int64     `json:"size"`
+	ModTime  time.Time `json:"mtime"`
+	Linkname string    `json:"link,omitempty"`
+	Offset   int64     `json:"offset"` // Offset of the entry's frame in the archive.
+	Length   int64     `json:"length"` // Compressed length of the entry's frame.
+}
+
+// Header described by an index entry.
+//
+// The header only carries the fields stored in the index.
+func (e indexEntry) header() *tar.Header {
+	return &tar.Header{
+		Name:     e.Name,
+		Typeflag: e.Typeflag,
+		Mode:     e.Mode,
+		Size:     e.Size,
+		ModTime:  e.ModTime,
+		Linkname: e.Linkname,
+	}
+}
+
+// Writes a tar archive as a seekable Zstandard stream.
+//
+// Every entry is compressed as a separate frame, and the end-of-archive
+// marker as a final frame. A skippable frame holding the index of the entry
+// frames follows, ending with a footer that locates it from the end of the
+// stream. Zstandard decoders concatenate the frames and ignore the skippable
+// one, so the output is an ordinary .tar.zst archive to other tools.
+type seekableWriter struct {
+	w       *countingWriter
+	enc     *zstd.Encoder
+	tw      *tar.Writer
+	index   []indexEntry
+	inFrame bool // Whether an entry frame is open.
+}
+
+// Returns a seekable writer writing to w, with encoder parameters from the
+// options.
+func newSeekableWriter(w io.Writer, opts CreateOptions) (*seekableWriter, error) {
+	cw := &countingWriter{w: w}
+	enc, err := newZstdEncoder(cw, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &seekableWriter{w: cw, enc: enc, tw: tar.NewWriter(enc)}, nil
+}
+
+// Starts the frame of a new entry and writes its header.
+func (s *seekableWriter) WriteHeader(header *tar.Header) error {
+	if err := s.endFrame(); err != nil {
+		return err
+	}
+
+	s.enc.Reset(s.w)
+	s.inFrame = true
+	s.index = append(s.index, indexEntry{
+		Name:     header.Name,
+		Typeflag: header.Typeflag,
+		Mode:     header.Mode,
+		Size:     header.Size,
+		ModTime:  header.ModTime.UTC(),
+		Linkname: header.Linkname,
+		Offset:   s.w.n,
+	})
+
+	return s.tw.WriteHeader(header)
+}
+
+// Writes contents of the current entry.
+func (s *seekableWriter) Write(p []byte) (int, error) {
+	return s.tw.Write(p)
+}
+
+// Ends the archive and writes the index.
+//
+// The encoder is released even if the archive is incomplete.
+func (s *seekableWriter) Close() error {
+	if err := s.endFrame(); err != nil {
+		s.enc.Close()
+		return err
+	}
+
+	s.enc.Reset(s.w)
+	if err := s.tw.Close(); err != nil {
+		s.enc.Close()
+		return err
+	}
+	if err := s.enc.Close(); err != nil {
+		return err
+	}
+
+	return s.writeIndex()
+}
+
+// Pads the current entry and closes its frame, if one is open.
+func (s *seekableWriter) endFrame() error {
+	if !s.inFrame {
+		return nil
+	}
+	s.inFrame = false
+
+	if err := s.tw.Flush(); err != nil {
+		return err
+	}
+	if err := s.enc.Close(); err != nil {
+		return err
+	}
+
+	last := &s.index[len(s.index)-1]
+	last.Length = s.w.n - last.Offset
+	return nil
+}
+
+// Writes the skippable frame holding the index.
+func (s *seekableWriter) writeIndex() error {
+	payload, err := json.Marshal(s.index)
+	if err != nil {
+		return err
+	}
+
+	frame := make([]byte, 0, frameHeaderLen+len(payload)+indexFooterLen)
+	frame = binary.LittleEndian.AppendUint32(frame, skippableMagic)
+	frame = binary.LittleEndian.AppendUint32(frame, uint32(len(payload)+indexFooterLen))
+	frame = append(frame, payload...)
+	frame = binary.LittleEndian.AppendUint32(frame, uint32(len(payload)))
+	frame = binary.LittleEndian.AppendUint32(frame, indexMagic)
+
+	_, err = s.w.Write(frame)
+	return err
+}
+
+// Reads the index at the end of a seekable Zstandard archive.
+//
+// Returns false if the archive has no index, as is the case for archives not
+// created by this package.
+func readIndex(r io.ReaderAt, size int64) ([]indexEntry, bool, error) {
+	if size < frameHeaderLen+indexFooterLen {
+		return nil, false, nil
+	}
+
+	footer := make([]byte, indexFooterLen)
+	if _, err := r.ReadAt(footer, size-indexFooterLen); err != nil {
+		return nil, false, err
+	}
+	if binary.LittleEndian.Uint32(footer[4:]) != indexMagic {
+		return nil, false, nil
+	}
+
+	payloadLen := int64(binary.LittleEndian.Uint32(footer))
+	start := size - indexFooterLen - payloadLen - frameHeaderLen
+	if start < 0 {
+		return nil, false, nil
+	}
+
+	frame := make([]byte, frameHeaderLen+payloadLen)
+	if _, err := r.ReadAt(frame, start); err != nil {
+		return nil, false, err
+	}
+	if binary.LittleEndian.Uint32(frame) != skippableMagic ||
+		int64(binary.LittleEndian.Uint32(frame[4:])) != payloadLen+indexFooterLen {
+		return nil, false, nil
+	}
+
+	var index []indexEntry
+	if err := json.Unmarshal(frame[frameHeaderLen:], &index); err != nil {
+		return nil, false, ErrInvalidIndex
+	}
+	for _, e := range index {
+		if e.Offset < 0 || e.Length < 0 || e.Offset+e.Length > start {
+			return nil, false, ErrInvalidIndex
+		}
+	}
+	return index, true, nil
+}
+
+// Writer that counts the bytes written through it.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+// Implements [io.Writer].
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
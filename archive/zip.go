@@ -0,0 +1,250 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/flate"
+	"io"
+	"io/fs"
+	"os"
+	"strings"
+)
+
+// Maximum size of a symlink target stored in a zip archive.
+const maxZipLinkLen = 4096
+
+// Destination of archive entries.
+//
+// Implemented by [tar.Writer] and by [zipWriter], so archives of every
+// format are written from the same tar headers.
+type entryWriter interface {
+	WriteHeader(header *tar.Header) error
+	Write(p []byte) (int, error)
+	Close() error
+}
+
+// Source of archive entries.
+//
+// Implemented by [tar.Reader] and by [zipReader], so archives of every
+// format are extracted through the same tar headers. Read returns the
+// contents of the current entry.
+type entryReader interface {
+	Next() (*tar.Header, error)
+	Read(p []byte) (int, error)
+}
+
+// Writes tar headers and contents as zip entries.
+//
+// Directories and symlinks are stored uncompressed, with symlink targets as
+// entry contents; regular files are deflated. Modification times are stored
+// in UTC so that reproducible archives do not depend on the host time zone.
+type zipWriter struct {
+	zw *zip.Writer
+	w  io.Writer // Current entry.
+}
+
+// Returns a zip writer writing to w.
+//
+// Files are deflated at the given level, or the default level if it is zero.
+// Returns [ErrInvalidLevel] for levels outside 1 to 9.
+func newZipWriter(w io.Writer, level int) (*zipWriter, error) {
+	zw := zip.NewWriter(w)
+	if level != 0 {
+		if level < flate.BestSpeed || level > flate.BestCompression {
+			return nil, ErrInvalidLevel
+		}
+		zw.RegisterCompressor(zip.Deflate, func(out io.Writer) (io.WriteCloser, error) {
+			return flate.NewWriter(out, level)
+		})
+	}
+	return &zipWriter{zw: zw}, nil
+}
+
+// Starts a new entry.
+//
+// Returns [ErrUnsupportedFileType] for hard links and special files, which
+// zip archives cannot represent.
+func (z *zipWriter) WriteHeader(header *tar.Header) error {
+	fh := &zip.FileHeader{
+		Name:     header.Name,
+		Modified: header.ModTime.UTC(),
+		Method:   zip.Deflate,
+	}
+	mode := fs.FileMode(header.Mode).Perm()
+
+	switch header.Typeflag {
+	case tar.TypeReg:
+	case tar.TypeDir:
+		fh.Name = strings.TrimSuffix(fh.Name, "/") + "/"
+		fh.Method = zip.Store
+		mode |= fs.ModeDir
+	case tar.TypeSymlink:
+		fh.Method = zip.Store
+		mode |= fs.ModeSymlink
+	default:
+		return ErrUnsupportedFileType
+	}
+	fh.SetMode(mode)
+
+	w, err := z.zw.CreateHeader(fh)
+	if err != nil {
+		return err
+	}
+	z.w = w
+
+	if header.Typeflag == tar.TypeSymlink {
+		_, err = io.WriteString(w, header.Linkname)
+	}
+	return err
+}
+
+// Writes contents of the current entry.
+func (z *zipWriter) Write(p []byte) (int, error) {
+	if z.w == nil {
+		return 0, zip.ErrFormat
+	}
+	return z.w.Write(p)
+}
+
+// Writes the zip central directory.
+func (z *zipWriter) Close() error {
+	return z.zw.Close()
+}
+
+// Reads zip entries as tar headers.
+type zipReader struct {
+	files []*zip.File
+	rc    io.ReadCloser             // Current entry, as opened.
+	r     io.Reader                 // Current entry, as passed through wrap.
+	wrap  func(io.Reader) io.Reader // Wraps the contents of each entry.
+}
+
+// Opens a zip archive from a stream.
+//
+// Zip archives keep their index at the end, so the stream is first copied to
+// a temporary file. The returned function closes and removes it. Each entry's
+// contents are passed through wrap, which lets extraction limits observe
+// decompressed bytes.
+func openZip(r io.Reader, wrap func(io.Reader) io.Reader) (*zipReader, func(), error) {
+	tmp, err := os.CreateTemp("", "archive-*.zip")
+	if err != nil {
+		return nil, nil, err
+	}
+	cleanup := func() {
+		tmp.Close()
+		os.Remove(tmp.Name())
+	}
+
+	size, err := io.Copy(tmp, r)
+	if err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	zr, err := zip.NewReader(tmp, size)
+	if err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	return &zipReader{files: zr.File, wrap: wrap}, cleanup, nil
+}
+
+// Advances to the next entry.
+//
+// Returns [io.EOF] after the last entry, and [ErrUnsupportedFileType] for
+// entries that are neither regular files, directories, nor symlinks.
+func (z *zipReader) Next() (*tar.Header, error) {
+	z.Close()
+	if len(z.files) == 0 {
+		return nil, io.EOF
+	}
+	f := z.files[0]
+	z.files = z.files[1:]
+
+	header, err := zipHeader(f)
+	if err != nil || header.Typeflag != tar.TypeReg {
+		return header, err
+	}
+
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	z.rc = rc
+	z.r = z.wrap(rc)
+	return header, nil
+}
+
+// Converts a zip entry to a tar header.
+//
+// Entries without permissions get 0755 for directories and 0644 for files.
+// The target of a symlink is read from its contents. Returns
+// [ErrUnsupportedFileType] for entries that are neither regular files,
+// directories, nor symlinks.
+func zipHeader(f *zip.File) (*tar.Header, error) {
+	mode := f.Mode()
+	header := &tar.Header{
+		Name:    f.Name,
+		Mode:    int64(mode.Perm()),
+		ModTime: f.Modified,
+	}
+
+	switch {
+	case mode.IsDir() || strings.HasSuffix(f.Name, "/"):
+		header.Typeflag = tar.TypeDir
+		if mode.Perm() == 0 {
+			header.Mode = 0755
+		}
+	case mode&fs.ModeSymlink != 0:
+		header.Typeflag = tar.TypeSymlink
+		link, err := readZipLink(f)
+		if err != nil {
+			return nil, err
+		}
+		header.Linkname = link
+	case mode.IsRegular():
+		header.Typeflag = tar.TypeReg
+		header.Size = int64(f.UncompressedSize64)
+		if mode.Perm() == 0 {
+			header.Mode = 0644
+		}
+	default:
+		return nil, ErrUnsupportedFileType
+	}
+	return header, nil
+}
+
+// Reads contents of the current entry.
+func (z *zipReader) Read(p []byte) (int, error) {
+	if z.r == nil {
+		return 0, io.EOF
+	}
+	return z.r.Read(p)
+}
+
+// Closes the current entry.
+func (z *zipReader) Close() error {
+	if z.rc == nil {
+		return nil
+	}
+	err := z.rc.Close()
+	z.rc, z.r = nil, nil
+	return err
+}
+
+// Reads the target of a symlink stored in a zip archive.
+func readZipLink(f *zip.File) (string, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return "", err
+	}
+	defer rc.Close()
+
+	data, err := io.ReadAll(io.LimitReader(rc, maxZipLinkLen+1))
+	if err != nil {
+		return "", err
+	}
+	if len(data) > maxZipLinkLen {
+		return "", ErrInvalidPath
+	}
+	return string(data), nil
+}
@@ -0,0 +1,15 @@
+//go:build !linux
+
+package cas
+
+import (
+	"errors"
+	"os"
+)
+
+// Makes dst a reflink clone of src.
+//
+// Reflinks are only supported on Linux; elsewhere blobs are copied.
+func clone(dst, src *os.File) error {
+	return errors.ErrUnsupported
+}
@@ -0,0 +1,38 @@
+// Package cas implements a content-addressed store for local artifacts.
+//
+// The daemon (cruxd) and the CLI (crux) keep downloaded archives and built
+// image tarballs in a [Store], keyed by the SHA-256 [reference.Digest] of
+// their content, so identical artifacts are stored once wherever they came
+// from. [Default] opens the store at [paths.Store], the location both
+// programs share.
+//
+// Content enters the store through [Store.Ingest], which verifies the digest
+// declared for a download, or [Store.Add], which computes it. Both write to
+// a temporary file and rename it into place, so readers never observe
+// partial content, and concurrent ingests of the same content are safe.
+// Blobs are read-only; [Store.Export] materialises one at another path by
+// hard link when possible, then by reflink, then by copying.
+//
+// A tag maps a name, such as a resource reference, to a digest
+// ([Store.Tag], [Store.Resolve]). Every read records the time the blob was
+// last used, and [Store.Prune] evicts the least recently used untagged blobs
+// to bring the store under a size budget. [Store.Fsck] rehashes every blob
+// and reports, and optionally removes, corrupt blobs and tags pointing to
+// missing ones.
+//
+// All state lives in files updated by atomic renames, and a lock file at the
+// root keeps blobs from being removed while they are being added or tagged,
+// so several processes may share a store.
+//
+// Caching a download:
+//
+//	store, err := cas.Default()
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	info, err := store.Ingest(resp.Body, digest)
+//	if err != nil {
+//		log.Fatal(err) // cas.ErrDigestMismatch if the download is corrupt
+//	}
+//	err = store.Tag("cruciblehq/hub:1.0.0", info.Digest)
+package cas
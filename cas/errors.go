@@ -0,0 +1,14 @@
+package cas
+
+import "errors"
+
+var (
+	ErrReadFailed  = errors.New("store read failed")
+	ErrWriteFailed = errors.New("store write failed")
+
+	ErrInvalidDigest  = errors.New("digest must be sha256 with a 64-character lowercase hex hash")
+	ErrDigestMismatch = errors.New("content does not match the expected digest")
+	ErrNotFound       = errors.New("blob not found")
+	ErrInvalidTag     = errors.New("tag must be 1 to 180 bytes without control characters")
+	ErrTagNotFound    = errors.New("tag not found")
+)
@@ -0,0 +1,71 @@
+package cas
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+
+	"github.com/cruciblehq/crex"
+	"github.com/cruciblehq/spec/paths"
+	"github.com/cruciblehq/spec/reference"
+)
+
+// Materialises a blob as a file at dest.
+//
+// Tries, in order, to hard link dest to the blob, to clone it with a reflink
+// on file systems that support one (such as Btrfs and XFS on Linux), and to
+// copy it. A hard-linked dest shares the blob's inode and read-only
+// permissions, so it costs no space but must not be modified; a cloned or
+// copied dest is an independent file with [paths.DefaultFileMode]. dest must
+// not exist. Records the use of the blob. Returns [ErrNotFound] if the blob
+// is not stored.
+func (s *Store) Export(digest reference.Digest, dest string) error {
+	src, err := s.Path(digest)
+	if err != nil {
+		return err
+	}
+
+	err = os.Link(src, dest)
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, fs.ErrExist) {
+		return crex.Wrap(ErrWriteFailed, err)
+	}
+
+	if err := copyBlob(src, dest); err != nil {
+		return crex.Wrap(ErrWriteFailed, err)
+	}
+	return nil
+}
+
+// Copies a blob to a new file, by reflink if possible.
+//
+// A partially written dest is removed.
+func copyBlob(src, dest string) (err error) {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dest, os.O_CREATE|os.O_EXCL|os.O_WRONLY, paths.DefaultFileMode)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := out.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			os.Remove(dest)
+		}
+	}()
+
+	if clone(out, in) == nil {
+		return nil
+	}
+	_, err = io.Copy(out, in)
+	return err
+}
@@ -0,0 +1,199 @@
+package cas
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/cruciblehq/crex"
+	"github.com/cruciblehq/spec/reference"
+)
+
+// Age after which a temporary file is considered abandoned by a crashed
+// ingest.
+const staleAge = 24 * time.Hour
+
+// Result of an integrity check of a store.
+type FsckReport struct {
+	Checked  int                // Number of blobs rehashed.
+	Corrupt  []reference.Digest // Blobs whose content does not match their digest.
+	Dangling []string           // Tags pointing to blobs that are not stored.
+	Invalid  []string           // Files that are neither blobs nor tags, relative to the store root.
+}
+
+// Whether the check found no problems.
+func (r *FsckReport) OK() bool {
+	return len(r.Corrupt) == 0 && len(r.Dangling) == 0 && len(r.Invalid) == 0
+}
+
+// Checks the integrity of the store.
+//
+// Rehashes every blob and compares the result with its digest, checks that
+// every tag points to a stored blob, and looks for stray files in the blob
+// and tag directories. With repair set, corrupt blobs, dangling tags, and
+// invalid files are removed, along with usage records of missing blobs and
+// temporary files abandoned for more than a day. The report lists what was
+// found, whether or not it was removed.
+func (s *Store) Fsck(repair bool) (*FsckReport, error) {
+	report := &FsckReport{Corrupt: []reference.Digest{}, Dangling: []string{}, Invalid: []string{}}
+
+	if repair {
+		unlock, err := s.lock(true)
+		if err != nil {
+			return nil, crex.Wrap(ErrWriteFailed, err)
+		}
+		defer unlock()
+	}
+
+	if err := s.checkBlobs(report, repair); err != nil {
+		return nil, crex.Wrap(ErrReadFailed, err)
+	}
+	if err := s.checkTags(report, repair); err != nil {
+		return nil, crex.Wrap(ErrReadFailed, err)
+	}
+	if repair {
+		if err := s.cleanUsage(); err != nil {
+			return nil, crex.Wrap(ErrWriteFailed, err)
+		}
+		if err := s.cleanTemp(); err != nil {
+			return nil, crex.Wrap(ErrWriteFailed, err)
+		}
+	}
+	return report, nil
+}
+
+// Rehashes every blob, recording corrupt blobs and invalid files.
+func (s *Store) checkBlobs(report *FsckReport, repair bool) error {
+	blobs := filepath.Join(s.root, "blobs")
+	return filepath.WalkDir(blobs, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+
+		digest := reference.Digest{Algorithm: DigestAlgorithm, Hash: d.Name()}
+		if validateDigest(digest) != nil || path != s.blobPath(digest) {
+			rel, _ := filepath.Rel(s.root, path)
+			report.Invalid = append(report.Invalid, filepath.ToSlash(rel))
+			if repair {
+				return os.Remove(path)
+			}
+			return nil
+		}
+
+		ok, err := matches(path, digest)
+		if err != nil {
+			return err
+		}
+		report.Checked++
+		if !ok {
+			report.Corrupt = append(report.Corrupt, digest)
+			if repair {
+				return s.remove(digest)
+			}
+		}
+		return nil
+	})
+}
+
+// Checks that every tag points to a stored blob.
+func (s *Store) checkTags(report *FsckReport, repair bool) error {
+	dir := filepath.Join(s.root, "tags")
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	tags, err := s.Tags()
+	if err != nil {
+		return err
+	}
+
+	valid := make(map[string]bool, len(tags))
+	for name, digest := range tags {
+		valid[filepath.Base(s.tagPath(name))] = true
+		if _, err := os.Stat(s.blobPath(digest)); errors.Is(err, fs.ErrNotExist) {
+			report.Dangling = append(report.Dangling, name)
+			if repair {
+				if err := s.Untag(name); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	slices.Sort(report.Dangling)
+
+	for _, e := range entries {
+		if valid[e.Name()] {
+			continue
+		}
+		report.Invalid = append(report.Invalid, "tags/"+e.Name())
+		if repair {
+			if err := os.RemoveAll(filepath.Join(dir, e.Name())); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Removes usage records of blobs that are not stored.
+func (s *Store) cleanUsage() error {
+	return filepath.WalkDir(filepath.Join(s.root, "usage"), func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		digest := reference.Digest{Algorithm: DigestAlgorithm, Hash: d.Name()}
+		if validateDigest(digest) == nil {
+			if _, err := os.Stat(s.blobPath(digest)); err == nil {
+				return nil
+			}
+		}
+		return os.Remove(path)
+	})
+}
+
+// Removes temporary files older than [staleAge].
+func (s *Store) cleanTemp() error {
+	dir := filepath.Join(s.root, "tmp")
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		info, err := e.Info()
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if time.Since(info.ModTime()) > staleAge {
+			if err := os.RemoveAll(filepath.Join(dir, e.Name())); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Whether the content of a file has the given digest.
+func matches(path string, digest reference.Digest) (bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return false, err
+	}
+	return hex.EncodeToString(h.Sum(nil)) == digest.Hash, nil
+}
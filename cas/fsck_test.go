@@ -0,0 +1,71 @@
+package cas
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/cruciblehq/spec/reference"
+)
+
+func TestFsck(t *testing.T) {
+	s := newTestStore(t)
+
+	good, err := s.Add(strings.NewReader("good"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	bad, err := s.Add(strings.NewReader("bad"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Tag("dangling", bad.Digest); err != nil {
+		t.Fatal(err)
+	}
+
+	// Corrupt one blob, and leave a stray file among the blobs.
+	path := s.blobPath(bad.Digest)
+	if err := os.Chmod(path, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("evil"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(s.root, "blobs", "stray"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := s.Fsck(false)
+	if err != nil {
+		t.Fatalf("Fsck failed: %v", err)
+	}
+	if report.OK() || report.Checked != 2 {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+	if !slices.Equal(report.Corrupt, []reference.Digest{bad.Digest}) {
+		t.Errorf("expected %v to be corrupt, got %v", bad.Digest, report.Corrupt)
+	}
+	if !slices.Equal(report.Invalid, []string{"blobs/stray"}) {
+		t.Errorf("unexpected invalid files: %v", report.Invalid)
+	}
+
+	// Repairing removes the corrupt blob, which leaves the tag dangling.
+	if _, err := s.Fsck(true); err != nil {
+		t.Fatalf("Fsck failed: %v", err)
+	}
+	report, err = s.Fsck(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.OK() {
+		t.Errorf("expected a clean store after repair, got %+v", report)
+	}
+	if _, err := s.Stat(good.Digest); err != nil {
+		t.Errorf("intact blob should be kept: %v", err)
+	}
+	if _, err := s.Resolve("dangling"); err == nil {
+		t.Error("dangling tag should be removed")
+	}
+}
@@ -0,0 +1,32 @@
+package cas
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/cruciblehq/spec/paths"
+)
+
+// Name of the lock file at the root of a store.
+const lockFile = "lock"
+
+// Locks the store against concurrent removal of blobs, returning the unlock
+// function.
+//
+// Operations that rely on a blob staying stored between two steps, such as
+// finding it present and then recording its use, take the lock shared.
+// Operations that remove blobs take it exclusive. The lock is a file lock,
+// so it serializes processes sharing the store as well as goroutines.
+func (s *Store) lock(exclusive bool) (func(), error) {
+	f, err := os.OpenFile(filepath.Join(s.root, lockFile), os.O_RDONLY|os.O_CREATE, paths.DefaultFileMode)
+	if err != nil {
+		return nil, err
+	}
+	if err := flock(f, exclusive); err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	// Closing the file releases the lock.
+	return func() { f.Close() }, nil
+}
@@ -0,0 +1,13 @@
+//go:build !unix
+
+package cas
+
+import "os"
+
+// Places an advisory lock on an open file.
+//
+// File locks are not available on this platform, so removal of blobs is not
+// serialized with their use.
+func flock(f *os.File, exclusive bool) error {
+	return nil
+}
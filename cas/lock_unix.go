@@ -0,0 +1,23 @@
+//go:build unix
+
+package cas
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// Places an advisory lock on an open file, waiting until it is available.
+func flock(f *os.File, exclusive bool) error {
+	how := syscall.LOCK_SH
+	if exclusive {
+		how = syscall.LOCK_EX
+	}
+	for {
+		err := syscall.Flock(int(f.Fd()), how)
+		if !errors.Is(err, syscall.EINTR) {
+			return err
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package cas
+
+import (
+	"slices"
+
+	"github.com/cruciblehq/crex"
+	"github.com/cruciblehq/spec/reference"
+)
+
+// Evicts the least recently used blobs until the store holds at most
+// maxSize bytes.
+//
+// Blobs pointed to by a tag are never evicted, so the store may remain above
+// maxSize if tagged blobs alone exceed it. Untagged blobs are removed in
+// order of last use, oldest first, until the total size of the remaining
+// blobs is within the budget. Returns the removed blobs. Ingests and tags
+// wait until pruning is done, but a blob only being read may still be
+// removed; open files remain readable on Unix.
+func (s *Store) Prune(maxSize int64) ([]Info, error) {
+	unlock, err := s.lock(true)
+	if err != nil {
+		return nil, crex.Wrap(ErrWriteFailed, err)
+	}
+	defer unlock()
+
+	tags, err := s.Tags()
+	if err != nil {
+		return nil, err
+	}
+	tagged := make(map[reference.Digest]bool, len(tags))
+	for _, digest := range tags {
+		tagged[digest] = true
+	}
+
+	infos, err := s.List()
+	if err != nil {
+		return nil, err
+	}
+
+	var total int64
+	for _, info := range infos {
+		total += info.Size
+	}
+
+	slices.SortFunc(infos, func(a, b Info) int {
+		return a.LastUsed.Compare(b.LastUsed)
+	})
+
+	removed := []Info{}
+	for _, info := range infos {
+		if total <= maxSize {
+			break
+		}
+		if tagged[info.Digest] {
+			continue
+		}
+		if err := s.remove(info.Digest); err != nil {
+			return removed, crex.Wrap(ErrWriteFailed, err)
+		}
+		total -= info.Size
+		removed = append(removed, info)
+	}
+	return removed, nil
+}
@@ -0,0 +1,85 @@
+package cas
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPrune(t *testing.T) {
+	s := newTestStore(t)
+
+	// Three blobs of 10 bytes, used an hour apart, oldest first.
+	var infos []*Info
+	for i, content := range []string{"aaaaaaaaaa", "bbbbbbbbbb", "cccccccccc"} {
+		info, err := s.Add(strings.NewReader(content))
+		if err != nil {
+			t.Fatal(err)
+		}
+		used := time.Now().Add(time.Duration(i-3) * time.Hour)
+		if err := os.Chtimes(s.usagePath(info.Digest), used, used); err != nil {
+			t.Fatal(err)
+		}
+		infos = append(infos, info)
+	}
+
+	// The oldest blob is tagged, and the second was just read.
+	if err := s.Tag("keep", infos[0].Digest); err != nil {
+		t.Fatal(err)
+	}
+	f, err := s.Open(infos[1].Digest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	removed, err := s.Prune(20)
+	if err != nil {
+		t.Fatalf("Prune failed: %v", err)
+	}
+	if len(removed) != 1 || removed[0].Digest != infos[2].Digest {
+		t.Fatalf("expected the least recently used untagged blob to be removed, got %+v", removed)
+	}
+
+	// Tagged blobs survive even when over budget.
+	removed, err = s.Prune(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(removed) != 1 || removed[0].Digest != infos[1].Digest {
+		t.Fatalf("expected only the untagged blob to be removed, got %+v", removed)
+	}
+	if _, err := s.Stat(infos[0].Digest); err != nil {
+		t.Errorf("tagged blob should be kept: %v", err)
+	}
+}
+
+func TestPruneExcludesTag(t *testing.T) {
+	s := newTestStore(t)
+	info, err := s.Add(strings.NewReader("data"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// While a prune holds the store, tagging waits rather than racing with
+	// the removal of the blob.
+	unlock, err := s.lock(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tagged := make(chan error)
+	go func() {
+		tagged <- s.Tag("keep", info.Digest)
+	}()
+
+	select {
+	case err := <-tagged:
+		t.Fatalf("Tag did not wait for the exclusive lock: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	unlock()
+	if err := <-tagged; err != nil {
+		t.Fatalf("Tag failed: %v", err)
+	}
+}
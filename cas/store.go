@@ -0,0 +1,328 @@
+package cas
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/cruciblehq/crex"
+	"github.com/cruciblehq/spec/paths"
+	"github.com/cruciblehq/spec/reference"
+)
+
+// Digest algorithm of stored blobs.
+const DigestAlgorithm = "sha256"
+
+// Permissions of stored blobs, which are never modified once written.
+const blobMode = 0o444
+
+// Content-addressed store rooted at a local directory.
+//
+// The directory holds:
+//
+//   - blobs/sha256/{hash[:2]}/{hash}: the content of each blob.
+//   - usage/sha256/{hash[:2]}/{hash}: an empty file whose modification time
+//     is the last use of the blob.
+//   - tags/{name}: the digest a tag points to, with the name encoded in
+//     unpadded URL-safe base64.
+//   - tmp: files being ingested.
+//
+// A Store is safe for concurrent use, including by several processes.
+// Operations that remove blobs ([Store.Prune], [Store.Delete], and
+// [Store.Fsck] with repair) hold a file lock at the root exclusively, and
+// [Store.Ingest], [Store.Add], and [Store.Tag] hold it shared, so a blob they
+// find stored is not removed before they return. Use [New] or [Default] to
+// construct one.
+type Store struct {
+	root string
+}
+
+// Identity and usage of a stored blob.
+type Info struct {
+	Digest   reference.Digest // SHA-256 digest of the content.
+	Size     int64            // Content size in bytes.
+	LastUsed time.Time        // When the blob was last added, read, or exported.
+}
+
+// Opens the store rooted at a directory.
+//
+// The directory and its layout are created if they do not exist.
+func New(root string) (*Store, error) {
+	for _, dir := range []string{"blobs", "usage", "tags", "tmp"} {
+		if err := os.MkdirAll(filepath.Join(root, dir), paths.DefaultDirMode); err != nil {
+			return nil, crex.Wrap(ErrWriteFailed, err)
+		}
+	}
+	return &Store{root: root}, nil
+}
+
+// Opens the store at [paths.Store], shared by the daemon and the CLI.
+func Default() (*Store, error) {
+	return New(paths.Store())
+}
+
+// Adds content to the store, verifying that it has the expected digest.
+//
+// Reads r to the end into a temporary file, and moves it into place only if
+// the digest of what was read matches; otherwise the file is discarded and
+// [ErrDigestMismatch] is returned. If the blob is already stored, the new
+// copy is discarded. Returns [ErrInvalidDigest] before reading anything if
+// the digest is not a SHA-256 digest.
+func (s *Store) Ingest(r io.Reader, digest reference.Digest) (*Info, error) {
+	if err := validateDigest(digest); err != nil {
+		return nil, err
+	}
+	return s.ingest(r, &digest)
+}
+
+// Adds content to the store under the digest computed while reading it.
+//
+// Behaves like [Store.Ingest] without verification.
+func (s *Store) Add(r io.Reader) (*Info, error) {
+	return s.ingest(r, nil)
+}
+
+// Writes r to a temporary file and installs it as a blob.
+//
+// If want is not nil, the content must match it.
+func (s *Store) ingest(r io.Reader, want *reference.Digest) (*Info, error) {
+	tmp, err := os.CreateTemp(filepath.Join(s.root, "tmp"), "ingest-*")
+	if err != nil {
+		return nil, crex.Wrap(ErrWriteFailed, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	h := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmp, h), r)
+	if err == nil {
+		err = tmp.Chmod(blobMode)
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, crex.Wrap(ErrWriteFailed, err)
+	}
+
+	digest := reference.Digest{Algorithm: DigestAlgorithm, Hash: hex.EncodeToString(h.Sum(nil))}
+	if want != nil && *want != digest {
+		return nil, crex.Wrap(ErrWriteFailed, ErrDigestMismatch)
+	}
+
+	unlock, err := s.lock(false)
+	if err != nil {
+		return nil, crex.Wrap(ErrWriteFailed, err)
+	}
+	defer unlock()
+
+	path := s.blobPath(digest)
+	if err := os.MkdirAll(filepath.Dir(path), paths.DefaultDirMode); err != nil {
+		return nil, crex.Wrap(ErrWriteFailed, err)
+	}
+	if _, err := os.Stat(path); errors.Is(err, fs.ErrNotExist) {
+		if err := os.Rename(tmp.Name(), path); err != nil {
+			return nil, crex.Wrap(ErrWriteFailed, err)
+		}
+	}
+
+	now, err := s.touch(digest)
+	if err != nil {
+		return nil, crex.Wrap(ErrWriteFailed, err)
+	}
+	return &Info{Digest: digest, Size: size, LastUsed: now}, nil
+}
+
+// Opens a blob for reading and records its use.
+//
+// Returns [ErrNotFound] if the blob is not stored.
+func (s *Store) Open(digest reference.Digest) (*os.File, error) {
+	if err := validateDigest(digest); err != nil {
+		return nil, err
+	}
+	f, err := os.Open(s.blobPath(digest))
+	if err != nil {
+		return nil, readError(err)
+	}
+	if _, err := s.touch(digest); err != nil {
+		f.Close()
+		return nil, crex.Wrap(ErrWriteFailed, err)
+	}
+	return f, nil
+}
+
+// Returns the path of a blob and records its use.
+//
+// The file at the path must not be modified. It may be removed by
+// [Store.Prune] or [Store.Delete]; callers needing a stable copy should use
+// [Store.Export]. Returns [ErrNotFound] if the blob is not stored.
+func (s *Store) Path(digest reference.Digest) (string, error) {
+	if _, err := s.Stat(digest); err != nil {
+		return "", err
+	}
+	if _, err := s.touch(digest); err != nil {
+		return "", crex.Wrap(ErrWriteFailed, err)
+	}
+	return s.blobPath(digest), nil
+}
+
+// Returns the size and last use of a blob.
+//
+// Does not record a use. Returns [ErrNotFound] if the blob is not stored.
+func (s *Store) Stat(digest reference.Digest) (*Info, error) {
+	if err := validateDigest(digest); err != nil {
+		return nil, err
+	}
+	fi, err := os.Stat(s.blobPath(digest))
+	if err != nil {
+		return nil, readError(err)
+	}
+	return &Info{Digest: digest, Size: fi.Size(), LastUsed: s.lastUsed(digest, fi)}, nil
+}
+
+// Lists the stored blobs.
+//
+// The order is unspecified. Files in the blob directory whose names are not
+// valid digests are ignored (see [Store.Fsck]).
+func (s *Store) List() ([]Info, error) {
+	var infos []Info
+	err := s.walkBlobs(func(digest reference.Digest, _ string, fi fs.FileInfo) error {
+		infos = append(infos, Info{Digest: digest, Size: fi.Size(), LastUsed: s.lastUsed(digest, fi)})
+		return nil
+	})
+	if err != nil {
+		return nil, crex.Wrap(ErrReadFailed, err)
+	}
+	return infos, nil
+}
+
+// Removes a blob.
+//
+// Tags pointing to the blob are left in place and reported as dangling by
+// [Store.Fsck]. The operation is idempotent.
+func (s *Store) Delete(digest reference.Digest) error {
+	if err := validateDigest(digest); err != nil {
+		return err
+	}
+	unlock, err := s.lock(true)
+	if err != nil {
+		return crex.Wrap(ErrWriteFailed, err)
+	}
+	defer unlock()
+
+	if err := s.remove(digest); err != nil {
+		return crex.Wrap(ErrWriteFailed, err)
+	}
+	return nil
+}
+
+// Removes the content and usage record of a blob, if present.
+func (s *Store) remove(digest reference.Digest) error {
+	if err := os.Remove(s.blobPath(digest)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	if err := os.Remove(s.usagePath(digest)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// Calls fn for each file in the blob directory named by a valid digest.
+func (s *Store) walkBlobs(fn func(digest reference.Digest, path string, fi fs.FileInfo) error) error {
+	dir := filepath.Join(s.root, "blobs", DigestAlgorithm)
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if errors.Is(err, fs.ErrNotExist) && path == dir {
+			return nil
+		}
+		if err != nil || d.IsDir() {
+			return err
+		}
+
+		digest := reference.Digest{Algorithm: DigestAlgorithm, Hash: d.Name()}
+		if validateDigest(digest) != nil || path != s.blobPath(digest) {
+			return nil
+		}
+		fi, err := d.Info()
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		return fn(digest, path, fi)
+	})
+}
+
+// Records the use of a blob now, returning the recorded time.
+func (s *Store) touch(digest reference.Digest) (time.Time, error) {
+	path := s.usagePath(digest)
+	now := time.Now()
+
+	err := os.Chtimes(path, now, now)
+	if errors.Is(err, fs.ErrNotExist) {
+		if err = os.MkdirAll(filepath.Dir(path), paths.DefaultDirMode); err != nil {
+			return time.Time{}, err
+		}
+		var f *os.File
+		if f, err = os.Create(path); err == nil {
+			f.Close()
+			err = os.Chtimes(path, now, now)
+		}
+	}
+	return now, err
+}
+
+// Last use of a blob whose content has the given file info.
+//
+// Falls back to the modification time of the content, which is when the
+// blob was added, if no use was recorded.
+func (s *Store) lastUsed(digest reference.Digest, fi fs.FileInfo) time.Time {
+	if ui, err := os.Stat(s.usagePath(digest)); err == nil {
+		return ui.ModTime()
+	}
+	return fi.ModTime()
+}
+
+// Path of the file holding a blob.
+//
+// The digest must have been validated, which guarantees the hash cannot
+// escape the root.
+func (s *Store) blobPath(digest reference.Digest) string {
+	return filepath.Join(s.root, "blobs", digest.Algorithm, digest.Hash[:2], digest.Hash)
+}
+
+// Path of the file recording the last use of a blob.
+func (s *Store) usagePath(digest reference.Digest) string {
+	return filepath.Join(s.root, "usage", digest.Algorithm, digest.Hash[:2], digest.Hash)
+}
+
+// Whether a digest can address a blob.
+//
+// The algorithm must be [DigestAlgorithm] and the hash 64 lowercase
+// hexadecimal characters; returns [ErrInvalidDigest] otherwise.
+func validateDigest(d reference.Digest) error {
+	if d.Algorithm != DigestAlgorithm || len(d.Hash) != 2*sha256.Size {
+		return ErrInvalidDigest
+	}
+	for _, c := range d.Hash {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return ErrInvalidDigest
+		}
+	}
+	return nil
+}
+
+// Wraps an error opening a blob, mapping a missing file to [ErrNotFound].
+func readError(err error) error {
+	if errors.Is(err, fs.ErrNotExist) {
+		return crex.Wrap(ErrReadFailed, ErrNotFound)
+	}
+	return crex.Wrap(ErrReadFailed, err)
+}
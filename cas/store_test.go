@@ -0,0 +1,179 @@
+package cas
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cruciblehq/spec/reference"
+)
+
+// Creates a store in a temporary directory.
+func newTestStore(t *testing.T) *Store {
+	t.Helper()
+
+	s, err := New(t.TempDir())
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	return s
+}
+
+// SHA-256 digest of a string.
+func digestOf(s string) reference.Digest {
+	sum := sha256.Sum256([]byte(s))
+	return reference.Digest{Algorithm: DigestAlgorithm, Hash: hex.EncodeToString(sum[:])}
+}
+
+func TestIngest(t *testing.T) {
+	s := newTestStore(t)
+	digest := digestOf("hello")
+
+	info, err := s.Ingest(strings.NewReader("hello"), digest)
+	if err != nil {
+		t.Fatalf("Ingest failed: %v", err)
+	}
+	if info.Digest != digest || info.Size != 5 || info.LastUsed.IsZero() {
+		t.Errorf("unexpected info: %+v", info)
+	}
+
+	f, err := s.Open(digest)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer f.Close()
+	data, err := io.ReadAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "hello" {
+		t.Errorf("expected %q, got %q", "hello", data)
+	}
+
+	// Ingesting the same content again keeps a single blob.
+	if _, err := s.Add(strings.NewReader("hello")); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	infos, err := s.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(infos) != 1 {
+		t.Errorf("expected 1 blob, got %d", len(infos))
+	}
+}
+
+func TestIngestDigestMismatch(t *testing.T) {
+	s := newTestStore(t)
+
+	_, err := s.Ingest(strings.NewReader("tampered"), digestOf("hello"))
+	if !errors.Is(err, ErrDigestMismatch) {
+		t.Fatalf("expected ErrDigestMismatch, got: %v", err)
+	}
+
+	if _, err := s.Stat(digestOf("hello")); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got: %v", err)
+	}
+	if _, err := s.Stat(digestOf("tampered")); !errors.Is(err, ErrNotFound) {
+		t.Errorf("mismatched content should not be stored, got: %v", err)
+	}
+	entries, _ := os.ReadDir(filepath.Join(s.root, "tmp"))
+	if len(entries) != 0 {
+		t.Errorf("expected no temporary files, got %d", len(entries))
+	}
+}
+
+func TestInvalidDigest(t *testing.T) {
+	s := newTestStore(t)
+
+	for _, d := range []reference.Digest{
+		{Algorithm: "md5", Hash: digestOf("x").Hash},
+		{Algorithm: DigestAlgorithm, Hash: "abc"},
+		{Algorithm: DigestAlgorithm, Hash: "../" + digestOf("x").Hash[3:]},
+		{Algorithm: DigestAlgorithm, Hash: strings.ToUpper(digestOf("x").Hash)},
+	} {
+		if _, err := s.Ingest(strings.NewReader("x"), d); !errors.Is(err, ErrInvalidDigest) {
+			t.Errorf("%v: expected ErrInvalidDigest, got: %v", d, err)
+		}
+		if _, err := s.Open(d); !errors.Is(err, ErrInvalidDigest) {
+			t.Errorf("%v: expected ErrInvalidDigest, got: %v", d, err)
+		}
+	}
+}
+
+func TestExport(t *testing.T) {
+	s := newTestStore(t)
+	info, err := s.Add(strings.NewReader("artifact"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, dir := range []string{t.TempDir(), filepath.Join(s.root, "tmp")} {
+		dest := filepath.Join(dir, "image.tar")
+		if err := s.Export(info.Digest, dest); err != nil {
+			t.Fatalf("Export failed: %v", err)
+		}
+		data, err := os.ReadFile(dest)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != "artifact" {
+			t.Errorf("expected %q, got %q", "artifact", data)
+		}
+
+		if err := s.Export(info.Digest, dest); err == nil {
+			t.Error("expected an error exporting over an existing file")
+		}
+	}
+
+	if err := s.Export(digestOf("missing"), filepath.Join(t.TempDir(), "x")); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got: %v", err)
+	}
+}
+
+func TestCopyBlob(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src")
+	if err := os.WriteFile(src, bytes.Repeat([]byte("x"), 100000), 0444); err != nil {
+		t.Fatal(err)
+	}
+
+	dest := filepath.Join(dir, "dest")
+	if err := copyBlob(src, dest); err != nil {
+		t.Fatalf("copyBlob failed: %v", err)
+	}
+	fi, err := os.Stat(dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Size() != 100000 {
+		t.Errorf("expected 100000 bytes, got %d", fi.Size())
+	}
+	if fi.Mode().Perm()&0200 == 0 {
+		t.Error("copied blob should be writable")
+	}
+}
+
+func TestDelete(t *testing.T) {
+	s := newTestStore(t)
+	info, err := s.Add(strings.NewReader("data"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.Delete(info.Digest); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if _, err := s.Open(info.Digest); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got: %v", err)
+	}
+	if err := s.Delete(info.Digest); err != nil {
+		t.Errorf("Delete should be idempotent, got: %v", err)
+	}
+}
@@ -0,0 +1,153 @@
+package cas
+
+import (
+	"encoding/base64"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	"github.com/cruciblehq/crex"
+	"github.com/cruciblehq/spec/paths"
+	"github.com/cruciblehq/spec/reference"
+)
+
+// Maximum length of a tag name in bytes, chosen so that its encoded file
+// name fits the 255-byte limit of common file systems.
+const maxTagLen = 180
+
+// Points a tag to a stored blob.
+//
+// The name is any string of 1 to 180 bytes without control characters,
+// typically a resource reference such as "cruciblehq/hub:1.0.0"; other
+// values return [ErrInvalidTag]. An existing tag with the name is replaced
+// atomically. Tagged blobs are never evicted by [Store.Prune]. Returns
+// [ErrNotFound] if the blob is not stored.
+func (s *Store) Tag(name string, digest reference.Digest) error {
+	if err := validateTag(name); err != nil {
+		return err
+	}
+	unlock, err := s.lock(false)
+	if err != nil {
+		return crex.Wrap(ErrWriteFailed, err)
+	}
+	defer unlock()
+
+	if _, err := s.Stat(digest); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Join(s.root, "tmp"), "tag-*")
+	if err != nil {
+		return crex.Wrap(ErrWriteFailed, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.WriteString(digest.String() + "\n")
+	if err == nil {
+		err = tmp.Chmod(paths.DefaultFileMode)
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), s.tagPath(name))
+	}
+	if err != nil {
+		return crex.Wrap(ErrWriteFailed, err)
+	}
+	return nil
+}
+
+// Returns the digest a tag points to.
+//
+// Returns [ErrTagNotFound] if there is no such tag. The blob itself may have
+// been removed since it was tagged.
+func (s *Store) Resolve(name string) (reference.Digest, error) {
+	if err := validateTag(name); err != nil {
+		return reference.Digest{}, err
+	}
+	digest, err := s.readTag(s.tagPath(name))
+	if errors.Is(err, fs.ErrNotExist) {
+		return reference.Digest{}, crex.Wrap(ErrReadFailed, ErrTagNotFound)
+	}
+	if err != nil {
+		return reference.Digest{}, crex.Wrap(ErrReadFailed, err)
+	}
+	return digest, nil
+}
+
+// Removes a tag.
+//
+// The blob it pointed to is kept until pruned. The operation is idempotent.
+func (s *Store) Untag(name string) error {
+	if err := validateTag(name); err != nil {
+		return err
+	}
+	if err := os.Remove(s.tagPath(name)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return crex.Wrap(ErrWriteFailed, err)
+	}
+	return nil
+}
+
+// Returns every tag and the digest it points to.
+//
+// Files in the tag directory that do not hold a valid tag are ignored (see
+// [Store.Fsck]).
+func (s *Store) Tags() (map[string]reference.Digest, error) {
+	entries, err := os.ReadDir(filepath.Join(s.root, "tags"))
+	if err != nil {
+		return nil, crex.Wrap(ErrReadFailed, err)
+	}
+
+	tags := make(map[string]reference.Digest, len(entries))
+	for _, e := range entries {
+		raw, err := base64.RawURLEncoding.DecodeString(e.Name())
+		if err != nil || validateTag(string(raw)) != nil {
+			continue
+		}
+		digest, err := s.readTag(filepath.Join(s.root, "tags", e.Name()))
+		if errors.Is(err, fs.ErrNotExist) || errors.Is(err, ErrInvalidDigest) {
+			continue
+		}
+		if err != nil {
+			return nil, crex.Wrap(ErrReadFailed, err)
+		}
+		tags[string(raw)] = digest
+	}
+	return tags, nil
+}
+
+// Reads the digest stored in a tag file.
+//
+// Returns [ErrInvalidDigest] if the file does not hold a valid digest.
+func (s *Store) readTag(path string) (reference.Digest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return reference.Digest{}, err
+	}
+	digest, err := reference.ParseDigest(string(data))
+	if err != nil || validateDigest(*digest) != nil {
+		return reference.Digest{}, ErrInvalidDigest
+	}
+	return *digest, nil
+}
+
+// Path of the file holding a tag.
+//
+// Names are encoded in unpadded URL-safe base64, so that any valid name maps
+// to a single file name.
+func (s *Store) tagPath(name string) string {
+	return filepath.Join(s.root, "tags", base64.RawURLEncoding.EncodeToString([]byte(name)))
+}
+
+// Checks a tag name, returning [ErrInvalidTag] if it is empty, longer than
+// [maxTagLen], or contains control characters.
+func validateTag(name string) error {
+	if name == "" || len(name) > maxTagLen || strings.ContainsFunc(name, unicode.IsControl) {
+		return ErrInvalidTag
+	}
+	return nil
+}
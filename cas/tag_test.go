@@ -0,0 +1,71 @@
+package cas
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestTags(t *testing.T) {
+	s := newTestStore(t)
+	v1, err := s.Add(strings.NewReader("v1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	v2, err := s.Add(strings.NewReader("v2"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const name = "registry.crucible.net/cruciblehq/hub:1.0.0"
+	if err := s.Tag(name, v1.Digest); err != nil {
+		t.Fatalf("Tag failed: %v", err)
+	}
+	if err := s.Tag(name, v2.Digest); err != nil {
+		t.Fatalf("retagging failed: %v", err)
+	}
+
+	got, err := s.Resolve(name)
+	if err != nil {
+		t.Fatalf("Resolve failed: %v", err)
+	}
+	if got != v2.Digest {
+		t.Errorf("expected %v, got %v", v2.Digest, got)
+	}
+
+	tags, err := s.Tags()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tags) != 1 || tags[name] != v2.Digest {
+		t.Errorf("unexpected tags: %v", tags)
+	}
+
+	if err := s.Untag(name); err != nil {
+		t.Fatalf("Untag failed: %v", err)
+	}
+	if _, err := s.Resolve(name); !errors.Is(err, ErrTagNotFound) {
+		t.Errorf("expected ErrTagNotFound, got: %v", err)
+	}
+}
+
+func TestTagErrors(t *testing.T) {
+	s := newTestStore(t)
+
+	if err := s.Tag("missing", digestOf("missing")); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got: %v", err)
+	}
+
+	info, err := s.Add(strings.NewReader("x"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"", "a\nb", strings.Repeat("a", maxTagLen+1)} {
+		if err := s.Tag(name, info.Digest); !errors.Is(err, ErrInvalidTag) {
+			t.Errorf("%q: expected ErrInvalidTag, got: %v", name, err)
+		}
+	}
+	if err := s.Tag(strings.Repeat("a", maxTagLen), info.Digest); err != nil {
+		t.Errorf("expected the longest tag to be accepted, got: %v", err)
+	}
+}
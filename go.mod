@@ -7,4 +7,7 @@ require (
 	gopkg.in/yaml.v3 v3.0.1
 )
 
-require github.com/klauspost/compress v1.18.4
+require (
+	github.com/klauspost/compress v1.18.4
+	github.com/ulikunitz/xz v0.5.15
+)
@@ -4,8 +4,9 @@ import "github.com/cruciblehq/crex"
 
 // Holds common metadata about the resource.
 //
-// Includes the resource type, qualified name, and version. The type field
-// determines how the rest of the manifest is interpreted.
+// Includes the resource type, qualified name, and version, plus optional
+// descriptive fields that the registry records as version metadata. The type
+// field determines how the rest of the manifest is interpreted.
 type Resource struct {
 
 	// The type of the resource.
@@ -26,6 +27,32 @@ type Resource struct {
 	// This is a semantic version string that indicates the version of the
 	// resource being defined. This field is required.
 	Version string `yaml:"version"`
+
+	// SPDX license expression for the resource (e.g. "Apache-2.0").
+	//
+	// Optional. Published to the registry as version metadata.
+	License string `yaml:"license,omitempty"`
+
+	// URL of the resource's home page.
+	//
+	// Optional. Published to the registry as version metadata.
+	Homepage string `yaml:"homepage,omitempty"`
+
+	// URL of the resource's source repository.
+	//
+	// Optional. Published to the registry as version metadata.
+	Source string `yaml:"source,omitempty"`
+
+	// Search keywords describing the resource.
+	//
+	// Optional. Published to the registry as version metadata.
+	Keywords []string `yaml:"keywords,omitempty"`
+
+	// Arbitrary key/value labels attached to the resource.
+	//
+	// Optional. Published to the registry as version metadata, where keys
+	// must follow the registry's label key format.
+	Labels map[string]string `yaml:"labels,omitempty"`
 }
 
 // Validates the resource metadata.
@@ -0,0 +1,120 @@
+package oci
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"hash"
+	"io"
+	"path"
+
+	"github.com/cruciblehq/crex"
+	"github.com/cruciblehq/spec/reference"
+)
+
+// Digest algorithm of blobs written by [Writer].
+const DigestAlgorithm = "sha256"
+
+// Returns a hash for a digest algorithm supported for blobs.
+//
+// SHA-256 and SHA-512 are supported, as in the OCI image specification.
+func newHash(algorithm string) (hash.Hash, bool) {
+	switch algorithm {
+	case "sha256":
+		return sha256.New(), true
+	case "sha512":
+		return sha512.New(), true
+	default:
+		return nil, false
+	}
+}
+
+// Parses the digest of a descriptor and returns a hash to verify it with.
+//
+// Returns [ErrInvalidDigest] if the digest is malformed or its hash is not
+// lowercase hexadecimal of the algorithm's length, and [ErrUnsupportedDigest]
+// if the algorithm is neither sha256 nor sha512. A valid digest always maps
+// to a path within [BlobsDir].
+func parseDigest(s string) (*reference.Digest, hash.Hash, error) {
+	d, err := reference.ParseDigest(s)
+	if err != nil || d.String() != s {
+		return nil, nil, crex.Wrapf(ErrInvalidDigest, "%q", s)
+	}
+	h, ok := newHash(d.Algorithm)
+	if !ok {
+		return nil, nil, crex.Wrapf(ErrUnsupportedDigest, "%q", d.Algorithm)
+	}
+	if b, err := hex.DecodeString(d.Hash); err != nil || len(b) != h.Size() {
+		return nil, nil, crex.Wrapf(ErrInvalidDigest, "%q", s)
+	}
+	return d, h, nil
+}
+
+// Returns the slash-separated path of a blob within a layout.
+func blobPath(d *reference.Digest) string {
+	return path.Join(BlobsDir, d.Algorithm, d.Hash)
+}
+
+// Computes the descriptor of a blob written by [Writer].
+func describe(mediaType string, data []byte) Descriptor {
+	sum := sha256.Sum256(data)
+	d := reference.Digest{Algorithm: DigestAlgorithm, Hash: hex.EncodeToString(sum[:])}
+	return Descriptor{MediaType: mediaType, Digest: d.String(), Size: int64(len(data))}
+}
+
+// Reads r to the end, checking that it has the size and digest of a
+// descriptor.
+//
+// Returns [ErrSizeMismatch] or [ErrDigestMismatch] if it does not, and
+// [ErrInvalidDigest] or [ErrUnsupportedDigest] if the digest cannot be
+// checked.
+func VerifyBlob(r io.Reader, desc Descriptor) error {
+	v, err := newVerifier(r, desc)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(io.Discard, v)
+	return err
+}
+
+// Reader checking the content read through it against a descriptor.
+//
+// Reads fail with [ErrSizeMismatch] as soon as more bytes than expected are
+// read, and the final read fails instead of returning [io.EOF] if the
+// content is short or does not match the digest.
+type verifier struct {
+	r    io.Reader
+	h    hash.Hash
+	want *reference.Digest
+	size int64 // Expected size.
+	n    int64 // Bytes read so far.
+}
+
+// Returns a verifier reading r against a descriptor.
+func newVerifier(r io.Reader, desc Descriptor) (*verifier, error) {
+	d, h, err := parseDigest(desc.Digest)
+	if err != nil {
+		return nil, err
+	}
+	return &verifier{r: r, h: h, want: d, size: desc.Size}, nil
+}
+
+// Implements [io.Reader].
+func (v *verifier) Read(p []byte) (int, error) {
+	n, err := v.r.Read(p)
+	v.h.Write(p[:n])
+	v.n += int64(n)
+	if v.n > v.size {
+		return n, ErrSizeMismatch
+	}
+	if err == io.EOF {
+		if v.n != v.size {
+			return n, ErrSizeMismatch
+		}
+		got := reference.Digest{Algorithm: v.want.Algorithm, Hash: hex.EncodeToString(v.h.Sum(nil))}
+		if !got.Equal(v.want) {
+			return n, crex.Wrapf(ErrDigestMismatch, "%s", v.want)
+		}
+	}
+	return n, err
+}
@@ -0,0 +1,40 @@
+// Package oci reads and writes OCI image layouts.
+//
+// Builds of runtimes and services produce [manifest.ImageFile], a tarball of
+// an OCI image layout: a [LayoutFile] marker, an [IndexFile] listing the
+// images, and content-addressed blobs holding image manifests,
+// configurations, and layers. [Open] reads such a tarball in place, or an
+// extracted layout directory, and [Writer] writes one.
+//
+// A [Layout] follows nested indexes, so the platforms of a multi-platform
+// image are listed by [Layout.Platforms], and the image for one of them is
+// selected with [Layout.Resolve] or [Layout.Image]. Image configurations
+// expose the entrypoint, environment, and working directory the image runs
+// with. Docker manifests and configurations are read as their OCI
+// equivalents. Every blob read is checked against the digest and size of its
+// descriptor, and [Layout.Verify] checks all of them, layers included.
+//
+// Checking that a built service image runs the entrypoint it declares:
+//
+//	layout, err := oci.Open("build/image.tar")
+//	if err != nil {
+//		return err
+//	}
+//	defer layout.Close()
+//
+//	if err := layout.CheckService(svc); err != nil {
+//		return err // image entrypoint differs from the manifest
+//	}
+//
+// Reading the configuration of the image for one platform:
+//
+//	platform, err := oci.ParsePlatform("linux/arm64")
+//	if err != nil {
+//		return err
+//	}
+//	img, err := layout.Image(platform)
+//	if err != nil {
+//		return err
+//	}
+//	fmt.Println(img.Config.Entrypoint, img.Config.WorkingDir)
+package oci
@@ -0,0 +1,35 @@
+package oci
+
+import "errors"
+
+var (
+	ErrReadFailed   = errors.New("failed to read image layout")
+	ErrWriteFailed  = errors.New("failed to write image layout")
+	ErrInvalidImage = errors.New("invalid image")
+
+	// Layout.
+
+	ErrLayoutMissing        = errors.New("image layout marker file is missing")
+	ErrUnsupportedLayout    = errors.New("unsupported image layout version")
+	ErrIndexMissing         = errors.New("image layout has no index")
+	ErrIndexTooDeep         = errors.New("image indexes are nested too deeply")
+	ErrUnsupportedMediaType = errors.New("unsupported media type")
+	ErrDecodeFailed         = errors.New("failed to decode image metadata")
+	ErrWriterClosed         = errors.New("image layout writer is closed")
+
+	// Images.
+
+	ErrInvalidPlatform    = errors.New("invalid platform")
+	ErrPlatformNotFound   = errors.New("no image for the platform")
+	ErrAmbiguousPlatform  = errors.New("several images match the platform")
+	ErrEntrypointMismatch = errors.New("image entrypoint does not match")
+
+	// Blobs.
+
+	ErrBlobMissing       = errors.New("blob is missing")
+	ErrBlobTooLarge      = errors.New("metadata blob is too large")
+	ErrInvalidDigest     = errors.New("invalid digest")
+	ErrUnsupportedDigest = errors.New("unsupported digest algorithm")
+	ErrDigestMismatch    = errors.New("blob does not match its digest")
+	ErrSizeMismatch      = errors.New("blob does not match its size")
+)
@@ -0,0 +1,442 @@
+package oci
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"slices"
+
+	"github.com/cruciblehq/crex"
+	"github.com/cruciblehq/spec/archive"
+	"github.com/cruciblehq/spec/manifest"
+)
+
+// Maximum size of index, manifest, and configuration blobs.
+//
+// They are read into memory, so a layout cannot exhaust memory through them.
+const maxMetadataSize = 4 << 20
+
+// Maximum number of image indexes nested below [IndexFile].
+const maxIndexDepth = 8
+
+// Annotation marking the build attestations that BuildKit stores next to
+// the images of an index.
+const (
+	referenceTypeAnnotation = "vnd.docker.reference.type"
+	attestationManifest     = "attestation-manifest"
+)
+
+// Image layout opened for reading.
+//
+// Blobs are read lazily, and every blob read is checked against the digest
+// and size of its descriptor. A Layout must be closed to release the
+// tarball it was opened from.
+type Layout struct {
+	fsys   fs.FS
+	closer io.Closer // Archive file system to close (nil when wrapping one).
+	index  *Index
+}
+
+// Opens an image layout directory or tarball, such as [manifest.ImageFile].
+//
+// Tarballs may be in any format [archive.Extract] accepts, and are read in
+// place without being extracted. Returns [ErrLayoutMissing],
+// [ErrUnsupportedLayout], or [ErrIndexMissing] if src is not an image layout,
+// wrapped in [ErrReadFailed] like all other errors.
+func Open(src string) (*Layout, error) {
+	info, err := os.Stat(src)
+	if err != nil {
+		return nil, crex.Wrap(ErrReadFailed, err)
+	}
+	if info.IsDir() {
+		return NewLayout(os.DirFS(src))
+	}
+
+	fsys, err := archive.OpenFS(src)
+	if err != nil {
+		return nil, crex.Wrap(ErrReadFailed, err)
+	}
+	l, err := NewLayout(fsys)
+	if err != nil {
+		fsys.Close()
+		return nil, err
+	}
+	l.closer = fsys
+	return l, nil
+}
+
+// Reads the image layout at the root of a file system.
+//
+// Behaves like [Open]. The file system must remain readable until the
+// layout is no longer used, and is not closed by [Layout.Close].
+func NewLayout(fsys fs.FS) (*Layout, error) {
+	data, err := fs.ReadFile(fsys, LayoutFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, crex.Wrap(ErrReadFailed, ErrLayoutMissing)
+	}
+	if err != nil {
+		return nil, crex.Wrap(ErrReadFailed, err)
+	}
+	var marker layoutMarker
+	if err := json.Unmarshal(data, &marker); err != nil {
+		return nil, crex.Wrap(ErrReadFailed, crex.Wrap(ErrDecodeFailed, err))
+	}
+	if marker.Version != LayoutVersion {
+		return nil, crex.Wrap(ErrReadFailed, crex.Wrapf(ErrUnsupportedLayout, "%q", marker.Version))
+	}
+
+	f, err := fsys.Open(IndexFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, crex.Wrap(ErrReadFailed, ErrIndexMissing)
+	}
+	if err != nil {
+		return nil, crex.Wrap(ErrReadFailed, err)
+	}
+	defer f.Close()
+
+	var index Index
+	if err := decodeJSON(f, &index); err != nil {
+		return nil, crex.Wrap(ErrReadFailed, err)
+	}
+	return &Layout{fsys: fsys, index: &index}, nil
+}
+
+// Releases the tarball the layout was opened from, if any.
+func (l *Layout) Close() error {
+	if l.closer == nil {
+		return nil
+	}
+	return l.closer.Close()
+}
+
+// Returns the top-level index of the layout, read from [IndexFile].
+func (l *Layout) Index() *Index {
+	return l.index
+}
+
+// Lists the image manifests of the layout.
+//
+// Nested indexes, such as the multi-platform index of an image, are
+// followed, and their image manifests listed in place. Build attestations
+// and descriptors of media types other than image indexes and manifests are
+// skipped.
+func (l *Layout) Manifests() ([]Descriptor, error) {
+	var descs []Descriptor
+	err := l.walk(l.index, 0, func(desc Descriptor) error {
+		descs = append(descs, desc)
+		return nil
+	})
+	if err != nil {
+		return nil, crex.Wrap(ErrReadFailed, err)
+	}
+	return descs, nil
+}
+
+// Lists the platforms of the images in the layout, without duplicates.
+//
+// The platform of an image is taken from its descriptor, or from its
+// configuration if the descriptor has none.
+func (l *Layout) Platforms() ([]Platform, error) {
+	var platforms []Platform
+	err := l.walk(l.index, 0, func(desc Descriptor) error {
+		p, err := l.platform(desc)
+		if err != nil {
+			return err
+		}
+		if !slices.Contains(platforms, p) {
+			platforms = append(platforms, p)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, crex.Wrap(ErrReadFailed, err)
+	}
+	return platforms, nil
+}
+
+// Reads an image manifest.
+//
+// Returns [ErrUnsupportedMediaType] if the descriptor is not of an OCI or
+// Docker image manifest.
+func (l *Layout) Manifest(desc Descriptor) (*Manifest, error) {
+	m, err := l.readManifest(desc)
+	if err != nil {
+		return nil, crex.Wrap(ErrReadFailed, err)
+	}
+	return m, nil
+}
+
+// Reads the configuration of an image manifest.
+//
+// Returns [ErrUnsupportedMediaType] if the manifest does not reference an
+// OCI or Docker image configuration.
+func (l *Layout) Config(m *Manifest) (*Image, error) {
+	img, err := l.readConfig(m)
+	if err != nil {
+		return nil, crex.Wrap(ErrReadFailed, err)
+	}
+	return img, nil
+}
+
+// Finds the image manifest for a platform.
+//
+// Images are selected with [Platform.Matches]. If platform is nil, the
+// layout must hold a single image. Returns [ErrPlatformNotFound] if no image
+// matches, and [ErrAmbiguousPlatform] if several do.
+func (l *Layout) Resolve(platform *Platform) (*Manifest, error) {
+	var found []Descriptor
+	err := l.walk(l.index, 0, func(desc Descriptor) error {
+		if platform != nil {
+			p, err := l.platform(desc)
+			if err != nil || !p.Matches(*platform) {
+				return err
+			}
+		}
+		found = append(found, desc)
+		return nil
+	})
+	if err == nil && len(found) == 0 {
+		err = ErrPlatformNotFound
+	}
+	if err == nil && len(found) > 1 {
+		err = ErrAmbiguousPlatform
+	}
+	if platform != nil && err != nil {
+		err = crex.Wrapf(err, "%s", platform)
+	}
+	if err != nil {
+		return nil, crex.Wrap(ErrReadFailed, err)
+	}
+	return l.Manifest(found[0])
+}
+
+// Reads the configuration of the image for a platform.
+//
+// Selects the image as [Layout.Resolve] does.
+func (l *Layout) Image(platform *Platform) (*Image, error) {
+	m, err := l.Resolve(platform)
+	if err != nil {
+		return nil, err
+	}
+	return l.Config(m)
+}
+
+// Opens a blob for reading.
+//
+// The returned reader fails with [ErrSizeMismatch] or [ErrDigestMismatch]
+// once the content is found not to match the descriptor, which for the
+// digest is only at the end; callers must read to [io.EOF] before trusting
+// what they read. Returns [ErrBlobMissing] if the layout has no such blob.
+func (l *Layout) OpenBlob(desc Descriptor) (io.ReadCloser, error) {
+	rc, err := l.openBlob(desc)
+	if err != nil {
+		return nil, crex.Wrap(ErrReadFailed, err)
+	}
+	return rc, nil
+}
+
+// Checks every blob reachable from the top-level index.
+//
+// Nested indexes, image manifests, configurations, and layers are read in
+// full and checked against their digests and sizes. Returns the first
+// failure wrapped in [ErrReadFailed].
+func (l *Layout) Verify() error {
+	seen := make(map[string]bool)
+	err := l.walk(l.index, 0, func(desc Descriptor) error {
+		m, err := l.readManifest(desc)
+		if err != nil {
+			return err
+		}
+		if _, err := l.readConfig(m); err != nil {
+			return err
+		}
+		for _, layer := range m.Layers {
+			if seen[layer.Digest] {
+				continue
+			}
+			seen[layer.Digest] = true
+			if err := l.verifyBlob(layer); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return crex.Wrap(ErrReadFailed, err)
+	}
+	return nil
+}
+
+// Checks that every image of the layout runs the entrypoint a service
+// declares.
+//
+// Builds set [manifest.Service].Entrypoint on the output image of each
+// platform, so the configuration of every image must carry it exactly.
+// Returns [ErrEntrypointMismatch] wrapped in [ErrInvalidImage] naming the
+// first platform that does not, and [ErrReadFailed] if the layout cannot be
+// read.
+func (l *Layout) CheckService(s *manifest.Service) error {
+	var mismatch error
+	err := l.walk(l.index, 0, func(desc Descriptor) error {
+		m, err := l.readManifest(desc)
+		if err != nil {
+			return err
+		}
+		img, err := l.readConfig(m)
+		if err != nil {
+			return err
+		}
+		if mismatch == nil && !slices.Equal(img.Config.Entrypoint, s.Entrypoint) {
+			mismatch = crex.Wrapf(ErrEntrypointMismatch, "%s: want %q, got %q", img.Platform(), s.Entrypoint, img.Config.Entrypoint)
+		}
+		return nil
+	})
+	if err != nil {
+		return crex.Wrap(ErrReadFailed, err)
+	}
+	if mismatch != nil {
+		return crex.Wrap(ErrInvalidImage, mismatch)
+	}
+	return nil
+}
+
+// Calls fn for each image manifest below an index, depth levels deep.
+func (l *Layout) walk(index *Index, depth int, fn func(Descriptor) error) error {
+	for _, desc := range index.Manifests {
+		switch {
+		case isIndex(desc.MediaType):
+			if depth == maxIndexDepth {
+				return ErrIndexTooDeep
+			}
+			var nested Index
+			if err := l.readJSON(desc, &nested); err != nil {
+				return err
+			}
+			if err := l.walk(&nested, depth+1, fn); err != nil {
+				return err
+			}
+		case isManifest(desc.MediaType):
+			if desc.Annotations[referenceTypeAnnotation] == attestationManifest {
+				continue
+			}
+			if err := fn(desc); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Returns the platform of an image manifest.
+func (l *Layout) platform(desc Descriptor) (Platform, error) {
+	if desc.Platform != nil {
+		return *desc.Platform, nil
+	}
+	m, err := l.readManifest(desc)
+	if err != nil {
+		return Platform{}, err
+	}
+	img, err := l.readConfig(m)
+	if err != nil {
+		return Platform{}, err
+	}
+	return img.Platform(), nil
+}
+
+// Reads and decodes an image manifest.
+func (l *Layout) readManifest(desc Descriptor) (*Manifest, error) {
+	if !isManifest(desc.MediaType) {
+		return nil, crex.Wrapf(ErrUnsupportedMediaType, "%q", desc.MediaType)
+	}
+	var m Manifest
+	if err := l.readJSON(desc, &m); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// Reads and decodes the configuration of an image manifest.
+func (l *Layout) readConfig(m *Manifest) (*Image, error) {
+	if m.Config.MediaType != MediaTypeConfig && m.Config.MediaType != MediaTypeDockerConfig {
+		return nil, crex.Wrapf(ErrUnsupportedMediaType, "%q", m.Config.MediaType)
+	}
+	var img Image
+	if err := l.readJSON(m.Config, &img); err != nil {
+		return nil, err
+	}
+	return &img, nil
+}
+
+// Reads a metadata blob and decodes it as JSON into v.
+func (l *Layout) readJSON(desc Descriptor, v any) error {
+	if desc.Size > maxMetadataSize {
+		return crex.Wrapf(ErrBlobTooLarge, "%s", desc.Digest)
+	}
+	rc, err := l.openBlob(desc)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	return decodeJSON(rc, v)
+}
+
+// Reads a blob to the end, checking it against its descriptor.
+func (l *Layout) verifyBlob(desc Descriptor) error {
+	rc, err := l.openBlob(desc)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	_, err = io.Copy(io.Discard, rc)
+	return err
+}
+
+// Opens a blob behind a verifier.
+func (l *Layout) openBlob(desc Descriptor) (io.ReadCloser, error) {
+	v, err := newVerifier(nil, desc)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := l.fsys.Open(blobPath(v.want))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, crex.Wrapf(ErrBlobMissing, "%s", desc.Digest)
+	}
+	if err != nil {
+		return nil, err
+	}
+	v.r = f
+	return struct {
+		io.Reader
+		io.Closer
+	}{v, f}, nil
+}
+
+// Reads r to the end, up to [maxMetadataSize] bytes, and decodes it as JSON
+// into v.
+func decodeJSON(r io.Reader, v any) error {
+	data, err := io.ReadAll(io.LimitReader(r, maxMetadataSize+1))
+	if err != nil {
+		return err
+	}
+	if len(data) > maxMetadataSize {
+		return ErrBlobTooLarge
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return crex.Wrap(ErrDecodeFailed, err)
+	}
+	return nil
+}
+
+// Whether a media type is of an image index.
+func isIndex(mediaType string) bool {
+	return mediaType == MediaTypeIndex || mediaType == MediaTypeDockerManifestList
+}
+
+// Whether a media type is of an image manifest.
+func isManifest(mediaType string) bool {
+	return mediaType == MediaTypeManifest || mediaType == MediaTypeDockerManifest
+}
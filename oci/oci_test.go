@@ -0,0 +1,378 @@
+package oci
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/cruciblehq/spec/manifest"
+)
+
+// Writes a tarball of two images sharing a layer, returning its path.
+func writeTestTarball(t *testing.T) string {
+	t.Helper()
+
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+
+	layer := []byte("layer contents")
+	layerDesc := describe(MediaTypeLayer, layer)
+	if err := w.CopyBlob(layerDesc, bytes.NewReader(layer)); err != nil {
+		t.Fatalf("CopyBlob failed: %v", err)
+	}
+
+	for _, img := range []*Image{
+		{OS: "linux", Architecture: "amd64"},
+		{OS: "linux", Architecture: "arm64", Variant: "v8"},
+	} {
+		img.Config = ImageConfig{
+			Entrypoint: []string{"/app/server"},
+			Env:        []string{"PATH=/usr/bin", "ARCH=" + img.Architecture},
+			WorkingDir: "/app",
+		}
+		img.RootFS = RootFS{Type: "layers", DiffIDs: []string{layerDesc.Digest}}
+		if _, err := w.WriteImage(img, []Descriptor{layerDesc}); err != nil {
+			t.Fatalf("WriteImage failed: %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), manifest.ImageFile)
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// Writes a blob into a layout directory, returning its descriptor.
+func putBlob(t *testing.T, dir, mediaType string, data []byte) Descriptor {
+	t.Helper()
+
+	desc := describe(mediaType, data)
+	d, _, err := parseDigest(desc.Digest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, filepath.FromSlash(blobPath(d)))
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return desc
+}
+
+// Marshals v as JSON, failing the test on error.
+func mustJSON(t *testing.T, v any) []byte {
+	t.Helper()
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// Writes a layout directory whose index nests a Docker manifest list holding
+// an image without a descriptor platform and a build attestation.
+func writeTestDir(t *testing.T) (string, Descriptor) {
+	t.Helper()
+
+	dir := t.TempDir()
+	layer := putBlob(t, dir, MediaTypeLayerGzip, []byte("gzipped layer"))
+	config := putBlob(t, dir, MediaTypeDockerConfig, mustJSON(t, &Image{
+		OS:           "linux",
+		Architecture: "riscv64",
+		Config:       ImageConfig{Entrypoint: []string{"/bin/sh"}},
+	}))
+	image := putBlob(t, dir, MediaTypeDockerManifest, mustJSON(t, &Manifest{
+		SchemaVersion: 2,
+		Config:        config,
+		Layers:        []Descriptor{layer},
+	}))
+
+	attestation := putBlob(t, dir, MediaTypeManifest, []byte("not decoded"))
+	attestation.Annotations = map[string]string{referenceTypeAnnotation: attestationManifest}
+
+	list := putBlob(t, dir, MediaTypeDockerManifestList, mustJSON(t, &Index{
+		SchemaVersion: 2,
+		Manifests:     []Descriptor{image, attestation},
+	}))
+
+	files := map[string][]byte{
+		LayoutFile: []byte(`{"imageLayoutVersion":"1.0.0"}`),
+		IndexFile:  mustJSON(t, &Index{SchemaVersion: 2, Manifests: []Descriptor{list}}),
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir, layer
+}
+
+func TestWriteAndOpen(t *testing.T) {
+	l, err := Open(writeTestTarball(t))
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer l.Close()
+
+	platforms, err := l.Platforms()
+	if err != nil {
+		t.Fatalf("Platforms failed: %v", err)
+	}
+	want := []Platform{
+		{OS: "linux", Architecture: "amd64"},
+		{OS: "linux", Architecture: "arm64", Variant: "v8"},
+	}
+	if !slices.Equal(platforms, want) {
+		t.Errorf("expected platforms %v, got %v", want, platforms)
+	}
+
+	img, err := l.Image(&Platform{OS: "linux", Architecture: "arm64"})
+	if err != nil {
+		t.Fatalf("Image failed: %v", err)
+	}
+	if img.Architecture != "arm64" || img.Config.WorkingDir != "/app" {
+		t.Errorf("unexpected image: %+v", img)
+	}
+	if arch, ok := img.Config.Getenv("ARCH"); !ok || arch != "arm64" {
+		t.Errorf("expected ARCH=arm64, got %q", arch)
+	}
+
+	if err := l.Verify(); err != nil {
+		t.Errorf("Verify failed: %v", err)
+	}
+}
+
+func TestResolve(t *testing.T) {
+	l, err := Open(writeTestTarball(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	m, err := l.Resolve(&Platform{OS: "linux", Architecture: "amd64"})
+	if err != nil {
+		t.Fatalf("Resolve failed: %v", err)
+	}
+	if len(m.Layers) != 1 || m.Layers[0].MediaType != MediaTypeLayer {
+		t.Errorf("unexpected manifest: %+v", m)
+	}
+
+	if _, err := l.Resolve(nil); !errors.Is(err, ErrAmbiguousPlatform) {
+		t.Errorf("expected ErrAmbiguousPlatform, got: %v", err)
+	}
+	if _, err := l.Resolve(&Platform{OS: "windows", Architecture: "amd64"}); !errors.Is(err, ErrPlatformNotFound) {
+		t.Errorf("expected ErrPlatformNotFound, got: %v", err)
+	}
+	if _, err := l.Resolve(&Platform{OS: "linux", Architecture: "arm64", Variant: "v7"}); !errors.Is(err, ErrPlatformNotFound) {
+		t.Errorf("expected ErrPlatformNotFound for another variant, got: %v", err)
+	}
+}
+
+func TestCheckService(t *testing.T) {
+	l, err := Open(writeTestTarball(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	if err := l.CheckService(&manifest.Service{Entrypoint: []string{"/app/server"}}); err != nil {
+		t.Errorf("CheckService failed: %v", err)
+	}
+
+	err = l.CheckService(&manifest.Service{Entrypoint: []string{"/app/server", "--debug"}})
+	if !errors.Is(err, ErrInvalidImage) || !errors.Is(err, ErrEntrypointMismatch) {
+		t.Errorf("expected ErrEntrypointMismatch, got: %v", err)
+	}
+}
+
+func TestWriterReproducible(t *testing.T) {
+	a, err := os.ReadFile(writeTestTarball(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(writeTestTarball(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(a, b) {
+		t.Error("expected identical tarballs")
+	}
+}
+
+func TestOpenDirectory(t *testing.T) {
+	dir, _ := writeTestDir(t)
+
+	l, err := Open(dir)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer l.Close()
+
+	descs, err := l.Manifests()
+	if err != nil {
+		t.Fatalf("Manifests failed: %v", err)
+	}
+	if len(descs) != 1 || descs[0].MediaType != MediaTypeDockerManifest {
+		t.Fatalf("expected the image manifest only, got %+v", descs)
+	}
+
+	platforms, err := l.Platforms()
+	if err != nil {
+		t.Fatalf("Platforms failed: %v", err)
+	}
+	if len(platforms) != 1 || platforms[0].String() != "linux/riscv64" {
+		t.Errorf("expected the platform from the config, got %v", platforms)
+	}
+
+	img, err := l.Image(nil)
+	if err != nil {
+		t.Fatalf("Image failed: %v", err)
+	}
+	if !slices.Equal(img.Config.Entrypoint, []string{"/bin/sh"}) {
+		t.Errorf("unexpected entrypoint: %v", img.Config.Entrypoint)
+	}
+	if err := l.Verify(); err != nil {
+		t.Errorf("Verify failed: %v", err)
+	}
+}
+
+func TestVerifyCorruptBlob(t *testing.T) {
+	dir, layer := writeTestDir(t)
+
+	d, _, err := parseDigest(layer.Digest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, filepath.FromSlash(blobPath(d)))
+
+	l, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		data []byte
+		err  error
+	}{
+		{[]byte("gzipped layeR"), ErrDigestMismatch},
+		{[]byte("gzipped"), ErrSizeMismatch},
+		{[]byte("gzipped layer and more"), ErrSizeMismatch},
+	} {
+		if err := os.WriteFile(path, tc.data, 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := l.Verify(); !errors.Is(err, tc.err) {
+			t.Errorf("%q: expected %v, got: %v", tc.data, tc.err, err)
+		}
+	}
+
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Verify(); !errors.Is(err, ErrBlobMissing) {
+		t.Errorf("expected ErrBlobMissing, got: %v", err)
+	}
+}
+
+func TestOpenInvalidLayout(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		files map[string]string
+		err   error
+	}{
+		{"no marker", map[string]string{IndexFile: `{"schemaVersion":2,"manifests":[]}`}, ErrLayoutMissing},
+		{"version", map[string]string{LayoutFile: `{"imageLayoutVersion":"2.0.0"}`}, ErrUnsupportedLayout},
+		{"no index", map[string]string{LayoutFile: `{"imageLayoutVersion":"1.0.0"}`}, ErrIndexMissing},
+		{"bad index", map[string]string{LayoutFile: `{"imageLayoutVersion":"1.0.0"}`, IndexFile: `{`}, ErrDecodeFailed},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, data := range tc.files {
+				if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			_, err := Open(dir)
+			if !errors.Is(err, ErrReadFailed) || !errors.Is(err, tc.err) {
+				t.Errorf("expected %v, got: %v", tc.err, err)
+			}
+		})
+	}
+}
+
+func TestCopyBlobMismatch(t *testing.T) {
+	w := NewWriter(&bytes.Buffer{})
+
+	desc := describe(MediaTypeLayer, []byte("expected"))
+	err := w.CopyBlob(desc, strings.NewReader("tampered"))
+	if !errors.Is(err, ErrWriteFailed) || !errors.Is(err, ErrDigestMismatch) {
+		t.Fatalf("expected ErrDigestMismatch, got: %v", err)
+	}
+	if err := w.Close(); !errors.Is(err, ErrDigestMismatch) {
+		t.Errorf("expected the writer to keep failing, got: %v", err)
+	}
+}
+
+func TestWriteImageMissingLayer(t *testing.T) {
+	w := NewWriter(&bytes.Buffer{})
+
+	layer := describe(MediaTypeLayer, []byte("never written"))
+	_, err := w.WriteImage(&Image{OS: "linux", Architecture: "amd64"}, []Descriptor{layer})
+	if !errors.Is(err, ErrBlobMissing) {
+		t.Errorf("expected ErrBlobMissing, got: %v", err)
+	}
+}
+
+func TestVerifyBlobDigests(t *testing.T) {
+	desc := describe(MediaTypeLayer, []byte("data"))
+	if err := VerifyBlob(strings.NewReader("data"), desc); err != nil {
+		t.Errorf("VerifyBlob failed: %v", err)
+	}
+
+	for _, tc := range []struct {
+		digest string
+		err    error
+	}{
+		{"sha256:../../../etc/passwd", ErrInvalidDigest},
+		{"sha256:" + strings.Repeat("a", 63), ErrInvalidDigest},
+		{"SHA256:" + strings.Repeat("a", 64), ErrInvalidDigest},
+		{"sha256", ErrInvalidDigest},
+		{"md5:" + strings.Repeat("a", 32), ErrUnsupportedDigest},
+	} {
+		desc := Descriptor{Digest: tc.digest, Size: 4}
+		if err := VerifyBlob(strings.NewReader("data"), desc); !errors.Is(err, tc.err) {
+			t.Errorf("%s: expected %v, got: %v", tc.digest, tc.err, err)
+		}
+	}
+}
+
+func TestParsePlatform(t *testing.T) {
+	for _, s := range []string{"linux/amd64", "linux/arm64/v8"} {
+		p, err := ParsePlatform(s)
+		if err != nil {
+			t.Errorf("%s: ParsePlatform failed: %v", s, err)
+			continue
+		}
+		if p.String() != s {
+			t.Errorf("expected %s, got %s", s, p)
+		}
+	}
+
+	for _, s := range []string{"", "linux", "linux/", "/amd64", "linux/arm64/v8/x"} {
+		if _, err := ParsePlatform(s); !errors.Is(err, ErrInvalidPlatform) {
+			t.Errorf("%q: expected ErrInvalidPlatform, got: %v", s, err)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package oci
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/cruciblehq/crex"
+)
+
+// Media types of image layout documents and blobs.
+const (
+	MediaTypeIndex     = "application/vnd.oci.image.index.v1+json"     // Image index.
+	MediaTypeManifest  = "application/vnd.oci.image.manifest.v1+json"  // Image manifest.
+	MediaTypeConfig    = "application/vnd.oci.image.config.v1+json"    // Image configuration.
+	MediaTypeLayer     = "application/vnd.oci.image.layer.v1.tar"      // Uncompressed layer.
+	MediaTypeLayerGzip = "application/vnd.oci.image.layer.v1.tar+gzip" // Gzip-compressed layer.
+	MediaTypeLayerZstd = "application/vnd.oci.image.layer.v1.tar+zstd" // Zstandard-compressed layer.
+
+	// Docker media types, read as their OCI equivalents.
+
+	MediaTypeDockerManifestList = "application/vnd.docker.distribution.manifest.list.v2+json" // Docker manifest list.
+	MediaTypeDockerManifest     = "application/vnd.docker.distribution.manifest.v2+json"      // Docker image manifest.
+	MediaTypeDockerConfig       = "application/vnd.docker.container.image.v1+json"            // Docker image configuration.
+)
+
+// Names of the files at the root of an image layout.
+const (
+	LayoutFile = "oci-layout" // Layout marker holding the layout version.
+	IndexFile  = "index.json" // Entry point of the layout.
+	BlobsDir   = "blobs"      // Content-addressed blobs, as blobs/{algorithm}/{hash}.
+)
+
+// Image layout version written to [LayoutFile], and the only one read.
+const LayoutVersion = "1.0.0"
+
+// Content of [LayoutFile].
+type layoutMarker struct {
+	Version string `json:"imageLayoutVersion"` // Layout version.
+}
+
+// Reference to a blob by digest, size, and media type.
+type Descriptor struct {
+	MediaType   string            `json:"mediaType"`             // Media type of the blob.
+	Digest      string            `json:"digest"`                // Digest of the blob, as "algorithm:hash".
+	Size        int64             `json:"size"`                  // Size of the blob in bytes.
+	Platform    *Platform         `json:"platform,omitempty"`    // Platform of an image manifest referenced by an index.
+	Annotations map[string]string `json:"annotations,omitempty"` // Arbitrary metadata.
+}
+
+// Operating system and CPU architecture an image runs on.
+type Platform struct {
+	OS           string `json:"os"`                   // Operating system, such as "linux".
+	Architecture string `json:"architecture"`         // CPU architecture, such as "amd64" or "arm64".
+	Variant      string `json:"variant,omitempty"`    // CPU variant, such as "v8".
+	OSVersion    string `json:"os.version,omitempty"` // Operating system version.
+}
+
+// Parses a platform in the "os/arch" or "os/arch/variant" form used by
+// [manifest.Stage].Platform.
+//
+// Returns [ErrInvalidPlatform] if a component is empty or missing.
+func ParsePlatform(s string) (*Platform, error) {
+	parts := strings.Split(s, "/")
+	if len(parts) < 2 || len(parts) > 3 || slices.Contains(parts, "") {
+		return nil, crex.Wrapf(ErrInvalidPlatform, "%q", s)
+	}
+	p := &Platform{OS: parts[0], Architecture: parts[1]}
+	if len(parts) == 3 {
+		p.Variant = parts[2]
+	}
+	return p, nil
+}
+
+// Returns the platform in the form accepted by [ParsePlatform].
+func (p Platform) String() string {
+	s := p.OS + "/" + p.Architecture
+	if p.Variant != "" {
+		s += "/" + p.Variant
+	}
+	return s
+}
+
+// Whether the platform satisfies a requested one.
+//
+// The operating system and architecture must be equal. The variant and
+// operating system version only need to be equal when the request sets them.
+func (p Platform) Matches(want Platform) bool {
+	return p.OS == want.OS && p.Architecture == want.Architecture &&
+		(want.Variant == "" || p.Variant == want.Variant) &&
+		(want.OSVersion == "" || p.OSVersion == want.OSVersion)
+}
+
+// Image index, listing image manifests and nested indexes.
+type Index struct {
+	SchemaVersion int               `json:"schemaVersion"`         // Always 2.
+	MediaType     string            `json:"mediaType,omitempty"`   // [MediaTypeIndex].
+	Manifests     []Descriptor      `json:"manifests"`             // Image manifests and indexes.
+	Annotations   map[string]string `json:"annotations,omitempty"` // Arbitrary metadata.
+}
+
+// Image manifest, referencing the configuration and layers of one image.
+type Manifest struct {
+	SchemaVersion int               `json:"schemaVersion"`         // Always 2.
+	MediaType     string            `json:"mediaType,omitempty"`   // [MediaTypeManifest].
+	Config        Descriptor        `json:"config"`                // Image configuration.
+	Layers        []Descriptor      `json:"layers"`                // Layers, from the base upwards.
+	Annotations   map[string]string `json:"annotations,omitempty"` // Arbitrary metadata.
+}
+
+// Image configuration.
+//
+// Only the fields relevant to running an image are decoded; others are
+// ignored when reading and omitted when writing.
+type Image struct {
+	OS           string      `json:"os"`                   // Operating system.
+	Architecture string      `json:"architecture"`         // CPU architecture.
+	Variant      string      `json:"variant,omitempty"`    // CPU variant.
+	OSVersion    string      `json:"os.version,omitempty"` // Operating system version.
+	Config       ImageConfig `json:"config"`               // Execution parameters.
+	RootFS       RootFS      `json:"rootfs"`               // Layer content digests.
+}
+
+// Returns the platform the image runs on.
+func (img *Image) Platform() Platform {
+	return Platform{OS: img.OS, Architecture: img.Architecture, Variant: img.Variant, OSVersion: img.OSVersion}
+}
+
+// Execution parameters of an image.
+type ImageConfig struct {
+	User       string            `json:"User,omitempty"`       // User the process runs as.
+	Env        []string          `json:"Env,omitempty"`        // Environment variables, as "NAME=value".
+	Entrypoint []string          `json:"Entrypoint,omitempty"` // Command run when the container starts.
+	Cmd        []string          `json:"Cmd,omitempty"`        // Default arguments to the entrypoint.
+	WorkingDir string            `json:"WorkingDir,omitempty"` // Working directory of the process.
+	Labels     map[string]string `json:"Labels,omitempty"`     // Arbitrary metadata.
+}
+
+// Layers of an image, identified by the digests of their uncompressed
+// content.
+type RootFS struct {
+	Type    string   `json:"type"`     // Always "layers".
+	DiffIDs []string `json:"diff_ids"` // Digests of the uncompressed layers, from the base upwards.
+}
+
+// Looks up an environment variable of the image configuration.
+//
+// Returns the value of the last definition of name, and whether there is one.
+func (c *ImageConfig) Getenv(name string) (string, bool) {
+	for i := len(c.Env) - 1; i >= 0; i-- {
+		if k, v, ok := strings.Cut(c.Env[i], "="); ok && k == name {
+			return v, true
+		}
+	}
+	return "", false
+}
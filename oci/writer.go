@@ -0,0 +1,233 @@
+package oci
+
+import (
+	"archive/tar"
+	"encoding/json"
+	"io"
+	"path"
+	"time"
+
+	"github.com/cruciblehq/crex"
+)
+
+// Modification time of every entry written by [Writer], so that tarballs of
+// the same images are identical.
+var entryTime = time.Unix(0, 0)
+
+// Writes an image layout tarball.
+//
+// Blobs are written as they are added, each once, followed on
+// [Writer.Close] by [IndexFile] listing the images added with
+// [Writer.WriteImage]. Entries have fixed times and permissions and no
+// ownership, so the tarball depends only on what is written and in which
+// order. The resulting tarball is read by [Open].
+//
+// A Writer is not safe for concurrent use. After a failed call, all further
+// calls fail, since the tarball may hold a partial entry.
+type Writer struct {
+	tw      *tar.Writer
+	started bool
+	dirs    map[string]bool // Blob directories written.
+	blobs   map[string]bool // Digests of blobs written.
+	index   []Descriptor    // Image manifests to list in the index.
+	err     error           // First failure, returned by every later call.
+}
+
+// Returns a writer of an image layout tarball to w.
+//
+// Nothing is written until the first blob is added or the writer is closed.
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{
+		tw:    tar.NewWriter(w),
+		dirs:  make(map[string]bool),
+		blobs: make(map[string]bool),
+	}
+}
+
+// Adds a blob held in memory, returning its descriptor.
+//
+// The digest is computed with [DigestAlgorithm].
+func (w *Writer) WriteBlob(mediaType string, data []byte) (Descriptor, error) {
+	desc := describe(mediaType, data)
+	if err := w.do(func() error { return w.writeBlob(desc, nil, data) }); err != nil {
+		return Descriptor{}, err
+	}
+	return desc, nil
+}
+
+// Adds a blob read from r, such as a layer, whose descriptor is known.
+//
+// Reads r to the end, checking it against the descriptor. The content is
+// written as it is read, so a blob that does not match leaves the tarball
+// unusable: the error is [ErrSizeMismatch] or [ErrDigestMismatch] wrapped in
+// [ErrWriteFailed], and the writer fails from then on. Returns without
+// reading r if the blob was already added.
+func (w *Writer) CopyBlob(desc Descriptor, r io.Reader) error {
+	return w.do(func() error { return w.writeBlob(desc, r, nil) })
+}
+
+// Adds an image, writing its configuration and manifest.
+//
+// The layers must have been added, with [Writer.CopyBlob], in the order of
+// img.RootFS.DiffIDs. The manifest is listed in the index with the platform
+// of the image. Returns the descriptor of the manifest.
+func (w *Writer) WriteImage(img *Image, layers []Descriptor) (Descriptor, error) {
+	var desc Descriptor
+	err := w.do(func() error {
+		for _, layer := range layers {
+			if !w.blobs[layer.Digest] {
+				return crex.Wrapf(ErrBlobMissing, "%s", layer.Digest)
+			}
+		}
+
+		config, err := json.Marshal(img)
+		if err != nil {
+			return err
+		}
+		configDesc := describe(MediaTypeConfig, config)
+		if err := w.writeBlob(configDesc, nil, config); err != nil {
+			return err
+		}
+
+		if layers == nil {
+			layers = []Descriptor{}
+		}
+		data, err := json.Marshal(&Manifest{
+			SchemaVersion: 2,
+			MediaType:     MediaTypeManifest,
+			Config:        configDesc,
+			Layers:        layers,
+		})
+		if err != nil {
+			return err
+		}
+		desc = describe(MediaTypeManifest, data)
+		if err := w.writeBlob(desc, nil, data); err != nil {
+			return err
+		}
+
+		platform := img.Platform()
+		desc.Platform = &platform
+		w.index = append(w.index, desc)
+		return nil
+	})
+	if err != nil {
+		return Descriptor{}, err
+	}
+	return desc, nil
+}
+
+// Writes [IndexFile] and finishes the tarball.
+//
+// Does not close the underlying writer.
+func (w *Writer) Close() error {
+	return w.do(func() error {
+		manifests := w.index
+		if manifests == nil {
+			manifests = []Descriptor{}
+		}
+		data, err := json.Marshal(&Index{SchemaVersion: 2, MediaType: MediaTypeIndex, Manifests: manifests})
+		if err != nil {
+			return err
+		}
+		if err := w.writeFile(IndexFile, data); err != nil {
+			return err
+		}
+		if err := w.tw.Close(); err != nil {
+			return err
+		}
+		w.err = ErrWriterClosed
+		return nil
+	})
+}
+
+// Runs fn unless the writer has failed, recording its failure.
+func (w *Writer) do(fn func() error) error {
+	if w.err != nil {
+		return crex.Wrap(ErrWriteFailed, w.err)
+	}
+	if !w.started {
+		w.started = true
+		if err := w.start(); err != nil {
+			w.err = err
+			return crex.Wrap(ErrWriteFailed, err)
+		}
+	}
+	if err := fn(); err != nil {
+		w.err = err
+		return crex.Wrap(ErrWriteFailed, err)
+	}
+	return nil
+}
+
+// Writes [LayoutFile] and the blob directory.
+func (w *Writer) start() error {
+	data, err := json.Marshal(&layoutMarker{Version: LayoutVersion})
+	if err != nil {
+		return err
+	}
+	if err := w.writeFile(LayoutFile, data); err != nil {
+		return err
+	}
+	return w.writeDir(BlobsDir)
+}
+
+// Writes a blob from r, or from data if r is nil, unless it was already
+// written.
+func (w *Writer) writeBlob(desc Descriptor, r io.Reader, data []byte) error {
+	if w.blobs[desc.Digest] {
+		return nil
+	}
+	v, err := newVerifier(r, desc)
+	if err != nil {
+		return err
+	}
+
+	name := blobPath(v.want)
+	if dir := path.Dir(name); !w.dirs[dir] {
+		if err := w.writeDir(dir); err != nil {
+			return err
+		}
+		w.dirs[dir] = true
+	}
+
+	if r == nil {
+		err = w.writeFile(name, data)
+	} else {
+		err = w.writeHeader(name, tar.TypeReg, 0o644, desc.Size)
+		if err == nil {
+			_, err = io.Copy(w.tw, v)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	w.blobs[desc.Digest] = true
+	return nil
+}
+
+// Writes a regular file entry.
+func (w *Writer) writeFile(name string, data []byte) error {
+	if err := w.writeHeader(name, tar.TypeReg, 0o644, int64(len(data))); err != nil {
+		return err
+	}
+	_, err := w.tw.Write(data)
+	return err
+}
+
+// Writes a directory entry.
+func (w *Writer) writeDir(name string) error {
+	return w.writeHeader(name+"/", tar.TypeDir, 0o755, 0)
+}
+
+// Writes an entry header with fixed times and no ownership.
+func (w *Writer) writeHeader(name string, typeflag byte, mode, size int64) error {
+	return w.tw.WriteHeader(&tar.Header{
+		Typeflag: typeflag,
+		Name:     name,
+		Mode:     mode,
+		Size:     size,
+		ModTime:  entryTime,
+		Format:   tar.FormatPAX,
+	})
+}
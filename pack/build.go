@@ -0,0 +1,149 @@
+package pack
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/cruciblehq/crex"
+	"github.com/cruciblehq/spec/archive"
+	"github.com/cruciblehq/spec/manifest"
+)
+
+// Permissions of every file in a package.
+//
+// Set explicitly rather than through the umask, so that packages built on
+// different hosts are identical.
+const fileMode = 0o644
+
+// Options controlling package builds.
+type BuildOptions struct {
+	Output string // Directory holding the build artifact (empty for the project directory).
+}
+
+// Builds a resource package from a project directory.
+//
+// Reads [manifest.ManifestFile] from dir and decodes it, which validates it,
+// then packages the re-encoded manifest, the build artifact of the resource
+// type found in opts.Output, and a [ChecksumFile] index of both, writing the
+// package to dest. The package is always Zstandard-compressed, whatever the
+// extension of dest, and reproducible: entry times come from
+// SOURCE_DATE_EPOCH, and ownership and source permissions are not recorded.
+//
+// Returns [ErrNoArtifact] for resource types without a packaged artifact
+// (see [ArtifactFile]), and [ErrArtifactMissing] if the artifact is not a
+// regular file in the output directory. All errors are wrapped in
+// [ErrBuildFailed], and a partially written package is removed.
+func Build(dir, dest string, opts BuildOptions) (err error) {
+	data, err := os.ReadFile(filepath.Join(dir, manifest.ManifestFile))
+	if err != nil {
+		return crex.Wrap(ErrBuildFailed, err)
+	}
+	m, err := manifest.Decode(data)
+	if err != nil {
+		return crex.Wrap(ErrBuildFailed, err)
+	}
+
+	artifact, ok := ArtifactFile(m.Resource.Type)
+	if !ok {
+		return crex.Wrap(ErrBuildFailed, ErrNoArtifact)
+	}
+
+	output := opts.Output
+	if output == "" {
+		output = dir
+	}
+
+	staging, err := os.MkdirTemp("", "crucible-pack-*")
+	if err != nil {
+		return crex.Wrap(ErrBuildFailed, err)
+	}
+	defer os.RemoveAll(staging)
+
+	if err := stage(m, filepath.Join(output, artifact), artifact, staging); err != nil {
+		return crex.Wrap(ErrBuildFailed, err)
+	}
+
+	file, err := os.Create(dest)
+	if err != nil {
+		return crex.Wrap(ErrBuildFailed, err)
+	}
+	defer func() {
+		file.Close()
+		if err != nil {
+			os.Remove(dest)
+		}
+	}()
+
+	if _, _, err = archive.CreateTo(file, staging, archive.Zstd, archive.CreateOptions{Reproducible: true}); err != nil {
+		return crex.Wrap(ErrBuildFailed, err)
+	}
+	return nil
+}
+
+// Writes the files of a package to the staging directory.
+//
+// The artifact at src is copied under the given name, and the checksum index
+// is computed as the files are written.
+func stage(m *manifest.Manifest, src, artifact, staging string) error {
+	info, err := os.Lstat(src)
+	if os.IsNotExist(err) || (err == nil && !info.Mode().IsRegular()) {
+		return ErrArtifactMissing
+	}
+	if err != nil {
+		return err
+	}
+
+	encoded, err := manifest.Encode(m)
+	if err != nil {
+		return err
+	}
+	if err := writeFile(filepath.Join(staging, manifest.ManifestFile), encoded); err != nil {
+		return err
+	}
+	sum := sha256.Sum256(encoded)
+
+	sums := checksums{manifest.ManifestFile: hex.EncodeToString(sum[:])}
+	if sums[artifact], err = copyFile(src, filepath.Join(staging, artifact)); err != nil {
+		return err
+	}
+
+	return writeFile(filepath.Join(staging, ChecksumFile), sums.encode())
+}
+
+// Writes a package file with [fileMode].
+func writeFile(path string, data []byte) error {
+	if err := os.WriteFile(path, data, fileMode); err != nil {
+		return err
+	}
+	return os.Chmod(path, fileMode)
+}
+
+// Copies a file, returning the hex-encoded SHA-256 digest of its contents.
+func copyFile(src, dest string) (string, error) {
+	in, err := os.Open(src)
+	if err != nil {
+		return "", err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dest, os.O_CREATE|os.O_WRONLY|os.O_EXCL, fileMode)
+	if err != nil {
+		return "", err
+	}
+	defer out.Close()
+	if err := out.Chmod(fileMode); err != nil {
+		return "", err
+	}
+
+	h := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(out, h), in); err != nil {
+		return "", err
+	}
+	if err := out.Close(); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
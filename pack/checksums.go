@@ -0,0 +1,93 @@
+package pack
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Checksum index of a package, mapping file names to hex-encoded SHA-256
+// digests.
+type checksums map[string]string
+
+// Encodes the index in the format of the sha256sum tool.
+//
+// Each line holds a digest, two spaces, and a file name. Lines are sorted by
+// name so that the encoding is deterministic.
+func (c checksums) encode() []byte {
+	names := make([]string, 0, len(c))
+	for name := range c {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	var buf bytes.Buffer
+	for _, name := range names {
+		fmt.Fprintf(&buf, "%s  %s\n", c[name], name)
+	}
+	return buf.Bytes()
+}
+
+// Parses an index in the format of the sha256sum tool.
+//
+// Digests must be 64 lowercase hexadecimal characters, names must be unique,
+// and the binary mode marker ("*" before the name) is accepted. Returns
+// [ErrInvalidChecksums] for malformed lines.
+func parseChecksums(data []byte) (checksums, error) {
+	c := make(checksums)
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for sc.Scan() {
+		line := sc.Text()
+		if line == "" {
+			continue
+		}
+
+		digest, name, ok := strings.Cut(line, " ")
+		if !ok || !validDigest(digest) {
+			return nil, ErrInvalidChecksums
+		}
+		name = strings.TrimPrefix(strings.TrimPrefix(name, " "), "*")
+		if name == "" {
+			return nil, ErrInvalidChecksums
+		}
+		if _, dup := c[name]; dup {
+			return nil, ErrInvalidChecksums
+		}
+		c[name] = digest
+	}
+	if err := sc.Err(); err != nil {
+		return nil, ErrInvalidChecksums
+	}
+	return c, nil
+}
+
+// Whether s is a hex-encoded SHA-256 digest in lowercase.
+func validDigest(s string) bool {
+	if len(s) != 64 || strings.ToLower(s) != s {
+		return false
+	}
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
+
+// Checks the digests of the files of a package against the index.
+func (c checksums) check(digests map[string]string) error {
+	for name, digest := range digests {
+		want, ok := c[name]
+		if !ok {
+			return ErrUnlistedFile
+		}
+		if want != digest {
+			return ErrChecksumMismatch
+		}
+	}
+	for name := range c {
+		if _, ok := digests[name]; !ok {
+			return ErrMissingFile
+		}
+	}
+	return nil
+}
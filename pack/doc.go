@@ -0,0 +1,41 @@
+// Package pack defines the Crucible resource package format.
+//
+// A resource package is the archive uploaded to the registry for a version.
+// It is a Zstandard-compressed tar archive, written by the archive package,
+// holding three regular files at its root:
+//
+//   - [manifest.ManifestFile], the validated manifest of the resource,
+//     re-encoded with [manifest.Encode].
+//   - The build artifact of the resource type: [manifest.ImageFile] for
+//     runtimes and services, and [manifest.WidgetMainFile] for widgets (see
+//     [ArtifactFile]).
+//   - [ChecksumFile], a checksum index listing the SHA-256 digest of every
+//     other file, in the format of the sha256sum tool.
+//
+// [Build] assembles a package from a project directory and its build output.
+// Packages are reproducible: building the same manifest and artifact twice
+// yields identical bytes. [Open] reads a package from a stream, such as the
+// body of an upload, checking every file against the index without storing
+// the artifact, and [Package.Verify] checks that the package declares the
+// resource name, type, and version of the registry location it is uploaded
+// to.
+//
+// Building a package:
+//
+//	err := pack.Build("myproject", "my-api-1.0.0.tar.zst", pack.BuildOptions{
+//		Output: "myproject/build",
+//	})
+//
+// Checking an upload:
+//
+//	pkg, err := pack.Open(body)
+//	if err != nil {
+//		return err // corrupt or malformed package
+//	}
+//	err = pkg.Verify(pack.Location{
+//		Namespace: "cruciblehq",
+//		Resource:  "my-api",
+//		Version:   "1.0.0",
+//		Type:      manifest.TypeService,
+//	})
+package pack
@@ -0,0 +1,30 @@
+package pack
+
+import "errors"
+
+var (
+	ErrBuildFailed      = errors.New("package build failed")
+	ErrInvalidPackage   = errors.New("invalid package")
+	ErrLocationMismatch = errors.New("package does not match its registry location")
+
+	// Package contents.
+
+	ErrNoArtifact        = errors.New("resource type has no packaged artifact")
+	ErrManifestMissing   = errors.New("package does not contain a manifest")
+	ErrArtifactMissing   = errors.New("package does not contain the build artifact")
+	ErrChecksumsMissing  = errors.New("package does not contain a checksum index")
+	ErrInvalidChecksums  = errors.New("malformed checksum index")
+	ErrChecksumMismatch  = errors.New("file does not match its checksum")
+	ErrUnlistedFile      = errors.New("file is not listed in the checksum index")
+	ErrMissingFile       = errors.New("file listed in the checksum index is missing")
+	ErrDuplicateEntry    = errors.New("duplicate package entry")
+	ErrUnexpectedEntry   = errors.New("package entry is not a regular file")
+	ErrEntryTooLarge     = errors.New("package metadata file is too large")
+	ErrUnsupportedFormat = errors.New("package is not a Zstandard-compressed tar archive")
+
+	// Location.
+
+	ErrNameMismatch    = errors.New("resource name does not match")
+	ErrTypeMismatch    = errors.New("resource type does not match")
+	ErrVersionMismatch = errors.New("resource version does not match")
+)
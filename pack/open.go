@@ -0,0 +1,161 @@
+package pack
+
+import (
+	"archive/tar"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"strings"
+
+	"github.com/cruciblehq/crex"
+	"github.com/cruciblehq/spec/archive"
+	"github.com/cruciblehq/spec/manifest"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Resource package read by [Open].
+type Package struct {
+	Manifest  *manifest.Manifest // Decoded and validated manifest.
+	Artifact  string             // Name of the build artifact.
+	Checksums map[string]string  // Hex-encoded SHA-256 digests of the files other than the index, by name.
+}
+
+// Reads and checks a resource package from a stream.
+//
+// Reads r to the end of the archive in a single pass, hashing every file as
+// it is decompressed; only the manifest and the checksum index are kept in
+// memory, and each is limited to 1 MiB ([ErrEntryTooLarge]). The package is
+// rejected unless:
+//
+//   - It is a Zstandard-compressed tar archive ([ErrUnsupportedFormat]).
+//   - It holds only regular files, each at most once ([ErrUnexpectedEntry],
+//     [ErrDuplicateEntry]).
+//   - It holds a checksum index ([ErrChecksumsMissing]) that is well formed
+//     ([ErrInvalidChecksums]) and lists every other file with its digest
+//     ([ErrUnlistedFile], [ErrMissingFile], [ErrChecksumMismatch]).
+//   - It holds a valid manifest ([ErrManifestMissing]) and the build
+//     artifact of its resource type ([ErrArtifactMissing]).
+//
+// All errors are wrapped in [ErrInvalidPackage]. The manifest is not checked
+// against a registry location; see [Package.Verify].
+func Open(r io.Reader) (*Package, error) {
+	f, r, err := archive.DetectFormat(r)
+	if err != nil || f != archive.Zstd {
+		return nil, crex.Wrap(ErrInvalidPackage, ErrUnsupportedFormat)
+	}
+
+	dec, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1))
+	if err != nil {
+		return nil, crex.Wrap(ErrInvalidPackage, err)
+	}
+	defer dec.Close()
+
+	pkg, err := readPackage(tar.NewReader(dec))
+	if err != nil {
+		return nil, crex.Wrap(ErrInvalidPackage, err)
+	}
+	return pkg, nil
+}
+
+// Checks that the package declares the resource at a registry location.
+//
+// The manifest's resource name must be the namespace and resource of the
+// location, compared case-insensitively ([ErrNameMismatch]), its version the
+// location's version exactly ([ErrVersionMismatch]), and, if the location
+// has a type, its type that type ([ErrTypeMismatch]). Errors are wrapped in
+// [ErrLocationMismatch].
+func (p *Package) Verify(loc Location) error {
+	r := &p.Manifest.Resource
+	if !strings.EqualFold(r.Name, loc.Name()) {
+		return crex.Wrap(ErrLocationMismatch, ErrNameMismatch)
+	}
+	if loc.Type != "" && r.Type != loc.Type {
+		return crex.Wrap(ErrLocationMismatch, ErrTypeMismatch)
+	}
+	if r.Version != loc.Version {
+		return crex.Wrap(ErrLocationMismatch, ErrVersionMismatch)
+	}
+	return nil
+}
+
+// Reads the entries of a package, checking them against its index.
+func readPackage(tr *tar.Reader) (*Package, error) {
+	digests := make(map[string]string)
+	var manifestData, index []byte
+
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		name := strings.TrimPrefix(header.Name, "./")
+		if header.Typeflag != tar.TypeReg {
+			return nil, ErrUnexpectedEntry
+		}
+		if _, dup := digests[name]; dup || (name == ChecksumFile && index != nil) {
+			return nil, ErrDuplicateEntry
+		}
+
+		switch name {
+		case ChecksumFile:
+			if index, err = readMetadata(tr, header); err != nil {
+				return nil, err
+			}
+			continue
+		case manifest.ManifestFile:
+			if manifestData, err = readMetadata(tr, header); err != nil {
+				return nil, err
+			}
+			sum := sha256.Sum256(manifestData)
+			digests[name] = hex.EncodeToString(sum[:])
+		default:
+			h := sha256.New()
+			if _, err := io.Copy(h, tr); err != nil {
+				return nil, err
+			}
+			digests[name] = hex.EncodeToString(h.Sum(nil))
+		}
+	}
+
+	if index == nil {
+		return nil, ErrChecksumsMissing
+	}
+	sums, err := parseChecksums(index)
+	if err != nil {
+		return nil, err
+	}
+	if err := sums.check(digests); err != nil {
+		return nil, err
+	}
+
+	if manifestData == nil {
+		return nil, ErrManifestMissing
+	}
+	m, err := manifest.Decode(manifestData)
+	if err != nil {
+		return nil, err
+	}
+
+	artifact, ok := ArtifactFile(m.Resource.Type)
+	if !ok {
+		return nil, ErrNoArtifact
+	}
+	if _, ok := digests[artifact]; !ok {
+		return nil, ErrArtifactMissing
+	}
+
+	return &Package{Manifest: m, Artifact: artifact, Checksums: digests}, nil
+}
+
+// Reads a metadata file of a package into memory.
+func readMetadata(r io.Reader, header *tar.Header) ([]byte, error) {
+	if header.Size > maxMetadataSize {
+		return nil, ErrEntryTooLarge
+	}
+	return io.ReadAll(r)
+}
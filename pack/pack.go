@@ -0,0 +1,41 @@
+package pack
+
+import "github.com/cruciblehq/spec/manifest"
+
+// Name of the checksum index at the root of a package.
+const ChecksumFile = "SHA256SUMS"
+
+// Maximum size of the manifest and of the checksum index.
+//
+// Both are read into memory, so a package cannot exhaust memory through
+// them.
+const maxMetadataSize = 1 << 20
+
+// Returns the name of the build artifact packaged for a resource type.
+//
+// Runtimes and services package [manifest.ImageFile], and widgets
+// [manifest.WidgetMainFile]. Returns false for resource types that have no
+// packaged artifact.
+func ArtifactFile(t manifest.ResourceType) (string, bool) {
+	switch t {
+	case manifest.TypeRuntime, manifest.TypeService:
+		return manifest.ImageFile, true
+	case manifest.TypeWidget:
+		return manifest.WidgetMainFile, true
+	default:
+		return "", false
+	}
+}
+
+// Registry location a package is uploaded to.
+type Location struct {
+	Namespace string                // Namespace of the resource.
+	Resource  string                // Name of the resource within the namespace.
+	Version   string                // Version string.
+	Type      manifest.ResourceType // Type of the registry resource (empty to skip the check).
+}
+
+// Qualified resource name, as declared in manifests ("namespace/name").
+func (l Location) Name() string {
+	return l.Namespace + "/" + l.Resource
+}
@@ -0,0 +1,262 @@
+package pack
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cruciblehq/spec/archive"
+	"github.com/cruciblehq/spec/manifest"
+)
+
+const widgetManifest = "version: 0\nresource:\n  type: widget\n  name: acme/clock\n  version: 1.0.0\nmain: src/index.ts\n"
+
+// Creates a widget project with its built bundle.
+func createProject(t *testing.T) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, manifest.ManifestFile), []byte(widgetManifest), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(dir, "build"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "build", manifest.WidgetMainFile), []byte("export default 1;\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+// Builds a package from a project and opens it.
+func buildAndOpen(t *testing.T, dir string) *Package {
+	t.Helper()
+
+	dest := filepath.Join(t.TempDir(), "clock.tar.zst")
+	if err := Build(dir, dest, BuildOptions{Output: filepath.Join(dir, "build")}); err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+
+	file, err := os.Open(dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	pkg, err := Open(file)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	return pkg
+}
+
+// Writes files to a directory and archives it in the given format.
+func createPackage(t *testing.T, files map[string]string, f archive.Format) *bytes.Buffer {
+	t.Helper()
+
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var buf bytes.Buffer
+	if _, _, err := archive.CreateTo(&buf, dir, f, archive.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	return &buf
+}
+
+// Hex-encoded SHA-256 digest of a string.
+func sha256Hex(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestBuildAndOpen(t *testing.T) {
+	pkg := buildAndOpen(t, createProject(t))
+
+	if pkg.Manifest.Resource.Name != "acme/clock" {
+		t.Errorf("unexpected manifest: %+v", pkg.Manifest.Resource)
+	}
+	if pkg.Artifact != manifest.WidgetMainFile {
+		t.Errorf("expected artifact %q, got %q", manifest.WidgetMainFile, pkg.Artifact)
+	}
+	if len(pkg.Checksums) != 2 || pkg.Checksums[manifest.ManifestFile] == "" || pkg.Checksums[manifest.WidgetMainFile] == "" {
+		t.Errorf("unexpected checksums: %v", pkg.Checksums)
+	}
+}
+
+func TestBuildReproducible(t *testing.T) {
+	t.Setenv("SOURCE_DATE_EPOCH", "1700000000")
+	dir := createProject(t)
+
+	var outputs [][]byte
+	for range 2 {
+		dest := filepath.Join(t.TempDir(), "clock.tar.zst")
+		if err := Build(dir, dest, BuildOptions{Output: filepath.Join(dir, "build")}); err != nil {
+			t.Fatalf("Build failed: %v", err)
+		}
+		data, err := os.ReadFile(dest)
+		if err != nil {
+			t.Fatal(err)
+		}
+		outputs = append(outputs, data)
+	}
+	if !bytes.Equal(outputs[0], outputs[1]) {
+		t.Error("packages built from the same project differ")
+	}
+}
+
+func TestBuildErrors(t *testing.T) {
+	t.Run("missing artifact", func(t *testing.T) {
+		dir := createProject(t)
+		err := Build(dir, filepath.Join(t.TempDir(), "p.tar.zst"), BuildOptions{})
+		if !errors.Is(err, ErrBuildFailed) || !errors.Is(err, ErrArtifactMissing) {
+			t.Errorf("expected ErrArtifactMissing, got: %v", err)
+		}
+	})
+
+	t.Run("no artifact", func(t *testing.T) {
+		dir := t.TempDir()
+		data := "version: 0\nresource:\n  type: template\n  name: acme/starter\n  version: 1.0.0\n"
+		if err := os.WriteFile(filepath.Join(dir, manifest.ManifestFile), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+		err := Build(dir, filepath.Join(t.TempDir(), "p.tar.zst"), BuildOptions{})
+		if !errors.Is(err, ErrNoArtifact) {
+			t.Errorf("expected ErrNoArtifact, got: %v", err)
+		}
+	})
+
+	t.Run("invalid manifest", func(t *testing.T) {
+		dir := t.TempDir()
+		if err := os.WriteFile(filepath.Join(dir, manifest.ManifestFile), []byte("version: 0\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		dest := filepath.Join(t.TempDir(), "p.tar.zst")
+		err := Build(dir, dest, BuildOptions{})
+		if !errors.Is(err, manifest.ErrDecodeFailed) {
+			t.Errorf("expected ErrDecodeFailed, got: %v", err)
+		}
+		if _, err := os.Stat(dest); !os.IsNotExist(err) {
+			t.Error("no package should be written")
+		}
+	})
+}
+
+func TestVerify(t *testing.T) {
+	pkg := buildAndOpen(t, createProject(t))
+
+	tests := []struct {
+		name string
+		loc  Location
+		want error
+	}{
+		{"match", Location{Namespace: "acme", Resource: "clock", Version: "1.0.0", Type: manifest.TypeWidget}, nil},
+		{"match without type", Location{Namespace: "acme", Resource: "clock", Version: "1.0.0"}, nil},
+		{"name", Location{Namespace: "acme", Resource: "watch", Version: "1.0.0"}, ErrNameMismatch},
+		{"namespace", Location{Namespace: "other", Resource: "clock", Version: "1.0.0"}, ErrNameMismatch},
+		{"type", Location{Namespace: "acme", Resource: "clock", Version: "1.0.0", Type: manifest.TypeService}, ErrTypeMismatch},
+		{"version", Location{Namespace: "acme", Resource: "clock", Version: "1.0.1"}, ErrVersionMismatch},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := pkg.Verify(tt.loc)
+			if tt.want == nil {
+				if err != nil {
+					t.Errorf("expected no error, got: %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrLocationMismatch) || !errors.Is(err, tt.want) {
+				t.Errorf("expected %v, got: %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestOpenRejectsInvalidPackages(t *testing.T) {
+	const bundle = "export default 1;\n"
+	sums := checksums{
+		manifest.ManifestFile:   sha256Hex(widgetManifest),
+		manifest.WidgetMainFile: sha256Hex(bundle),
+	}
+	valid := map[string]string{
+		manifest.ManifestFile:   widgetManifest,
+		manifest.WidgetMainFile: bundle,
+		ChecksumFile:            string(sums.encode()),
+	}
+
+	// Returns the valid files with one of them changed, or removed if the
+	// content is empty.
+	with := func(name, content string) map[string]string {
+		files := make(map[string]string, len(valid)+1)
+		for k, v := range valid {
+			files[k] = v
+		}
+		if content == "" {
+			delete(files, name)
+		} else {
+			files[name] = content
+		}
+		return files
+	}
+
+	tests := []struct {
+		name   string
+		files  map[string]string
+		format archive.Format
+		want   error
+	}{
+		{"modified artifact", with(manifest.WidgetMainFile, "tampered"), archive.Zstd, ErrChecksumMismatch},
+		{"unlisted file", with("extra.js", "x"), archive.Zstd, ErrUnlistedFile},
+		{"missing file", with(manifest.WidgetMainFile, ""), archive.Zstd, ErrMissingFile},
+		{"missing index", with(ChecksumFile, ""), archive.Zstd, ErrChecksumsMissing},
+		{"malformed index", with(ChecksumFile, "not a checksum\n"), archive.Zstd, ErrInvalidChecksums},
+		{"gzip", valid, archive.Gzip, ErrUnsupportedFormat},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Open(createPackage(t, tt.files, tt.format))
+			if !errors.Is(err, ErrInvalidPackage) || !errors.Is(err, tt.want) {
+				t.Errorf("expected %v, got: %v", tt.want, err)
+			}
+		})
+	}
+
+	if _, err := Open(createPackage(t, valid, archive.Zstd)); err != nil {
+		t.Errorf("expected the valid package to open, got: %v", err)
+	}
+}
+
+func TestParseChecksums(t *testing.T) {
+	digest := strings.Repeat("ab", 32)
+
+	sums, err := parseChecksums([]byte(digest + "  a.txt\n" + digest + " *b.bin\n"))
+	if err != nil {
+		t.Fatalf("parseChecksums failed: %v", err)
+	}
+	if sums["a.txt"] != digest || sums["b.bin"] != digest {
+		t.Errorf("unexpected checksums: %v", sums)
+	}
+
+	for _, data := range []string{
+		"abc  a.txt\n",
+		strings.ToUpper(digest) + "  a.txt\n",
+		digest + "  a.txt\n" + digest + "  a.txt\n",
+		digest + "\n",
+	} {
+		if _, err := parseChecksums([]byte(data)); !errors.Is(err, ErrInvalidChecksums) {
+			t.Errorf("%q: expected ErrInvalidChecksums, got: %v", data, err)
+		}
+	}
+}
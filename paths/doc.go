@@ -2,5 +2,5 @@
 //
 // These are the well-known paths where cruxd operates on Linux. Both the
 // daemon (cruxd) and the CLI (crux) import these constants so they agree
-// on socket and runtime directory locations.
+// on socket, runtime, and store directory locations.
 package paths
@@ -15,6 +15,9 @@ const (
 
 	// Base directory for cruxd runtime state under /run.
 	baseDir = "/run/cruxd"
+
+	// Base directory for persistent cruxd state under /var/lib.
+	stateDir = "/var/lib/cruxd"
 )
 
 // Path to the runtime directory for a cruxd instance.
@@ -42,3 +45,14 @@ func Socket(name string) string {
 func PIDFile(name string) string {
 	return filepath.Join(InstanceDir(name), "cruxd.pid")
 }
+
+// Path to the content-addressed artifact store.
+//
+// Holds downloaded archives and built images keyed by digest. Unlike the
+// instance directories, it persists across reboots and is shared by all
+// instances and by the CLI.
+//
+//	/var/lib/cruxd/store
+func Store() string {
+	return filepath.Join(stateDir, "store")
+}
@@ -0,0 +1,155 @@
+package registry
+
+import "context"
+
+// Interface for registry access control decisions.
+//
+// Implementations identify the caller from the context (for example, from a
+// session or an API token attached by the HTTP handler) and decide whether it
+// holds the permission that the operation requires on the target. Both the
+// HTTP handler in hub and the registry stores consult the same authorizer so
+// that enforcement is consistent regardless of the entry point.
+type Authorizer interface {
+
+	// Decides whether the caller may perform an operation.
+	//
+	// The required permission is given by [Operation.Permission]. Returns nil
+	// if the operation is allowed. Otherwise returns an [*Error] with code
+	// [ErrorCodeUnauthorized] when the caller could not be authenticated
+	// (missing, invalid, revoked, or expired credentials), or
+	// [ErrorCodeForbidden] when the caller is authenticated but lacks the
+	// permission on the target.
+	Authorize(ctx context.Context, op Operation, target Target) error
+}
+
+// Entity an operation is performed on.
+//
+// Namespace is empty only for operations that are not scoped to a namespace
+// ([OperationCreateNamespace] and [OperationListNamespaces]). Resource is
+// empty for namespace-level operations. Token restrictions (see
+// [TokenSummary.Allows]) are evaluated against Resource.
+type Target struct {
+	Namespace string // Namespace the operation applies to.
+	Resource  string // Resource the operation applies to, if any.
+}
+
+// Identifies a [Registry] method for authorization.
+//
+// Each value is the name of the corresponding [Registry] method.
+type Operation string
+
+const (
+	OperationCreateNamespace    Operation = "CreateNamespace"
+	OperationReadNamespace      Operation = "ReadNamespace"
+	OperationUpdateNamespace    Operation = "UpdateNamespace"
+	OperationDeleteNamespace    Operation = "DeleteNamespace"
+	OperationListNamespaces     Operation = "ListNamespaces"
+	OperationRenameNamespace    Operation = "RenameNamespace"
+	OperationCreateResource     Operation = "CreateResource"
+	OperationReadResource       Operation = "ReadResource"
+	OperationUpdateResource     Operation = "UpdateResource"
+	OperationDeleteResource     Operation = "DeleteResource"
+	OperationListResources      Operation = "ListResources"
+	OperationRenameResource     Operation = "RenameResource"
+	OperationTransferResource   Operation = "TransferResource"
+	OperationListRedirects      Operation = "ListRedirects"
+	OperationDeleteRedirect     Operation = "DeleteRedirect"
+	OperationCreateVersion      Operation = "CreateVersion"
+	OperationReadVersion        Operation = "ReadVersion"
+	OperationUpdateVersion      Operation = "UpdateVersion"
+	OperationDeleteVersion      Operation = "DeleteVersion"
+	OperationListVersions       Operation = "ListVersions"
+	OperationListDependents     Operation = "ListDependents"
+	OperationUploadArchive      Operation = "UploadArchive"
+	OperationDownloadArchive    Operation = "DownloadArchive"
+	OperationStartUpload        Operation = "StartUpload"
+	OperationUploadChunk        Operation = "UploadChunk"
+	OperationReadUpload         Operation = "ReadUpload"
+	OperationCompleteUpload     Operation = "CompleteUpload"
+	OperationCancelUpload       Operation = "CancelUpload"
+	OperationCreateChannel      Operation = "CreateChannel"
+	OperationUpdateChannel      Operation = "UpdateChannel"
+	OperationReadChannel        Operation = "ReadChannel"
+	OperationDeleteChannel      Operation = "DeleteChannel"
+	OperationListChannels       Operation = "ListChannels"
+	OperationListChannelHistory Operation = "ListChannelHistory"
+	OperationRollbackChannel    Operation = "RollbackChannel"
+	OperationCreateMember       Operation = "CreateMember"
+	OperationReadMember         Operation = "ReadMember"
+	OperationUpdateMember       Operation = "UpdateMember"
+	OperationDeleteMember       Operation = "DeleteMember"
+	OperationListMembers        Operation = "ListMembers"
+	OperationCreateToken        Operation = "CreateToken"
+	OperationDeleteToken        Operation = "DeleteToken"
+	OperationListTokens         Operation = "ListTokens"
+)
+
+// Permission required by each operation.
+//
+// Namespace creation and listing only require an authenticated caller; the
+// creator of a namespace becomes its owner. Moving a channel is a publishing
+// action, while managing the namespace itself, its members, and its tokens
+// requires ownership, as does renaming or transferring anything, since that
+// changes the paths other users depend on.
+var operationPermissions = map[Operation]Permission{
+	OperationCreateNamespace:    PermissionAuthenticated,
+	OperationReadNamespace:      PermissionRead,
+	OperationUpdateNamespace:    PermissionAdmin,
+	OperationDeleteNamespace:    PermissionAdmin,
+	OperationListNamespaces:     PermissionAuthenticated,
+	OperationRenameNamespace:    PermissionAdmin,
+	OperationCreateResource:     PermissionPublish,
+	OperationReadResource:       PermissionRead,
+	OperationUpdateResource:     PermissionPublish,
+	OperationDeleteResource:     PermissionPublish,
+	OperationListResources:      PermissionRead,
+	OperationRenameResource:     PermissionAdmin,
+	OperationTransferResource:   PermissionAdmin,
+	OperationListRedirects:      PermissionRead,
+	OperationDeleteRedirect:     PermissionAdmin,
+	OperationCreateVersion:      PermissionPublish,
+	OperationReadVersion:        PermissionRead,
+	OperationUpdateVersion:      PermissionPublish,
+	OperationDeleteVersion:      PermissionPublish,
+	OperationListVersions:       PermissionRead,
+	OperationListDependents:     PermissionRead,
+	OperationUploadArchive:      PermissionPublish,
+	OperationDownloadArchive:    PermissionRead,
+	OperationStartUpload:        PermissionPublish,
+	OperationUploadChunk:        PermissionPublish,
+	OperationReadUpload:         PermissionPublish,
+	OperationCompleteUpload:     PermissionPublish,
+	OperationCancelUpload:       PermissionPublish,
+	OperationCreateChannel:      PermissionPublish,
+	OperationUpdateChannel:      PermissionPublish,
+	OperationReadChannel:        PermissionRead,
+	OperationDeleteChannel:      PermissionPublish,
+	OperationListChannels:       PermissionRead,
+	OperationListChannelHistory: PermissionRead,
+	OperationRollbackChannel:    PermissionPublish,
+	OperationCreateMember:       PermissionAdmin,
+	OperationReadMember:         PermissionRead,
+	OperationUpdateMember:       PermissionAdmin,
+	OperationDeleteMember:       PermissionAdmin,
+	OperationListMembers:        PermissionRead,
+	OperationCreateToken:        PermissionAdmin,
+	OperationDeleteToken:        PermissionAdmin,
+	OperationListTokens:         PermissionAdmin,
+}
+
+// Permission required to perform the operation.
+//
+// Unknown operations require [PermissionAdmin], so that an operation missing
+// from the table fails closed rather than open.
+func (op Operation) Permission() Permission {
+	if p, ok := operationPermissions[op]; ok {
+		return p
+	}
+	return PermissionAdmin
+}
+
+// Whether the operation is a known value.
+func (op Operation) Valid() bool {
+	_, ok := operationPermissions[op]
+	return ok
+}
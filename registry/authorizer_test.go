@@ -0,0 +1,92 @@
+package registry
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestOperationsCoverRegistry(t *testing.T) {
+	typ := reflect.TypeFor[Registry]()
+	for i := 0; i < typ.NumMethod(); i++ {
+		op := Operation(typ.Method(i).Name)
+		if !op.Valid() {
+			t.Errorf("Registry.%s has no operation permission", op)
+		}
+	}
+	if got, want := len(operationPermissions), typ.NumMethod(); got != want {
+		t.Errorf("operation table has %d entries, Registry has %d methods", got, want)
+	}
+}
+
+func TestOperationPermissionUnknown(t *testing.T) {
+	if got := Operation("Bogus").Permission(); got != PermissionAdmin {
+		t.Errorf("Permission() = %q, want %q", got, PermissionAdmin)
+	}
+}
+
+func TestRoleGrants(t *testing.T) {
+	tests := []struct {
+		role Role
+		perm Permission
+		want bool
+	}{
+		{RoleReader, PermissionAuthenticated, true},
+		{RoleReader, PermissionRead, true},
+		{RoleReader, PermissionPublish, false},
+		{RoleReader, PermissionAdmin, false},
+		{RolePublisher, PermissionRead, true},
+		{RolePublisher, PermissionPublish, true},
+		{RolePublisher, PermissionAdmin, false},
+		{RoleOwner, PermissionPublish, true},
+		{RoleOwner, PermissionAdmin, true},
+		{Role("guest"), PermissionRead, false},
+		{RoleOwner, Permission("superuser"), false},
+	}
+	for _, tt := range tests {
+		if got := tt.role.Grants(tt.perm); got != tt.want {
+			t.Errorf("%q.Grants(%q) = %v, want %v", tt.role, tt.perm, got, tt.want)
+		}
+	}
+}
+
+func TestTokenAllows(t *testing.T) {
+	restricted := TokenSummary{Role: RolePublisher, Resources: []string{"hub"}}
+	unrestricted := TokenSummary{Role: RolePublisher}
+
+	tests := []struct {
+		name     string
+		token    TokenSummary
+		resource string
+		perm     Permission
+		want     bool
+	}{
+		{"unrestricted resource", unrestricted, "other", PermissionPublish, true},
+		{"unrestricted namespace", unrestricted, "", PermissionPublish, true},
+		{"unrestricted beyond role", unrestricted, "hub", PermissionAdmin, false},
+		{"restricted listed", restricted, "hub", PermissionPublish, true},
+		{"restricted unlisted", restricted, "other", PermissionRead, false},
+		{"restricted namespace read", restricted, "", PermissionRead, true},
+		{"restricted namespace publish", restricted, "", PermissionPublish, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.token.Allows(tt.resource, tt.perm); got != tt.want {
+				t.Errorf("Allows(%q, %q) = %v, want %v", tt.resource, tt.perm, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTokenExpired(t *testing.T) {
+	expiry := int64(100)
+	token := TokenSummary{ExpiresAt: &expiry}
+	if token.Expired(99) {
+		t.Error("token expired before its expiry")
+	}
+	if !token.Expired(100) {
+		t.Error("token not expired at its expiry")
+	}
+	if (&TokenSummary{}).Expired(1 << 40) {
+		t.Error("token without expiry reported expired")
+	}
+}
@@ -0,0 +1,159 @@
+package registry
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"io"
+
+	"github.com/cruciblehq/spec/reference"
+)
+
+// Digest algorithm used for blobs.
+const BlobDigestAlgorithm = "sha256"
+
+// Content-addressed storage for archive bytes.
+//
+// Blobs are keyed by the SHA-256 [reference.Digest] of their content, so
+// storing the same bytes twice keeps a single copy. A blob store knows nothing
+// about namespaces, resources, or versions; the mapping from versions to
+// digests is kept by a [MetadataStore]. Implementations must be safe for
+// concurrent use. Operations on a missing blob return an [*Error] with code
+// [ErrorCodeNotFound].
+type BlobStore interface {
+
+	// Stores a blob.
+	//
+	// Reads r to the end, computing the digest and size of its content. If a
+	// blob with the same digest already exists, the content is discarded and
+	// the existing blob is kept. A partially written blob is never visible to
+	// readers.
+	Put(ctx context.Context, r io.Reader) (*BlobInfo, error)
+
+	// Opens a blob for reading.
+	//
+	// The caller is responsible for closing the reader.
+	Get(ctx context.Context, digest reference.Digest) (io.ReadCloser, error)
+
+	// Opens a byte range of a blob for reading.
+	//
+	// Reads length bytes starting at offset. A negative length, or one that
+	// extends past the end of the blob, reads to the end. The offset must not
+	// be negative or exceed the blob size, otherwise [ErrRangeInvalid] is
+	// returned. The caller is responsible for closing the reader.
+	GetRange(ctx context.Context, digest reference.Digest, offset, length int64) (io.ReadCloser, error)
+
+	// Returns the digest and size of a blob.
+	Stat(ctx context.Context, digest reference.Digest) (*BlobInfo, error)
+
+	// Permanently deletes a blob.
+	//
+	// The operation is idempotent, returning success if the blob does not
+	// exist. Callers must ensure no version still refers to the blob (see
+	// [MetadataStore.CountArchiveReferences]).
+	Delete(ctx context.Context, digest reference.Digest) error
+
+	// Lists the stored blobs.
+	//
+	// Returns every committed blob, ordered by digest. Staged uploads are not
+	// listed. Used to find blobs no version refers to any more (see
+	// [BlobSweeper]).
+	List(ctx context.Context) ([]BlobInfo, error)
+
+	// Appends bytes to a staged upload.
+	//
+	// Staged uploads are named by the caller (see [ValidateUploadID]) and
+	// are not readable until committed. The offset must equal the number of
+	// bytes already staged under the name, otherwise an [*Error] with code
+	// [ErrorCodeOffsetMismatch] is returned. Reads r to the end and returns
+	// the new staged size. If reading r fails, the bytes received before the
+	// failure remain staged.
+	Append(ctx context.Context, upload string, offset int64, r io.Reader) (int64, error)
+
+	// Returns the number of bytes staged under a name.
+	//
+	// Returns zero for names with no staged bytes.
+	StagedSize(ctx context.Context, upload string) (int64, error)
+
+	// Moves a staged upload into the store as a blob.
+	//
+	// Computes the digest of the staged bytes, stores them as by Put, and
+	// removes the staged upload. If nothing is staged under the name, an
+	// [*Error] with code [ErrorCodeNotFound] is returned.
+	Commit(ctx context.Context, upload string) (*BlobInfo, error)
+
+	// Discards a staged upload.
+	//
+	// The operation is idempotent, returning success if nothing is staged
+	// under the name.
+	Abort(ctx context.Context, upload string) error
+}
+
+// Identity of a stored blob.
+type BlobInfo struct {
+	Digest reference.Digest // SHA-256 digest of the content.
+	Size   int64            // Content size in bytes.
+}
+
+// Whether a digest can address a blob.
+//
+// The algorithm must be [BlobDigestAlgorithm] and the hash 64 lowercase
+// hexadecimal characters.
+func ValidateBlobDigest(d reference.Digest) error {
+	if d.Algorithm != BlobDigestAlgorithm {
+		return ErrDigestAlgorithm
+	}
+	if len(d.Hash) != 2*sha256.Size {
+		return ErrDigestInvalid
+	}
+	return ValidateDigest(d.String())
+}
+
+// Returns a hash for computing blob digests.
+func newBlobHash() *blobHash {
+	return &blobHash{h: sha256.New()}
+}
+
+// Running blob digest and size.
+type blobHash struct {
+	h    hash.Hash
+	size int64
+}
+
+// Implements io.Writer.
+func (b *blobHash) Write(p []byte) (int, error) {
+	n, _ := b.h.Write(p)
+	b.size += int64(n)
+	return n, nil
+}
+
+// Returns the digest and size of the content written so far.
+func (b *blobHash) info() *BlobInfo {
+	return &BlobInfo{
+		Digest: reference.Digest{Algorithm: BlobDigestAlgorithm, Hash: hex.EncodeToString(b.h.Sum(nil))},
+		Size:   b.size,
+	}
+}
+
+// Clamps a byte range to a blob of the given size.
+//
+// Returns the number of bytes to read from offset.
+func clampRange(size, offset, length int64) (int64, error) {
+	if offset < 0 || offset > size {
+		return 0, ErrRangeInvalid
+	}
+	if length < 0 || offset+length > size {
+		length = size - offset
+	}
+	return length, nil
+}
+
+// Error returned for a missing blob.
+func blobNotFound(d reference.Digest) error {
+	return &Error{
+		Code:    ErrorCodeNotFound,
+		Message: "blob " + d.String() + " not found",
+		Details: map[string]string{DetailEntity: "blob"},
+	}
+}
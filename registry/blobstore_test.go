@@ -0,0 +1,184 @@
+package registry
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/cruciblehq/spec/reference"
+)
+
+func blobStores(t *testing.T) map[string]BlobStore {
+	fs, err := NewFSBlobStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return map[string]BlobStore{"fs": fs, "memory": NewMemoryBlobStore()}
+}
+
+func readBlob(t *testing.T, rc io.ReadCloser, err error) string {
+	t.Helper()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rc.Close()
+	data, err := io.ReadAll(rc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestBlobStore(t *testing.T) {
+	const content = "hello, crucible"
+	const digest = "sha256:b2596a17880cafeec64e47713fbbf0eb35f079ed090635cb857e4578b95df8f4"
+
+	for name, store := range blobStores(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+
+			info, err := store.Put(ctx, strings.NewReader(content))
+			if err != nil {
+				t.Fatalf("Put failed: %v", err)
+			}
+			if info.Size != int64(len(content)) {
+				t.Errorf("Size = %d, want %d", info.Size, len(content))
+			}
+			if got := info.Digest.String(); got != digest {
+				t.Errorf("Digest = %s, want %s", got, digest)
+			}
+
+			again, err := store.Put(ctx, strings.NewReader(content))
+			if err != nil || !again.Digest.Equal(&info.Digest) {
+				t.Errorf("second Put = %v, %v; want same digest", again, err)
+			}
+
+			rc, err := store.Get(ctx, info.Digest)
+			if got := readBlob(t, rc, err); got != content {
+				t.Errorf("Get = %q, want %q", got, content)
+			}
+
+			rc, err = store.GetRange(ctx, info.Digest, 7, 8)
+			if got := readBlob(t, rc, err); got != "crucible" {
+				t.Errorf("GetRange(7, 8) = %q, want %q", got, "crucible")
+			}
+			rc, err = store.GetRange(ctx, info.Digest, 7, 100)
+			if got := readBlob(t, rc, err); got != "crucible" {
+				t.Errorf("GetRange(7, 100) = %q, want %q", got, "crucible")
+			}
+			if _, err := store.GetRange(ctx, info.Digest, 100, -1); !errors.Is(err, ErrRangeInvalid) {
+				t.Errorf("GetRange(100) error = %v, want %v", err, ErrRangeInvalid)
+			}
+
+			stat, err := store.Stat(ctx, info.Digest)
+			if err != nil || stat.Size != info.Size {
+				t.Errorf("Stat = %v, %v", stat, err)
+			}
+
+			if err := store.Delete(ctx, info.Digest); err != nil {
+				t.Fatalf("Delete failed: %v", err)
+			}
+			if err := store.Delete(ctx, info.Digest); err != nil {
+				t.Errorf("second Delete failed: %v", err)
+			}
+			if _, err := store.Get(ctx, info.Digest); !errors.Is(err, ErrNotFound) {
+				t.Errorf("Get after Delete error = %v, want %v", err, ErrNotFound)
+			}
+
+			bad := reference.Digest{Algorithm: "sha256", Hash: "../../etc/passwd"}
+			if _, err := store.Stat(ctx, bad); !errors.Is(err, ErrDigestInvalid) {
+				t.Errorf("Stat(bad) error = %v, want %v", err, ErrDigestInvalid)
+			}
+			md5 := reference.Digest{Algorithm: "md5", Hash: "d41d8cd98f00b204e9800998ecf8427e"}
+			if _, err := store.Stat(ctx, md5); !errors.Is(err, ErrDigestAlgorithm) {
+				t.Errorf("Stat(md5) error = %v, want %v", err, ErrDigestAlgorithm)
+			}
+		})
+	}
+}
+
+func TestBlobStoreStaging(t *testing.T) {
+	const id = "0123456789abcdef"
+
+	for name, store := range blobStores(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+
+			if n, err := store.StagedSize(ctx, id); err != nil || n != 0 {
+				t.Fatalf("StagedSize = %d, %v; want 0", n, err)
+			}
+			if n, err := store.Append(ctx, id, 0, strings.NewReader("hello, ")); err != nil || n != 7 {
+				t.Fatalf("Append = %d, %v; want 7", n, err)
+			}
+			if _, err := store.Append(ctx, id, 0, strings.NewReader("hello, ")); !errors.Is(err, ErrOffsetMismatch) {
+				t.Fatalf("Append at stale offset error = %v, want %v", err, ErrOffsetMismatch)
+			}
+			if n, err := store.Append(ctx, id, 7, strings.NewReader("crucible")); err != nil || n != 15 {
+				t.Fatalf("Append = %d, %v; want 15", n, err)
+			}
+
+			info, err := store.Commit(ctx, id)
+			if err != nil {
+				t.Fatalf("Commit failed: %v", err)
+			}
+			rc, err := store.Get(ctx, info.Digest)
+			if got := readBlob(t, rc, err); got != "hello, crucible" {
+				t.Errorf("Get = %q, want %q", got, "hello, crucible")
+			}
+			if n, _ := store.StagedSize(ctx, id); n != 0 {
+				t.Errorf("StagedSize after Commit = %d, want 0", n)
+			}
+			if _, err := store.Commit(ctx, id); !errors.Is(err, ErrNotFound) {
+				t.Errorf("second Commit error = %v, want %v", err, ErrNotFound)
+			}
+
+			store.Append(ctx, id, 0, strings.NewReader("discard me"))
+			if err := store.Abort(ctx, id); err != nil {
+				t.Fatalf("Abort failed: %v", err)
+			}
+			if n, _ := store.StagedSize(ctx, id); n != 0 {
+				t.Errorf("StagedSize after Abort = %d, want 0", n)
+			}
+			if _, err := store.Append(ctx, "../escape-attempt", 0, strings.NewReader("x")); !errors.Is(err, ErrUploadIDInvalid) {
+				t.Errorf("Append with bad ID error = %v, want %v", err, ErrUploadIDInvalid)
+			}
+		})
+	}
+}
+
+func TestBlobStoreList(t *testing.T) {
+	for name, store := range blobStores(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+
+			if infos, err := store.List(ctx); err != nil || len(infos) != 0 {
+				t.Fatalf("List on empty store = %v, %v; want none", infos, err)
+			}
+			a, err := store.Put(ctx, strings.NewReader("first"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, err := store.Put(ctx, strings.NewReader("second blob"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := store.Append(ctx, "0123456789abcdef", 0, strings.NewReader("staged")); err != nil {
+				t.Fatal(err)
+			}
+
+			infos, err := store.List(ctx)
+			if err != nil {
+				t.Fatalf("List failed: %v", err)
+			}
+			want := []BlobInfo{*a, *b}
+			if a.Digest.String() > b.Digest.String() {
+				want = []BlobInfo{*b, *a}
+			}
+			if len(infos) != 2 || infos[0] != want[0] || infos[1] != want[1] {
+				t.Errorf("List = %v, want %v", infos, want)
+			}
+		})
+	}
+}
@@ -7,24 +7,31 @@ import "github.com/cruciblehq/crex"
 // Used as the request body for channel creation and update operations. For
 // update requests, the name field must match the URL path parameter or update
 // context. The version field is a simple string reference to an existing version;
-// changing this pointer updates where the channel points. Contains only user-
-// modifiable fields. The media type is [MediaTypeChannelInfo].
+// changing this pointer updates where the channel points. The optional reason
+// is not stored on the channel itself but recorded in its [ChannelHistory].
+// Contains only user-modifiable fields. The media type is
+// [MediaTypeChannelInfo].
 type ChannelInfo struct {
-	Name        string `json:"name"`        // Channel name.
-	Version     string `json:"version"`     // Version this channel points to.
-	Description string `json:"description"` // Description.
+	Name        string `json:"name"`             // Channel name.
+	Version     string `json:"version"`          // Version this channel points to.
+	Description string `json:"description"`      // Description.
+	Reason      string `json:"reason,omitempty"` // Reason for the move, recorded in the channel history.
 }
 
 // Validates the channel info.
 //
-// The name must conform to the registry naming rules (see [ValidateName]) and
-// the version must be a valid semantic version (see [ValidateVersionString]).
+// The name must conform to the registry naming rules (see [ValidateName]),
+// the version must be a valid semantic version (see [ValidateVersionString]),
+// and the reason must not exceed 512 bytes (see [ValidateReason]).
 func (info *ChannelInfo) Validate() error {
 	if err := ValidateName(info.Name); err != nil {
-		return crex.Wrap(ErrInvalidChannel, err)
+		return crex.Wrap(ErrInvalidChannel, fieldError("name", err))
 	}
 	if err := ValidateVersionString(info.Version); err != nil {
-		return crex.Wrap(ErrInvalidChannel, err)
+		return crex.Wrap(ErrInvalidChannel, fieldError("version", err))
+	}
+	if err := ValidateReason(info.Reason); err != nil {
+		return crex.Wrap(ErrInvalidChannel, fieldError("reason", err))
 	}
 	return nil
 }
@@ -45,13 +52,13 @@ type ChannelSummary struct {
 // Validates the channel summary.
 func (s *ChannelSummary) Validate() error {
 	if err := ValidateName(s.Name); err != nil {
-		return crex.Wrap(ErrInvalidChannel, err)
+		return crex.Wrap(ErrInvalidChannel, fieldError("name", err))
 	}
 	if err := ValidateVersionString(s.Version); err != nil {
-		return crex.Wrap(ErrInvalidChannel, err)
+		return crex.Wrap(ErrInvalidChannel, fieldError("version", err))
 	}
 	if err := ValidateTimestamps(s.CreatedAt, s.UpdatedAt); err != nil {
-		return crex.Wrap(ErrInvalidChannel, err)
+		return crex.Wrap(ErrInvalidChannel, fieldError("updatedAt", err))
 	}
 	return nil
 }
@@ -65,7 +72,8 @@ func (s *ChannelSummary) Validate() error {
 // references during development but are discouraged for production use where
 // explicit version references ensure reproducibility. Includes scoping
 // information to identify the channel's location. The media type is
-// [MediaTypeChannel].
+// [MediaTypeChannelV1]; [MediaTypeChannel] carries the older [ChannelV0]
+// shape, embedding a [VersionV0].
 type Channel struct {
 	Namespace   string  `json:"namespace"`   // Namespace this channel belongs to.
 	Resource    string  `json:"resource"`    // Resource this channel belongs to.
@@ -79,19 +87,19 @@ type Channel struct {
 // Validates the channel.
 func (ch *Channel) Validate() error {
 	if err := ValidateName(ch.Namespace); err != nil {
-		return crex.Wrap(ErrInvalidChannel, err)
+		return crex.Wrap(ErrInvalidChannel, fieldError("namespace", err))
 	}
 	if err := ValidateName(ch.Resource); err != nil {
-		return crex.Wrap(ErrInvalidChannel, err)
+		return crex.Wrap(ErrInvalidChannel, fieldError("resource", err))
 	}
 	if err := ValidateName(ch.Name); err != nil {
-		return crex.Wrap(ErrInvalidChannel, err)
+		return crex.Wrap(ErrInvalidChannel, fieldError("name", err))
 	}
 	if err := ValidateTimestamps(ch.CreatedAt, ch.UpdatedAt); err != nil {
-		return crex.Wrap(ErrInvalidChannel, err)
+		return crex.Wrap(ErrInvalidChannel, fieldError("updatedAt", err))
 	}
 	if err := ch.Version.Validate(); err != nil {
-		return crex.Wrap(ErrInvalidChannel, err)
+		return crex.Wrap(ErrInvalidChannel, fieldError("version", err))
 	}
 	return nil
 }
@@ -107,7 +115,7 @@ type ChannelList struct {
 func (l *ChannelList) Validate() error {
 	for i := range l.Channels {
 		if err := l.Channels[i].Validate(); err != nil {
-			return crex.Wrap(ErrInvalidChannel, err)
+			return crex.Wrap(ErrInvalidChannel, fieldError(indexField("channels", i), err))
 		}
 	}
 	return nil
@@ -0,0 +1,119 @@
+package registry
+
+import "github.com/cruciblehq/crex"
+
+// Record of a single channel move.
+//
+// A new entry is appended whenever a channel is created, updated to point to a
+// different version, or rolled back. Entries are never modified or removed
+// while the channel exists, so the history answers what a channel pointed to
+// at any point in time. Sequence numbers start at 1 and increase by one with
+// each entry. When the move was a rollback, Rollback holds the sequence number
+// of the entry that was restored.
+type ChannelHistoryEntry struct {
+	Sequence  int64  `json:"sequence"`           // Position in the channel history, starting at 1.
+	Version   string `json:"version"`            // Version the channel pointed to after the move.
+	Actor     string `json:"actor"`              // User or token that moved the channel.
+	Reason    string `json:"reason"`             // Reason given for the move. May be empty.
+	Rollback  *int64 `json:"rollback,omitempty"` // Sequence of the restored entry, for rollbacks.
+	Timestamp int64  `json:"timestamp"`          // When the move happened.
+}
+
+// Validates the channel history entry.
+//
+// The sequence must be positive, the version a valid semantic version (see
+// [ValidateVersionString]), the actor a valid name (see [ValidateName]), and
+// the reason at most 512 bytes. A rollback must refer to an earlier entry.
+func (e *ChannelHistoryEntry) Validate() error {
+	if e.Sequence <= 0 {
+		return crex.Wrap(ErrInvalidHistory, fieldError("sequence", ErrSequenceInvalid))
+	}
+	if err := ValidateVersionString(e.Version); err != nil {
+		return crex.Wrap(ErrInvalidHistory, fieldError("version", err))
+	}
+	if err := ValidateName(e.Actor); err != nil {
+		return crex.Wrap(ErrInvalidHistory, fieldError("actor", err))
+	}
+	if err := ValidateReason(e.Reason); err != nil {
+		return crex.Wrap(ErrInvalidHistory, fieldError("reason", err))
+	}
+	if e.Rollback != nil && (*e.Rollback <= 0 || *e.Rollback >= e.Sequence) {
+		return crex.Wrap(ErrInvalidHistory, fieldError("rollback", ErrRollbackOrder))
+	}
+	if e.Timestamp <= 0 {
+		return crex.Wrap(ErrInvalidHistory, fieldError("timestamp", ErrTimestampInvalid))
+	}
+	return nil
+}
+
+// Append-only history of a channel.
+//
+// Entries are ordered oldest first. The last entry describes where the channel
+// currently points. The history is removed together with the channel. The
+// media type is [MediaTypeChannelHistory].
+type ChannelHistory struct {
+	Namespace string                `json:"namespace"` // Namespace the channel belongs to.
+	Resource  string                `json:"resource"`  // Resource the channel belongs to.
+	Channel   string                `json:"channel"`   // Channel name.
+	Entries   []ChannelHistoryEntry `json:"entries"`   // History entries, oldest first.
+}
+
+// Validates the channel history.
+//
+// Each entry must be valid, sequence numbers must be strictly increasing, and
+// timestamps must not decrease from one entry to the next.
+func (h *ChannelHistory) Validate() error {
+	if err := ValidateChannelReference(h.Namespace, h.Resource, h.Channel); err != nil {
+		return crex.Wrap(ErrInvalidHistory, err)
+	}
+	for i := range h.Entries {
+		e := &h.Entries[i]
+		if err := e.Validate(); err != nil {
+			return fieldError(indexField("entries", i), err)
+		}
+		if i == 0 {
+			continue
+		}
+		prev := &h.Entries[i-1]
+		if e.Sequence <= prev.Sequence || e.Timestamp < prev.Timestamp {
+			return crex.Wrap(ErrInvalidHistory, fieldError(indexField("entries", i), ErrHistoryOrder))
+		}
+	}
+	return nil
+}
+
+// Returns the entry with the given sequence number.
+//
+// Returns nil if the history holds no such entry.
+func (h *ChannelHistory) Entry(sequence int64) *ChannelHistoryEntry {
+	for i := range h.Entries {
+		if h.Entries[i].Sequence == sequence {
+			return &h.Entries[i]
+		}
+	}
+	return nil
+}
+
+// Request to point a channel back to a prior history entry.
+//
+// The channel is moved to the version recorded in the entry with the given
+// sequence number. The rollback itself is appended to the history as a new
+// entry; earlier entries are left untouched. The media type is
+// [MediaTypeChannelRollback].
+type ChannelRollback struct {
+	Sequence int64  `json:"sequence"` // Sequence of the history entry to restore.
+	Reason   string `json:"reason"`   // Reason for the rollback. May be empty.
+}
+
+// Validates the rollback request.
+//
+// The sequence must be positive and the reason at most 512 bytes.
+func (r *ChannelRollback) Validate() error {
+	if r.Sequence <= 0 {
+		return crex.Wrap(ErrInvalidHistory, fieldError("sequence", ErrSequenceInvalid))
+	}
+	if err := ValidateReason(r.Reason); err != nil {
+		return crex.Wrap(ErrInvalidHistory, fieldError("reason", err))
+	}
+	return nil
+}
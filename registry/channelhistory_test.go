@@ -0,0 +1,73 @@
+package registry
+
+import (
+	"errors"
+	"testing"
+)
+
+func validHistory() ChannelHistory {
+	return ChannelHistory{
+		Namespace: "acme",
+		Resource:  "api",
+		Channel:   "stable",
+		Entries: []ChannelHistoryEntry{
+			{Sequence: 1, Version: "1.0.0", Actor: "alice", Timestamp: 100},
+			{Sequence: 2, Version: "1.1.0", Actor: "ci", Reason: "release", Timestamp: 200},
+			{Sequence: 3, Version: "1.0.0", Actor: "alice", Reason: "bad release", Rollback: ptr(int64(1)), Timestamp: 200},
+		},
+	}
+}
+
+func TestChannelHistoryValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(h *ChannelHistory)
+		want   error
+	}{
+		{"valid", func(h *ChannelHistory) {}, nil},
+		{"empty", func(h *ChannelHistory) { h.Entries = nil }, nil},
+		{"sequence zero", func(h *ChannelHistory) { h.Entries[0].Sequence = 0 }, ErrSequenceInvalid},
+		{"sequence not increasing", func(h *ChannelHistory) { h.Entries[2].Sequence = 2 }, ErrHistoryOrder},
+		{"timestamp decreasing", func(h *ChannelHistory) { h.Entries[1].Timestamp = 50 }, ErrHistoryOrder},
+		{"rollback to later entry", func(h *ChannelHistory) { h.Entries[2].Rollback = ptr(int64(3)) }, ErrRollbackOrder},
+		{"invalid actor", func(h *ChannelHistory) { h.Entries[1].Actor = "CI" }, ErrNameInvalid},
+		{"invalid version", func(h *ChannelHistory) { h.Entries[1].Version = "latest" }, ErrVersionInvalid},
+		{"invalid channel", func(h *ChannelHistory) { h.Channel = "" }, ErrNameEmpty},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := validHistory()
+			tt.modify(&h)
+			err := h.Validate()
+			if tt.want == nil {
+				if err != nil {
+					t.Fatalf("Validate() error = %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.want) || !errors.Is(err, ErrInvalidHistory) {
+				t.Fatalf("Validate() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestChannelHistoryEntry(t *testing.T) {
+	h := validHistory()
+	if e := h.Entry(2); e == nil || e.Version != "1.1.0" {
+		t.Errorf("Entry(2) = %+v, want version 1.1.0", e)
+	}
+	if e := h.Entry(4); e != nil {
+		t.Errorf("Entry(4) = %+v, want nil", e)
+	}
+}
+
+func TestChannelRollbackValidate(t *testing.T) {
+	if err := (&ChannelRollback{Sequence: 1}).Validate(); err != nil {
+		t.Errorf("Validate() error = %v", err)
+	}
+	if err := (&ChannelRollback{}).Validate(); !errors.Is(err, ErrSequenceInvalid) {
+		t.Errorf("Validate() error = %v, want %v", err, ErrSequenceInvalid)
+	}
+}
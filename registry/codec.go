@@ -2,6 +2,8 @@ package registry
 
 import (
 	"encoding/json"
+	"sort"
+	"sync"
 
 	"github.com/cruciblehq/crex"
 )
@@ -42,3 +44,195 @@ func Decode[T any](data []byte) (*T, error) {
 
 	return &v, nil
 }
+
+// Wire format of a registry type under one media type.
+//
+// Encode receives a pointer to the current Go type and produces the bytes of
+// the media type. Decode reverses it, returning a pointer to the current Go
+// type. Codecs of older versions convert between the current type and the
+// older shape, so callers only ever handle the current type. Use
+// [IdentityCodec] and [ConvertCodec] to build codecs.
+type Codec struct {
+	Encode func(v any) ([]byte, error)    // Encodes a value of the current type.
+	Decode func(data []byte) (any, error) // Decodes into a value of the current type.
+}
+
+// Codec for a type sent as is, using [Encode] and [Decode].
+func IdentityCodec[T any]() Codec {
+	return Codec{
+		Encode: func(v any) ([]byte, error) {
+			if _, ok := v.(*T); !ok {
+				return nil, crex.Wrap(ErrEncodeFailed, ErrCodecType)
+			}
+			return Encode(v)
+		},
+		Decode: func(data []byte) (any, error) {
+			return Decode[T](data)
+		},
+	}
+}
+
+// Codec for a type T sent in an older wire shape W.
+//
+// Down converts a value of the current type into the older shape before
+// encoding, dropping what the shape cannot express. Up converts a decoded
+// value back, leaving the fields the shape lacks at their zero values. Both
+// shapes are validated as usual.
+func ConvertCodec[T, W any](down func(*T) *W, up func(*W) *T) Codec {
+	return Codec{
+		Encode: func(v any) ([]byte, error) {
+			t, ok := v.(*T)
+			if !ok {
+				return nil, crex.Wrap(ErrEncodeFailed, ErrCodecType)
+			}
+			if val, ok := v.(interface{ Validate() error }); ok {
+				if err := val.Validate(); err != nil {
+					return nil, crex.Wrap(ErrEncodeFailed, err)
+				}
+			}
+			return Encode(down(t))
+		},
+		Decode: func(data []byte) (any, error) {
+			w, err := Decode[W](data)
+			if err != nil {
+				return nil, err
+			}
+			return up(w), nil
+		},
+	}
+}
+
+var (
+	codecsMu sync.RWMutex
+	codecs   = map[MediaType]Codec{}
+)
+
+// Registers the codec of a media type.
+//
+// Every registry wire type is registered under its media types by this
+// package. Registering a media type twice panics.
+func RegisterCodec(mediaType MediaType, c Codec) {
+	codecsMu.Lock()
+	defer codecsMu.Unlock()
+	if _, ok := codecs[mediaType]; ok {
+		panic("registry: codec registered twice for " + string(mediaType))
+	}
+	codecs[mediaType] = c
+}
+
+// Codec registered for a media type.
+//
+// Parameters of the media type are ignored.
+func codecFor(mediaType MediaType) (Codec, bool) {
+	if p, err := ParseMediaType(string(mediaType)); err == nil {
+		mediaType = p.MediaType()
+	}
+	codecsMu.RLock()
+	defer codecsMu.RUnlock()
+	c, ok := codecs[mediaType]
+	return c, ok
+}
+
+// Registered versions of a Crucible media type name, newest first.
+//
+// The result is suitable as the offers of [Negotiate]. Returns nil if no
+// media type with the name is registered.
+func MediaTypeVersions(name string) []MediaType {
+	codecsMu.RLock()
+	defer codecsMu.RUnlock()
+
+	var versions []MediaType
+	for mt := range codecs {
+		if mt.Name() == name {
+			versions = append(versions, mt)
+		}
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		return versions[i].Version() > versions[j].Version()
+	})
+	return versions
+}
+
+// Encodes a registry type in the wire format of a media type.
+//
+// Typically used with the result of [Negotiate]. Returns [ErrEncodeFailed]
+// on failure, wrapping an [*Error] with [ErrorCodeNotAcceptable] if no codec
+// is registered for the media type.
+func EncodeAs(mediaType MediaType, v any) ([]byte, error) {
+	c, ok := codecFor(mediaType)
+	if !ok {
+		return nil, crex.Wrap(ErrEncodeFailed, &Error{
+			Code:    ErrorCodeNotAcceptable,
+			Message: "no codec for media type " + string(mediaType),
+		})
+	}
+	return c.Encode(v)
+}
+
+// Decodes a registry type from the wire format of a media type.
+//
+// Typically used with the Content-Type of a request. Returns
+// [ErrDecodeFailed] on failure, wrapping an [*Error] with
+// [ErrorCodeUnsupportedMediaType] if no codec is registered for the media
+// type, and [ErrCodecType] if the codec does not produce a T.
+func DecodeAs[T any](mediaType MediaType, data []byte) (*T, error) {
+	c, ok := codecFor(mediaType)
+	if !ok {
+		return nil, crex.Wrap(ErrDecodeFailed, &Error{
+			Code:    ErrorCodeUnsupportedMediaType,
+			Message: "no codec for media type " + string(mediaType),
+		})
+	}
+	v, err := c.Decode(data)
+	if err != nil {
+		return nil, err
+	}
+	t, ok := v.(*T)
+	if !ok {
+		return nil, crex.Wrap(ErrDecodeFailed, ErrCodecType)
+	}
+	return t, nil
+}
+
+func init() {
+	RegisterCodec(MediaTypeError, IdentityCodec[Error]())
+	RegisterCodec(MediaTypeNamespaceInfo, IdentityCodec[NamespaceInfo]())
+	RegisterCodec(MediaTypeNamespace, ConvertCodec(NamespaceToV0, NamespaceFromV0))
+	RegisterCodec(MediaTypeNamespaceV1, IdentityCodec[Namespace]())
+	RegisterCodec(MediaTypeNamespaceList, IdentityCodec[NamespaceList]())
+	RegisterCodec(MediaTypeResourceInfo, ConvertCodec(ResourceInfoToV0, ResourceInfoFromV0))
+	RegisterCodec(MediaTypeResourceInfoV1, IdentityCodec[ResourceInfo]())
+	RegisterCodec(MediaTypeResource, ConvertCodec(ResourceToV0, ResourceFromV0))
+	RegisterCodec(MediaTypeResourceV1, IdentityCodec[Resource]())
+	RegisterCodec(MediaTypeResourceList, ConvertCodec(ResourceListToV0, ResourceListFromV0))
+	RegisterCodec(MediaTypeResourceListV1, IdentityCodec[ResourceList]())
+	RegisterCodec(MediaTypeVersionInfo, ConvertCodec(VersionInfoToV0, VersionInfoFromV0))
+	RegisterCodec(MediaTypeVersionInfoV1, IdentityCodec[VersionInfo]())
+	RegisterCodec(MediaTypeVersion, ConvertCodec(VersionToV0, VersionFromV0))
+	RegisterCodec(MediaTypeVersionV1, IdentityCodec[Version]())
+	RegisterCodec(MediaTypeVersionList, ConvertCodec(VersionListToV0, VersionListFromV0))
+	RegisterCodec(MediaTypeVersionListV1, IdentityCodec[VersionList]())
+	RegisterCodec(MediaTypeChannelInfo, IdentityCodec[ChannelInfo]())
+	RegisterCodec(MediaTypeChannel, ConvertCodec(ChannelToV0, ChannelFromV0))
+	RegisterCodec(MediaTypeChannelV1, IdentityCodec[Channel]())
+	RegisterCodec(MediaTypeChannelList, IdentityCodec[ChannelList]())
+	RegisterCodec(MediaTypeMemberInfo, IdentityCodec[MemberInfo]())
+	RegisterCodec(MediaTypeMember, IdentityCodec[Member]())
+	RegisterCodec(MediaTypeMemberList, IdentityCodec[MemberList]())
+	RegisterCodec(MediaTypeTokenInfo, IdentityCodec[TokenInfo]())
+	RegisterCodec(MediaTypeToken, IdentityCodec[Token]())
+	RegisterCodec(MediaTypeTokenList, IdentityCodec[TokenList]())
+	RegisterCodec(MediaTypeDependentList, IdentityCodec[DependentList]())
+	RegisterCodec(MediaTypeUploadInfo, IdentityCodec[UploadInfo]())
+	RegisterCodec(MediaTypeUpload, IdentityCodec[Upload]())
+	RegisterCodec(MediaTypeRename, IdentityCodec[Rename]())
+	RegisterCodec(MediaTypeTransfer, IdentityCodec[Transfer]())
+	RegisterCodec(MediaTypeRedirect, IdentityCodec[Redirect]())
+	RegisterCodec(MediaTypeRedirectList, IdentityCodec[RedirectList]())
+	RegisterCodec(MediaTypeChannelHistory, IdentityCodec[ChannelHistory]())
+	RegisterCodec(MediaTypeChannelRollback, IdentityCodec[ChannelRollback]())
+	RegisterCodec(MediaTypeRetentionPolicy, IdentityCodec[RetentionPolicy]())
+	RegisterCodec(MediaTypeRetentionPolicyList, IdentityCodec[RetentionPolicyList]())
+	RegisterCodec(MediaTypeGCPlan, IdentityCodec[GCPlan]())
+	RegisterCodec(MediaTypeGCResult, IdentityCodec[GCResult]())
+}
@@ -0,0 +1,199 @@
+package registry
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/cruciblehq/crex"
+	"github.com/cruciblehq/spec/manifest"
+	"github.com/cruciblehq/spec/reference"
+)
+
+// Declared dependency of a version on another resource.
+//
+// Wraps a parsed [reference.Reference]. On the wire, a dependency is encoded
+// as the reference string produced by [reference.Reference.String], which
+// always starts with the resource type, for example
+// "runtime crucible/runtime ^1.0.0". Recorded dependencies are qualified: the
+// namespace is always present. An empty registry means the dependency lives in
+// the same registry as the dependent version.
+type Dependency struct {
+	Reference *reference.Reference // Parsed reference to the dependency.
+}
+
+// Validates the dependency.
+//
+// The reference must be set and must include a namespace.
+func (d *Dependency) Validate() error {
+	if d.Reference == nil {
+		return crex.Wrap(ErrInvalidDependency, ErrDependencyEmpty)
+	}
+	if d.Reference.Namespace() == "" {
+		return crex.Wrap(ErrInvalidDependency, ErrNamespaceMissing)
+	}
+	return nil
+}
+
+// Whether the dependency refers to the given resource or version.
+//
+// The namespace and resource name must match, after resolving the
+// dependency's path through redirects (see [ResolveRedirects]), so that a
+// dependency recorded before its resource or namespace was renamed or
+// transferred still matches the resource at its current path. When version
+// is empty, any dependency on the resource matches. Otherwise a
+// version-based dependency matches when its constraint is satisfied by
+// version. Channel-based dependencies never match a specific version here,
+// since resolving the channel requires registry state; stores resolve them
+// separately. Returns [ErrDependencyEmpty] if the reference is not set.
+func (d *Dependency) Matches(namespace, resource, version string, redirects []Redirect) (bool, error) {
+	ref := d.Reference
+	if ref == nil {
+		return false, crex.Wrap(ErrInvalidDependency, ErrDependencyEmpty)
+	}
+	ns, res := ResolveRedirects(redirects, ref.Namespace(), ref.Name())
+	if ns != namespace || res != resource {
+		return false, nil
+	}
+	if version == "" {
+		return true, nil
+	}
+	if !ref.IsVersionBased() {
+		return false, nil
+	}
+	return ref.Version().Matches(version)
+}
+
+// Implements [json.Marshaler].
+//
+// Returns [ErrDependencyEmpty] if the reference is not set, since an empty
+// string could not be decoded back.
+func (d Dependency) MarshalJSON() ([]byte, error) {
+	if d.Reference == nil {
+		return nil, crex.Wrap(ErrInvalidDependency, ErrDependencyEmpty)
+	}
+	return json.Marshal(d.Reference.String())
+}
+
+// Implements [json.Unmarshaler].
+//
+// The leading type token of the reference string is used as the context type
+// when parsing.
+func (d *Dependency) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		return crex.Wrap(ErrInvalidDependency, ErrDependencyEmpty)
+	}
+
+	ref, err := reference.Parse(s, fields[0])
+	if err != nil {
+		return crex.Wrap(ErrInvalidDependency, err)
+	}
+
+	d.Reference = ref
+	return nil
+}
+
+// Derives the dependencies declared by a manifest.
+//
+// Recipe-based resources (runtimes, services, and machines) depend on the
+// runtimes referenced by their stages' base image sources (see
+// [manifest.Stage.ParseFrom]). File and OCI sources are not registry
+// resources and are ignored. References without a namespace are qualified
+// with defaultNamespace. Duplicate references are recorded once, in stage
+// order. Other resource types have no dependencies.
+func DependenciesFromManifest(m *manifest.Manifest, defaultNamespace string) ([]Dependency, error) {
+	var recipe *manifest.Recipe
+	switch cfg := m.Config.(type) {
+	case *manifest.Runtime:
+		recipe = &cfg.Recipe
+	case *manifest.Service:
+		recipe = &cfg.Recipe
+	case *manifest.Machine:
+		recipe = &cfg.Recipe
+	default:
+		return nil, nil
+	}
+
+	var deps []Dependency
+	seen := make(map[string]bool)
+	for i := range recipe.Stages {
+		src, err := recipe.Stages[i].ParseFrom()
+		if err != nil {
+			return nil, crex.Wrap(ErrInvalidDependency, err)
+		}
+		if src.Type != manifest.SourceRef {
+			continue
+		}
+
+		ref, err := reference.Parse(src.Value, string(manifest.TypeRuntime))
+		if err != nil {
+			return nil, crex.Wrap(ErrInvalidDependency, err)
+		}
+		ref = ref.WithDefaults("", defaultNamespace)
+
+		key := ref.String()
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		dep := Dependency{Reference: ref}
+		if err := dep.Validate(); err != nil {
+			return nil, err
+		}
+		deps = append(deps, dep)
+	}
+	return deps, nil
+}
+
+// Version that declares a dependency on another resource.
+//
+// Returned by reverse-dependency queries. Reference is the dependency as
+// declared by the dependent version, so callers can show which constraint or
+// channel would be affected by a change.
+type Dependent struct {
+	Namespace string     `json:"namespace"` // Namespace of the dependent resource.
+	Resource  string     `json:"resource"`  // Name of the dependent resource.
+	Type      string     `json:"type"`      // Type of the dependent resource.
+	Version   string     `json:"version"`   // Dependent version string.
+	Reference Dependency `json:"reference"` // Declared dependency that matched.
+}
+
+// Validates the dependent.
+func (d *Dependent) Validate() error {
+	if err := ValidateName(d.Namespace); err != nil {
+		return crex.Wrap(ErrInvalidDependency, fieldError("namespace", err))
+	}
+	if err := ValidateName(d.Resource); err != nil {
+		return crex.Wrap(ErrInvalidDependency, fieldError("resource", err))
+	}
+	if err := ValidateResourceType(d.Type); err != nil {
+		return crex.Wrap(ErrInvalidDependency, fieldError("type", err))
+	}
+	if err := ValidateVersionString(d.Version); err != nil {
+		return crex.Wrap(ErrInvalidDependency, fieldError("version", err))
+	}
+	return d.Reference.Validate()
+}
+
+// Collection of versions depending on a resource or version.
+//
+// The media type is [MediaTypeDependentList].
+type DependentList struct {
+	Dependents []Dependent `json:"dependents"` // List of dependents.
+}
+
+// Validates the dependent list.
+func (l *DependentList) Validate() error {
+	for i := range l.Dependents {
+		if err := l.Dependents[i].Validate(); err != nil {
+			return crex.Wrap(ErrInvalidDependency, fieldError(indexField("dependents", i), err))
+		}
+	}
+	return nil
+}
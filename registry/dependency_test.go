@@ -0,0 +1,169 @@
+package registry
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/cruciblehq/spec/manifest"
+	"github.com/cruciblehq/spec/reference"
+)
+
+func TestDependenciesFromManifest(t *testing.T) {
+	m := &manifest.Manifest{
+		Resource: manifest.Resource{Type: manifest.TypeService, Name: "cruciblehq/hub", Version: "1.0.0"},
+		Config: &manifest.Service{
+			Recipe: manifest.Recipe{Stages: []manifest.Stage{
+				{Name: "builder", Transient: true, From: "crucible/go ^1.22.0"},
+				{Name: "tools", Transient: true, From: "oci alpine:3.21"},
+				{Name: "local", Transient: true, From: "file ./base.tar"},
+				{Name: "again", Transient: true, From: "ref crucible/go ^1.22.0"},
+				{From: "base :stable"},
+			}},
+			Entrypoint: []string{"/hub"},
+		},
+	}
+
+	deps, err := DependenciesFromManifest(m, "official")
+	if err != nil {
+		t.Fatalf("DependenciesFromManifest failed: %v", err)
+	}
+	if len(deps) != 2 {
+		t.Fatalf("got %d dependencies, want 2: %v", len(deps), deps)
+	}
+
+	if got := deps[0].Reference.Path(); got != "crucible/go" {
+		t.Errorf("deps[0] path = %q, want %q", got, "crucible/go")
+	}
+	if got := deps[1].Reference.Path(); got != "official/base" {
+		t.Errorf("deps[1] path = %q, want %q", got, "official/base")
+	}
+	if !deps[1].Reference.IsChannelBased() {
+		t.Error("deps[1] should be channel-based")
+	}
+	for _, d := range deps {
+		if d.Reference.Type() != string(manifest.TypeRuntime) {
+			t.Errorf("dependency type = %q, want runtime", d.Reference.Type())
+		}
+	}
+}
+
+func TestDependenciesFromManifestWidget(t *testing.T) {
+	m := &manifest.Manifest{
+		Resource: manifest.Resource{Type: manifest.TypeWidget, Name: "cruciblehq/clock", Version: "1.0.0"},
+		Config:   &manifest.Widget{Main: "index.js"},
+	}
+	deps, err := DependenciesFromManifest(m, "official")
+	if err != nil || deps != nil {
+		t.Fatalf("DependenciesFromManifest = %v, %v; want nil, nil", deps, err)
+	}
+}
+
+func TestDependencyJSONRoundTrip(t *testing.T) {
+	tests := []string{
+		"runtime crucible/go ^1.22.0",
+		"runtime crucible/base :stable",
+		"runtime hub.crucible.dev/crucible/go >=1.0.0 <2.0.0",
+	}
+	for _, s := range tests {
+		t.Run(s, func(t *testing.T) {
+			dep := Dependency{Reference: reference.MustParse(s, "runtime")}
+
+			data, err := json.Marshal(dep)
+			if err != nil {
+				t.Fatalf("Marshal failed: %v", err)
+			}
+
+			var got Dependency
+			if err := json.Unmarshal(data, &got); err != nil {
+				t.Fatalf("Unmarshal(%s) failed: %v", data, err)
+			}
+			if got.Reference.String() != dep.Reference.String() {
+				t.Errorf("round trip = %q, want %q", got.Reference.String(), dep.Reference.String())
+			}
+		})
+	}
+}
+
+func TestDependencyValidate(t *testing.T) {
+	if err := (&Dependency{}).Validate(); !errors.Is(err, ErrDependencyEmpty) {
+		t.Errorf("expected ErrDependencyEmpty, got %v", err)
+	}
+	dep := Dependency{Reference: reference.MustParse("go ^1.22.0", "runtime")}
+	if err := dep.Validate(); !errors.Is(err, ErrNamespaceMissing) {
+		t.Errorf("expected ErrNamespaceMissing, got %v", err)
+	}
+}
+
+func TestDependencyMatches(t *testing.T) {
+	versioned := Dependency{Reference: reference.MustParse("crucible/go ^1.22.0", "runtime")}
+	channel := Dependency{Reference: reference.MustParse("crucible/go :stable", "runtime")}
+
+	tests := []struct {
+		name     string
+		dep      Dependency
+		resource string
+		version  string
+		want     bool
+	}{
+		{"resource match", versioned, "go", "", true},
+		{"other resource", versioned, "rust", "", false},
+		{"version in range", versioned, "go", "1.23.4", true},
+		{"version out of range", versioned, "go", "2.0.0", false},
+		{"channel resource", channel, "go", "", true},
+		{"channel version", channel, "go", "1.23.4", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.dep.Matches("crucible", tt.resource, tt.version, nil)
+			if err != nil {
+				t.Fatalf("Matches failed: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Matches = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDependencyMatchesRedirects(t *testing.T) {
+	dep := Dependency{Reference: reference.MustParse("crucible/go ^1.22.0", "runtime")}
+	redirects := []Redirect{
+		{Namespace: "crucible", TargetNamespace: "cruciblehq", CreatedAt: 100},
+		{Namespace: "cruciblehq", Resource: "go", TargetNamespace: "langs", TargetResource: "golang", CreatedAt: 200},
+	}
+
+	tests := []struct {
+		name      string
+		namespace string
+		resource  string
+		redirects []Redirect
+		want      bool
+	}{
+		{"old path without redirects", "crucible", "go", nil, true},
+		{"old path", "crucible", "go", redirects, false},
+		{"renamed namespace", "cruciblehq", "go", redirects[:1], true},
+		{"transferred after rename", "langs", "golang", redirects, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := dep.Matches(tt.namespace, tt.resource, "1.23.4", tt.redirects)
+			if err != nil {
+				t.Fatalf("Matches failed: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Matches = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDependencyEmptyReference(t *testing.T) {
+	var d Dependency
+	if _, err := d.Matches("crucible", "go", "", nil); !errors.Is(err, ErrDependencyEmpty) {
+		t.Errorf("Matches error = %v, want %v", err, ErrDependencyEmpty)
+	}
+	if _, err := json.Marshal(d); !errors.Is(err, ErrDependencyEmpty) {
+		t.Errorf("Marshal error = %v, want %v", err, ErrDependencyEmpty)
+	}
+}
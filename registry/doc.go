@@ -16,15 +16,73 @@
 // returned inside lists, [Namespace] is the full representation with embedded
 // resource summaries, and [NamespaceList] wraps a slice of summaries. The
 // [Error] type carries machine-readable [ErrorCode] values alongside
-// human-readable messages for API error responses.
+// human-readable messages for API error responses. Each code maps to an HTTP
+// status ([StatusFor], [CodeFor]) and to a sentinel error, so clients can test
+// responses with [errors.Is] (e.g., against [ErrNotFound]). [AsError] turns the
+// package's validation errors into [ErrorCodeBadRequest] responses naming the
+// offending field.
+//
+// Resources and versions carry descriptive [Metadata]: validated key/value
+// labels, a readme, an SPDX license expression, source and home page URLs, and
+// search keywords. Version metadata is derived from the manifest inside the
+// uploaded archive (see [MetadataFromArchive]); summary types omit the readme.
+// The same manifest yields the version's [Dependency] list: the runtimes its
+// build stages are based on (see [DependenciesFromManifest]).
+// [Registry.ListDependents] answers the reverse question, listing the versions
+// that would be affected by a change to a resource or version.
 //
 // Every wire type has a corresponding [MediaType] constant following the
-// pattern application/vnd.crucible.{name}.v0, used in HTTP Content-Type and
-// Accept headers for format negotiation.
+// pattern application/vnd.crucible.{name}.v{version}, used in HTTP
+// Content-Type and Accept headers for format negotiation. When a type's shape
+// changes, a new version is added and the old one keeps being served:
+// [Negotiate] picks a version from the client's Accept header, and
+// [EncodeAs] and [DecodeAs] convert through the [Codec] registered for it.
+// [Version] is sent as [MediaTypeVersionV1] to current clients and as the
+// older [VersionV0] shape to clients that only accept [MediaTypeVersion];
+// the other types that gained fields follow the same rule (see [MediaType]).
 //
 // The [Registry] interface defines the full set of CRUD operations across all
 // entity types, including archive upload and download. Both the HTTP client in
-// crux and the SQL store in hub implement this interface.
+// crux and the SQL store in hub implement this interface. Stores may keep
+// archive bytes apart from metadata: [NewRegistry] combines a [MetadataStore]
+// with a content-addressed [BlobStore] ([FSBlobStore], [MemoryBlobStore]), so
+// identical archives are stored once and storage backends can be swapped
+// without touching metadata code.
+//
+// Large archives are transferred through resumable upload sessions: the client
+// declares the archive's size and digest with [Registry.StartUpload], sends
+// chunks at the committed offset, resumes from [Registry.ReadUpload] after a
+// failure, and finalizes with [Registry.CompleteUpload], which verifies the
+// digest. The protocol is specified on [Upload]; registry quotas are given by
+// [UploadLimits].
+//
+// Access to a namespace is granted through membership. Each [Member] holds a
+// [Role] (owner, publisher, or reader) that grants a set of permissions on the
+// namespace and everything it contains. API tokens ([Token]) act with a role of
+// their own, may be restricted to specific resources, and may expire. Every
+// [Registry] method has a corresponding [Operation] whose required [Permission]
+// is given by [Operation.Permission]. An [Authorizer] decides whether the
+// caller of an operation holds that permission, rejecting it with
+// [ErrorCodeUnauthorized] or [ErrorCodeForbidden] otherwise.
+//
+// Namespaces and resources can be renamed, and resources transferred between
+// namespaces, without touching their versions or archives. Each move leaves a
+// [Redirect] that keeps the old path reserved; operations on it fail with
+// [ErrorCodeMoved], and [Moved] extracts the new location so that clients can
+// follow it.
+//
+// Every channel keeps an append-only [ChannelHistory] recording each move with
+// its version, actor, reason, and timestamp. [Registry.RollbackChannel] points
+// a channel back to a prior entry, appending the rollback to the history.
+//
+// Unpublished versions are pruned according to declarative [RetentionPolicy]
+// values, set per namespace or per resource. [PlanGC] computes a reviewable
+// [GCPlan] listing the versions to delete and those kept because a channel
+// points at them; published versions are never selected. [ApplyGC] carries
+// out a plan through the [Registry] interface, re-checking each version so that
+// one published or pointed to in the meantime is skipped. For registries that
+// implement [BlobSweeper], the plan also lists the stored blobs no version
+// refers to, and applying it deletes those still unreferenced.
 //
 // All types implement a Validate method that checks field constraints: name
 // format, version string format, timestamp ordering, resource type, archive
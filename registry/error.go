@@ -1,14 +1,51 @@
 package registry
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Well-known keys of [Error.Details].
+const (
+	DetailEntity    = "entity"    // Kind of entity the error concerns (e.g., "version").
+	DetailNamespace = "namespace" // Namespace of the entity.
+	DetailResource  = "resource"  // Resource of the entity.
+	DetailVersion   = "version"   // Version of the entity.
+	DetailChannel   = "channel"   // Channel of the entity.
+	DetailField     = "field"     // Path to the offending request field (e.g., "metadata.labels").
+	DetailOffset    = "offset"    // Committed offset of an upload session.
+	DetailLimit     = "limit"     // Limit that a request exceeded.
+	DetailLocation  = "location"  // Current path of a moved entity (e.g., "acme/clock").
+)
+
+// Set of known detail keys for validation.
+var validDetailKeys = map[string]bool{
+	DetailEntity:    true,
+	DetailNamespace: true,
+	DetailResource:  true,
+	DetailVersion:   true,
+	DetailChannel:   true,
+	DetailField:     true,
+	DetailOffset:    true,
+	DetailLimit:     true,
+	DetailLocation:  true,
+}
 
 // Error response from the registry API.
 //
 // Provides both machine-readable error classification through the Code field
-// and context through the Message field. The media type is [MediaTypeError].
+// and context through the Message field. Details optionally identify the
+// entity or request field the error concerns, keyed by the Detail constants.
+// An Error matches the sentinel of its code with [errors.Is] (for example,
+// [ErrNotFound] for [ErrorCodeNotFound]), so callers can branch on error
+// kinds without inspecting codes or messages. The media type is
+// [MediaTypeError].
 type Error struct {
-	Code    ErrorCode `json:"code"`    // Error code (see [ErrorCode]).
-	Message string    `json:"message"` // Error description.
+	Code    ErrorCode         `json:"code"`              // Error code (see [ErrorCode]).
+	Message string            `json:"message"`           // Error description.
+	Details map[string]string `json:"details,omitempty"` // Structured context (see [DetailEntity] and related keys).
 }
 
 // Implements the error interface.
@@ -16,9 +53,29 @@ func (e *Error) Error() string {
 	return fmt.Sprintf("%s: %s", e.Code, e.Message)
 }
 
+// Whether the error matches a sentinel.
+//
+// Reports true for the sentinel of the error's code, and for the sentinel of
+// the general code it refines, if any. An error with code
+// [ErrorCodeVersionExists] therefore matches both [ErrVersionExists] and
+// [ErrConflict], while one with [ErrorCodeDigestMismatch] matches only
+// [ErrDigestMismatch].
+func (e *Error) Is(target error) bool {
+	sentinel := sentinelFor(e.Code)
+	if sentinel == nil {
+		return false
+	}
+	if target == sentinel {
+		return true
+	}
+	parent, ok := parentCodes[e.Code]
+	return ok && target == sentinelFor(parent)
+}
+
 // Validates the error response.
 //
-// The code must be a known [ErrorCode] and the message must not be empty.
+// The code must be a known [ErrorCode], the message must not be empty, and
+// every detail key must be one of the Detail constants.
 func (e *Error) Validate() error {
 	if !isValidErrorCode(e.Code) {
 		return ErrErrorCodeInvalid
@@ -26,5 +83,154 @@ func (e *Error) Validate() error {
 	if e.Message == "" {
 		return ErrErrorMessageEmpty
 	}
+	for key := range e.Details {
+		if !validDetailKeys[key] {
+			return ErrDetailKeyInvalid
+		}
+	}
 	return nil
 }
+
+// Validation error attributed to a request field.
+//
+// Validate methods wrap failures in a FieldError naming the offending field by
+// its JSON path, relative to the validated value (e.g., "versions[2].digest").
+// The underlying validation sentinel remains reachable through [errors.Is].
+type FieldError struct {
+	Field string // JSON path of the field.
+	Err   error  // Underlying validation error.
+}
+
+// Implements the error interface.
+func (e *FieldError) Error() string {
+	return e.Field + ": " + e.Err.Error()
+}
+
+// Returns the underlying validation error.
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// Attributes a validation error to a field.
+//
+// If err already carries a [FieldError] from a nested value, the field is
+// prefixed to its path instead of adding another layer, so the innermost
+// field error holds the full path.
+func fieldError(field string, err error) error {
+	var fe *FieldError
+	if errors.As(err, &fe) {
+		fe.Field = joinField(field, fe.Field)
+		return err
+	}
+	return &FieldError{Field: field, Err: err}
+}
+
+// Path segment for an element of a list field.
+func indexField(field string, i int) string {
+	return field + "[" + strconv.Itoa(i) + "]"
+}
+
+// Joins two segments of a field path.
+func joinField(parent, child string) string {
+	if strings.HasPrefix(child, "[") {
+		return parent + child
+	}
+	return parent + "." + child
+}
+
+// Validation errors converted to [ErrorCodeBadRequest] by [AsError].
+var validationErrors = []error{
+	ErrNameEmpty,
+	ErrNameTooLong,
+	ErrNameInvalid,
+	ErrVersionInvalid,
+	ErrTimestampInvalid,
+	ErrTimestampOrder,
+	ErrPublishedOrder,
+	ErrTypeEmpty,
+	ErrArchiveEmpty,
+	ErrSizeInvalid,
+	ErrDigestInvalid,
+	ErrArchiveIncomplete,
+	ErrCountNegative,
+	ErrRoleInvalid,
+	ErrExpiryOrder,
+	ErrResourceDuplicate,
+	ErrTokenSecretEmpty,
+	ErrTooManyLabels,
+	ErrLabelKeyInvalid,
+	ErrLabelValueTooLong,
+	ErrReadmeTooLong,
+	ErrLicenseInvalid,
+	ErrURLInvalid,
+	ErrTooManyKeywords,
+	ErrKeywordInvalid,
+	ErrKeywordDuplicate,
+	ErrDependencyEmpty,
+	ErrNamespaceMissing,
+	ErrRetentionEmpty,
+	ErrMaxAgeInvalid,
+	ErrPolicyDuplicate,
+	ErrGCReasonInvalid,
+	ErrReasonTooLong,
+	ErrSequenceInvalid,
+	ErrHistoryOrder,
+	ErrRollbackOrder,
+	ErrDigestAlgorithm,
+	ErrRangeInvalid,
+	ErrUploadIDInvalid,
+	ErrOffsetInvalid,
+	ErrRedirectKind,
+	ErrRedirectLoop,
+	ErrMediaTypeInvalid,
+	ErrInvalidNamespace,
+	ErrInvalidResource,
+	ErrInvalidVersion,
+	ErrInvalidChannel,
+	ErrInvalidMember,
+	ErrInvalidToken,
+	ErrInvalidMetadata,
+	ErrInvalidDependency,
+	ErrInvalidPolicy,
+	ErrInvalidGCPlan,
+	ErrInvalidHistory,
+	ErrInvalidUpload,
+	ErrInvalidRedirect,
+	ErrManifestMissing,
+	ErrManifestInvalid,
+	ErrManifestTarget,
+	ErrDecodeFailed,
+}
+
+// Converts an error into an API error response.
+//
+// An [*Error] anywhere in the chain is returned as is. Validation errors from
+// this package, including decoding failures, become [ErrorCodeBadRequest]
+// with the error text as the message and, when the error carries a
+// [FieldError], the field path under [DetailField]. Any other error becomes
+// [ErrorCodeInternalError] with a generic message, so that internal details
+// are not exposed to clients. Returns nil for a nil error.
+func AsError(err error) *Error {
+	if err == nil {
+		return nil
+	}
+
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		return apiErr
+	}
+
+	for _, v := range validationErrors {
+		if !errors.Is(err, v) {
+			continue
+		}
+		e := &Error{Code: ErrorCodeBadRequest, Message: err.Error()}
+		var fe *FieldError
+		if errors.As(err, &fe) {
+			e.Details = map[string]string{DetailField: fe.Field}
+		}
+		return e
+	}
+
+	return &Error{Code: ErrorCodeInternalError, Message: "internal error"}
+}
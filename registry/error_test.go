@@ -0,0 +1,135 @@
+package registry
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestErrorIs(t *testing.T) {
+	err := fmt.Errorf("create version: %w", &Error{Code: ErrorCodeVersionExists, Message: "version 1.0.0 exists"})
+
+	if !errors.Is(err, ErrVersionExists) {
+		t.Error("expected match with ErrVersionExists")
+	}
+	if !errors.Is(err, ErrConflict) {
+		t.Error("expected match with ErrConflict")
+	}
+	if errors.Is(err, ErrNotFound) {
+		t.Error("unexpected match with ErrNotFound")
+	}
+	if errors.Is(&Error{Code: "bogus", Message: "x"}, ErrInternal) {
+		t.Error("unknown code must not match any sentinel")
+	}
+}
+
+func TestErrorIsSameStatus(t *testing.T) {
+	mismatch := &Error{Code: ErrorCodeDigestMismatch, Message: "digest mismatch"}
+	if !errors.Is(mismatch, ErrDigestMismatch) {
+		t.Error("expected match with ErrDigestMismatch")
+	}
+	if errors.Is(mismatch, ErrBadRequest) {
+		t.Error("unexpected match with ErrBadRequest")
+	}
+	if errors.Is(&Error{Code: ErrorCodeOffsetMismatch, Message: "x"}, ErrConflict) {
+		t.Error("unexpected match with ErrConflict")
+	}
+}
+
+func TestErrorCodesComplete(t *testing.T) {
+	for code, c := range errorCodes {
+		if c.sentinel == nil {
+			t.Errorf("%s has no sentinel", code)
+		}
+		if !errors.Is(&Error{Code: code, Message: "x"}, c.sentinel) {
+			t.Errorf("%s does not match its sentinel", code)
+		}
+	}
+	for status, code := range statusCodes {
+		if got := StatusFor(code); got != status {
+			t.Errorf("StatusFor(%s) = %d, want %d", code, got, status)
+		}
+	}
+}
+
+func TestStatusMapping(t *testing.T) {
+	tests := []struct {
+		code   ErrorCode
+		status int
+	}{
+		{ErrorCodeBadRequest, http.StatusBadRequest},
+		{ErrorCodeNotFound, http.StatusNotFound},
+		{ErrorCodeChannelExists, http.StatusConflict},
+		{ErrorCodeUnauthorized, http.StatusUnauthorized},
+		{ErrorCode("bogus"), http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		if got := StatusFor(tt.code); got != tt.status {
+			t.Errorf("StatusFor(%s) = %d, want %d", tt.code, got, tt.status)
+		}
+	}
+
+	if got := CodeFor(http.StatusTeapot); got != ErrorCodeBadRequest {
+		t.Errorf("CodeFor(418) = %s, want %s", got, ErrorCodeBadRequest)
+	}
+	if got := CodeFor(http.StatusBadGateway); got != ErrorCodeInternalError {
+		t.Errorf("CodeFor(502) = %s, want %s", got, ErrorCodeInternalError)
+	}
+}
+
+func TestErrorValidateDetails(t *testing.T) {
+	e := &Error{Code: ErrorCodeNotFound, Message: "x", Details: map[string]string{DetailEntity: "version"}}
+	if err := e.Validate(); err != nil {
+		t.Errorf("Validate() error = %v", err)
+	}
+	e.Details["color"] = "red"
+	if err := e.Validate(); !errors.Is(err, ErrDetailKeyInvalid) {
+		t.Errorf("Validate() error = %v, want %v", err, ErrDetailKeyInvalid)
+	}
+}
+
+func TestAsError(t *testing.T) {
+	resource := &Resource{
+		Namespace: "acme",
+		Name:      "api",
+		Type:      "service",
+		CreatedAt: 1,
+		UpdatedAt: 1,
+		Versions: []VersionSummary{
+			{String: "1.0.0", CreatedAt: 1, UpdatedAt: 1},
+			{String: "1.1.0", Metadata: Metadata{Keywords: []string{"Bad"}}, CreatedAt: 1, UpdatedAt: 1},
+		},
+	}
+
+	tests := []struct {
+		name  string
+		err   error
+		code  ErrorCode
+		field string
+	}{
+		{"nested field", resource.Validate(), ErrorCodeBadRequest, "versions[1].metadata.keywords"},
+		{"raw validation", ValidateName("Bad"), ErrorCodeBadRequest, ""},
+		{"api error", fmt.Errorf("wrapped: %w", &Error{Code: ErrorCodeForbidden, Message: "no"}), ErrorCodeForbidden, ""},
+		{"other", errors.New("disk on fire"), ErrorCodeInternalError, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := AsError(tt.err)
+			if e.Code != tt.code {
+				t.Fatalf("Code = %s, want %s (err: %v)", e.Code, tt.code, tt.err)
+			}
+			if got := e.Details[DetailField]; got != tt.field {
+				t.Errorf("field = %q, want %q", got, tt.field)
+			}
+			if err := e.Validate(); err != nil {
+				t.Errorf("converted error invalid: %v", err)
+			}
+		})
+	}
+
+	if AsError(nil) != nil {
+		t.Error("AsError(nil) must be nil")
+	}
+}
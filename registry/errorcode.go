@@ -1,15 +1,21 @@
 package registry
 
+import "net/http"
+
 // Platform-specific error code for machine-readable error classification.
 //
 // Provides granular error information beyond HTTP status codes, enabling
 // clients to implement specific error handling logic. Used in the Code field
-// of [Error] responses.
+// of [Error] responses. Each code has a canonical HTTP status (see
+// [StatusFor]) and a sentinel error that matching [Error] values satisfy
+// with [errors.Is].
 type ErrorCode string
 
 const (
 	ErrorCodeBadRequest           ErrorCode = "bad_request"                     // Request validation failed (malformed body, invalid fields).
 	ErrorCodeNotFound             ErrorCode = "not_found"                       // Requested namespace, resource, version, or channel does not exist.
+	ErrorCodeMoved                ErrorCode = "moved"                           // Requested namespace or resource was renamed or transferred.
+	ErrorCodeConflict             ErrorCode = "conflict"                        // Request conflicts with the current state of the target.
 	ErrorCodeNamespaceExists      ErrorCode = "namespace_exists"                // Cannot create namespace: name already in use.
 	ErrorCodeNamespaceNotEmpty    ErrorCode = "namespace_not_empty"             // Cannot delete namespace: contains resources.
 	ErrorCodeResourceExists       ErrorCode = "resource_exists"                 // Cannot create resource: name already in use within namespace.
@@ -17,26 +23,128 @@ const (
 	ErrorCodeVersionExists        ErrorCode = "version_exists"                  // Cannot create version: version string already in use.
 	ErrorCodeVersionPublished     ErrorCode = "version_published"               // Cannot modify or delete version: already published and immutable.
 	ErrorCodeChannelExists        ErrorCode = "channel_exists"                  // Cannot create channel: name already in use.
+	ErrorCodeMemberExists         ErrorCode = "member_exists"                   // Cannot create member: user is already a member of the namespace.
+	ErrorCodeLastOwner            ErrorCode = "last_owner"                      // Cannot remove or demote member: namespace must keep at least one owner.
+	ErrorCodeTokenExists          ErrorCode = "token_exists"                    // Cannot create token: name already in use within namespace.
+	ErrorCodeUnauthorized         ErrorCode = "unauthorized"                    // Credentials missing, invalid, revoked, or expired.
+	ErrorCodeForbidden            ErrorCode = "forbidden"                       // Caller lacks the permission required by the operation.
 	ErrorCodePreconditionFailed   ErrorCode = "precondition_failed"             // Request precondition not met (e.g., If-Match header mismatch).
+	ErrorCodeNotAcceptable        ErrorCode = "not_acceptable"                  // No media type offered for the response is acceptable to the client.
+	ErrorCodeUnsupportedMediaType ErrorCode = "unsupported_media_type"          // Request body media type is unknown or not accepted by the operation.
+	ErrorCodeOffsetMismatch       ErrorCode = "offset_mismatch"                 // Upload chunk offset differs from the committed offset.
+	ErrorCodeQuotaExceeded        ErrorCode = "quota_exceeded"                  // Request exceeds a registry quota (size, chunk, or session count).
+	ErrorCodeDigestMismatch       ErrorCode = "digest_mismatch"                 // Uploaded content does not match the declared digest.
+	ErrorCodeUploadIncomplete     ErrorCode = "upload_incomplete"               // Cannot complete upload: not all declared bytes were received.
 	ErrorCodeInternalError        ErrorCode = "internal_error"                  // Unexpected server error occurred.
 )
 
-// Set of known error codes for validation.
-var validErrorCodes = map[ErrorCode]bool{
-	ErrorCodeBadRequest:           true,
-	ErrorCodeNotFound:             true,
-	ErrorCodeNamespaceExists:      true,
-	ErrorCodeNamespaceNotEmpty:    true,
-	ErrorCodeResourceExists:       true,
-	ErrorCodeResourceHasPublished: true,
-	ErrorCodeVersionExists:        true,
-	ErrorCodeVersionPublished:     true,
-	ErrorCodeChannelExists:        true,
-	ErrorCodePreconditionFailed:   true,
-	ErrorCodeInternalError:        true,
+// HTTP status and sentinel error for each known error code.
+//
+// The sentinel is what an [Error] with the code matches through
+// [errors.Is]. Codes refining a more general code also match the sentinel
+// of that code (see [parentCodes]).
+var errorCodes = map[ErrorCode]struct {
+	status   int
+	sentinel error
+}{
+	ErrorCodeBadRequest:           {http.StatusBadRequest, ErrBadRequest},
+	ErrorCodeNotFound:             {http.StatusNotFound, ErrNotFound},
+	ErrorCodeMoved:                {http.StatusPermanentRedirect, ErrMoved},
+	ErrorCodeConflict:             {http.StatusConflict, ErrConflict},
+	ErrorCodeNamespaceExists:      {http.StatusConflict, ErrNamespaceExists},
+	ErrorCodeNamespaceNotEmpty:    {http.StatusConflict, ErrNamespaceNotEmpty},
+	ErrorCodeResourceExists:       {http.StatusConflict, ErrResourceExists},
+	ErrorCodeResourceHasPublished: {http.StatusConflict, ErrResourceHasPublished},
+	ErrorCodeVersionExists:        {http.StatusConflict, ErrVersionExists},
+	ErrorCodeVersionPublished:     {http.StatusConflict, ErrVersionPublished},
+	ErrorCodeChannelExists:        {http.StatusConflict, ErrChannelExists},
+	ErrorCodeMemberExists:         {http.StatusConflict, ErrMemberExists},
+	ErrorCodeLastOwner:            {http.StatusConflict, ErrLastOwner},
+	ErrorCodeTokenExists:          {http.StatusConflict, ErrTokenExists},
+	ErrorCodeUnauthorized:         {http.StatusUnauthorized, ErrUnauthorized},
+	ErrorCodeForbidden:            {http.StatusForbidden, ErrForbidden},
+	ErrorCodePreconditionFailed:   {http.StatusPreconditionFailed, ErrPreconditionFailed},
+	ErrorCodeNotAcceptable:        {http.StatusNotAcceptable, ErrNotAcceptable},
+	ErrorCodeUnsupportedMediaType: {http.StatusUnsupportedMediaType, ErrUnsupportedMediaType},
+	ErrorCodeOffsetMismatch:       {http.StatusConflict, ErrOffsetMismatch},
+	ErrorCodeQuotaExceeded:        {http.StatusRequestEntityTooLarge, ErrQuotaExceeded},
+	ErrorCodeDigestMismatch:       {http.StatusUnprocessableEntity, ErrDigestMismatch},
+	ErrorCodeUploadIncomplete:     {http.StatusConflict, ErrUploadIncomplete},
+	ErrorCodeInternalError:        {http.StatusInternalServerError, ErrInternal},
+}
+
+// General code refined by each specific error code.
+//
+// An [Error] with a code listed here also matches the sentinel of its
+// parent, so that, for example, every state conflict matches [ErrConflict].
+// Sharing a status does not imply a parent: a digest mismatch is not a bad
+// request, and an offset mismatch is not a state conflict.
+var parentCodes = map[ErrorCode]ErrorCode{
+	ErrorCodeNamespaceExists:      ErrorCodeConflict,
+	ErrorCodeNamespaceNotEmpty:    ErrorCodeConflict,
+	ErrorCodeResourceExists:       ErrorCodeConflict,
+	ErrorCodeResourceHasPublished: ErrorCodeConflict,
+	ErrorCodeVersionExists:        ErrorCodeConflict,
+	ErrorCodeVersionPublished:     ErrorCodeConflict,
+	ErrorCodeChannelExists:        ErrorCodeConflict,
+	ErrorCodeMemberExists:         ErrorCodeConflict,
+	ErrorCodeLastOwner:            ErrorCodeConflict,
+	ErrorCodeTokenExists:          ErrorCodeConflict,
+}
+
+// Canonical error code for each HTTP status.
+var statusCodes = map[int]ErrorCode{
+	http.StatusPermanentRedirect:     ErrorCodeMoved,
+	http.StatusBadRequest:            ErrorCodeBadRequest,
+	http.StatusUnauthorized:          ErrorCodeUnauthorized,
+	http.StatusForbidden:             ErrorCodeForbidden,
+	http.StatusNotFound:              ErrorCodeNotFound,
+	http.StatusConflict:              ErrorCodeConflict,
+	http.StatusNotAcceptable:         ErrorCodeNotAcceptable,
+	http.StatusPreconditionFailed:    ErrorCodePreconditionFailed,
+	http.StatusUnsupportedMediaType:  ErrorCodeUnsupportedMediaType,
+	http.StatusRequestEntityTooLarge: ErrorCodeQuotaExceeded,
+	http.StatusInternalServerError:   ErrorCodeInternalError,
 }
 
 // Whether the error code is a known value.
 func isValidErrorCode(code ErrorCode) bool {
-	return validErrorCodes[code]
+	_, ok := errorCodes[code]
+	return ok
+}
+
+// HTTP status for an error code.
+//
+// Unknown codes map to 500 Internal Server Error.
+func StatusFor(code ErrorCode) int {
+	if c, ok := errorCodes[code]; ok {
+		return c.status
+	}
+	return http.StatusInternalServerError
+}
+
+// Canonical error code for an HTTP status.
+//
+// Used by clients to classify error responses that carry no recognizable
+// body. A 308 maps to [ErrorCodeMoved]; unlike a 301, it keeps clients that
+// follow the redirect from turning the request into a GET. Other statuses
+// without a dedicated code map to [ErrorCodeBadRequest] when in the 4xx
+// range and to [ErrorCodeInternalError] otherwise. Several codes share a
+// status (every 409 code, for instance), so CodeFor(StatusFor(c)) is not
+// necessarily c.
+func CodeFor(status int) ErrorCode {
+	if code, ok := statusCodes[status]; ok {
+		return code
+	}
+	if status >= 400 && status < 500 {
+		return ErrorCodeBadRequest
+	}
+	return ErrorCodeInternalError
+}
+
+// Sentinel error for an error code.
+//
+// Returns nil for unknown codes.
+func sentinelFor(code ErrorCode) error {
+	return errorCodes[code].sentinel
 }
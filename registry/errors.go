@@ -12,6 +12,7 @@ var (
 	ErrVersionInvalid    = errors.New("version format must be semantic version")
 	ErrTimestampInvalid  = errors.New("timestamp must be a positive unix epoch")
 	ErrTimestampOrder    = errors.New("updatedAt must not be before createdAt")
+	ErrPublishedOrder    = errors.New("publishedAt must not be before createdAt")
 	ErrTypeEmpty         = errors.New("resource type cannot be empty")
 	ErrArchiveEmpty      = errors.New("archive URL cannot be empty when set")
 	ErrSizeInvalid       = errors.New("archive size must be positive")
@@ -20,16 +21,103 @@ var (
 	ErrCountNegative     = errors.New("count must not be negative")
 	ErrErrorCodeInvalid  = errors.New("error code must be a known value")
 	ErrErrorMessageEmpty = errors.New("error message cannot be empty")
+	ErrRoleInvalid       = errors.New("role must be one of owner, publisher, or reader")
+	ErrExpiryOrder       = errors.New("expiresAt must be after createdAt")
+	ErrResourceDuplicate = errors.New("resource must not be listed more than once")
+	ErrTokenSecretEmpty  = errors.New("token secret cannot be empty")
+	ErrTooManyLabels     = errors.New("cannot have more than 64 labels")
+	ErrLabelKeyInvalid   = errors.New("label key must be an optional DNS subdomain prefix and a name of up to 63 lowercase alphanumeric characters, dots, underscores, or hyphens")
+	ErrLabelValueTooLong = errors.New("label value cannot exceed 256 bytes")
+	ErrReadmeTooLong     = errors.New("readme cannot exceed 512 KiB")
+	ErrLicenseInvalid    = errors.New("license must be a valid SPDX license expression")
+	ErrURLInvalid        = errors.New("URL must be an absolute http or https URL")
+	ErrTooManyKeywords   = errors.New("cannot have more than 20 keywords")
+	ErrKeywordInvalid    = errors.New("keyword must contain only lowercase letters, numbers, and hyphens, and must not exceed 32 characters")
+	ErrKeywordDuplicate  = errors.New("keyword must not be listed more than once")
+	ErrDependencyEmpty   = errors.New("dependency reference cannot be empty")
+	ErrNamespaceMissing  = errors.New("dependency reference must include a namespace")
+	ErrRetentionEmpty    = errors.New("retention policy must set keepUnpublished or maxUnpublishedAge")
+	ErrMaxAgeInvalid     = errors.New("maxUnpublishedAge must be positive")
+	ErrPolicyDuplicate   = errors.New("only one retention policy may apply to a namespace or resource")
+	ErrGCReasonInvalid   = errors.New("GC reason must be a known value")
+	ErrReasonTooLong     = errors.New("reason cannot exceed 512 bytes")
+	ErrSequenceInvalid   = errors.New("sequence must be positive")
+	ErrHistoryOrder      = errors.New("history entries must be in increasing sequence and timestamp order")
+	ErrRollbackOrder     = errors.New("rollback must refer to an earlier history entry")
+	ErrDetailKeyInvalid  = errors.New("error detail key must be a known value")
+	ErrDigestAlgorithm   = errors.New("digest algorithm must be sha256")
+	ErrRangeInvalid      = errors.New("range offset must lie within the blob")
+	ErrUploadIDInvalid   = errors.New("upload ID must be 16 to 128 letters, digits, underscores, or hyphens")
+	ErrOffsetInvalid     = errors.New("upload offset must lie between 0 and the declared size")
+	ErrRedirectKind      = errors.New("redirect must map a namespace to a namespace or a resource to a resource")
+	ErrRedirectLoop      = errors.New("redirect must not point to its own path")
+	ErrMediaTypeInvalid  = errors.New("media type is malformed")
 
 	// Type validation errors.
 
-	ErrInvalidNamespace = errors.New("invalid namespace")
-	ErrInvalidResource  = errors.New("invalid resource")
-	ErrInvalidVersion   = errors.New("invalid version")
-	ErrInvalidChannel   = errors.New("invalid channel")
+	ErrInvalidNamespace  = errors.New("invalid namespace")
+	ErrInvalidResource   = errors.New("invalid resource")
+	ErrInvalidVersion    = errors.New("invalid version")
+	ErrInvalidChannel    = errors.New("invalid channel")
+	ErrInvalidMember     = errors.New("invalid member")
+	ErrInvalidToken      = errors.New("invalid token")
+	ErrInvalidMetadata   = errors.New("invalid metadata")
+	ErrInvalidDependency = errors.New("invalid dependency")
+	ErrInvalidPolicy     = errors.New("invalid retention policy")
+	ErrInvalidGCPlan     = errors.New("invalid GC plan")
+	ErrInvalidHistory    = errors.New("invalid channel history")
+	ErrInvalidUpload     = errors.New("invalid upload")
+	ErrInvalidRedirect   = errors.New("invalid redirect")
+
+	// Archive manifest errors.
+
+	ErrManifestMissing = errors.New("archive does not contain a manifest")
+	ErrManifestInvalid = errors.New("archive manifest is invalid")
+	ErrManifestTooLong = errors.New("archive manifest cannot exceed 1 MiB")
+	ErrManifestTarget  = errors.New("archive manifest does not match the upload target")
+	ErrManifestName    = errors.New("manifest resource name does not match")
+	ErrManifestType    = errors.New("manifest resource type does not match")
+	ErrManifestVersion = errors.New("manifest resource version does not match")
+
+	// Blob storage errors.
+
+	ErrBlobReadFailed  = errors.New("blob read failed")
+	ErrBlobWriteFailed = errors.New("blob write failed")
+
+	// Garbage collection errors.
+
+	ErrGCFailed = errors.New("garbage collection failed")
+
+	// API errors, matched by [Error] values carrying the corresponding code.
+
+	ErrBadRequest           = errors.New("bad request")
+	ErrNotFound             = errors.New("not found")
+	ErrConflict             = errors.New("conflict")
+	ErrNamespaceExists      = errors.New("namespace already exists")
+	ErrNamespaceNotEmpty    = errors.New("namespace is not empty")
+	ErrResourceExists       = errors.New("resource already exists")
+	ErrResourceHasPublished = errors.New("resource has published versions")
+	ErrVersionExists        = errors.New("version already exists")
+	ErrVersionPublished     = errors.New("version is published")
+	ErrChannelExists        = errors.New("channel already exists")
+	ErrMemberExists         = errors.New("member already exists")
+	ErrLastOwner            = errors.New("namespace must keep at least one owner")
+	ErrTokenExists          = errors.New("token already exists")
+	ErrUnauthorized         = errors.New("unauthorized")
+	ErrForbidden            = errors.New("forbidden")
+	ErrPreconditionFailed   = errors.New("precondition failed")
+	ErrNotAcceptable        = errors.New("not acceptable")
+	ErrUnsupportedMediaType = errors.New("unsupported media type")
+	ErrOffsetMismatch       = errors.New("upload offset mismatch")
+	ErrQuotaExceeded        = errors.New("quota exceeded")
+	ErrDigestMismatch       = errors.New("digest mismatch")
+	ErrUploadIncomplete     = errors.New("upload incomplete")
+	ErrMoved                = errors.New("moved")
+	ErrInternal             = errors.New("internal error")
 
 	// Codec errors.
 
 	ErrEncodeFailed = errors.New("failed to encode registry type")
 	ErrDecodeFailed = errors.New("failed to decode registry type")
+	ErrCodecType    = errors.New("codec does not produce the requested type")
 )
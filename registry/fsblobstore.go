@@ -0,0 +1,301 @@
+package registry
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/cruciblehq/crex"
+	"github.com/cruciblehq/spec/reference"
+)
+
+// [BlobStore] backed by a local directory.
+//
+// Blobs are stored as files under root at {algorithm}/{hash[:2]}/{hash}.
+// Uploads are written to a temporary file in root and renamed into place once
+// their digest is known, so readers never observe partial content. Staged
+// uploads live under root/uploads until committed. Appends and commits of an
+// upload are serialized within the store value only, so a directory must not
+// be shared by several stores. Use [NewFSBlobStore] to construct one.
+type FSBlobStore struct {
+	root  string
+	locks keyedMutex // Per-upload mutexes serializing appends and commits.
+}
+
+// Creates a filesystem blob store rooted at the given directory.
+//
+// The directory is created if it does not exist.
+func NewFSBlobStore(root string) (*FSBlobStore, error) {
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return nil, crex.Wrap(ErrBlobWriteFailed, err)
+	}
+	return &FSBlobStore{root: root}, nil
+}
+
+// Implements [BlobStore.Put].
+func (s *FSBlobStore) Put(ctx context.Context, r io.Reader) (*BlobInfo, error) {
+	tmp, err := os.CreateTemp(s.root, ".upload-*")
+	if err != nil {
+		return nil, crex.Wrap(ErrBlobWriteFailed, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	h := newBlobHash()
+	if _, err := io.Copy(io.MultiWriter(tmp, h), r); err != nil {
+		tmp.Close()
+		return nil, crex.Wrap(ErrBlobWriteFailed, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return nil, crex.Wrap(ErrBlobWriteFailed, err)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	info := h.info()
+	if err := s.install(tmp.Name(), info.Digest); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// Implements [BlobStore.Get].
+func (s *FSBlobStore) Get(ctx context.Context, digest reference.Digest) (io.ReadCloser, error) {
+	return s.GetRange(ctx, digest, 0, -1)
+}
+
+// Implements [BlobStore.GetRange].
+func (s *FSBlobStore) GetRange(ctx context.Context, digest reference.Digest, offset, length int64) (io.ReadCloser, error) {
+	f, err := s.open(digest)
+	if err != nil {
+		return nil, err
+	}
+
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, crex.Wrap(ErrBlobReadFailed, err)
+	}
+	n, err := clampRange(fi.Size(), offset, length)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		f.Close()
+		return nil, crex.Wrap(ErrBlobReadFailed, err)
+	}
+
+	return struct {
+		io.Reader
+		io.Closer
+	}{io.LimitReader(f, n), f}, nil
+}
+
+// Implements [BlobStore.Stat].
+func (s *FSBlobStore) Stat(ctx context.Context, digest reference.Digest) (*BlobInfo, error) {
+	if err := ValidateBlobDigest(digest); err != nil {
+		return nil, err
+	}
+	fi, err := os.Stat(s.path(digest))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, blobNotFound(digest)
+	}
+	if err != nil {
+		return nil, crex.Wrap(ErrBlobReadFailed, err)
+	}
+	return &BlobInfo{Digest: digest, Size: fi.Size()}, nil
+}
+
+// Implements [BlobStore.Delete].
+func (s *FSBlobStore) Delete(ctx context.Context, digest reference.Digest) error {
+	if err := ValidateBlobDigest(digest); err != nil {
+		return err
+	}
+	if err := os.Remove(s.path(digest)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return crex.Wrap(ErrBlobWriteFailed, err)
+	}
+	return nil
+}
+
+// Implements [BlobStore.List].
+//
+// Walks the directory of [BlobDigestAlgorithm] in lexical order, which is
+// digest order. Files that do not sit at the path of a valid digest, such
+// as leftovers of interrupted writes, are skipped.
+func (s *FSBlobStore) List(ctx context.Context) ([]BlobInfo, error) {
+	infos := []BlobInfo{}
+	dir := filepath.Join(s.root, BlobDigestAlgorithm)
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == dir && errors.Is(err, fs.ErrNotExist) {
+				return fs.SkipDir
+			}
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		digest := reference.Digest{Algorithm: BlobDigestAlgorithm, Hash: d.Name()}
+		if ValidateBlobDigest(digest) != nil || s.path(digest) != path {
+			return nil
+		}
+		fi, err := d.Info()
+		if err != nil {
+			return err
+		}
+		infos = append(infos, BlobInfo{Digest: digest, Size: fi.Size()})
+		return nil
+	})
+	if err != nil {
+		return nil, crex.Wrap(ErrBlobReadFailed, err)
+	}
+	return infos, nil
+}
+
+// Opens the file holding a blob.
+func (s *FSBlobStore) open(digest reference.Digest) (*os.File, error) {
+	if err := ValidateBlobDigest(digest); err != nil {
+		return nil, err
+	}
+	f, err := os.Open(s.path(digest))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, blobNotFound(digest)
+	}
+	if err != nil {
+		return nil, crex.Wrap(ErrBlobReadFailed, err)
+	}
+	return f, nil
+}
+
+// Path of the file holding a blob.
+//
+// The digest must have been validated with [ValidateBlobDigest], which
+// guarantees the hash cannot escape the root.
+func (s *FSBlobStore) path(digest reference.Digest) string {
+	return filepath.Join(s.root, digest.Algorithm, digest.Hash[:2], digest.Hash)
+}
+
+// Implements [BlobStore.Append].
+func (s *FSBlobStore) Append(ctx context.Context, upload string, offset int64, r io.Reader) (int64, error) {
+	if err := ValidateUploadID(upload); err != nil {
+		return 0, err
+	}
+	defer s.lock(upload)()
+
+	if err := os.MkdirAll(filepath.Dir(s.stagingPath(upload)), 0755); err != nil {
+		return 0, crex.Wrap(ErrBlobWriteFailed, err)
+	}
+	f, err := os.OpenFile(s.stagingPath(upload), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return 0, crex.Wrap(ErrBlobWriteFailed, err)
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return 0, crex.Wrap(ErrBlobWriteFailed, err)
+	}
+	if fi.Size() != offset {
+		return fi.Size(), offsetMismatch(fi.Size())
+	}
+
+	n, err := io.Copy(f, r)
+	if err != nil {
+		return offset + n, crex.Wrap(ErrBlobWriteFailed, err)
+	}
+	return offset + n, nil
+}
+
+// Implements [BlobStore.StagedSize].
+func (s *FSBlobStore) StagedSize(ctx context.Context, upload string) (int64, error) {
+	if err := ValidateUploadID(upload); err != nil {
+		return 0, err
+	}
+	fi, err := os.Stat(s.stagingPath(upload))
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, crex.Wrap(ErrBlobReadFailed, err)
+	}
+	return fi.Size(), nil
+}
+
+// Implements [BlobStore.Commit].
+func (s *FSBlobStore) Commit(ctx context.Context, upload string) (*BlobInfo, error) {
+	if err := ValidateUploadID(upload); err != nil {
+		return nil, err
+	}
+	defer s.lock(upload)()
+
+	path := s.stagingPath(upload)
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, uploadNotFound(upload)
+	}
+	if err != nil {
+		return nil, crex.Wrap(ErrBlobReadFailed, err)
+	}
+	h := newBlobHash()
+	_, err = io.Copy(h, f)
+	f.Close()
+	if err != nil {
+		return nil, crex.Wrap(ErrBlobReadFailed, err)
+	}
+
+	info := h.info()
+	if err := s.install(path, info.Digest); err != nil {
+		return nil, err
+	}
+	os.Remove(path)
+	return info, nil
+}
+
+// Implements [BlobStore.Abort].
+func (s *FSBlobStore) Abort(ctx context.Context, upload string) error {
+	if err := ValidateUploadID(upload); err != nil {
+		return err
+	}
+	defer s.lock(upload)()
+
+	if err := os.Remove(s.stagingPath(upload)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return crex.Wrap(ErrBlobWriteFailed, err)
+	}
+	return nil
+}
+
+// Moves a fully written file into place as the blob with the given digest.
+//
+// If the blob already exists, the file is left for the caller to remove.
+func (s *FSBlobStore) install(src string, digest reference.Digest) error {
+	path := s.path(digest)
+	if _, err := os.Stat(path); err == nil {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return crex.Wrap(ErrBlobWriteFailed, err)
+	}
+	if err := os.Rename(src, path); err != nil {
+		return crex.Wrap(ErrBlobWriteFailed, err)
+	}
+	return nil
+}
+
+// Locks a staged upload, returning the unlock function.
+func (s *FSBlobStore) lock(upload string) func() {
+	return s.locks.lock(upload)
+}
+
+// Path of the file holding a staged upload.
+//
+// The name must have been validated with [ValidateUploadID].
+func (s *FSBlobStore) stagingPath(upload string) string {
+	return filepath.Join(s.root, "uploads", upload)
+}
@@ -0,0 +1,402 @@
+package registry
+
+import (
+	"cmp"
+	"context"
+	"slices"
+
+	"github.com/cruciblehq/crex"
+	"github.com/cruciblehq/spec/reference"
+)
+
+// Why a version was selected for deletion or protected from it.
+type GCReason string
+
+const (
+	GCReasonKeepCount GCReason = "keep_count" // Beyond the newest KeepUnpublished unpublished versions.
+	GCReasonMaxAge    GCReason = "max_age"    // Not updated for longer than MaxUnpublishedAge.
+	GCReasonChannel   GCReason = "channel"    // Selected by a rule but pointed to by a channel.
+	GCReasonPublished GCReason = "published"  // Published after the plan was computed.
+)
+
+// Set of known GC reasons for validation.
+var validGCReasons = map[GCReason]bool{
+	GCReasonKeepCount: true,
+	GCReasonMaxAge:    true,
+	GCReasonChannel:   true,
+	GCReasonPublished: true,
+}
+
+// Version selected by a garbage collection plan.
+//
+// Identifies the version, the reason it was selected or protected, and the
+// policy scope that selected it. Channels lists the channels pointing to the
+// version when Reason is [GCReasonChannel].
+type GCEntry struct {
+	Namespace string   `json:"namespace"` // Namespace of the version.
+	Resource  string   `json:"resource"`  // Resource of the version.
+	Version   string   `json:"version"`   // Version string.
+	Reason    GCReason `json:"reason"`    // Why the version was selected or protected.
+	Channels  []string `json:"channels"`  // Channels pointing to the version (protected entries only).
+	UpdatedAt int64    `json:"updatedAt"` // When the version was last updated.
+}
+
+// Validates the GC entry.
+func (e *GCEntry) Validate() error {
+	if err := ValidateReference(e.Namespace, e.Resource, e.Version); err != nil {
+		return crex.Wrap(ErrInvalidGCPlan, err)
+	}
+	if !validGCReasons[e.Reason] {
+		return crex.Wrap(ErrInvalidGCPlan, fieldError("reason", ErrGCReasonInvalid))
+	}
+	for i, ch := range e.Channels {
+		if err := ValidateName(ch); err != nil {
+			return crex.Wrap(ErrInvalidGCPlan, fieldError(indexField("channels", i), err))
+		}
+	}
+	return nil
+}
+
+// Stored blob that no version refers to.
+//
+// Listed by [PlanGC] for registries that implement [BlobSweeper], such as a
+// blob left behind when its versions were removed without releasing it.
+type GCBlob struct {
+	Digest string `json:"digest"` // Blob digest (e.g., "sha256:abc...").
+	Size   int64  `json:"size"`   // Blob size in bytes.
+}
+
+// Validates the GC blob.
+func (b *GCBlob) Validate() error {
+	if err := ValidateDigest(b.Digest); err != nil {
+		return crex.Wrap(ErrInvalidGCPlan, fieldError("digest", err))
+	}
+	if b.Size <= 0 {
+		return crex.Wrap(ErrInvalidGCPlan, fieldError("size", ErrSizeInvalid))
+	}
+	return nil
+}
+
+// Deletion plan computed by [PlanGC].
+//
+// Deletions lists the versions that [ApplyGC] would delete. Protected lists
+// versions that a policy selected but that are kept because a channel points
+// to them, so operators can review why space is not reclaimed. Published
+// versions are never considered and never appear in the plan. Orphans lists
+// the stored blobs that no version refers to, which [ApplyGC] would sweep;
+// it is omitted for registries that do not implement [BlobSweeper]. The plan
+// is a dry run: computing it changes nothing. The media type is
+// [MediaTypeGCPlan].
+type GCPlan struct {
+	Deletions []GCEntry `json:"deletions"`         // Versions to delete.
+	Protected []GCEntry `json:"protected"`         // Versions selected by a rule but kept.
+	Orphans   []GCBlob  `json:"orphans,omitempty"` // Unreferenced blobs to delete.
+	CreatedAt int64     `json:"createdAt"`         // When the plan was computed.
+}
+
+// Validates the GC plan.
+func (p *GCPlan) Validate() error {
+	if p.CreatedAt <= 0 {
+		return crex.Wrap(ErrInvalidGCPlan, fieldError("createdAt", ErrTimestampInvalid))
+	}
+	for i := range p.Deletions {
+		if err := p.Deletions[i].Validate(); err != nil {
+			return fieldError(indexField("deletions", i), err)
+		}
+	}
+	for i := range p.Protected {
+		if err := p.Protected[i].Validate(); err != nil {
+			return fieldError(indexField("protected", i), err)
+		}
+	}
+	for i := range p.Orphans {
+		if err := p.Orphans[i].Validate(); err != nil {
+			return fieldError(indexField("orphans", i), err)
+		}
+	}
+	return nil
+}
+
+// Outcome of applying a GC plan.
+//
+// Deleted lists the versions that were deleted. Skipped lists planned
+// deletions that were no longer safe when the plan was applied, with Reason
+// describing what changed. Swept lists the planned orphan blobs that were
+// deleted; orphans that a version has come to refer to since are kept and
+// not listed. The media type is [MediaTypeGCResult].
+type GCResult struct {
+	Deleted []GCEntry `json:"deleted"`         // Versions deleted.
+	Skipped []GCEntry `json:"skipped"`         // Planned deletions that were skipped.
+	Swept   []GCBlob  `json:"swept,omitempty"` // Orphan blobs deleted.
+}
+
+// Validates the GC result.
+func (r *GCResult) Validate() error {
+	for i := range r.Deleted {
+		if err := r.Deleted[i].Validate(); err != nil {
+			return fieldError(indexField("deleted", i), err)
+		}
+	}
+	for i := range r.Skipped {
+		if err := r.Skipped[i].Validate(); err != nil {
+			return fieldError(indexField("skipped", i), err)
+		}
+	}
+	for i := range r.Swept {
+		if err := r.Swept[i].Validate(); err != nil {
+			return fieldError(indexField("swept", i), err)
+		}
+	}
+	return nil
+}
+
+// Registry that can find and delete blobs no version refers to.
+//
+// Blobs are normally deleted when the last version referring to them is
+// released, but some are left behind, for example by namespace deletion (see
+// [NewRegistry]). [PlanGC] and [ApplyGC] sweep them when the registry
+// implements this interface, as registries assembled by [NewRegistry] do.
+type BlobSweeper interface {
+
+	// Lists the stored blobs that no version refers to.
+	OrphanedBlobs(ctx context.Context) ([]BlobInfo, error)
+
+	// Deletes a blob if no version refers to it.
+	//
+	// References are counted again while uploads of the same content are
+	// held off, so a blob that a version has come to refer to since it was
+	// listed is kept. Returns whether the blob was deleted.
+	SweepBlob(ctx context.Context, digest reference.Digest) (bool, error)
+}
+
+// Computes a garbage collection plan from retention policies.
+//
+// Walks every resource covered by a policy through reg and selects the
+// unpublished versions the effective policy discards, as of the unix epoch
+// now. Published versions are never selected. Versions pointed to by a
+// channel are moved to [GCPlan.Protected] instead of being deleted. The plan
+// lists deletions ordered by namespace, resource, and creation time. If reg
+// implements [BlobSweeper], the blobs no version refers to are listed in
+// [GCPlan.Orphans], in digest order and regardless of the policies. A policy
+// naming a resource or namespace that does not exist fails the plan.
+func PlanGC(ctx context.Context, reg Registry, policies []RetentionPolicy, now int64) (*GCPlan, error) {
+	list := RetentionPolicyList{Policies: policies}
+	if err := list.Validate(); err != nil {
+		return nil, crex.Wrap(ErrGCFailed, err)
+	}
+
+	plan := &GCPlan{Deletions: []GCEntry{}, Protected: []GCEntry{}, CreatedAt: now}
+	for _, target := range effectivePolicies(ctx, reg, policies) {
+		if target.err != nil {
+			return nil, crex.Wrap(ErrGCFailed, target.err)
+		}
+		if err := planResource(ctx, reg, plan, target.namespace, target.resource, target.policy, now); err != nil {
+			return nil, crex.Wrap(ErrGCFailed, err)
+		}
+	}
+
+	if sweeper, ok := reg.(BlobSweeper); ok {
+		orphans, err := sweeper.OrphanedBlobs(ctx)
+		if err != nil {
+			return nil, crex.Wrap(ErrGCFailed, err)
+		}
+		plan.Orphans = []GCBlob{}
+		for _, b := range orphans {
+			plan.Orphans = append(plan.Orphans, GCBlob{Digest: b.Digest.String(), Size: b.Size})
+		}
+	}
+	return plan, nil
+}
+
+// Applies a garbage collection plan through the registry interface.
+//
+// Each planned deletion is re-checked before deleting, since the registry may
+// have changed since the plan was computed: versions that have been published
+// or that a channel now points to are skipped and reported in
+// [GCResult.Skipped]. Versions that no longer exist are treated as deleted.
+// Once the versions are deleted, the planned orphan blobs are swept if reg
+// implements [BlobSweeper], each re-checked under [BlobSweeper.SweepBlob].
+// Stops at the first registry error, returning the partial result alongside
+// the error.
+func ApplyGC(ctx context.Context, reg Registry, plan *GCPlan) (*GCResult, error) {
+	result := &GCResult{Deleted: []GCEntry{}, Skipped: []GCEntry{}}
+	targets := make(map[[2]string]map[string][]string)
+
+	for _, entry := range plan.Deletions {
+		key := [2]string{entry.Namespace, entry.Resource}
+		channels, ok := targets[key]
+		if !ok {
+			var err error
+			if channels, err = channelTargets(ctx, reg, entry.Namespace, entry.Resource); err != nil {
+				return result, crex.Wrap(ErrGCFailed, err)
+			}
+			targets[key] = channels
+		}
+
+		if names := channels[entry.Version]; len(names) > 0 {
+			entry.Reason = GCReasonChannel
+			entry.Channels = names
+			result.Skipped = append(result.Skipped, entry)
+			continue
+		}
+
+		v, err := reg.ReadVersion(ctx, entry.Namespace, entry.Resource, entry.Version)
+		if isNotFound(err) {
+			result.Deleted = append(result.Deleted, entry)
+			continue
+		}
+		if err != nil {
+			return result, crex.Wrap(ErrGCFailed, err)
+		}
+		if v.Published() {
+			entry.Reason = GCReasonPublished
+			result.Skipped = append(result.Skipped, entry)
+			continue
+		}
+
+		if err := reg.DeleteVersion(ctx, entry.Namespace, entry.Resource, entry.Version); err != nil {
+			return result, crex.Wrap(ErrGCFailed, err)
+		}
+		result.Deleted = append(result.Deleted, entry)
+	}
+
+	sweeper, ok := reg.(BlobSweeper)
+	if !ok || len(plan.Orphans) == 0 {
+		return result, nil
+	}
+	result.Swept = []GCBlob{}
+	for _, b := range plan.Orphans {
+		digest, err := reference.ParseDigest(b.Digest)
+		if err != nil {
+			return result, crex.Wrap(ErrGCFailed, err)
+		}
+		deleted, err := sweeper.SweepBlob(ctx, *digest)
+		if err != nil {
+			return result, crex.Wrap(ErrGCFailed, err)
+		}
+		if deleted {
+			result.Swept = append(result.Swept, b)
+		}
+	}
+	return result, nil
+}
+
+// Resource together with the policy that governs it.
+type gcTarget struct {
+	namespace string
+	resource  string
+	policy    *RetentionPolicy
+	err       error
+}
+
+// Resolves the effective policy of every covered resource.
+//
+// Resource-level policies take precedence over namespace-level policies.
+// Namespace-level policies are expanded by listing the namespace's resources.
+// Listing failures are reported through the err field of a target.
+func effectivePolicies(ctx context.Context, reg Registry, policies []RetentionPolicy) []gcTarget {
+	byResource := make(map[[2]string]*RetentionPolicy)
+	byNamespace := make(map[string]*RetentionPolicy)
+	var namespaces []string
+	for i := range policies {
+		p := &policies[i]
+		if p.Resource != "" {
+			byResource[[2]string{p.Namespace, p.Resource}] = p
+			continue
+		}
+		byNamespace[p.Namespace] = p
+		namespaces = append(namespaces, p.Namespace)
+	}
+
+	var targets []gcTarget
+	for _, ns := range namespaces {
+		list, err := reg.ListResources(ctx, ns)
+		if err != nil {
+			return append(targets, gcTarget{err: err})
+		}
+		for _, r := range list.Resources {
+			if _, ok := byResource[[2]string{ns, r.Name}]; ok {
+				continue
+			}
+			targets = append(targets, gcTarget{namespace: ns, resource: r.Name, policy: byNamespace[ns]})
+		}
+	}
+	for key, p := range byResource {
+		targets = append(targets, gcTarget{namespace: key[0], resource: key[1], policy: p})
+	}
+
+	slices.SortFunc(targets, func(a, b gcTarget) int {
+		return cmp.Or(cmp.Compare(a.namespace, b.namespace), cmp.Compare(a.resource, b.resource))
+	})
+	return targets
+}
+
+// Adds the versions of one resource selected by a policy to the plan.
+func planResource(ctx context.Context, reg Registry, plan *GCPlan, namespace, resource string, p *RetentionPolicy, now int64) error {
+	versions, err := reg.ListVersions(ctx, namespace, resource)
+	if err != nil {
+		return err
+	}
+	channels, err := channelTargets(ctx, reg, namespace, resource)
+	if err != nil {
+		return err
+	}
+
+	var unpublished []VersionSummary
+	for _, v := range versions.Versions {
+		if !v.Published() {
+			unpublished = append(unpublished, v)
+		}
+	}
+
+	// Newest first, so the first KeepUnpublished entries are the ones kept.
+	slices.SortFunc(unpublished, func(a, b VersionSummary) int {
+		return cmp.Or(cmp.Compare(b.CreatedAt, a.CreatedAt), cmp.Compare(b.String, a.String))
+	})
+
+	var selected []GCEntry
+	for i, v := range unpublished {
+		var reason GCReason
+		switch {
+		case p.KeepUnpublished != nil && i >= *p.KeepUnpublished:
+			reason = GCReasonKeepCount
+		case p.MaxUnpublishedAge != nil && now-v.UpdatedAt > *p.MaxUnpublishedAge:
+			reason = GCReasonMaxAge
+		default:
+			continue
+		}
+		selected = append(selected, GCEntry{
+			Namespace: namespace,
+			Resource:  resource,
+			Version:   v.String,
+			Reason:    reason,
+			UpdatedAt: v.UpdatedAt,
+		})
+	}
+
+	// Report oldest first, which reads naturally in a dry-run review.
+	slices.Reverse(selected)
+	for _, e := range selected {
+		if names := channels[e.Version]; len(names) > 0 {
+			e.Reason = GCReasonChannel
+			e.Channels = names
+			plan.Protected = append(plan.Protected, e)
+			continue
+		}
+		plan.Deletions = append(plan.Deletions, e)
+	}
+	return nil
+}
+
+// Maps each version of a resource to the channels pointing to it.
+func channelTargets(ctx context.Context, reg Registry, namespace, resource string) (map[string][]string, error) {
+	list, err := reg.ListChannels(ctx, namespace, resource)
+	if err != nil {
+		return nil, err
+	}
+	targets := make(map[string][]string, len(list.Channels))
+	for _, ch := range list.Channels {
+		targets[ch.Version] = append(targets[ch.Version], ch.Name)
+	}
+	return targets, nil
+}
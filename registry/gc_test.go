@@ -0,0 +1,251 @@
+package registry
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"slices"
+	"testing"
+)
+
+// In-memory registry serving the calls made by the GC engine.
+//
+// Embeds the interface so that only the methods under test need an
+// implementation; any other call panics.
+type gcRegistry struct {
+	Registry
+	resources map[string][]string
+	versions  map[[2]string][]VersionSummary
+	channels  map[[2]string][]ChannelSummary
+	deleted   []string
+	readErr   error // Returned by ReadVersion when set.
+}
+
+func (r *gcRegistry) ListResources(_ context.Context, ns string) (*ResourceList, error) {
+	list := &ResourceList{}
+	for _, name := range r.resources[ns] {
+		list.Resources = append(list.Resources, ResourceSummary{Name: name})
+	}
+	return list, nil
+}
+
+func (r *gcRegistry) ListVersions(_ context.Context, ns, res string) (*VersionList, error) {
+	return &VersionList{Versions: r.versions[[2]string{ns, res}]}, nil
+}
+
+func (r *gcRegistry) ListChannels(_ context.Context, ns, res string) (*ChannelList, error) {
+	return &ChannelList{Channels: r.channels[[2]string{ns, res}]}, nil
+}
+
+func (r *gcRegistry) ReadVersion(_ context.Context, ns, res, ver string) (*Version, error) {
+	if r.readErr != nil {
+		return nil, r.readErr
+	}
+	for _, v := range r.versions[[2]string{ns, res}] {
+		if v.String == ver {
+			return &Version{Namespace: ns, Resource: res, String: v.String, PublishedAt: v.PublishedAt}, nil
+		}
+	}
+	return nil, &Error{Code: ErrorCodeNotFound, Message: "version not found"}
+}
+
+func (r *gcRegistry) DeleteVersion(_ context.Context, ns, res, ver string) error {
+	r.deleted = append(r.deleted, ns+"/"+res+"@"+ver)
+	return nil
+}
+
+func ptr[T any](v T) *T { return &v }
+
+func newGCRegistry() *gcRegistry {
+	return &gcRegistry{
+		resources: map[string][]string{"acme": {"api", "web"}},
+		versions: map[[2]string][]VersionSummary{
+			{"acme", "api"}: {
+				{String: "1.0.0", CreatedAt: 100, UpdatedAt: 100, PublishedAt: ptr(int64(150))},
+				{String: "1.1.0-dev.1", CreatedAt: 200, UpdatedAt: 200},
+				{String: "1.1.0-dev.2", CreatedAt: 300, UpdatedAt: 300},
+				{String: "1.1.0-dev.3", CreatedAt: 400, UpdatedAt: 400},
+				{String: "1.1.0-dev.4", CreatedAt: 500, UpdatedAt: 950},
+			},
+			{"acme", "web"}: {
+				{String: "2.0.0-rc.1", CreatedAt: 100, UpdatedAt: 100},
+				{String: "2.0.0-rc.2", CreatedAt: 900, UpdatedAt: 900},
+			},
+		},
+		channels: map[[2]string][]ChannelSummary{
+			{"acme", "api"}: {{Name: "beta", Version: "1.1.0-dev.2"}},
+		},
+	}
+}
+
+func versionsOf(entries []GCEntry) []string {
+	var out []string
+	for _, e := range entries {
+		out = append(out, e.Resource+"@"+e.Version+":"+string(e.Reason))
+	}
+	return out
+}
+
+func TestPlanGCKeepCount(t *testing.T) {
+	reg := newGCRegistry()
+	plan, err := PlanGC(context.Background(), reg, []RetentionPolicy{
+		{Namespace: "acme", KeepUnpublished: ptr(1)},
+	}, 1000)
+	if err != nil {
+		t.Fatalf("PlanGC failed: %v", err)
+	}
+
+	wantDeletions := []string{
+		"api@1.1.0-dev.1:keep_count",
+		"api@1.1.0-dev.3:keep_count",
+		"web@2.0.0-rc.1:keep_count",
+	}
+	if got := versionsOf(plan.Deletions); !slices.Equal(got, wantDeletions) {
+		t.Errorf("Deletions = %v, want %v", got, wantDeletions)
+	}
+
+	wantProtected := []string{"api@1.1.0-dev.2:channel"}
+	if got := versionsOf(plan.Protected); !slices.Equal(got, wantProtected) {
+		t.Errorf("Protected = %v, want %v", got, wantProtected)
+	}
+	if !slices.Equal(plan.Protected[0].Channels, []string{"beta"}) {
+		t.Errorf("Protected channels = %v, want [beta]", plan.Protected[0].Channels)
+	}
+	if err := plan.Validate(); err != nil {
+		t.Errorf("plan invalid: %v", err)
+	}
+}
+
+func TestPlanGCResourceOverridesNamespace(t *testing.T) {
+	reg := newGCRegistry()
+	plan, err := PlanGC(context.Background(), reg, []RetentionPolicy{
+		{Namespace: "acme", KeepUnpublished: ptr(0)},
+		{Namespace: "acme", Resource: "api", MaxUnpublishedAge: ptr(int64(650))},
+	}, 1000)
+	if err != nil {
+		t.Fatalf("PlanGC failed: %v", err)
+	}
+
+	want := []string{
+		"api@1.1.0-dev.1:max_age",
+		"web@2.0.0-rc.1:keep_count",
+		"web@2.0.0-rc.2:keep_count",
+	}
+	if got := versionsOf(plan.Deletions); !slices.Equal(got, want) {
+		t.Errorf("Deletions = %v, want %v", got, want)
+	}
+}
+
+func TestPlanGCInvalidPolicy(t *testing.T) {
+	_, err := PlanGC(context.Background(), newGCRegistry(), []RetentionPolicy{{Namespace: "acme"}}, 1000)
+	if err == nil {
+		t.Fatal("expected error for policy without rules")
+	}
+}
+
+func TestApplyGCRechecks(t *testing.T) {
+	reg := newGCRegistry()
+	plan, err := PlanGC(context.Background(), reg, []RetentionPolicy{
+		{Namespace: "acme", Resource: "api", KeepUnpublished: ptr(0)},
+	}, 1000)
+	if err != nil {
+		t.Fatalf("PlanGC failed: %v", err)
+	}
+
+	// The registry changes between planning and applying.
+	api := reg.versions[[2]string{"acme", "api"}]
+	api[1].PublishedAt = ptr(int64(990))
+	reg.channels[[2]string{"acme", "api"}] = append(reg.channels[[2]string{"acme", "api"}], ChannelSummary{Name: "edge", Version: "1.1.0-dev.4"})
+
+	result, err := ApplyGC(context.Background(), reg, plan)
+	if err != nil {
+		t.Fatalf("ApplyGC failed: %v", err)
+	}
+
+	wantDeleted := []string{"acme/api@1.1.0-dev.3"}
+	if !slices.Equal(reg.deleted, wantDeleted) {
+		t.Errorf("deleted = %v, want %v", reg.deleted, wantDeleted)
+	}
+	wantSkipped := []string{"api@1.1.0-dev.1:published", "api@1.1.0-dev.4:channel"}
+	if got := versionsOf(result.Skipped); !slices.Equal(got, wantSkipped) {
+		t.Errorf("Skipped = %v, want %v", got, wantSkipped)
+	}
+}
+
+func TestApplyGCReadFailure(t *testing.T) {
+	reg := newGCRegistry()
+	plan, err := PlanGC(context.Background(), reg, []RetentionPolicy{
+		{Namespace: "acme", Resource: "api", KeepUnpublished: ptr(0)},
+	}, 1000)
+	if err != nil {
+		t.Fatalf("PlanGC failed: %v", err)
+	}
+
+	reg.readErr = &Error{Code: ErrorCodeInternalError, Message: "store unavailable"}
+	if _, err := ApplyGC(context.Background(), reg, plan); !errors.Is(err, ErrGCFailed) {
+		t.Fatalf("ApplyGC error = %v, want %v", err, ErrGCFailed)
+	}
+	if len(reg.deleted) != 0 {
+		t.Errorf("deleted %v after a failed read", reg.deleted)
+	}
+}
+
+func TestGCSweepsOrphanedBlobs(t *testing.T) {
+	ctx := context.Background()
+	meta := &memMetadataStore{versions: map[string]*Version{
+		"1.0.0": {Namespace: "acme", Resource: "clock", String: "1.0.0", CreatedAt: 1, UpdatedAt: 1},
+	}}
+	blobs := NewMemoryBlobStore()
+	reg := NewRegistry(meta, blobs, UploadLimits{})
+
+	data := buildArchive(t, map[string]string{
+		"crucible.yaml": "version: 0\nresource:\n  type: widget\n  name: acme/clock\n  version: 1.0.0\nmain: index.js\n",
+		"index.js":      "export {}",
+	})
+	v, err := reg.UploadArchive(ctx, "acme", "clock", "1.0.0", bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("UploadArchive failed: %v", err)
+	}
+
+	plan, err := PlanGC(ctx, reg, nil, 1000)
+	if err != nil {
+		t.Fatalf("PlanGC failed: %v", err)
+	}
+	if len(plan.Orphans) != 0 {
+		t.Fatalf("Orphans = %v, want none while referenced", plan.Orphans)
+	}
+
+	// Remove the version behind the registry's back, as namespace deletion
+	// does, leaving its blob unreferenced.
+	removed := meta.versions["1.0.0"]
+	delete(meta.versions, "1.0.0")
+	plan, err = PlanGC(ctx, reg, nil, 1000)
+	if err != nil {
+		t.Fatalf("PlanGC failed: %v", err)
+	}
+	if len(plan.Orphans) != 1 || plan.Orphans[0].Digest != *v.Digest || plan.Orphans[0].Size != int64(len(data)) {
+		t.Fatalf("Orphans = %v, want the archive blob", plan.Orphans)
+	}
+	if err := plan.Validate(); err != nil {
+		t.Fatalf("invalid plan: %v", err)
+	}
+
+	// A version refers to the blob again before the plan is applied.
+	meta.versions["1.0.0"] = removed
+	result, err := ApplyGC(ctx, reg, plan)
+	if err != nil {
+		t.Fatalf("ApplyGC failed: %v", err)
+	}
+	if len(result.Swept) != 0 || len(blobs.blobs) != 1 {
+		t.Fatalf("swept %v, a referenced blob", result.Swept)
+	}
+
+	delete(meta.versions, "1.0.0")
+	result, err = ApplyGC(ctx, reg, plan)
+	if err != nil {
+		t.Fatalf("ApplyGC failed: %v", err)
+	}
+	if len(result.Swept) != 1 || len(blobs.blobs) != 0 {
+		t.Fatalf("Swept = %v with %d blobs left, want the orphan deleted", result.Swept, len(blobs.blobs))
+	}
+}
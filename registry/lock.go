@@ -0,0 +1,46 @@
+package registry
+
+import "sync"
+
+// Set of mutexes keyed by name.
+//
+// A mutex exists only while some goroutine holds or waits for it, so the set
+// does not grow with the number of keys ever locked. The zero value is ready
+// to use.
+type keyedMutex struct {
+	mu    sync.Mutex
+	locks map[string]*keyedLock
+}
+
+// Mutex of a [keyedMutex] with the number of goroutines holding or waiting
+// for it.
+type keyedLock struct {
+	mu   sync.Mutex
+	refs int
+}
+
+// Locks the mutex for a key, returning the unlock function.
+func (k *keyedMutex) lock(key string) func() {
+	k.mu.Lock()
+	if k.locks == nil {
+		k.locks = make(map[string]*keyedLock)
+	}
+	l, ok := k.locks[key]
+	if !ok {
+		l = &keyedLock{}
+		k.locks[key] = l
+	}
+	l.refs++
+	k.mu.Unlock()
+
+	l.mu.Lock()
+	return func() {
+		l.mu.Unlock()
+
+		k.mu.Lock()
+		if l.refs--; l.refs == 0 {
+			delete(k.locks, key)
+		}
+		k.mu.Unlock()
+	}
+}
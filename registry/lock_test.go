@@ -0,0 +1,42 @@
+package registry
+
+import (
+	"runtime"
+	"sync"
+	"testing"
+)
+
+func TestKeyedMutex(t *testing.T) {
+	var k keyedMutex
+	var wg sync.WaitGroup
+	held := make(map[string]int)
+	var heldMu sync.Mutex
+
+	for i := 0; i < 100; i++ {
+		key := []string{"a", "b"}[i%2]
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			unlock := k.lock(key)
+			defer unlock()
+
+			heldMu.Lock()
+			held[key]++
+			if held[key] > 1 {
+				t.Errorf("key %s locked twice", key)
+			}
+			heldMu.Unlock()
+
+			runtime.Gosched()
+
+			heldMu.Lock()
+			held[key]--
+			heldMu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	if len(k.locks) != 0 {
+		t.Errorf("%d mutexes left after unlocking", len(k.locks))
+	}
+}
@@ -1,10 +1,31 @@
 package registry
 
+import (
+	"mime"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/cruciblehq/crex"
+)
+
 // String identifier for HTTP Content-Type and Accept headers.
 //
 // Defines vendor-specific media types for the Crucible registry API following
-// the pattern application/vnd.crucible.{name}.v0. Used in Content-Type headers
-// for request bodies and Accept headers for response format negotiation.
+// the pattern application/vnd.crucible.{name}.v{version}. Used in Content-Type
+// headers for request bodies and Accept headers for response format
+// negotiation (see [Negotiate]).
+//
+// Every type starts at version 0. A field may be added to an existing version
+// only if it is omitted from the JSON when empty, so that payloads of that
+// version without it are unchanged (e.g., [Error.Details] and
+// [ChannelInfo.Reason]). Any other change to the wire shape, including a
+// field that is always sent, introduces a new version, and so does a change
+// to a nested type: [Channel] embeds [Version], and [Resource] and
+// [Namespace] list summaries that gained fields. Older versions keep being
+// served through codecs registered with [ConvertCodec], which down-convert
+// the current type by dropping the fields the older shape lacks and
+// up-convert by leaving them empty (see [VersionToV0] and [VersionFromV0]).
 type MediaType string
 
 const (
@@ -21,5 +42,204 @@ const (
 	MediaTypeChannelInfo   MediaType = "application/vnd.crucible.channel-info.v0"   // Channel create/update requests.
 	MediaTypeChannel       MediaType = "application/vnd.crucible.channel.v0"        // Complete channel with full version object.
 	MediaTypeChannelList   MediaType = "application/vnd.crucible.channel-list.v0"   // Collection of channel summaries.
+	MediaTypeMemberInfo    MediaType = "application/vnd.crucible.member-info.v0"    // Member create/update requests.
+	MediaTypeMember        MediaType = "application/vnd.crucible.member.v0"         // Namespace membership with role.
+	MediaTypeMemberList    MediaType = "application/vnd.crucible.member-list.v0"    // Collection of namespace members.
+	MediaTypeTokenInfo     MediaType = "application/vnd.crucible.token-info.v0"     // Token create requests.
+	MediaTypeToken         MediaType = "application/vnd.crucible.token.v0"          // Created token including its secret.
+	MediaTypeTokenList     MediaType = "application/vnd.crucible.token-list.v0"     // Collection of token summaries.
+	MediaTypeDependentList MediaType = "application/vnd.crucible.dependent-list.v0" // Collection of versions depending on a resource.
 	MediaTypeArchive       MediaType = "application/vnd.crucible.archive.v0"        // Binary archive data (tar.zst format).
+	MediaTypeUploadInfo    MediaType = "application/vnd.crucible.upload-info.v0"    // Upload session start requests.
+	MediaTypeUpload        MediaType = "application/vnd.crucible.upload.v0"         // Upload session with committed offset.
+	MediaTypeUploadChunk   MediaType = "application/vnd.crucible.upload-chunk.v0"   // Binary chunk of an archive upload.
+	MediaTypeRename        MediaType = "application/vnd.crucible.rename.v0"         // Namespace or resource rename requests.
+	MediaTypeTransfer      MediaType = "application/vnd.crucible.transfer.v0"       // Resource transfer requests.
+	MediaTypeRedirect      MediaType = "application/vnd.crucible.redirect.v0"       // Old path of a moved namespace or resource.
+	MediaTypeRedirectList  MediaType = "application/vnd.crucible.redirect-list.v0"  // Collection of redirects in a namespace.
+
+	MediaTypeChannelHistory      MediaType = "application/vnd.crucible.channel-history.v0"       // Append-only history of channel moves.
+	MediaTypeChannelRollback     MediaType = "application/vnd.crucible.channel-rollback.v0"      // Channel rollback requests.
+	MediaTypeRetentionPolicy     MediaType = "application/vnd.crucible.retention-policy.v0"      // Retention rule for unpublished versions.
+	MediaTypeRetentionPolicyList MediaType = "application/vnd.crucible.retention-policy-list.v0" // Collection of retention policies.
+	MediaTypeGCPlan              MediaType = "application/vnd.crucible.gc-plan.v0"               // Dry-run garbage collection plan.
+	MediaTypeGCResult            MediaType = "application/vnd.crucible.gc-result.v0"             // Outcome of applying a garbage collection plan.
+
+	MediaTypeNamespaceV1    MediaType = "application/vnd.crucible.namespace.v1"     // Complete namespace with resource summaries and their metadata.
+	MediaTypeResourceInfoV1 MediaType = "application/vnd.crucible.resource-info.v1" // Resource create/update requests with metadata.
+	MediaTypeResourceV1     MediaType = "application/vnd.crucible.resource.v1"      // Complete resource with metadata and version/channel summaries.
+	MediaTypeResourceListV1 MediaType = "application/vnd.crucible.resource-list.v1" // Collection of resource summaries with metadata.
+	MediaTypeVersionInfoV1  MediaType = "application/vnd.crucible.version-info.v1"  // Version create/update requests with metadata.
+	MediaTypeVersionV1      MediaType = "application/vnd.crucible.version.v1"       // Complete version with metadata, dependencies, and publication time.
+	MediaTypeVersionListV1  MediaType = "application/vnd.crucible.version-list.v1"  // Collection of version summaries with metadata and publication time.
+	MediaTypeChannelV1      MediaType = "application/vnd.crucible.channel.v1"       // Complete channel with a v1 version object.
 )
+
+// Pattern of Crucible media types, capturing the name and version.
+var mediaTypePattern = regexp.MustCompile(`^application/vnd\.crucible\.([a-z0-9]+(?:-[a-z0-9]+)*)\.v(0|[1-9][0-9]*)$`)
+
+// Builds the Crucible media type for a type name and format version.
+func NewMediaType(name string, version int) MediaType {
+	return MediaType("application/vnd.crucible." + name + ".v" + strconv.Itoa(version))
+}
+
+// Type name of a Crucible media type (e.g., "version").
+//
+// Returns an empty string for other media types. Parameters are ignored.
+func (m MediaType) Name() string {
+	p, err := ParseMediaType(string(m))
+	if err != nil {
+		return ""
+	}
+	return p.Name
+}
+
+// Format version of a Crucible media type.
+//
+// Returns 0 for other media types. Parameters are ignored.
+func (m MediaType) Version() int {
+	p, err := ParseMediaType(string(m))
+	if err != nil {
+		return 0
+	}
+	return p.Version
+}
+
+// Components of a media type string.
+type ParsedMediaType struct {
+	Type    string            // Type and subtype in lowercase, without parameters.
+	Name    string            // Type name for Crucible media types (e.g., "version"), empty otherwise.
+	Version int               // Format version for Crucible media types.
+	Params  map[string]string // Parameters, keyed by lowercase name.
+}
+
+// Media type without parameters.
+func (p *ParsedMediaType) MediaType() MediaType {
+	return MediaType(p.Type)
+}
+
+// Whether the media type is a Crucible media type.
+func (p *ParsedMediaType) Crucible() bool {
+	return p.Name != ""
+}
+
+// Parses a media type string, such as a Content-Type header value.
+//
+// Accepts any media type as defined by RFC 2045, including ranges such as
+// "application/*" that appear in Accept headers. Name and Version are set
+// when the type follows the Crucible pattern. Returns [ErrMediaTypeInvalid]
+// if the string is malformed.
+func ParseMediaType(s string) (*ParsedMediaType, error) {
+	typ, params, err := mime.ParseMediaType(s)
+	if err != nil {
+		return nil, crex.Wrap(ErrMediaTypeInvalid, err)
+	}
+	if !strings.Contains(typ, "/") {
+		return nil, ErrMediaTypeInvalid
+	}
+
+	p := &ParsedMediaType{Type: typ, Params: params}
+	if m := mediaTypePattern.FindStringSubmatch(typ); m != nil {
+		version, err := strconv.Atoi(m[2])
+		if err != nil {
+			return nil, crex.Wrap(ErrMediaTypeInvalid, err)
+		}
+		p.Name = m[1]
+		p.Version = version
+	}
+	return p, nil
+}
+
+// Media range of an Accept header with its quality.
+type acceptRange struct {
+	typ     string  // Media range without parameters (e.g., "application/*").
+	quality float64 // Quality value between 0 and 1.
+}
+
+// Specificity of the range's match against a media type.
+//
+// Returns 3 for an exact match, 2 for a subtype wildcard, 1 for "*/*", and 0
+// if the range does not match.
+func (r acceptRange) match(typ string) int {
+	switch {
+	case r.typ == typ:
+		return 3
+	case r.typ == "*/*":
+		return 1
+	case strings.HasSuffix(r.typ, "/*") && strings.HasPrefix(typ, strings.TrimSuffix(r.typ, "*")):
+		return 2
+	}
+	return 0
+}
+
+// Parses the media ranges of an Accept header.
+//
+// Malformed ranges and quality values are skipped rather than rejected, as
+// recommended for HTTP servers.
+func parseAccept(accept string) []acceptRange {
+	var ranges []acceptRange
+	for _, part := range strings.Split(accept, ",") {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+		p, err := ParseMediaType(part)
+		if err != nil {
+			continue
+		}
+		quality := 1.0
+		if q, ok := p.Params["q"]; ok {
+			quality, err = strconv.ParseFloat(q, 64)
+			if err != nil || quality < 0 || quality > 1 {
+				continue
+			}
+		}
+		ranges = append(ranges, acceptRange{typ: p.Type, quality: quality})
+	}
+	return ranges
+}
+
+// Selects the media type of a response from an Accept header.
+//
+// Offers lists the media types the server can produce, in order of
+// preference; typically the registered versions of a type (see
+// [MediaTypeVersions]). Each offer takes the quality of the most specific
+// range that matches it, and the offer with the highest quality wins, ties
+// going to the earlier offer. An empty or absent Accept header accepts the
+// first offer. Returns an [*Error] with [ErrorCodeNotAcceptable] if no offer
+// is acceptable.
+func Negotiate(accept string, offers ...MediaType) (MediaType, error) {
+	if strings.TrimSpace(accept) == "" && len(offers) > 0 {
+		return offers[0], nil
+	}
+
+	ranges := parseAccept(accept)
+	best, bestQuality := MediaType(""), 0.0
+	for _, offer := range offers {
+		specificity, quality := 0, 0.0
+		for _, r := range ranges {
+			if s := r.match(string(offer)); s > specificity {
+				specificity, quality = s, r.quality
+			}
+		}
+		if quality > bestQuality {
+			best, bestQuality = offer, quality
+		}
+	}
+
+	if best == "" {
+		return "", notAcceptable(offers)
+	}
+	return best, nil
+}
+
+// Builds the error returned when no offered media type is acceptable.
+func notAcceptable(offers []MediaType) *Error {
+	names := make([]string, len(offers))
+	for i, offer := range offers {
+		names[i] = string(offer)
+	}
+	return &Error{
+		Code:    ErrorCodeNotAcceptable,
+		Message: "acceptable media types: " + strings.Join(names, ", "),
+	}
+}
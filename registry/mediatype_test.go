@@ -0,0 +1,264 @@
+package registry
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func validVersion() Version {
+	return Version{
+		Namespace: "acme",
+		Resource:  "api",
+		String:    "1.0.0",
+		Archive:   ptr("https://registry.example/acme/api/1.0.0/archive"),
+		Size:      ptr(int64(1024)),
+		Digest:    ptr("sha256:" + strings.Repeat("a", 64)),
+		CreatedAt: 100,
+		UpdatedAt: 200,
+	}
+}
+
+func TestParseMediaType(t *testing.T) {
+	tests := []struct {
+		input   string
+		typ     string
+		name    string
+		version int
+		wantErr bool
+	}{
+		{"application/vnd.crucible.version.v1", "application/vnd.crucible.version.v1", "version", 1, false},
+		{"Application/VND.Crucible.Namespace-List.v0; charset=utf-8", "application/vnd.crucible.namespace-list.v0", "namespace-list", 0, false},
+		{"application/json", "application/json", "", 0, false},
+		{"application/vnd.crucible.version.v01", "application/vnd.crucible.version.v01", "", 0, false},
+		{"application/*", "application/*", "", 0, false},
+		{"", "", "", 0, true},
+		{"application", "", "", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			p, err := ParseMediaType(tt.input)
+			if tt.wantErr {
+				if !errors.Is(err, ErrMediaTypeInvalid) {
+					t.Fatalf("got %v, want ErrMediaTypeInvalid", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if p.Type != tt.typ || p.Name != tt.name || p.Version != tt.version {
+				t.Fatalf("got (%q, %q, %d), want (%q, %q, %d)", p.Type, p.Name, p.Version, tt.typ, tt.name, tt.version)
+			}
+		})
+	}
+}
+
+func TestNewMediaType(t *testing.T) {
+	if got := NewMediaType("version", 1); got != MediaTypeVersionV1 {
+		t.Fatalf("got %q, want %q", got, MediaTypeVersionV1)
+	}
+	if MediaTypeVersion.Name() != "version" || MediaTypeVersion.Version() != 0 {
+		t.Fatalf("unexpected components of %q", MediaTypeVersion)
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	offers := []MediaType{MediaTypeVersionV1, MediaTypeVersion}
+
+	tests := []struct {
+		name   string
+		accept string
+		want   MediaType
+	}{
+		{"absent", "", MediaTypeVersionV1},
+		{"v0 client", "application/vnd.crucible.version.v0", MediaTypeVersion},
+		{"v1 client", "application/vnd.crucible.version.v1", MediaTypeVersionV1},
+		{"wildcard", "*/*", MediaTypeVersionV1},
+		{"quality", "application/vnd.crucible.version.v1;q=0.5, application/vnd.crucible.version.v0", MediaTypeVersion},
+		{"specific beats wildcard", "application/*;q=0.9, application/vnd.crucible.version.v1;q=0", MediaTypeVersion},
+		{"malformed ranges skipped", "garbage, application/vnd.crucible.version.v0;q=x, application/vnd.crucible.version.v0", MediaTypeVersion},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Negotiate(tt.accept, offers...)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNegotiateNotAcceptable(t *testing.T) {
+	_, err := Negotiate("application/json", MediaTypeVersionV1, MediaTypeVersion)
+	if !errors.Is(err, ErrNotAcceptable) {
+		t.Fatalf("got %v, want ErrNotAcceptable", err)
+	}
+	if StatusFor(AsError(err).Code) != http.StatusNotAcceptable {
+		t.Fatalf("not acceptable does not map to 406")
+	}
+}
+
+func TestMediaTypeVersions(t *testing.T) {
+	got := MediaTypeVersions("version")
+	if len(got) != 2 || got[0] != MediaTypeVersionV1 || got[1] != MediaTypeVersion {
+		t.Fatalf("got %v", got)
+	}
+	if got := MediaTypeVersions("unknown"); got != nil {
+		t.Fatalf("got %v, want nil", got)
+	}
+}
+
+func TestEncodeAsVersionV0(t *testing.T) {
+	v := validVersion()
+	v.Metadata = Metadata{License: "MIT"}
+	v.PublishedAt = ptr(v.CreatedAt)
+
+	data, err := EncodeAs(MediaTypeVersion, &v)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var fields map[string]any
+	if err := json.Unmarshal(data, &fields); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"metadata", "dependencies", "publishedAt"} {
+		if _, ok := fields[key]; ok {
+			t.Errorf("v0 encoding contains %q", key)
+		}
+	}
+	if fields["digest"] != *v.Digest {
+		t.Errorf("digest = %v, want %q", fields["digest"], *v.Digest)
+	}
+
+	decoded, err := DecodeAs[Version](MediaTypeVersion, data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if decoded.String != v.String || decoded.Published() || decoded.Metadata.License != "" {
+		t.Fatalf("unexpected round trip: %+v", decoded)
+	}
+}
+
+func TestEncodeAsChannelV0(t *testing.T) {
+	v := validVersion()
+	v.Metadata = Metadata{License: "MIT"}
+	ch := Channel{Namespace: "acme", Resource: "clock", Name: "stable", Version: v, CreatedAt: 1, UpdatedAt: 1}
+
+	data, err := EncodeAs(MediaTypeChannel, &ch)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var fields struct {
+		Version map[string]any `json:"version"`
+	}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"metadata", "dependencies", "publishedAt"} {
+		if _, ok := fields.Version[key]; ok {
+			t.Errorf("v0 channel version contains %q", key)
+		}
+	}
+
+	decoded, err := DecodeAs[Channel](MediaTypeChannel, data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if decoded.Version.String != v.String || decoded.Version.Metadata.License != "" {
+		t.Fatalf("unexpected round trip: %+v", decoded)
+	}
+}
+
+func TestEncodeAsResourceV0(t *testing.T) {
+	r := Resource{
+		Namespace: "acme", Name: "clock", Type: "widget", Metadata: Metadata{License: "MIT"},
+		Versions:  []VersionSummary{{String: "1.0.0", Metadata: Metadata{License: "MIT"}, PublishedAt: ptr(int64(1)), CreatedAt: 1, UpdatedAt: 1}},
+		CreatedAt: 1, UpdatedAt: 1,
+	}
+
+	data, err := EncodeAs(MediaTypeResource, &r)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var fields struct {
+		Metadata *Metadata        `json:"metadata"`
+		Versions []map[string]any `json:"versions"`
+	}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		t.Fatal(err)
+	}
+	if fields.Metadata != nil {
+		t.Error("v0 resource contains metadata")
+	}
+	for _, key := range []string{"metadata", "publishedAt"} {
+		if _, ok := fields.Versions[0][key]; ok {
+			t.Errorf("v0 version summary contains %q", key)
+		}
+	}
+
+	data, err = EncodeAs(MediaTypeResourceV1, &r)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	decoded, err := DecodeAs[Resource](MediaTypeResourceV1, data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if decoded.Metadata.License != "MIT" || !decoded.Versions[0].Published() {
+		t.Fatalf("fields lost in v1 round trip: %+v", decoded)
+	}
+}
+
+func TestMediaTypeVersionsChanged(t *testing.T) {
+	for _, name := range []string{"namespace", "resource-info", "resource", "resource-list", "version-info", "version", "version-list", "channel"} {
+		if got := MediaTypeVersions(name); len(got) != 2 || got[0].Version() != 1 {
+			t.Errorf("MediaTypeVersions(%q) = %v, want v1 and v0", name, got)
+		}
+	}
+}
+
+func TestEncodeAsVersionV1(t *testing.T) {
+	v := validVersion()
+	v.Metadata = Metadata{License: "MIT"}
+
+	data, err := EncodeAs(MediaTypeVersionV1+"; charset=utf-8", &v)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	decoded, err := DecodeAs[Version](MediaTypeVersionV1, data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if decoded.Metadata.License != "MIT" {
+		t.Fatalf("metadata lost in v1 round trip")
+	}
+}
+
+func TestCodecErrors(t *testing.T) {
+	v := validVersion()
+
+	if _, err := EncodeAs("application/vnd.crucible.version.v9", &v); !errors.Is(err, ErrNotAcceptable) {
+		t.Errorf("unknown encode media type: got %v", err)
+	}
+	if _, err := DecodeAs[Version]("application/json", []byte("{}")); !errors.Is(err, ErrUnsupportedMediaType) {
+		t.Errorf("unknown decode media type: got %v", err)
+	}
+	if _, err := EncodeAs(MediaTypeNamespace, &v); !errors.Is(err, ErrCodecType) {
+		t.Errorf("wrong encode type: got %v", err)
+	}
+	data, err := EncodeAs(MediaTypeVersionV1, &v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := DecodeAs[Namespace](MediaTypeVersionV1, data); !errors.Is(err, ErrCodecType) {
+		t.Errorf("wrong decode type: got %v", err)
+	}
+}
@@ -0,0 +1,76 @@
+package registry
+
+import "github.com/cruciblehq/crex"
+
+// Mutable properties of a namespace member for creation or update.
+//
+// Used as the request body for member creation and update operations. The user
+// is the identifier of the account being granted access and cannot be changed
+// once the membership exists. For update requests, User must match the URL
+// path parameter or update context. The media type is [MediaTypeMemberInfo].
+type MemberInfo struct {
+	User string `json:"user"` // User name.
+	Role Role   `json:"role"` // Role granted within the namespace.
+}
+
+// Validates the member info.
+//
+// The user must conform to the registry naming rules (see [ValidateName]) and
+// the role must be a known [Role].
+func (info *MemberInfo) Validate() error {
+	if err := ValidateName(info.User); err != nil {
+		return crex.Wrap(ErrInvalidMember, fieldError("user", err))
+	}
+	if err := ValidateRole(info.Role); err != nil {
+		return crex.Wrap(ErrInvalidMember, fieldError("role", err))
+	}
+	return nil
+}
+
+// Membership of a user in a namespace.
+//
+// Grants the user the permissions of its [Role] on the namespace and on every
+// resource it contains. Every namespace has at least one owner. Includes
+// scoping information to identify the membership's location. The media type
+// is [MediaTypeMember].
+type Member struct {
+	Namespace string `json:"namespace"` // Namespace this membership belongs to.
+	User      string `json:"user"`      // User name.
+	Role      Role   `json:"role"`      // Role granted within the namespace.
+	CreatedAt int64  `json:"createdAt"` // When the membership was created.
+	UpdatedAt int64  `json:"updatedAt"` // When the membership was last updated.
+}
+
+// Validates the member.
+func (m *Member) Validate() error {
+	if err := ValidateName(m.Namespace); err != nil {
+		return crex.Wrap(ErrInvalidMember, fieldError("namespace", err))
+	}
+	if err := ValidateName(m.User); err != nil {
+		return crex.Wrap(ErrInvalidMember, fieldError("user", err))
+	}
+	if err := ValidateRole(m.Role); err != nil {
+		return crex.Wrap(ErrInvalidMember, fieldError("role", err))
+	}
+	if err := ValidateTimestamps(m.CreatedAt, m.UpdatedAt); err != nil {
+		return crex.Wrap(ErrInvalidMember, fieldError("updatedAt", err))
+	}
+	return nil
+}
+
+// Collection of namespace members.
+//
+// The media type is [MediaTypeMemberList].
+type MemberList struct {
+	Members []Member `json:"members"` // List of members.
+}
+
+// Validates the member list.
+func (l *MemberList) Validate() error {
+	for i := range l.Members {
+		if err := l.Members[i].Validate(); err != nil {
+			return crex.Wrap(ErrInvalidMember, fieldError(indexField("members", i), err))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,191 @@
+package registry
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/cruciblehq/crex"
+	"github.com/cruciblehq/spec/reference"
+)
+
+// [BlobStore] that keeps blobs in memory.
+//
+// Intended for tests and short-lived tools. Content is lost when the store is
+// discarded. Use [NewMemoryBlobStore] to construct one.
+type MemoryBlobStore struct {
+	mu      sync.RWMutex
+	blobs   map[string][]byte
+	staging map[string][]byte
+}
+
+// Creates an empty in-memory blob store.
+func NewMemoryBlobStore() *MemoryBlobStore {
+	return &MemoryBlobStore{
+		blobs:   make(map[string][]byte),
+		staging: make(map[string][]byte),
+	}
+}
+
+// Implements [BlobStore.Put].
+func (s *MemoryBlobStore) Put(ctx context.Context, r io.Reader) (*BlobInfo, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, crex.Wrap(ErrBlobWriteFailed, err)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.store(data), nil
+}
+
+// Implements [BlobStore.Get].
+func (s *MemoryBlobStore) Get(ctx context.Context, digest reference.Digest) (io.ReadCloser, error) {
+	return s.GetRange(ctx, digest, 0, -1)
+}
+
+// Implements [BlobStore.GetRange].
+//
+// The returned reader shares the stored bytes, which are never modified.
+func (s *MemoryBlobStore) GetRange(ctx context.Context, digest reference.Digest, offset, length int64) (io.ReadCloser, error) {
+	data, err := s.lookup(digest)
+	if err != nil {
+		return nil, err
+	}
+	n, err := clampRange(int64(len(data)), offset, length)
+	if err != nil {
+		return nil, err
+	}
+	return io.NopCloser(bytes.NewReader(data[offset : offset+n])), nil
+}
+
+// Implements [BlobStore.Stat].
+func (s *MemoryBlobStore) Stat(ctx context.Context, digest reference.Digest) (*BlobInfo, error) {
+	data, err := s.lookup(digest)
+	if err != nil {
+		return nil, err
+	}
+	return &BlobInfo{Digest: digest, Size: int64(len(data))}, nil
+}
+
+// Implements [BlobStore.Delete].
+func (s *MemoryBlobStore) Delete(ctx context.Context, digest reference.Digest) error {
+	if err := ValidateBlobDigest(digest); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.blobs, digest.String())
+	return nil
+}
+
+// Implements [BlobStore.List].
+func (s *MemoryBlobStore) List(ctx context.Context) ([]BlobInfo, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	infos := make([]BlobInfo, 0, len(s.blobs))
+	for key, data := range s.blobs {
+		digest, err := reference.ParseDigest(key)
+		if err != nil {
+			return nil, crex.Wrap(ErrBlobReadFailed, err)
+		}
+		infos = append(infos, BlobInfo{Digest: *digest, Size: int64(len(data))})
+	}
+	slices.SortFunc(infos, func(a, b BlobInfo) int {
+		return strings.Compare(a.Digest.String(), b.Digest.String())
+	})
+	return infos, nil
+}
+
+// Returns the content of a blob.
+func (s *MemoryBlobStore) lookup(digest reference.Digest) ([]byte, error) {
+	if err := ValidateBlobDigest(digest); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	data, ok := s.blobs[digest.String()]
+	if !ok {
+		return nil, blobNotFound(digest)
+	}
+	return data, nil
+}
+
+// Implements [BlobStore.Append].
+//
+// The chunk is read before the store is locked, so concurrent appends to the
+// same upload are resolved by whichever finishes reading first.
+func (s *MemoryBlobStore) Append(ctx context.Context, upload string, offset int64, r io.Reader) (int64, error) {
+	if err := ValidateUploadID(upload); err != nil {
+		return 0, err
+	}
+	data, readErr := io.ReadAll(r)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	staged := s.staging[upload]
+	if int64(len(staged)) != offset {
+		return int64(len(staged)), offsetMismatch(int64(len(staged)))
+	}
+	s.staging[upload] = append(staged, data...)
+	size := int64(len(s.staging[upload]))
+	if readErr != nil {
+		return size, crex.Wrap(ErrBlobWriteFailed, readErr)
+	}
+	return size, nil
+}
+
+// Implements [BlobStore.StagedSize].
+func (s *MemoryBlobStore) StagedSize(ctx context.Context, upload string) (int64, error) {
+	if err := ValidateUploadID(upload); err != nil {
+		return 0, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return int64(len(s.staging[upload])), nil
+}
+
+// Implements [BlobStore.Commit].
+func (s *MemoryBlobStore) Commit(ctx context.Context, upload string) (*BlobInfo, error) {
+	if err := ValidateUploadID(upload); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, ok := s.staging[upload]
+	if !ok {
+		return nil, uploadNotFound(upload)
+	}
+	delete(s.staging, upload)
+	return s.store(data), nil
+}
+
+// Implements [BlobStore.Abort].
+func (s *MemoryBlobStore) Abort(ctx context.Context, upload string) error {
+	if err := ValidateUploadID(upload); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.staging, upload)
+	return nil
+}
+
+// Stores content as a blob unless it already exists.
+//
+// The caller must hold the write lock.
+func (s *MemoryBlobStore) store(data []byte) *BlobInfo {
+	h := newBlobHash()
+	h.Write(data)
+	info := h.info()
+	if _, ok := s.blobs[info.Digest.String()]; !ok {
+		s.blobs[info.Digest.String()] = data
+	}
+	return info
+}
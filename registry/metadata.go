@@ -0,0 +1,175 @@
+package registry
+
+import (
+	"archive/tar"
+	"io"
+	"maps"
+	"strings"
+
+	"github.com/cruciblehq/crex"
+	"github.com/cruciblehq/spec/manifest"
+)
+
+// Descriptive metadata attached to resources and versions.
+//
+// Used by the hub UI and search. All fields are optional. Resource metadata is
+// set by clients through [ResourceInfo]. Version metadata is extracted from the
+// archive's manifest when the archive is uploaded (see [MetadataFromManifest])
+// and may be supplemented through [VersionInfo]. Summary types carry the same
+// metadata with Readme left empty to keep list payloads compact.
+type Metadata struct {
+	Labels   map[string]string `json:"labels"`   // Key/value labels (see [ValidateLabels]).
+	Readme   string            `json:"readme"`   // Markdown readme (empty in summaries).
+	License  string            `json:"license"`  // SPDX license expression (e.g., "MIT OR Apache-2.0").
+	Source   string            `json:"source"`   // Source repository URL.
+	Homepage string            `json:"homepage"` // Home page URL.
+	Keywords []string          `json:"keywords"` // Search keywords.
+}
+
+// Validates the metadata.
+//
+// Labels must satisfy [ValidateLabels], the readme must not exceed the size
+// limit, the license must be a valid SPDX expression, URLs must be absolute
+// http or https URLs, and keywords must satisfy [ValidateKeywords].
+func (m *Metadata) Validate() error {
+	if err := ValidateLabels(m.Labels); err != nil {
+		return crex.Wrap(ErrInvalidMetadata, fieldError("labels", err))
+	}
+	if err := ValidateReadme(m.Readme); err != nil {
+		return crex.Wrap(ErrInvalidMetadata, fieldError("readme", err))
+	}
+	if err := ValidateLicense(m.License); err != nil {
+		return crex.Wrap(ErrInvalidMetadata, fieldError("license", err))
+	}
+	if err := ValidateURL(m.Source); err != nil {
+		return crex.Wrap(ErrInvalidMetadata, fieldError("source", err))
+	}
+	if err := ValidateURL(m.Homepage); err != nil {
+		return crex.Wrap(ErrInvalidMetadata, fieldError("homepage", err))
+	}
+	if err := ValidateKeywords(m.Keywords); err != nil {
+		return crex.Wrap(ErrInvalidMetadata, fieldError("keywords", err))
+	}
+	return nil
+}
+
+// Returns m with the non-empty fields of o applied on top.
+//
+// Scalar fields and keywords of o replace those of m when set. Labels are
+// merged key by key, with o taking precedence. Used to combine metadata
+// extracted from an archive with metadata supplied by the client.
+func (m Metadata) Overlay(o Metadata) Metadata {
+	if len(o.Labels) > 0 {
+		labels := make(map[string]string, len(m.Labels)+len(o.Labels))
+		maps.Copy(labels, m.Labels)
+		maps.Copy(labels, o.Labels)
+		m.Labels = labels
+	}
+	if o.Readme != "" {
+		m.Readme = o.Readme
+	}
+	if o.License != "" {
+		m.License = o.License
+	}
+	if o.Source != "" {
+		m.Source = o.Source
+	}
+	if o.Homepage != "" {
+		m.Homepage = o.Homepage
+	}
+	if len(o.Keywords) > 0 {
+		m.Keywords = o.Keywords
+	}
+	return m
+}
+
+// Returns the metadata with the readme removed, for use in summary types.
+func (m Metadata) Summary() Metadata {
+	m.Readme = ""
+	return m
+}
+
+// Reads and decodes the manifest from a resource archive.
+//
+// Scans the tar stream for [manifest.ManifestFile] at the archive root, with
+// or without a leading "./", and decodes it with [manifest.Decode], which
+// validates it. The manifest is read into memory, so one larger than 1 MiB
+// is rejected with [ErrManifestTooLong] before it is read. Returns
+// [ErrManifestMissing] if the archive has no manifest. The tar reader is
+// advanced past the manifest entry.
+func ReadArchiveManifest(tr *tar.Reader) (*manifest.Manifest, error) {
+	header, err := tr.Next()
+	for err == nil && strings.TrimPrefix(header.Name, "./") != manifest.ManifestFile {
+		header, err = tr.Next()
+	}
+	if err == io.EOF {
+		return nil, ErrManifestMissing
+	}
+	if err != nil {
+		return nil, crex.Wrap(ErrManifestInvalid, err)
+	}
+	if header.Size > maxManifestLen {
+		return nil, crex.Wrap(ErrManifestInvalid, ErrManifestTooLong)
+	}
+
+	data, err := io.ReadAll(tr)
+	if err != nil {
+		return nil, crex.Wrap(ErrManifestInvalid, err)
+	}
+
+	m, err := manifest.Decode(data)
+	if err != nil {
+		return nil, crex.Wrap(ErrManifestInvalid, err)
+	}
+	return m, nil
+}
+
+// Checks that a manifest describes the version it is uploaded to.
+//
+// The manifest's qualified name must be the resource's namespace and name,
+// compared without regard to case, its type the resource's type, and its
+// version the version string. Returns [ErrManifestTarget] wrapping
+// [ErrManifestName], [ErrManifestType], or [ErrManifestVersion] otherwise.
+func checkManifestTarget(m *manifest.Manifest, res *Resource, version string) error {
+	r := &m.Resource
+	if !strings.EqualFold(r.Name, res.Namespace+"/"+res.Name) {
+		return crex.Wrap(ErrManifestTarget, ErrManifestName)
+	}
+	if string(r.Type) != res.Type {
+		return crex.Wrap(ErrManifestTarget, ErrManifestType)
+	}
+	if r.Version != version {
+		return crex.Wrap(ErrManifestTarget, ErrManifestVersion)
+	}
+	return nil
+}
+
+// Extracts version metadata from a manifest.
+//
+// Copies the license, homepage, source, keywords, and labels declared in the
+// manifest's resource section. The manifest does not carry a readme, so the
+// readme is left empty. The result is not validated; callers storing it
+// should call [Metadata.Validate].
+func MetadataFromManifest(m *manifest.Manifest) Metadata {
+	r := &m.Resource
+	return Metadata{
+		Labels:   maps.Clone(r.Labels),
+		License:  r.License,
+		Source:   r.Source,
+		Homepage: r.Homepage,
+		Keywords: append([]string(nil), r.Keywords...),
+	}
+}
+
+// Extracts version metadata from a resource archive.
+//
+// Combines [ReadArchiveManifest] and [MetadataFromManifest]. The tar reader
+// must read the decompressed archive stream.
+func MetadataFromArchive(tr *tar.Reader) (*Metadata, error) {
+	m, err := ReadArchiveManifest(tr)
+	if err != nil {
+		return nil, err
+	}
+	md := MetadataFromManifest(m)
+	return &md, nil
+}
@@ -0,0 +1,231 @@
+package registry
+
+import (
+	"archive/tar"
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestValidateLabelKey(t *testing.T) {
+	tests := []struct {
+		key     string
+		wantErr bool
+	}{
+		{"tier", false},
+		{"app.kubernetes_io-name", false},
+		{"crucible.dev/tier", false},
+		{"a/b", false},
+		{"", true},
+		{"Tier", true},
+		{"-tier", true},
+		{"tier-", true},
+		{"/tier", true},
+		{"crucible.dev/", true},
+		{"crucible..dev/tier", true},
+		{"a/b/c", true},
+		{strings.Repeat("a", 64), true},
+		{strings.Repeat("a", 63), false},
+	}
+	for _, tt := range tests {
+		err := ValidateLabelKey(tt.key)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ValidateLabelKey(%q) error = %v, wantErr %v", tt.key, err, tt.wantErr)
+		}
+	}
+}
+
+func TestValidateLabels(t *testing.T) {
+	if err := ValidateLabels(map[string]string{"tier": strings.Repeat("x", 257)}); !errors.Is(err, ErrLabelValueTooLong) {
+		t.Errorf("expected ErrLabelValueTooLong, got %v", err)
+	}
+
+	many := make(map[string]string)
+	for i := 0; i <= maxLabels; i++ {
+		many[strings.Repeat("a", i+1)] = ""
+	}
+	if err := ValidateLabels(many); !errors.Is(err, ErrTooManyLabels) {
+		t.Errorf("expected ErrTooManyLabels, got %v", err)
+	}
+}
+
+func TestValidateLicense(t *testing.T) {
+	tests := []struct {
+		license string
+		wantErr bool
+	}{
+		{"", false},
+		{"MIT", false},
+		{"Apache-2.0", false},
+		{"GPL-2.0+", false},
+		{"MIT OR Apache-2.0", false},
+		{"(MIT OR Apache-2.0) AND BSD-3-Clause", false},
+		{"GPL-2.0-only WITH Classpath-exception-2.0", false},
+		{"LicenseRef-proprietary", false},
+		{"DocumentRef-spdx-tool-1.2:LicenseRef-MIT-Style-2", false},
+		{"MIT OR", true},
+		{"OR MIT", true},
+		{"MIT Apache-2.0", true},
+		{"(MIT", true},
+		{"MIT)", true},
+		{"MIT WITH", true},
+		{"MIT and Apache-2.0", true},
+		{"MIT/Apache", true},
+	}
+	for _, tt := range tests {
+		err := ValidateLicense(tt.license)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ValidateLicense(%q) error = %v, wantErr %v", tt.license, err, tt.wantErr)
+		}
+	}
+}
+
+func TestValidateURL(t *testing.T) {
+	tests := []struct {
+		url     string
+		wantErr bool
+	}{
+		{"", false},
+		{"https://github.com/cruciblehq/spec", false},
+		{"http://example.com", false},
+		{"ftp://example.com", true},
+		{"github.com/cruciblehq/spec", true},
+		{"https://", true},
+		{"::", true},
+	}
+	for _, tt := range tests {
+		err := ValidateURL(tt.url)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ValidateURL(%q) error = %v, wantErr %v", tt.url, err, tt.wantErr)
+		}
+	}
+}
+
+func TestValidateKeywords(t *testing.T) {
+	if err := ValidateKeywords([]string{"http", "api-gateway"}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := ValidateKeywords([]string{"HTTP"}); !errors.Is(err, ErrKeywordInvalid) {
+		t.Errorf("expected ErrKeywordInvalid, got %v", err)
+	}
+	if err := ValidateKeywords([]string{"http", "http"}); !errors.Is(err, ErrKeywordDuplicate) {
+		t.Errorf("expected ErrKeywordDuplicate, got %v", err)
+	}
+	if err := ValidateKeywords(make([]string, maxKeywords+1)); !errors.Is(err, ErrTooManyKeywords) {
+		t.Errorf("expected ErrTooManyKeywords, got %v", err)
+	}
+}
+
+func TestMetadataOverlay(t *testing.T) {
+	base := Metadata{
+		Labels:   map[string]string{"tier": "backend", "team": "core"},
+		Readme:   "# Hub",
+		License:  "MIT",
+		Keywords: []string{"old"},
+	}
+	got := base.Overlay(Metadata{
+		Labels:   map[string]string{"tier": "frontend"},
+		License:  "Apache-2.0",
+		Homepage: "https://crucible.dev",
+	})
+
+	if got.Labels["tier"] != "frontend" || got.Labels["team"] != "core" {
+		t.Errorf("labels not merged: %v", got.Labels)
+	}
+	if base.Labels["tier"] != "backend" {
+		t.Error("overlay modified the base labels")
+	}
+	if got.Readme != "# Hub" {
+		t.Errorf("Readme = %q, want base readme", got.Readme)
+	}
+	if got.License != "Apache-2.0" || got.Homepage != "https://crucible.dev" {
+		t.Errorf("scalar fields not overlaid: %+v", got)
+	}
+	if len(got.Keywords) != 1 || got.Keywords[0] != "old" {
+		t.Errorf("Keywords = %v, want base keywords", got.Keywords)
+	}
+}
+
+func TestMetadataFromArchive(t *testing.T) {
+	manifest := `version: 0
+resource:
+  type: widget
+  name: cruciblehq/clock
+  version: 1.2.0
+  license: MIT
+  homepage: https://crucible.dev/clock
+  keywords: [time, clock]
+  labels:
+    crucible.dev/tier: ui
+main: index.js
+`
+	tr := tar.NewReader(buildTar(t, map[string]string{
+		"index.js":      "export {}",
+		"crucible.yaml": manifest,
+	}))
+
+	md, err := MetadataFromArchive(tr)
+	if err != nil {
+		t.Fatalf("MetadataFromArchive failed: %v", err)
+	}
+	if md.License != "MIT" || md.Homepage != "https://crucible.dev/clock" {
+		t.Errorf("unexpected metadata: %+v", md)
+	}
+	if len(md.Keywords) != 2 || md.Labels["crucible.dev/tier"] != "ui" {
+		t.Errorf("unexpected keywords or labels: %+v", md)
+	}
+	if err := md.Validate(); err != nil {
+		t.Errorf("extracted metadata invalid: %v", err)
+	}
+}
+
+func TestMetadataFromArchiveMissingManifest(t *testing.T) {
+	tr := tar.NewReader(buildTar(t, map[string]string{"index.js": "export {}"}))
+	if _, err := MetadataFromArchive(tr); !errors.Is(err, ErrManifestMissing) {
+		t.Fatalf("expected ErrManifestMissing, got %v", err)
+	}
+}
+
+func TestReadArchiveManifestDotPrefix(t *testing.T) {
+	tr := tar.NewReader(buildTar(t, map[string]string{
+		"./crucible.yaml": "version: 0\nresource:\n  type: widget\n  name: cruciblehq/clock\n  version: 1.2.0\nmain: index.js\n",
+	}))
+	m, err := ReadArchiveManifest(tr)
+	if err != nil {
+		t.Fatalf("ReadArchiveManifest failed: %v", err)
+	}
+	if m.Resource.Name != "cruciblehq/clock" {
+		t.Errorf("Name = %q, want %q", m.Resource.Name, "cruciblehq/clock")
+	}
+}
+
+func TestReadArchiveManifestTooLong(t *testing.T) {
+	tr := tar.NewReader(buildTar(t, map[string]string{
+		"crucible.yaml": "version: 0\n" + strings.Repeat("#", maxManifestLen),
+	}))
+	_, err := ReadArchiveManifest(tr)
+	if !errors.Is(err, ErrManifestTooLong) || !errors.Is(err, ErrManifestInvalid) {
+		t.Fatalf("expected ErrManifestTooLong, got %v", err)
+	}
+}
+
+// Builds an uncompressed tar stream holding the given files in order.
+func buildTar(t *testing.T, files map[string]string) *bytes.Reader {
+	t.Helper()
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for name, content := range files {
+		hdr := &tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return bytes.NewReader(buf.Bytes())
+}
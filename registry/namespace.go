@@ -5,11 +5,11 @@ import "github.com/cruciblehq/crex"
 // Mutable properties of a namespace for creation or update.
 //
 // Used as the request body for namespace creation and update operations. The
-// name is the unique identifier for the namespace and cannot be changed once
-// created. For update requests, Name must match the URL path parameter or
-// update context. Contains only user-modifiable fields; system-managed fields
-// are set by the server and appear only in response types. The media type is
-// [MediaTypeNamespaceInfo].
+// name is the unique identifier for the namespace and cannot be changed by an
+// update; use [Registry.RenameNamespace] instead. For update requests, Name
+// must match the URL path parameter or update context. Contains only
+// user-modifiable fields; system-managed fields are set by the server and
+// appear only in response types. The media type is [MediaTypeNamespaceInfo].
 type NamespaceInfo struct {
 	Name        string `json:"name"`        // Namespace name.
 	Description string `json:"description"` // Description.
@@ -20,7 +20,7 @@ type NamespaceInfo struct {
 // The name must conform to the registry naming rules (see [ValidateName]).
 func (info *NamespaceInfo) Validate() error {
 	if err := ValidateName(info.Name); err != nil {
-		return crex.Wrap(ErrInvalidNamespace, err)
+		return crex.Wrap(ErrInvalidNamespace, fieldError("name", err))
 	}
 	return nil
 }
@@ -41,13 +41,13 @@ type NamespaceSummary struct {
 // Validates the namespace summary.
 func (s *NamespaceSummary) Validate() error {
 	if err := ValidateName(s.Name); err != nil {
-		return crex.Wrap(ErrInvalidNamespace, err)
+		return crex.Wrap(ErrInvalidNamespace, fieldError("name", err))
 	}
 	if err := ValidateCount(s.ResourceCount); err != nil {
-		return crex.Wrap(ErrInvalidNamespace, err)
+		return crex.Wrap(ErrInvalidNamespace, fieldError("resourceCount", err))
 	}
 	if err := ValidateTimestamps(s.CreatedAt, s.UpdatedAt); err != nil {
-		return crex.Wrap(ErrInvalidNamespace, err)
+		return crex.Wrap(ErrInvalidNamespace, fieldError("updatedAt", err))
 	}
 	return nil
 }
@@ -57,7 +57,8 @@ func (s *NamespaceSummary) Validate() error {
 // Serves as the organizational unit for grouping resources. The resources list
 // contains lightweight [ResourceSummary] entries without full version and channel
 // details. For complete resource information, fetch individual resources. The
-// media type is [MediaTypeNamespace].
+// media type is [MediaTypeNamespaceV1]; [MediaTypeNamespace] carries the older
+// [NamespaceV0] shape.
 type Namespace struct {
 	Name        string            `json:"name"`        // Namespace name.
 	Description string            `json:"description"` // Description.
@@ -69,14 +70,14 @@ type Namespace struct {
 // Validates the namespace.
 func (ns *Namespace) Validate() error {
 	if err := ValidateName(ns.Name); err != nil {
-		return crex.Wrap(ErrInvalidNamespace, err)
+		return crex.Wrap(ErrInvalidNamespace, fieldError("name", err))
 	}
 	if err := ValidateTimestamps(ns.CreatedAt, ns.UpdatedAt); err != nil {
-		return crex.Wrap(ErrInvalidNamespace, err)
+		return crex.Wrap(ErrInvalidNamespace, fieldError("updatedAt", err))
 	}
 	for i := range ns.Resources {
 		if err := ns.Resources[i].Validate(); err != nil {
-			return crex.Wrap(ErrInvalidNamespace, err)
+			return crex.Wrap(ErrInvalidNamespace, fieldError(indexField("resources", i), err))
 		}
 	}
 	return nil
@@ -94,7 +95,7 @@ type NamespaceList struct {
 func (l *NamespaceList) Validate() error {
 	for i := range l.Namespaces {
 		if err := l.Namespaces[i].Validate(); err != nil {
-			return crex.Wrap(ErrInvalidNamespace, err)
+			return crex.Wrap(ErrInvalidNamespace, fieldError(indexField("namespaces", i), err))
 		}
 	}
 	return nil
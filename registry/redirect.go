@@ -0,0 +1,228 @@
+package registry
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/cruciblehq/crex"
+)
+
+// Request to rename a namespace or resource.
+//
+// The new name must not be in use, either by an existing entity or by a
+// redirect. The media type is [MediaTypeRename].
+type Rename struct {
+	Name string `json:"name"` // New name.
+}
+
+// Validates the rename request.
+//
+// The name must conform to the registry naming rules (see [ValidateName]).
+func (r *Rename) Validate() error {
+	if err := ValidateName(r.Name); err != nil {
+		return crex.Wrap(ErrInvalidRedirect, fieldError("name", err))
+	}
+	return nil
+}
+
+// Request to move a resource to another namespace.
+//
+// Name optionally renames the resource in the same step; when empty, the
+// resource keeps its name. The media type is [MediaTypeTransfer].
+type Transfer struct {
+	Namespace string `json:"namespace"` // Destination namespace.
+	Name      string `json:"name"`      // New resource name, or empty to keep it.
+}
+
+// Validates the transfer request.
+func (t *Transfer) Validate() error {
+	if err := ValidateName(t.Namespace); err != nil {
+		return crex.Wrap(ErrInvalidRedirect, fieldError("namespace", err))
+	}
+	if t.Name != "" {
+		if err := ValidateName(t.Name); err != nil {
+			return crex.Wrap(ErrInvalidRedirect, fieldError("name", err))
+		}
+	}
+	return nil
+}
+
+// Record of a renamed or transferred namespace or resource.
+//
+// A redirect is created whenever a namespace is renamed or a resource is
+// renamed or transferred, and keeps the old path reserved. Operations on the
+// old path, or on anything beneath it, fail with an [*Error] of code
+// [ErrorCodeMoved] that carries the new location (see [MovedError]). Resource
+// is empty for namespace redirects. Redirects always point at the current
+// location: when a moved entity moves again, existing redirects to it are
+// updated rather than chained, and a redirect is removed when an entity is
+// moved back to its path. The media type is [MediaTypeRedirect].
+type Redirect struct {
+	Namespace       string `json:"namespace"`       // Old namespace.
+	Resource        string `json:"resource"`        // Old resource name, or empty for a namespace.
+	TargetNamespace string `json:"targetNamespace"` // Current namespace.
+	TargetResource  string `json:"targetResource"`  // Current resource name, or empty for a namespace.
+	CreatedAt       int64  `json:"createdAt"`       // When the move happened.
+}
+
+// Validates the redirect.
+//
+// Resource and TargetResource must both be set or both be empty, and the
+// redirect must not point at its own path.
+func (r *Redirect) Validate() error {
+	if err := ValidateName(r.Namespace); err != nil {
+		return crex.Wrap(ErrInvalidRedirect, fieldError("namespace", err))
+	}
+	if err := ValidateName(r.TargetNamespace); err != nil {
+		return crex.Wrap(ErrInvalidRedirect, fieldError("targetNamespace", err))
+	}
+	if (r.Resource == "") != (r.TargetResource == "") {
+		return crex.Wrap(ErrInvalidRedirect, fieldError("targetResource", ErrRedirectKind))
+	}
+	if r.Resource != "" {
+		if err := ValidateName(r.Resource); err != nil {
+			return crex.Wrap(ErrInvalidRedirect, fieldError("resource", err))
+		}
+		if err := ValidateName(r.TargetResource); err != nil {
+			return crex.Wrap(ErrInvalidRedirect, fieldError("targetResource", err))
+		}
+	}
+	if r.Location() == r.Path() {
+		return crex.Wrap(ErrInvalidRedirect, ErrRedirectLoop)
+	}
+	if r.CreatedAt <= 0 {
+		return crex.Wrap(ErrInvalidRedirect, fieldError("createdAt", ErrTimestampInvalid))
+	}
+	return nil
+}
+
+// Old path, as "namespace" or "namespace/resource".
+func (r *Redirect) Path() string {
+	return joinPath(r.Namespace, r.Resource)
+}
+
+// Current path, as "namespace" or "namespace/resource".
+func (r *Redirect) Location() string {
+	return joinPath(r.TargetNamespace, r.TargetResource)
+}
+
+// Applies the redirect to a namespace and resource.
+//
+// Returns the current location of the given path and true if the redirect
+// covers it: a namespace redirect covers the namespace and every resource in
+// it, a resource redirect only that resource. Resource names are kept when
+// following a namespace redirect.
+func (r *Redirect) Resolve(namespace, resource string) (string, string, bool) {
+	if namespace != r.Namespace {
+		return "", "", false
+	}
+	if r.Resource == "" {
+		return r.TargetNamespace, resource, true
+	}
+	if resource != r.Resource {
+		return "", "", false
+	}
+	return r.TargetNamespace, r.TargetResource, true
+}
+
+// Resolves a namespace and resource through a set of redirects.
+//
+// Returns the current location of the path. A resource redirect takes
+// precedence over a namespace redirect covering the same path, and the
+// result is resolved again, since a resource reached through a namespace
+// redirect may itself have moved since. Redirects forming a cycle stop being
+// followed once every redirect has been applied. Paths no redirect covers
+// are returned unchanged.
+func ResolveRedirects(redirects []Redirect, namespace, resource string) (string, string) {
+	for range redirects {
+		ns, res, ok := resolveStep(redirects, namespace, resource)
+		if !ok {
+			break
+		}
+		namespace, resource = ns, res
+	}
+	return namespace, resource
+}
+
+// Applies the most specific redirect covering a path, if any.
+func resolveStep(redirects []Redirect, namespace, resource string) (string, string, bool) {
+	var ns, res string
+	found := false
+	for i := range redirects {
+		n, r, ok := redirects[i].Resolve(namespace, resource)
+		if !ok {
+			continue
+		}
+		if redirects[i].Resource != "" {
+			return n, r, true
+		}
+		ns, res, found = n, r, true
+	}
+	return ns, res, found
+}
+
+// Collection of redirects in a namespace.
+//
+// The media type is [MediaTypeRedirectList].
+type RedirectList struct {
+	Redirects []Redirect `json:"redirects"` // List of redirects.
+}
+
+// Validates the redirect list.
+func (l *RedirectList) Validate() error {
+	for i := range l.Redirects {
+		if err := l.Redirects[i].Validate(); err != nil {
+			return fieldError(indexField("redirects", i), err)
+		}
+	}
+	return nil
+}
+
+// Builds the error returned for an operation on a moved path.
+//
+// The requested namespace and resource are given by the path the caller
+// used, which may lie beneath the redirect (for example, a resource in a
+// renamed namespace). The error carries them under [DetailNamespace] and
+// [DetailResource] and the current location under [DetailLocation].
+func MovedError(r *Redirect, namespace, resource string) *Error {
+	targetNamespace, targetResource, _ := r.Resolve(namespace, resource)
+	location := joinPath(targetNamespace, targetResource)
+	details := map[string]string{
+		DetailNamespace: namespace,
+		DetailLocation:  location,
+	}
+	if resource != "" {
+		details[DetailResource] = resource
+	}
+	return &Error{
+		Code:    ErrorCodeMoved,
+		Message: joinPath(namespace, resource) + " has moved to " + location,
+		Details: details,
+	}
+}
+
+// Extracts the new location from an [ErrorCodeMoved] error.
+//
+// Returns the current namespace and resource (empty for a namespace) and
+// true if err is such an error, so that callers can retry the operation at
+// the new location and warn users that their reference is outdated.
+func Moved(err error) (namespace, resource string, ok bool) {
+	var apiErr *Error
+	if !errors.As(err, &apiErr) || apiErr.Code != ErrorCodeMoved {
+		return "", "", false
+	}
+	location, ok := apiErr.Details[DetailLocation]
+	if !ok || location == "" {
+		return "", "", false
+	}
+	namespace, resource, _ = strings.Cut(location, "/")
+	return namespace, resource, true
+}
+
+// Joins a namespace and an optional resource into a path.
+func joinPath(namespace, resource string) string {
+	if resource == "" {
+		return namespace
+	}
+	return namespace + "/" + resource
+}
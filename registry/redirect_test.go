@@ -0,0 +1,131 @@
+package registry
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestRedirectValidate(t *testing.T) {
+	valid := func() Redirect {
+		return Redirect{Namespace: "acme", Resource: "api", TargetNamespace: "globex", TargetResource: "api", CreatedAt: 100}
+	}
+
+	tests := []struct {
+		name   string
+		modify func(r *Redirect)
+		want   error
+	}{
+		{"resource", func(r *Redirect) {}, nil},
+		{"namespace", func(r *Redirect) { r.Resource, r.TargetResource = "", "" }, nil},
+		{"rename in place", func(r *Redirect) { r.TargetNamespace = "acme"; r.TargetResource = "gateway" }, nil},
+		{"mixed kinds", func(r *Redirect) { r.TargetResource = "" }, ErrRedirectKind},
+		{"loop", func(r *Redirect) { r.TargetNamespace = "acme" }, ErrRedirectLoop},
+		{"invalid target", func(r *Redirect) { r.TargetNamespace = "Globex" }, ErrNameInvalid},
+		{"missing timestamp", func(r *Redirect) { r.CreatedAt = 0 }, ErrTimestampInvalid},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := valid()
+			tt.modify(&r)
+			err := r.Validate()
+			if tt.want == nil {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.want) || !errors.Is(err, ErrInvalidRedirect) {
+				t.Fatalf("got %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestRedirectResolve(t *testing.T) {
+	ns := Redirect{Namespace: "acme", TargetNamespace: "globex"}
+	res := Redirect{Namespace: "acme", Resource: "api", TargetNamespace: "globex", TargetResource: "gateway"}
+
+	tests := []struct {
+		name      string
+		redirect  Redirect
+		namespace string
+		resource  string
+		wantNS    string
+		wantRes   string
+		wantOK    bool
+	}{
+		{"namespace itself", ns, "acme", "", "globex", "", true},
+		{"resource in namespace", ns, "acme", "web", "globex", "web", true},
+		{"other namespace", ns, "initech", "web", "", "", false},
+		{"moved resource", res, "acme", "api", "globex", "gateway", true},
+		{"sibling resource", res, "acme", "web", "", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotNS, gotRes, ok := tt.redirect.Resolve(tt.namespace, tt.resource)
+			if gotNS != tt.wantNS || gotRes != tt.wantRes || ok != tt.wantOK {
+				t.Fatalf("got (%q, %q, %v), want (%q, %q, %v)", gotNS, gotRes, ok, tt.wantNS, tt.wantRes, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestMovedError(t *testing.T) {
+	r := &Redirect{Namespace: "acme", TargetNamespace: "globex", CreatedAt: 100}
+	e := MovedError(r, "acme", "web")
+
+	if err := e.Validate(); err != nil {
+		t.Fatalf("invalid error: %v", err)
+	}
+	if !errors.Is(e, ErrMoved) {
+		t.Fatalf("error does not match ErrMoved")
+	}
+	if StatusFor(e.Code) != http.StatusPermanentRedirect || CodeFor(http.StatusPermanentRedirect) != ErrorCodeMoved {
+		t.Fatalf("moved code does not map to 308")
+	}
+	if got := e.Details[DetailLocation]; got != "globex/web" {
+		t.Fatalf("location = %q, want %q", got, "globex/web")
+	}
+
+	ns, res, ok := Moved(e)
+	if !ok || ns != "globex" || res != "web" {
+		t.Fatalf("Moved() = (%q, %q, %v)", ns, res, ok)
+	}
+	if _, _, ok := Moved(&Error{Code: ErrorCodeNotFound, Message: "not found"}); ok {
+		t.Fatalf("Moved() reported a not found error as moved")
+	}
+}
+
+func TestResolveRedirects(t *testing.T) {
+	redirects := []Redirect{
+		{Namespace: "acme", TargetNamespace: "globex", CreatedAt: 100},
+		{Namespace: "globex", Resource: "web", TargetNamespace: "initech", TargetResource: "site", CreatedAt: 200},
+		{Namespace: "loop-a", TargetNamespace: "loop-b", CreatedAt: 300},
+		{Namespace: "loop-b", TargetNamespace: "loop-a", CreatedAt: 400},
+	}
+
+	tests := []struct {
+		namespace, resource string
+		wantNS, wantRes     string
+	}{
+		{"acme", "api", "globex", "api"},
+		{"acme", "web", "initech", "site"},
+		{"globex", "web", "initech", "site"},
+		{"other", "web", "other", "web"},
+		{"acme", "", "globex", ""},
+	}
+	for _, tt := range tests {
+		ns, res := ResolveRedirects(redirects, tt.namespace, tt.resource)
+		if ns != tt.wantNS || res != tt.wantRes {
+			t.Errorf("ResolveRedirects(%q, %q) = (%q, %q), want (%q, %q)", tt.namespace, tt.resource, ns, res, tt.wantNS, tt.wantRes)
+		}
+	}
+
+	// A cycle terminates after every redirect has been applied.
+	if ns, _ := ResolveRedirects(redirects, "loop-a", "x"); ns != "loop-a" && ns != "loop-b" {
+		t.Errorf("cycle resolved to %q", ns)
+	}
+}
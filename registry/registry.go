@@ -11,7 +11,78 @@ import (
 // into namespaces and resources. Supports published (immutable) and unpublished
 // (mutable) versions, version channels, and compressed archive distribution.
 // All operations are context-aware for cancellation and timeout control.
+//
+// The metadata operations are grouped in [Catalog]. Implementations that keep
+// archive bytes in a [BlobStore] can be assembled from a [MetadataStore] with
+// [NewRegistry].
 type Registry interface {
+	Catalog
+
+	// Uploads a version archive.
+	//
+	// Uploads the archive data for a version in a single request. The archive
+	// can be replaced by uploading again. Large archives should use an upload
+	// session (see [Registry.StartUpload]) so that transfers can be resumed.
+	// The digest is calculated from the archive data using SHA-256 for
+	// integrity verification. The archive must be a zstd-compressed tarball
+	// (see [MediaTypeArchive]) holding the resource manifest, crucible.yaml,
+	// at its root, named with or without a leading "./" and no larger than
+	// 1 MiB. The manifest must name the resource, its type, and the version
+	// being uploaded to, and is read to derive the version's metadata and
+	// dependencies (see [ReadArchiveManifest]). Returns the updated version
+	// with populated archive metadata.
+	UploadArchive(ctx context.Context, namespace string, resource string, version string, archive io.Reader) (*Version, error)
+
+	// Downloads a version archive.
+	//
+	// Returns a reader for the archive data. The caller is responsible for
+	// closing the reader. If the namespace, resource, or version does not
+	// exist, or if the version has no uploaded archive, an error is returned.
+	DownloadArchive(ctx context.Context, namespace string, resource string, version string) (io.ReadCloser, error)
+
+	// Starts a resumable archive upload.
+	//
+	// Declares the size and digest of the archive that will be sent in chunks
+	// (see [Upload] for the protocol). The version must exist and be
+	// unpublished. Returns the new session with a zero offset.
+	StartUpload(ctx context.Context, namespace string, resource string, version string, info UploadInfo) (*Upload, error)
+
+	// Appends a chunk to an upload session.
+	//
+	// The offset must equal the session's committed offset. Returns the
+	// session with the updated offset.
+	UploadChunk(ctx context.Context, namespace string, resource string, version string, upload string, offset int64, chunk io.Reader) (*Upload, error)
+
+	// Retrieves an upload session.
+	//
+	// Returns the session with its committed offset, from which an
+	// interrupted upload resumes. If the session does not exist or has
+	// expired, an error is returned.
+	ReadUpload(ctx context.Context, namespace string, resource string, version string, upload string) (*Upload, error)
+
+	// Finalizes an upload session.
+	//
+	// Verifies that all declared bytes were received and that their digest
+	// matches the declared one, then attaches the archive to the version as
+	// [Registry.UploadArchive] does. Returns the updated version.
+	CompleteUpload(ctx context.Context, namespace string, resource string, version string, upload string) (*Version, error)
+
+	// Discards an upload session and its received bytes.
+	//
+	// The operation is idempotent, returning success if the session does not
+	// exist.
+	CancelUpload(ctx context.Context, namespace string, resource string, version string, upload string) error
+}
+
+// Registry operations on metadata.
+//
+// Covers every [Registry] operation except archive transfer: namespaces,
+// resources, versions, channels, members, and tokens.
+//
+// Operations that address a namespace or resource by a path it was moved away
+// from fail with [ErrorCodeMoved], carrying the new location (see [Redirect]
+// and [Moved]).
+type Catalog interface {
 
 	// Creates a new namespace.
 	//
@@ -50,6 +121,15 @@ type Registry interface {
 	// exist. The list order is implementation-dependent.
 	ListNamespaces(ctx context.Context) (*NamespaceList, error)
 
+	// Renames a namespace.
+	//
+	// Moves the namespace with all of its resources, versions, channels,
+	// members, and tokens to the new name, and records a [Redirect] from the
+	// old name. Archives and their digests are unchanged. If the new name is
+	// used by a namespace or a redirect, an error is returned. The response
+	// includes the namespace under its new name.
+	RenameNamespace(ctx context.Context, namespace string, rename Rename) (*Namespace, error)
+
 	// Creates a new resource.
 	//
 	// Resource names follow the same constraints as namespace names. If a resource
@@ -85,6 +165,39 @@ type Registry interface {
 	// an error is returned.
 	ListResources(ctx context.Context, namespace string) (*ResourceList, error)
 
+	// Renames a resource within its namespace.
+	//
+	// Moves the resource with all of its versions and channels to the new
+	// name, and records a [Redirect] from the old path. Archives and their
+	// digests are unchanged. If the new name is used by a resource or a
+	// redirect in the namespace, an error is returned. The response includes
+	// the resource under its new name.
+	RenameResource(ctx context.Context, namespace string, resource string, rename Rename) (*Resource, error)
+
+	// Moves a resource to another namespace.
+	//
+	// Behaves like [Catalog.RenameResource], optionally renaming the resource
+	// in the same step. The caller must be allowed to create resources in the
+	// destination namespace in addition to holding the permission this
+	// operation requires on the source. The response includes the resource at
+	// its new location.
+	TransferResource(ctx context.Context, namespace string, resource string, transfer Transfer) (*Resource, error)
+
+	// Lists the redirects from old paths in a namespace.
+	//
+	// Returns the namespace redirect if the namespace itself was renamed, and
+	// the redirects of resources moved away from it. The list is empty if
+	// nothing was moved.
+	ListRedirects(ctx context.Context, namespace string) (*RedirectList, error)
+
+	// Deletes a redirect, releasing the old path.
+	//
+	// Afterwards the old path is reported as not found, and its name can be
+	// used again. Resource is empty to delete a namespace redirect. The
+	// operation is idempotent, returning success if the redirect does not
+	// exist.
+	DeleteRedirect(ctx context.Context, namespace string, resource string) error
+
 	// Creates a new version.
 	//
 	// If a version with the given string already exists, an error is returned.
@@ -120,33 +233,37 @@ type Registry interface {
 	// namespace or resource does not exist, an error is returned.
 	ListVersions(ctx context.Context, namespace string, resource string) (*VersionList, error)
 
-	// Uploads a version archive.
+	// Lists versions that depend on a resource or version.
 	//
-	// Uploads the archive data for a version. The archive can be replaced by
-	// uploading again. The digest is calculated from the archive data using
-	// SHA-256 for integrity verification. Returns the updated version with
-	// populated archive metadata.
-	UploadArchive(ctx context.Context, namespace string, resource string, version string, archive io.Reader) (*Version, error)
-
-	// Downloads a version archive.
-	//
-	// Returns a reader for the archive data. The caller is responsible for
-	// closing the reader. If the namespace, resource, or version does not
-	// exist, or if the version has no uploaded archive, an error is returned.
-	DownloadArchive(ctx context.Context, namespace string, resource string, version string) (io.ReadCloser, error)
+	// Returns the versions, across all namespaces, whose recorded dependencies
+	// refer to the given resource. When version is empty, every dependency on
+	// the resource is listed. Otherwise only dependencies that would resolve
+	// to that version are listed: version-based dependencies whose constraint
+	// matches it, and channel-based dependencies whose channel currently points
+	// to it. Dependencies are recorded with the path their manifest used, so
+	// stores must resolve each dependency's path through the registry's
+	// redirects before comparing it (see [Dependency.Matches]); dependents
+	// that still refer to an old path of a renamed or transferred resource or
+	// namespace are listed. Dependents in namespaces the caller cannot read
+	// are omitted. If the namespace or resource does not exist, an error is
+	// returned, and if it has moved, the error carries the new location (see
+	// [MovedError]).
+	ListDependents(ctx context.Context, namespace string, resource string, version string) (*DependentList, error)
 
 	// Creates a new channel.
 	//
 	// Channel names follow the same constraints as namespace names. The
 	// referenced version must exist. Returns an error if a channel with the
-	// same name already exists. The response includes the channel's metadata
+	// same name already exists. The creation is the first entry of the channel
+	// history. The response includes the channel's metadata
 	// with the full version object it points to.
 	CreateChannel(ctx context.Context, namespace string, resource string, info ChannelInfo) (*Channel, error)
 
 	// Updates a channel's mutable metadata.
 	//
 	// The target version and description can be modified. The channel name
-	// cannot be changed after creation. Returns an error if the channel does
+	// cannot be changed after creation. Changing the target version appends an
+	// entry to the channel history. Returns an error if the channel does
 	// not exist. The response includes the updated channel's metadata with the
 	// full version object it points to.
 	UpdateChannel(ctx context.Context, namespace string, resource string, channel string, info ChannelInfo) (*Channel, error)
@@ -160,13 +277,81 @@ type Registry interface {
 
 	// Permanently deletes a channel.
 	//
-	// The operation is idempotent, returning success if the channel does not exist.
+	// The channel history is deleted with it. The operation is idempotent,
+	// returning success if the channel does not exist.
 	DeleteChannel(ctx context.Context, namespace string, resource string, channel string) error
 
+	// Lists the history of a channel.
+	//
+	// Returns every move of the channel, oldest first, including its creation
+	// and any rollbacks. If the namespace, resource, or channel does not exist,
+	// an error is returned.
+	ListChannelHistory(ctx context.Context, namespace string, resource string, channel string) (*ChannelHistory, error)
+
+	// Points a channel back to a prior history entry.
+	//
+	// Moves the channel to the version recorded in the history entry with the
+	// given sequence number and appends a new entry for the rollback. The
+	// history is never rewritten. If the entry does not exist, or its version
+	// has since been deleted, an error is returned. The response includes the
+	// channel's metadata with the full version object it now points to.
+	RollbackChannel(ctx context.Context, namespace string, resource string, channel string, rollback ChannelRollback) (*Channel, error)
+
 	// Lists all channels for a resource.
 	//
 	// Returns a list of channel summaries including current version targets
 	// and timestamps. The list is empty if the resource has no channels. If
 	// the namespace or resource does not exist, an error is returned.
 	ListChannels(ctx context.Context, namespace string, resource string) (*ChannelList, error)
+
+	// Adds a member to a namespace.
+	//
+	// Grants the user the given role on the namespace and all of its resources.
+	// Returns an error if the user is already a member. The caller that creates
+	// a namespace becomes its first owner.
+	CreateMember(ctx context.Context, namespace string, info MemberInfo) (*Member, error)
+
+	// Retrieves a namespace membership.
+	//
+	// If the namespace does not exist or the user is not a member, an error is
+	// returned.
+	ReadMember(ctx context.Context, namespace string, user string) (*Member, error)
+
+	// Changes a member's role.
+	//
+	// The user cannot be changed. Demoting the last owner of a namespace fails,
+	// since every namespace must keep at least one owner.
+	UpdateMember(ctx context.Context, namespace string, user string, info MemberInfo) (*Member, error)
+
+	// Removes a member from a namespace.
+	//
+	// Removing the last owner fails. The operation is idempotent, returning
+	// success if the user is not a member.
+	DeleteMember(ctx context.Context, namespace string, user string) error
+
+	// Lists all members of a namespace.
+	//
+	// The list order is implementation-dependent. If the namespace does not
+	// exist, an error is returned.
+	ListMembers(ctx context.Context, namespace string) (*MemberList, error)
+
+	// Creates an API token scoped to a namespace.
+	//
+	// The response carries the token secret, which is never returned again.
+	// A token cannot be created with a role higher than the caller's own role
+	// in the namespace. Returns an error if a token with the same name exists.
+	CreateToken(ctx context.Context, namespace string, info TokenInfo) (*Token, error)
+
+	// Revokes an API token.
+	//
+	// Requests authenticated with the token fail from then on. The operation
+	// is idempotent, returning success if the token does not exist.
+	DeleteToken(ctx context.Context, namespace string, token string) error
+
+	// Lists all API tokens of a namespace.
+	//
+	// Returns token summaries without secrets, including expired tokens that
+	// have not been revoked. If the namespace does not exist, an error is
+	// returned.
+	ListTokens(ctx context.Context, namespace string) (*TokenList, error)
 }
@@ -7,23 +7,29 @@ import "github.com/cruciblehq/crex"
 // Used as the request body for resource creation and update operations. For
 // update requests, the name field must match the URL path parameter or update
 // context. Contains only user-modifiable fields. The media type is
-// [MediaTypeResourceInfo].
+// [MediaTypeResourceInfoV1]; [MediaTypeResourceInfo] carries the older
+// [ResourceInfoV0] shape.
 type ResourceInfo struct {
-	Name        string `json:"name"`        // Resource name.
-	Type        string `json:"type"`        // Resource type (e.g., "widget", "service").
-	Description string `json:"description"` // Description.
+	Name        string   `json:"name"`        // Resource name.
+	Type        string   `json:"type"`        // Resource type (e.g., "widget", "service").
+	Description string   `json:"description"` // Description.
+	Metadata    Metadata `json:"metadata"`    // Descriptive metadata (labels, readme, license, links).
 }
 
 // Validates the resource info.
 //
-// The name must conform to the registry naming rules (see [ValidateName])
-// and the type must not be empty.
+// The name must conform to the registry naming rules (see [ValidateName]),
+// the type must not be empty, and the metadata must be valid (see
+// [Metadata.Validate]).
 func (info *ResourceInfo) Validate() error {
 	if err := ValidateName(info.Name); err != nil {
-		return crex.Wrap(ErrInvalidResource, err)
+		return crex.Wrap(ErrInvalidResource, fieldError("name", err))
 	}
 	if err := ValidateResourceType(info.Type); err != nil {
-		return crex.Wrap(ErrInvalidResource, err)
+		return crex.Wrap(ErrInvalidResource, fieldError("type", err))
+	}
+	if err := info.Metadata.Validate(); err != nil {
+		return crex.Wrap(ErrInvalidResource, fieldError("metadata", err))
 	}
 	return nil
 }
@@ -35,37 +41,41 @@ func (info *ResourceInfo) Validate() error {
 // read-only fields like timestamps, statistics, and latest version information
 // to help with navigation decisions.
 type ResourceSummary struct {
-	Name          string  `json:"name"`          // Resource name.
-	Type          string  `json:"type"`          // Resource type (e.g., "widget", "service").
-	Description   string  `json:"description"`   // Description.
-	LatestVersion *string `json:"latestVersion"` // Most recent version string (null if no versions).
-	VersionCount  int     `json:"versionCount"`  // Number of versions for this resource.
-	ChannelCount  int     `json:"channelCount"`  // Number of channels for this resource.
-	CreatedAt     int64   `json:"createdAt"`     // When the resource was created.
-	UpdatedAt     int64   `json:"updatedAt"`     // When the resource was last updated.
+	Name          string   `json:"name"`          // Resource name.
+	Type          string   `json:"type"`          // Resource type (e.g., "widget", "service").
+	Description   string   `json:"description"`   // Description.
+	Metadata      Metadata `json:"metadata"`      // Descriptive metadata without readme.
+	LatestVersion *string  `json:"latestVersion"` // Most recent version string (null if no versions).
+	VersionCount  int      `json:"versionCount"`  // Number of versions for this resource.
+	ChannelCount  int      `json:"channelCount"`  // Number of channels for this resource.
+	CreatedAt     int64    `json:"createdAt"`     // When the resource was created.
+	UpdatedAt     int64    `json:"updatedAt"`     // When the resource was last updated.
 }
 
 // Validates the resource summary.
 func (s *ResourceSummary) Validate() error {
 	if err := ValidateName(s.Name); err != nil {
-		return crex.Wrap(ErrInvalidResource, err)
+		return crex.Wrap(ErrInvalidResource, fieldError("name", err))
 	}
 	if err := ValidateResourceType(s.Type); err != nil {
-		return crex.Wrap(ErrInvalidResource, err)
+		return crex.Wrap(ErrInvalidResource, fieldError("type", err))
+	}
+	if err := s.Metadata.Validate(); err != nil {
+		return crex.Wrap(ErrInvalidResource, fieldError("metadata", err))
 	}
 	if s.LatestVersion != nil {
 		if err := ValidateVersionString(*s.LatestVersion); err != nil {
-			return crex.Wrap(ErrInvalidResource, err)
+			return crex.Wrap(ErrInvalidResource, fieldError("latestVersion", err))
 		}
 	}
 	if err := ValidateCount(s.VersionCount); err != nil {
-		return crex.Wrap(ErrInvalidResource, err)
+		return crex.Wrap(ErrInvalidResource, fieldError("versionCount", err))
 	}
 	if err := ValidateCount(s.ChannelCount); err != nil {
-		return crex.Wrap(ErrInvalidResource, err)
+		return crex.Wrap(ErrInvalidResource, fieldError("channelCount", err))
 	}
 	if err := ValidateTimestamps(s.CreatedAt, s.UpdatedAt); err != nil {
-		return crex.Wrap(ErrInvalidResource, err)
+		return crex.Wrap(ErrInvalidResource, fieldError("updatedAt", err))
 	}
 	return nil
 }
@@ -76,12 +86,14 @@ func (s *ResourceSummary) Validate() error {
 // channels. The versions and channels lists contain lightweight summary entries
 // without full archive details. For complete version information, fetch version
 // details. Includes scoping information to identify the resource's location. The
-// media type is [MediaTypeResource].
+// media type is [MediaTypeResourceV1]; [MediaTypeResource] carries the older
+// [ResourceV0] shape.
 type Resource struct {
 	Namespace   string           `json:"namespace"`   // Namespace this resource belongs to.
 	Name        string           `json:"name"`        // Resource name.
 	Type        string           `json:"type"`        // Resource type (e.g., "widget", "service").
 	Description string           `json:"description"` // Description.
+	Metadata    Metadata         `json:"metadata"`    // Descriptive metadata (labels, readme, license, links).
 	Versions    []VersionSummary `json:"versions"`    // List of versions (summary form).
 	Channels    []ChannelSummary `json:"channels"`    // List of channels (summary form).
 	CreatedAt   int64            `json:"createdAt"`   // When the resource was created.
@@ -91,25 +103,28 @@ type Resource struct {
 // Validates the resource.
 func (r *Resource) Validate() error {
 	if err := ValidateName(r.Namespace); err != nil {
-		return crex.Wrap(ErrInvalidResource, err)
+		return crex.Wrap(ErrInvalidResource, fieldError("namespace", err))
 	}
 	if err := ValidateName(r.Name); err != nil {
-		return crex.Wrap(ErrInvalidResource, err)
+		return crex.Wrap(ErrInvalidResource, fieldError("name", err))
 	}
 	if err := ValidateResourceType(r.Type); err != nil {
-		return crex.Wrap(ErrInvalidResource, err)
+		return crex.Wrap(ErrInvalidResource, fieldError("type", err))
+	}
+	if err := r.Metadata.Validate(); err != nil {
+		return crex.Wrap(ErrInvalidResource, fieldError("metadata", err))
 	}
 	if err := ValidateTimestamps(r.CreatedAt, r.UpdatedAt); err != nil {
-		return crex.Wrap(ErrInvalidResource, err)
+		return crex.Wrap(ErrInvalidResource, fieldError("updatedAt", err))
 	}
 	for i := range r.Versions {
 		if err := r.Versions[i].Validate(); err != nil {
-			return crex.Wrap(ErrInvalidResource, err)
+			return crex.Wrap(ErrInvalidResource, fieldError(indexField("versions", i), err))
 		}
 	}
 	for i := range r.Channels {
 		if err := r.Channels[i].Validate(); err != nil {
-			return crex.Wrap(ErrInvalidResource, err)
+			return crex.Wrap(ErrInvalidResource, fieldError(indexField("channels", i), err))
 		}
 	}
 	return nil
@@ -117,7 +132,8 @@ func (r *Resource) Validate() error {
 
 // Collection of resources.
 //
-// The media type is [MediaTypeResourceList].
+// The media type is [MediaTypeResourceListV1]; [MediaTypeResourceList]
+// carries the older [ResourceListV0] shape.
 type ResourceList struct {
 	Resources []ResourceSummary `json:"resources"` // List of resources.
 }
@@ -126,7 +142,7 @@ type ResourceList struct {
 func (l *ResourceList) Validate() error {
 	for i := range l.Resources {
 		if err := l.Resources[i].Validate(); err != nil {
-			return crex.Wrap(ErrInvalidResource, err)
+			return crex.Wrap(ErrInvalidResource, fieldError(indexField("resources", i), err))
 		}
 	}
 	return nil
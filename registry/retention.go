@@ -0,0 +1,78 @@
+package registry
+
+import "github.com/cruciblehq/crex"
+
+// Declarative rule for discarding unpublished versions.
+//
+// A policy applies to a single resource when Resource is set, or to every
+// resource in the namespace otherwise. A resource-level policy replaces the
+// namespace-level policy for that resource entirely; rules are not merged.
+// Policies only ever select unpublished versions, and never select versions
+// that a channel points to (see [PlanGC]).
+//
+// KeepUnpublished keeps the given number of most recently created unpublished
+// versions and selects the rest. MaxUnpublishedAge selects unpublished
+// versions that have not been updated for longer than the given number of
+// seconds. When both rules are set, a version is selected if either rule
+// selects it. The media type is [MediaTypeRetentionPolicy].
+type RetentionPolicy struct {
+	Namespace         string `json:"namespace"`         // Namespace the policy applies to.
+	Resource          string `json:"resource"`          // Resource the policy applies to (empty for all).
+	KeepUnpublished   *int   `json:"keepUnpublished"`   // Number of newest unpublished versions to keep (null for no limit).
+	MaxUnpublishedAge *int64 `json:"maxUnpublishedAge"` // Maximum unpublished version age in seconds (null for no limit).
+}
+
+// Validates the retention policy.
+//
+// The namespace must be a valid name and the resource must be empty or a valid
+// name. At least one rule must be set. KeepUnpublished must not be negative
+// and MaxUnpublishedAge must be positive.
+func (p *RetentionPolicy) Validate() error {
+	if err := ValidateName(p.Namespace); err != nil {
+		return crex.Wrap(ErrInvalidPolicy, fieldError("namespace", err))
+	}
+	if p.Resource != "" {
+		if err := ValidateName(p.Resource); err != nil {
+			return crex.Wrap(ErrInvalidPolicy, fieldError("resource", err))
+		}
+	}
+	if p.KeepUnpublished == nil && p.MaxUnpublishedAge == nil {
+		return crex.Wrap(ErrInvalidPolicy, ErrRetentionEmpty)
+	}
+	if p.KeepUnpublished != nil {
+		if err := ValidateCount(*p.KeepUnpublished); err != nil {
+			return crex.Wrap(ErrInvalidPolicy, fieldError("keepUnpublished", err))
+		}
+	}
+	if p.MaxUnpublishedAge != nil && *p.MaxUnpublishedAge <= 0 {
+		return crex.Wrap(ErrInvalidPolicy, fieldError("maxUnpublishedAge", ErrMaxAgeInvalid))
+	}
+	return nil
+}
+
+// Collection of retention policies.
+//
+// Each namespace and resource may have at most one policy. The media type is
+// [MediaTypeRetentionPolicyList].
+type RetentionPolicyList struct {
+	Policies []RetentionPolicy `json:"policies"` // List of policies.
+}
+
+// Validates the retention policy list.
+//
+// Each policy must be valid and no two policies may share the same scope.
+func (l *RetentionPolicyList) Validate() error {
+	seen := make(map[[2]string]bool, len(l.Policies))
+	for i := range l.Policies {
+		p := &l.Policies[i]
+		if err := p.Validate(); err != nil {
+			return fieldError(indexField("policies", i), err)
+		}
+		scope := [2]string{p.Namespace, p.Resource}
+		if seen[scope] {
+			return crex.Wrap(ErrInvalidPolicy, fieldError(indexField("policies", i), ErrPolicyDuplicate))
+		}
+		seen[scope] = true
+	}
+	return nil
+}
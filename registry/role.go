@@ -0,0 +1,68 @@
+package registry
+
+// Role held by a member within a namespace.
+//
+// Roles are ordered: each role grants every permission of the roles below it.
+// A reader can only read, a publisher can additionally create resources,
+// upload archives, and move channels, and an owner can additionally manage
+// the namespace itself, its members, and its API tokens.
+type Role string
+
+const (
+	RoleReader    Role = "reader"    // Read-only access to namespace contents.
+	RolePublisher Role = "publisher" // Read access plus publishing and channel management.
+	RoleOwner     Role = "owner"     // Full control, including members and tokens.
+)
+
+// Access level required to perform a registry operation.
+//
+// Each [Operation] maps to exactly one permission (see [Operation.Permission]).
+// Permissions other than [PermissionAuthenticated] are scoped to a namespace
+// and granted through a [Role].
+type Permission string
+
+const (
+	PermissionAuthenticated Permission = "authenticated" // Any authenticated caller, no namespace role needed.
+	PermissionRead          Permission = "read"          // Read namespace, resource, version, and channel data.
+	PermissionPublish       Permission = "publish"       // Create and modify resources, versions, archives, and channels.
+	PermissionAdmin         Permission = "admin"         // Modify or delete the namespace, manage members and tokens.
+)
+
+// Rank of each role, used to compare roles.
+var roleRanks = map[Role]int{
+	RoleReader:    1,
+	RolePublisher: 2,
+	RoleOwner:     3,
+}
+
+// Minimum role rank required for each namespace-scoped permission.
+var permissionRanks = map[Permission]int{
+	PermissionAuthenticated: 0,
+	PermissionRead:          1,
+	PermissionPublish:       2,
+	PermissionAdmin:         3,
+}
+
+// Whether the role grants the given permission.
+//
+// Unknown roles grant nothing. [PermissionAuthenticated] is granted by every
+// known role since holding a role implies an authenticated caller.
+func (r Role) Grants(p Permission) bool {
+	rank, ok := roleRanks[r]
+	if !ok {
+		return false
+	}
+	required, ok := permissionRanks[p]
+	if !ok {
+		return false
+	}
+	return rank >= required
+}
+
+// Whether a role is a known value.
+func ValidateRole(r Role) error {
+	if _, ok := roleRanks[r]; !ok {
+		return ErrRoleInvalid
+	}
+	return nil
+}
@@ -0,0 +1,584 @@
+package registry
+
+import (
+	"archive/tar"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/cruciblehq/crex"
+	"github.com/cruciblehq/spec/reference"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Registry metadata storage without archive bytes.
+//
+// Implements every [Catalog] operation and records which blob holds each
+// version's archive. Combined with a [BlobStore] through [NewRegistry], it
+// forms a complete [Registry]. Because archives are addressed by digest,
+// several versions may share one blob.
+type MetadataStore interface {
+	Catalog
+
+	// Records the archive of a version.
+	//
+	// Sets the version's archive URL, size, and digest from the record and
+	// replaces its metadata and dependencies with the record's, which the
+	// caller has already combined with the version's current metadata. Only
+	// unpublished versions can be changed. Returns the updated version.
+	SetArchive(ctx context.Context, namespace string, resource string, version string, archive ArchiveRecord) (*Version, error)
+
+	// Counts the versions whose archive has the given digest.
+	//
+	// Used to decide whether a blob can be deleted once a version releases it.
+	CountArchiveReferences(ctx context.Context, digest reference.Digest) (int, error)
+
+	// Records a new upload session.
+	//
+	// The session's Offset is not persisted; it is derived from the bytes
+	// staged in the [BlobStore].
+	CreateUploadSession(ctx context.Context, upload Upload) error
+
+	// Retrieves an upload session record.
+	//
+	// Returns an [*Error] with code [ErrorCodeNotFound] if the session does
+	// not exist. Expired sessions are returned as stored.
+	ReadUploadSession(ctx context.Context, namespace string, resource string, version string, upload string) (*Upload, error)
+
+	// Records that an upload session received bytes at the given time.
+	TouchUploadSession(ctx context.Context, namespace string, resource string, version string, upload string, updatedAt int64) error
+
+	// Deletes an upload session record.
+	//
+	// The operation is idempotent, returning success if the session does not
+	// exist.
+	DeleteUploadSession(ctx context.Context, namespace string, resource string, version string, upload string) error
+
+	// Counts the unexpired upload sessions in a namespace at the given time.
+	CountUploadSessions(ctx context.Context, namespace string, now int64) (int, error)
+}
+
+// Archive details recorded on a version by [MetadataStore.SetArchive].
+type ArchiveRecord struct {
+	Blob         BlobInfo     // Stored blob holding the archive.
+	Metadata     Metadata     // Current metadata overlaid with that derived from the archive manifest.
+	Dependencies []Dependency // Dependencies derived from the archive manifest.
+}
+
+// Composes a metadata store and a blob store into a [Registry].
+//
+// Catalog operations are served by meta. Uploaded archives are written to
+// blobs, their manifest is read back, checked against the version, and used
+// to derive the version's dependencies and the metadata overlaid on what the
+// client supplied, and the version is pointed at the blob. Identical
+// archives are stored once. A blob is deleted when the last version referring
+// to it is deleted or has its archive replaced; within one registry, deletion
+// is serialized with uploads of the same content, so a blob is never deleted
+// while a version is being pointed at it. Blobs of versions removed by other
+// means, such as namespace deletion, are left in place until swept by
+// garbage collection; the registry implements [BlobSweeper].
+//
+// Upload sessions are recorded in meta and their bytes staged in blobs, so
+// an interrupted session can be resumed from its committed offset after the
+// registry restarts. Appends, commits, and blob releases are serialized in
+// process only, by the registry and by the blob stores of this package, so
+// the stores must be served by a single registry instance. Both
+// single-request and session uploads are subject to limits.
+func NewRegistry(meta MetadataStore, blobs BlobStore, limits UploadLimits) Registry {
+	return &blobRegistry{MetadataStore: meta, blobs: blobs, limits: limits}
+}
+
+// [Registry] assembled by [NewRegistry].
+type blobRegistry struct {
+	MetadataStore
+	blobs  BlobStore
+	limits UploadLimits
+	locks  keyedMutex // Per-digest mutexes serializing attachment and release of blobs.
+}
+
+// Implements [Registry.UploadArchive].
+//
+// The archive is staged under a fresh upload name while its digest is
+// computed, then committed and attached as a session upload would be.
+func (r *blobRegistry) UploadArchive(ctx context.Context, namespace string, resource string, version string, archive io.Reader) (*Version, error) {
+	current, err := r.readUnpublished(ctx, namespace, resource, version)
+	if err != nil {
+		return nil, err
+	}
+
+	if r.limits.MaxSize > 0 {
+		archive = &quotaReader{r: archive, limit: r.limits.MaxSize}
+	}
+	upload, err := newUploadID()
+	if err != nil {
+		return nil, err
+	}
+	h := newBlobHash()
+	if _, err := r.blobs.Append(ctx, upload, 0, io.TeeReader(archive, h)); err != nil {
+		r.blobs.Abort(ctx, upload)
+		return nil, err
+	}
+	digest := h.info().Digest
+
+	unlock := r.hold(digest)
+	blob, err := r.blobs.Commit(ctx, upload)
+	if err != nil {
+		unlock()
+		r.blobs.Abort(ctx, upload)
+		return nil, err
+	}
+	return r.attach(ctx, current, blob, unlock)
+}
+
+// Points a version at a stored blob.
+//
+// The caller must hold the blob's digest (see [blobRegistry.hold]) from
+// before the blob is stored, and attach calls unlock once the version refers
+// to it. Reads the archive manifest from the blob and records the archive on
+// the version. On failure the blob is released; on success the version's
+// previous archive is.
+func (r *blobRegistry) attach(ctx context.Context, current *Version, blob *BlobInfo, unlock func()) (*Version, error) {
+	namespace, resource, version := current.Namespace, current.Resource, current.String
+	record, err := r.readRecord(ctx, current, blob)
+	if err != nil {
+		r.releaseHeld(ctx, blob.Digest)
+		unlock()
+		return nil, err
+	}
+
+	updated, err := r.SetArchive(ctx, namespace, resource, version, *record)
+	if err != nil {
+		r.releaseHeld(ctx, blob.Digest)
+		unlock()
+		return nil, err
+	}
+	unlock()
+
+	if old := archiveDigest(current); old != nil && !old.Equal(&blob.Digest) {
+		r.release(ctx, *old)
+	}
+	return updated, nil
+}
+
+// Implements [Registry.DownloadArchive].
+func (r *blobRegistry) DownloadArchive(ctx context.Context, namespace string, resource string, version string) (io.ReadCloser, error) {
+	v, err := r.ReadVersion(ctx, namespace, resource, version)
+	if err != nil {
+		return nil, err
+	}
+	digest := archiveDigest(v)
+	if digest == nil {
+		return nil, &Error{
+			Code:    ErrorCodeNotFound,
+			Message: "version " + version + " has no archive",
+			Details: map[string]string{DetailEntity: "archive"},
+		}
+	}
+	return r.blobs.Get(ctx, *digest)
+}
+
+// Implements [Registry.StartUpload].
+func (r *blobRegistry) StartUpload(ctx context.Context, namespace string, resource string, version string, info UploadInfo) (*Upload, error) {
+	if err := info.Validate(); err != nil {
+		return nil, err
+	}
+	if _, err := r.readUnpublished(ctx, namespace, resource, version); err != nil {
+		return nil, err
+	}
+
+	now := time.Now().Unix()
+	sessions, err := r.CountUploadSessions(ctx, namespace, now)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.limits.CheckStart(info, sessions); err != nil {
+		return nil, err
+	}
+
+	id, err := newUploadID()
+	if err != nil {
+		return nil, err
+	}
+	upload := Upload{
+		ID:        id,
+		Namespace: namespace,
+		Resource:  resource,
+		Version:   version,
+		Size:      info.Size,
+		Digest:    info.Digest,
+		ExpiresAt: now + r.limits.ttl(),
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := r.CreateUploadSession(ctx, upload); err != nil {
+		return nil, err
+	}
+	return &upload, nil
+}
+
+// Implements [Registry.UploadChunk].
+func (r *blobRegistry) UploadChunk(ctx context.Context, namespace string, resource string, version string, upload string, offset int64, chunk io.Reader) (*Upload, error) {
+	u, err := r.ReadUpload(ctx, namespace, resource, version, upload)
+	if err != nil {
+		return nil, err
+	}
+	if offset != u.Offset {
+		return nil, offsetMismatch(u.Offset)
+	}
+
+	// Accept at most the remaining declared bytes and the chunk limit, then
+	// check whether the client tried to send more.
+	accept := u.Size - u.Offset
+	if r.limits.MaxChunk > 0 && r.limits.MaxChunk < accept {
+		accept = r.limits.MaxChunk
+	}
+	probe := &probeReader{r: chunk}
+	size, err := r.blobs.Append(ctx, upload, offset, io.LimitReader(probe, accept))
+	if size > u.Offset {
+		r.TouchUploadSession(ctx, namespace, resource, version, upload, time.Now().Unix())
+	}
+	if err != nil {
+		return nil, err
+	}
+	u.Offset = size
+
+	if probe.more(accept) {
+		if accept < u.Size-offset {
+			return nil, r.limits.CheckChunk(accept + 1)
+		}
+		return nil, &Error{
+			Code:    ErrorCodeBadRequest,
+			Message: "chunk extends past the declared upload size",
+			Details: map[string]string{DetailOffset: strconv.FormatInt(size, 10)},
+		}
+	}
+	return u, nil
+}
+
+// Implements [Registry.ReadUpload].
+func (r *blobRegistry) ReadUpload(ctx context.Context, namespace string, resource string, version string, upload string) (*Upload, error) {
+	if err := ValidateUploadID(upload); err != nil {
+		return nil, err
+	}
+	u, err := r.ReadUploadSession(ctx, namespace, resource, version, upload)
+	if err != nil {
+		return nil, err
+	}
+	if u.Expired(time.Now().Unix()) {
+		r.discard(ctx, u)
+		return nil, uploadNotFound(upload)
+	}
+	if u.Offset, err = r.blobs.StagedSize(ctx, upload); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// Implements [Registry.CompleteUpload].
+func (r *blobRegistry) CompleteUpload(ctx context.Context, namespace string, resource string, version string, upload string) (*Version, error) {
+	u, err := r.ReadUpload(ctx, namespace, resource, version, upload)
+	if err != nil {
+		return nil, err
+	}
+	if !u.Complete() {
+		return nil, &Error{
+			Code:    ErrorCodeUploadIncomplete,
+			Message: "upload has not received all declared bytes",
+			Details: map[string]string{DetailOffset: strconv.FormatInt(u.Offset, 10)},
+		}
+	}
+	current, err := r.readUnpublished(ctx, namespace, resource, version)
+	if err != nil {
+		return nil, err
+	}
+	declared, err := reference.ParseDigest(u.Digest)
+	if err != nil {
+		return nil, ErrDigestInvalid
+	}
+
+	unlock := r.hold(*declared)
+	blob, err := r.blobs.Commit(ctx, upload)
+	if err != nil {
+		unlock()
+		return nil, err
+	}
+	r.DeleteUploadSession(ctx, namespace, resource, version, upload)
+
+	if !blob.Digest.Equal(declared) {
+		unlock()
+		r.release(ctx, blob.Digest)
+		return nil, &Error{
+			Code:    ErrorCodeDigestMismatch,
+			Message: "uploaded archive has digest " + blob.Digest.String() + ", declared " + u.Digest,
+		}
+	}
+	return r.attach(ctx, current, blob, unlock)
+}
+
+// Implements [Registry.CancelUpload].
+func (r *blobRegistry) CancelUpload(ctx context.Context, namespace string, resource string, version string, upload string) error {
+	if err := ValidateUploadID(upload); err != nil {
+		return err
+	}
+
+	// Staged bytes are named by the upload alone, so the session must be
+	// found under this version before they are discarded.
+	if _, err := r.ReadUploadSession(ctx, namespace, resource, version, upload); err != nil {
+		if isNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	if err := r.blobs.Abort(ctx, upload); err != nil {
+		return err
+	}
+	return r.DeleteUploadSession(ctx, namespace, resource, version, upload)
+}
+
+// Removes an upload session and its staged bytes, ignoring failures.
+func (r *blobRegistry) discard(ctx context.Context, u *Upload) {
+	r.blobs.Abort(ctx, u.ID)
+	r.DeleteUploadSession(ctx, u.Namespace, u.Resource, u.Version, u.ID)
+}
+
+// Reads a version that must not be published.
+func (r *blobRegistry) readUnpublished(ctx context.Context, namespace string, resource string, version string) (*Version, error) {
+	v, err := r.ReadVersion(ctx, namespace, resource, version)
+	if err != nil {
+		return nil, err
+	}
+	if v.Published() {
+		return nil, &Error{Code: ErrorCodeVersionPublished, Message: "version " + version + " is published"}
+	}
+	return v, nil
+}
+
+// Implements [Catalog.DeleteVersion], releasing the version's archive.
+func (r *blobRegistry) DeleteVersion(ctx context.Context, namespace string, resource string, version string) error {
+	v, err := r.ReadVersion(ctx, namespace, resource, version)
+	if err != nil {
+		if isNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	if err := r.MetadataStore.DeleteVersion(ctx, namespace, resource, version); err != nil {
+		return err
+	}
+	if digest := archiveDigest(v); digest != nil {
+		r.release(ctx, *digest)
+	}
+	return nil
+}
+
+// Implements [Catalog.DeleteResource], releasing the archives of its versions.
+func (r *blobRegistry) DeleteResource(ctx context.Context, namespace string, resource string) error {
+	list, err := r.ListVersions(ctx, namespace, resource)
+	if err != nil {
+		if isNotFound(err) {
+			return nil
+		}
+		return err
+	}
+
+	var digests []reference.Digest
+	for _, s := range list.Versions {
+		v, err := r.ReadVersion(ctx, namespace, resource, s.String)
+		if err != nil {
+			return err
+		}
+		if digest := archiveDigest(v); digest != nil {
+			digests = append(digests, *digest)
+		}
+	}
+
+	if err := r.MetadataStore.DeleteResource(ctx, namespace, resource); err != nil {
+		return err
+	}
+	for _, d := range digests {
+		r.release(ctx, d)
+	}
+	return nil
+}
+
+// Reads the manifest of a stored archive into an archive record for a
+// version.
+//
+// The manifest must name the version's resource, type, and version (see
+// [checkManifestTarget]). The record's metadata is the version's current
+// metadata overlaid with the metadata derived from the manifest. Registry
+// archives are zstd-compressed tarballs (see [MediaTypeArchive]).
+func (r *blobRegistry) readRecord(ctx context.Context, current *Version, blob *BlobInfo) (*ArchiveRecord, error) {
+	rc, err := r.blobs.Get(ctx, blob.Digest)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	zr, err := zstd.NewReader(rc)
+	if err != nil {
+		return nil, crex.Wrap(ErrManifestInvalid, err)
+	}
+	defer zr.Close()
+
+	m, err := ReadArchiveManifest(tar.NewReader(zr))
+	if err != nil {
+		return nil, err
+	}
+	res, err := r.ReadResource(ctx, current.Namespace, current.Resource)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkManifestTarget(m, res, current.String); err != nil {
+		return nil, err
+	}
+	deps, err := DependenciesFromManifest(m, current.Namespace)
+	if err != nil {
+		return nil, err
+	}
+	return &ArchiveRecord{
+		Blob:         *blob,
+		Metadata:     current.Metadata.Overlay(MetadataFromManifest(m)),
+		Dependencies: deps,
+	}, nil
+}
+
+// Locks a digest against [blobRegistry.release], returning the unlock
+// function.
+//
+// Held from before a blob is stored until a version refers to it, so that a
+// concurrent release of the same content, which counts no references yet,
+// cannot delete the blob in between. Locks are per registry: registries
+// sharing stores do not coordinate.
+func (r *blobRegistry) hold(digest reference.Digest) func() {
+	return r.locks.lock(digest.String())
+}
+
+// Deletes a blob if no version refers to it any more.
+//
+// Failures are ignored: a blob that is not deleted only wastes space, while
+// the operation that released it has already succeeded.
+func (r *blobRegistry) release(ctx context.Context, digest reference.Digest) {
+	defer r.hold(digest)()
+	r.releaseHeld(ctx, digest)
+}
+
+// Implements [BlobSweeper.OrphanedBlobs].
+func (r *blobRegistry) OrphanedBlobs(ctx context.Context) ([]BlobInfo, error) {
+	blobs, err := r.blobs.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	orphans := []BlobInfo{}
+	for _, b := range blobs {
+		n, err := r.CountArchiveReferences(ctx, b.Digest)
+		if err != nil {
+			return nil, err
+		}
+		if n == 0 {
+			orphans = append(orphans, b)
+		}
+	}
+	return orphans, nil
+}
+
+// Implements [BlobSweeper.SweepBlob].
+//
+// Unlike [blobRegistry.release], failures are reported, since sweeping is
+// the operation itself.
+func (r *blobRegistry) SweepBlob(ctx context.Context, digest reference.Digest) (bool, error) {
+	defer r.hold(digest)()
+	n, err := r.CountArchiveReferences(ctx, digest)
+	if err != nil || n > 0 {
+		return false, err
+	}
+	if err := r.blobs.Delete(ctx, digest); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Behaves like [blobRegistry.release] for a digest the caller holds.
+func (r *blobRegistry) releaseHeld(ctx context.Context, digest reference.Digest) {
+	n, err := r.CountArchiveReferences(ctx, digest)
+	if err != nil || n > 0 {
+		return
+	}
+	r.blobs.Delete(ctx, digest)
+}
+
+// Parsed archive digest of a version, or nil if it has no archive.
+func archiveDigest(v *Version) *reference.Digest {
+	if v.Digest == nil {
+		return nil
+	}
+	d, err := reference.ParseDigest(*v.Digest)
+	if err != nil {
+		return nil
+	}
+	return d
+}
+
+// Whether an error reports a missing entity.
+func isNotFound(err error) bool {
+	return errors.Is(err, ErrNotFound)
+}
+
+// Generates a random upload session ID.
+func newUploadID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b[:]), nil
+}
+
+// Reader failing with [ErrorCodeQuotaExceeded] once more than limit bytes
+// have been read.
+type quotaReader struct {
+	r     io.Reader
+	limit int64
+	read  int64
+}
+
+// Implements io.Reader.
+func (q *quotaReader) Read(p []byte) (int, error) {
+	if max := q.limit - q.read + 1; int64(len(p)) > max {
+		p = p[:max]
+	}
+	n, err := q.r.Read(p)
+	q.read += int64(n)
+	if q.read > q.limit {
+		return n, quotaExceeded("archive size exceeds the registry limit", q.limit)
+	}
+	return n, err
+}
+
+// Reader recording whether its source had bytes beyond what was consumed.
+type probeReader struct {
+	r    io.Reader
+	read int64
+}
+
+// Implements io.Reader.
+func (p *probeReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	p.read += int64(n)
+	return n, err
+}
+
+// Whether the source holds more than n bytes.
+//
+// Reads at most one byte past n; must be called after n bytes were consumed.
+func (p *probeReader) more(n int64) bool {
+	if p.read < n {
+		return false
+	}
+	var b [1]byte
+	m, _ := io.ReadFull(p.r, b[:])
+	return m > 0
+}
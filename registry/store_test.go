@@ -0,0 +1,292 @@
+package registry
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/cruciblehq/spec/reference"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Metadata store holding versions of a single widget resource.
+//
+// Embeds the interface so that only the methods used by the composed registry
+// need an implementation; any other call panics.
+type memMetadataStore struct {
+	MetadataStore
+	versions map[string]*Version
+	uploads  map[string]*Upload
+}
+
+func (s *memMetadataStore) ReadResource(_ context.Context, ns, res string) (*Resource, error) {
+	return &Resource{Namespace: ns, Name: res, Type: "widget", CreatedAt: 1, UpdatedAt: 1}, nil
+}
+
+func (s *memMetadataStore) ReadVersion(_ context.Context, ns, res, ver string) (*Version, error) {
+	v, ok := s.versions[ver]
+	if !ok {
+		return nil, &Error{Code: ErrorCodeNotFound, Message: "version not found"}
+	}
+	clone := *v
+	return &clone, nil
+}
+
+func (s *memMetadataStore) DeleteVersion(_ context.Context, ns, res, ver string) error {
+	delete(s.versions, ver)
+	return nil
+}
+
+func (s *memMetadataStore) SetArchive(_ context.Context, ns, res, ver string, rec ArchiveRecord) (*Version, error) {
+	v := s.versions[ver]
+	url := "https://hub.example/" + rec.Blob.Digest.String()
+	digest := rec.Blob.Digest.String()
+	v.Archive, v.Size, v.Digest = &url, &rec.Blob.Size, &digest
+	v.Metadata, v.Dependencies = rec.Metadata, rec.Dependencies
+	return v, nil
+}
+
+func (s *memMetadataStore) CountArchiveReferences(_ context.Context, d reference.Digest) (int, error) {
+	n := 0
+	for _, v := range s.versions {
+		if v.Digest != nil && *v.Digest == d.String() {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (s *memMetadataStore) CreateUploadSession(_ context.Context, u Upload) error {
+	if s.uploads == nil {
+		s.uploads = make(map[string]*Upload)
+	}
+	s.uploads[u.ID] = &u
+	return nil
+}
+
+func (s *memMetadataStore) ReadUploadSession(_ context.Context, ns, res, ver, id string) (*Upload, error) {
+	u, ok := s.uploads[id]
+	if !ok || u.Namespace != ns || u.Resource != res || u.Version != ver {
+		return nil, uploadNotFound(id)
+	}
+	clone := *u
+	return &clone, nil
+}
+
+func (s *memMetadataStore) TouchUploadSession(_ context.Context, ns, res, ver, id string, at int64) error {
+	s.uploads[id].UpdatedAt = at
+	return nil
+}
+
+func (s *memMetadataStore) DeleteUploadSession(_ context.Context, ns, res, ver, id string) error {
+	if u, ok := s.uploads[id]; !ok || u.Namespace != ns || u.Resource != res || u.Version != ver {
+		return nil
+	}
+	delete(s.uploads, id)
+	return nil
+}
+
+func (s *memMetadataStore) CountUploadSessions(_ context.Context, ns string, now int64) (int, error) {
+	n := 0
+	for _, u := range s.uploads {
+		if !u.Expired(now) {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func buildArchive(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw, err := zstd.NewWriter(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.Copy(zw, buildTar(t, files)); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestNewRegistryArchives(t *testing.T) {
+	ctx := context.Background()
+	meta := &memMetadataStore{versions: map[string]*Version{
+		"1.0.0": {Namespace: "acme", Resource: "clock", String: "1.0.0", CreatedAt: 1, UpdatedAt: 1},
+		"1.0.1": {Namespace: "acme", Resource: "clock", String: "1.0.1", CreatedAt: 1, UpdatedAt: 1},
+	}}
+	blobs := NewMemoryBlobStore()
+	reg := NewRegistry(meta, blobs, UploadLimits{})
+
+	data := buildArchive(t, map[string]string{
+		"crucible.yaml": "version: 0\nresource:\n  type: widget\n  name: acme/clock\n  version: 1.0.0\n  license: MIT\nmain: index.js\n",
+		"index.js":      "export {}",
+	})
+
+	v1, err := reg.UploadArchive(ctx, "acme", "clock", "1.0.0", bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("UploadArchive failed: %v", err)
+	}
+	if v1.Metadata.License != "MIT" {
+		t.Errorf("License = %q, want MIT", v1.Metadata.License)
+	}
+	v1, err = reg.UploadArchive(ctx, "acme", "clock", "1.0.0", bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("UploadArchive failed: %v", err)
+	}
+	if len(blobs.blobs) != 1 {
+		t.Fatalf("stored %d blobs, want 1", len(blobs.blobs))
+	}
+
+	// Point a second version at the same blob, as a store copying versions
+	// would; archives uploaded through the registry name their version.
+	v2 := meta.versions["1.0.1"]
+	v2.Archive, v2.Size, v2.Digest = v1.Archive, v1.Size, v1.Digest
+	if len(blobs.blobs) != 1 {
+		t.Fatalf("stored %d blobs, want 1", len(blobs.blobs))
+	}
+
+	rc, err := reg.DownloadArchive(ctx, "acme", "clock", "1.0.0")
+	if got := readBlob(t, rc, err); got != string(data) {
+		t.Error("downloaded archive differs from upload")
+	}
+
+	if err := reg.DeleteVersion(ctx, "acme", "clock", "1.0.0"); err != nil {
+		t.Fatalf("DeleteVersion failed: %v", err)
+	}
+	if len(blobs.blobs) != 1 {
+		t.Fatal("blob deleted while still referenced")
+	}
+	if err := reg.DeleteVersion(ctx, "acme", "clock", "1.0.1"); err != nil {
+		t.Fatalf("DeleteVersion failed: %v", err)
+	}
+	if len(blobs.blobs) != 0 {
+		t.Error("unreferenced blob not deleted")
+	}
+}
+
+func TestNewRegistryOverlaysClientMetadata(t *testing.T) {
+	ctx := context.Background()
+	meta := &memMetadataStore{versions: map[string]*Version{
+		"1.0.0": {
+			Namespace: "acme", Resource: "clock", String: "1.0.0", CreatedAt: 1, UpdatedAt: 1,
+			Metadata: Metadata{License: "Apache-2.0", Readme: "# Clock", Labels: map[string]string{"team": "time"}},
+		},
+	}}
+	reg := NewRegistry(meta, NewMemoryBlobStore(), UploadLimits{})
+
+	data := buildArchive(t, map[string]string{
+		"crucible.yaml": "version: 0\nresource:\n  type: widget\n  name: acme/clock\n  version: 1.0.0\n  license: MIT\nmain: index.js\n",
+		"index.js":      "export {}",
+	})
+	v, err := reg.UploadArchive(ctx, "acme", "clock", "1.0.0", bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("UploadArchive failed: %v", err)
+	}
+	if v.Metadata.License != "MIT" {
+		t.Errorf("License = %q, want the manifest's MIT", v.Metadata.License)
+	}
+	if v.Metadata.Readme != "# Clock" || v.Metadata.Labels["team"] != "time" {
+		t.Errorf("client metadata lost: %+v", v.Metadata)
+	}
+}
+
+func TestNewRegistryRejectsMismatchedManifest(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name     string
+		manifest string
+		want     error
+	}{
+		{"name", "version: 0\nresource:\n  type: widget\n  name: acme/watch\n  version: 1.0.0\nmain: index.js\n", ErrManifestName},
+		{"namespace", "version: 0\nresource:\n  type: widget\n  name: other/clock\n  version: 1.0.0\nmain: index.js\n", ErrManifestName},
+		{"type", "version: 0\nresource:\n  type: runtime\n  name: acme/clock\n  version: 1.0.0\nstages:\n  - from: oci alpine:3.21\n    steps: []\n", ErrManifestType},
+		{"version", "version: 0\nresource:\n  type: widget\n  name: acme/clock\n  version: 1.0.1\nmain: index.js\n", ErrManifestVersion},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			meta := &memMetadataStore{versions: map[string]*Version{
+				"1.0.0": {Namespace: "acme", Resource: "clock", String: "1.0.0", CreatedAt: 1, UpdatedAt: 1},
+			}}
+			blobs := NewMemoryBlobStore()
+			reg := NewRegistry(meta, blobs, UploadLimits{})
+
+			data := buildArchive(t, map[string]string{"crucible.yaml": tt.manifest, "index.js": "export {}"})
+			_, err := reg.UploadArchive(ctx, "acme", "clock", "1.0.0", bytes.NewReader(data))
+			if !errors.Is(err, ErrManifestTarget) || !errors.Is(err, tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, err)
+			}
+			if AsError(err).Code != ErrorCodeBadRequest {
+				t.Errorf("code = %s, want %s", AsError(err).Code, ErrorCodeBadRequest)
+			}
+			if meta.versions["1.0.0"].Digest != nil || len(blobs.blobs) != 0 {
+				t.Error("mismatched archive attached or kept")
+			}
+		})
+	}
+}
+
+func TestNewRegistryRejectsArchiveWithoutManifest(t *testing.T) {
+	ctx := context.Background()
+	meta := &memMetadataStore{versions: map[string]*Version{
+		"1.0.0": {Namespace: "acme", Resource: "clock", String: "1.0.0", CreatedAt: 1, UpdatedAt: 1},
+	}}
+	blobs := NewMemoryBlobStore()
+	reg := NewRegistry(meta, blobs, UploadLimits{})
+
+	data := buildArchive(t, map[string]string{"index.js": "export {}"})
+	if _, err := reg.UploadArchive(ctx, "acme", "clock", "1.0.0", bytes.NewReader(data)); !errors.Is(err, ErrManifestMissing) {
+		t.Fatalf("UploadArchive error = %v, want %v", err, ErrManifestMissing)
+	}
+	if len(blobs.blobs) != 0 {
+		t.Error("rejected archive left a blob behind")
+	}
+	if _, err := reg.DownloadArchive(ctx, "acme", "clock", "1.0.0"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("DownloadArchive error = %v, want %v", err, ErrNotFound)
+	}
+}
+
+func TestNewRegistryReleaseWaitsForAttach(t *testing.T) {
+	ctx := context.Background()
+	meta := &memMetadataStore{versions: map[string]*Version{
+		"1.0.0": {Namespace: "acme", Resource: "clock", String: "1.0.0", CreatedAt: 1, UpdatedAt: 1},
+	}}
+	blobs := NewMemoryBlobStore()
+	reg := NewRegistry(meta, blobs, UploadLimits{}).(*blobRegistry)
+
+	blob, err := blobs.Put(ctx, bytes.NewReader([]byte("archive")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// An upload holds the digest while pointing a version at the blob, and a
+	// release of the same content must wait for it and then count the new
+	// reference.
+	unlock := reg.hold(blob.Digest)
+	released := make(chan struct{})
+	go func() {
+		reg.release(ctx, blob.Digest)
+		close(released)
+	}()
+
+	select {
+	case <-released:
+		t.Fatal("release did not wait for the held digest")
+	case <-time.After(50 * time.Millisecond):
+	}
+	digest := blob.Digest.String()
+	meta.versions["1.0.0"].Digest = &digest
+	unlock()
+	<-released
+
+	if _, err := blobs.Stat(ctx, blob.Digest); err != nil {
+		t.Errorf("blob referenced by a version was deleted: %v", err)
+	}
+}
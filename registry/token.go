@@ -0,0 +1,160 @@
+package registry
+
+import "github.com/cruciblehq/crex"
+
+// Mutable properties of an API token for creation.
+//
+// Used as the request body for token creation. Tokens are scoped to the
+// namespace they are created in and act with the permissions of their [Role].
+// When Resources is non-empty, the token is further restricted to the listed
+// resources within the namespace. A null ExpiresAt creates a token that never
+// expires. Tokens cannot be updated after creation; revoke and recreate them
+// instead. The media type is [MediaTypeTokenInfo].
+type TokenInfo struct {
+	Name      string   `json:"name"`      // Token name, unique within the namespace.
+	Role      Role     `json:"role"`      // Role the token acts with.
+	Resources []string `json:"resources"` // Resources the token is restricted to (empty for all).
+	ExpiresAt *int64   `json:"expiresAt"` // When the token expires (null if it never expires).
+}
+
+// Validates the token info.
+//
+// The name must conform to the registry naming rules (see [ValidateName]), the
+// role must be a known [Role], resource names must be valid and unique, and
+// the expiry, when set, must be a positive unix epoch.
+func (info *TokenInfo) Validate() error {
+	if err := ValidateName(info.Name); err != nil {
+		return crex.Wrap(ErrInvalidToken, fieldError("name", err))
+	}
+	if err := ValidateRole(info.Role); err != nil {
+		return crex.Wrap(ErrInvalidToken, fieldError("role", err))
+	}
+	if err := ValidateTokenResources(info.Resources); err != nil {
+		return crex.Wrap(ErrInvalidToken, fieldError("resources", err))
+	}
+	if info.ExpiresAt != nil && *info.ExpiresAt <= 0 {
+		return crex.Wrap(ErrInvalidToken, fieldError("expiresAt", ErrTimestampInvalid))
+	}
+	return nil
+}
+
+// Lightweight token representation for listings.
+//
+// Describes a token without its secret. The secret is only ever returned once,
+// in the [Token] produced by token creation.
+type TokenSummary struct {
+	Name      string   `json:"name"`      // Token name, unique within the namespace.
+	Role      Role     `json:"role"`      // Role the token acts with.
+	Resources []string `json:"resources"` // Resources the token is restricted to (empty for all).
+	ExpiresAt *int64   `json:"expiresAt"` // When the token expires (null if it never expires).
+	CreatedAt int64    `json:"createdAt"` // When the token was created.
+	UpdatedAt int64    `json:"updatedAt"` // When the token was last updated.
+}
+
+// Validates the token summary.
+func (s *TokenSummary) Validate() error {
+	if err := ValidateName(s.Name); err != nil {
+		return crex.Wrap(ErrInvalidToken, fieldError("name", err))
+	}
+	if err := ValidateRole(s.Role); err != nil {
+		return crex.Wrap(ErrInvalidToken, fieldError("role", err))
+	}
+	if err := ValidateTokenResources(s.Resources); err != nil {
+		return crex.Wrap(ErrInvalidToken, fieldError("resources", err))
+	}
+	if err := ValidateTimestamps(s.CreatedAt, s.UpdatedAt); err != nil {
+		return crex.Wrap(ErrInvalidToken, fieldError("updatedAt", err))
+	}
+	if err := ValidateExpiry(s.CreatedAt, s.ExpiresAt); err != nil {
+		return crex.Wrap(ErrInvalidToken, fieldError("expiresAt", err))
+	}
+	return nil
+}
+
+// Whether the token has expired at the given unix epoch.
+func (s *TokenSummary) Expired(now int64) bool {
+	return s.ExpiresAt != nil && now >= *s.ExpiresAt
+}
+
+// Whether the token grants the permission on the given resource.
+//
+// The resource may be empty for namespace-level operations, in which case a
+// token restricted to specific resources only grants [PermissionRead]. Expiry
+// is not considered; check [TokenSummary.Expired] separately.
+func (s *TokenSummary) Allows(resource string, p Permission) bool {
+	if !s.Role.Grants(p) {
+		return false
+	}
+	if len(s.Resources) == 0 {
+		return true
+	}
+	if resource == "" {
+		return p == PermissionRead || p == PermissionAuthenticated
+	}
+	for _, r := range s.Resources {
+		if r == resource {
+			return true
+		}
+	}
+	return false
+}
+
+// API token scoped to a namespace.
+//
+// Returned by token creation. The Secret field carries the bearer credential
+// in plain text and is only present in the creation response; registries store
+// a hash and never return the secret again. Includes scoping information to
+// identify the token's location. The media type is [MediaTypeToken].
+type Token struct {
+	Namespace string   `json:"namespace"` // Namespace this token belongs to.
+	Name      string   `json:"name"`      // Token name, unique within the namespace.
+	Role      Role     `json:"role"`      // Role the token acts with.
+	Resources []string `json:"resources"` // Resources the token is restricted to (empty for all).
+	Secret    string   `json:"secret"`    // Bearer credential (only returned on creation).
+	ExpiresAt *int64   `json:"expiresAt"` // When the token expires (null if it never expires).
+	CreatedAt int64    `json:"createdAt"` // When the token was created.
+	UpdatedAt int64    `json:"updatedAt"` // When the token was last updated.
+}
+
+// Validates the token.
+func (t *Token) Validate() error {
+	if err := ValidateName(t.Namespace); err != nil {
+		return crex.Wrap(ErrInvalidToken, fieldError("namespace", err))
+	}
+	if err := ValidateName(t.Name); err != nil {
+		return crex.Wrap(ErrInvalidToken, fieldError("name", err))
+	}
+	if err := ValidateRole(t.Role); err != nil {
+		return crex.Wrap(ErrInvalidToken, fieldError("role", err))
+	}
+	if err := ValidateTokenResources(t.Resources); err != nil {
+		return crex.Wrap(ErrInvalidToken, fieldError("resources", err))
+	}
+	if t.Secret == "" {
+		return crex.Wrap(ErrInvalidToken, fieldError("secret", ErrTokenSecretEmpty))
+	}
+	if err := ValidateTimestamps(t.CreatedAt, t.UpdatedAt); err != nil {
+		return crex.Wrap(ErrInvalidToken, fieldError("updatedAt", err))
+	}
+	if err := ValidateExpiry(t.CreatedAt, t.ExpiresAt); err != nil {
+		return crex.Wrap(ErrInvalidToken, fieldError("expiresAt", err))
+	}
+	return nil
+}
+
+// Collection of API tokens in a namespace.
+//
+// The media type is [MediaTypeTokenList].
+type TokenList struct {
+	Tokens []TokenSummary `json:"tokens"` // List of tokens.
+}
+
+// Validates the token list.
+func (l *TokenList) Validate() error {
+	for i := range l.Tokens {
+		if err := l.Tokens[i].Validate(); err != nil {
+			return crex.Wrap(ErrInvalidToken, fieldError(indexField("tokens", i), err))
+		}
+	}
+	return nil
+}
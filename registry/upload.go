@@ -0,0 +1,206 @@
+package registry
+
+import (
+	"regexp"
+	"strconv"
+
+	"github.com/cruciblehq/crex"
+	"github.com/cruciblehq/spec/reference"
+)
+
+// Valid upload session ID pattern.
+var uploadIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{16,128}$`)
+
+// Parameters for starting a resumable archive upload.
+//
+// The client declares the exact size and SHA-256 digest of the archive before
+// sending any bytes, so the registry can reject oversized uploads up front and
+// verify the content once all bytes have arrived. The media type is
+// [MediaTypeUploadInfo].
+type UploadInfo struct {
+	Size   int64  `json:"size"`   // Archive size in bytes.
+	Digest string `json:"digest"` // SHA-256 digest of the archive (e.g., "sha256:abc...").
+}
+
+// Validates the upload info.
+//
+// The size must be positive and the digest a SHA-256 digest (see
+// [ValidateBlobDigest]).
+func (info *UploadInfo) Validate() error {
+	if info.Size <= 0 {
+		return crex.Wrap(ErrInvalidUpload, fieldError("size", ErrSizeInvalid))
+	}
+	if err := validateUploadDigest(info.Digest); err != nil {
+		return crex.Wrap(ErrInvalidUpload, fieldError("digest", err))
+	}
+	return nil
+}
+
+// Resumable archive upload session.
+//
+// An upload transfers a version archive in chunks that can be resent after a
+// failure without starting over. The protocol is:
+//
+//  1. [Registry.StartUpload] declares the size and digest and returns a
+//     session with Offset 0. The version must exist and be unpublished, and
+//     the size must be within the registry's [UploadLimits], otherwise the
+//     session is refused with [ErrorCodeQuotaExceeded].
+//  2. [Registry.UploadChunk] appends bytes at an offset, which must equal the
+//     session's committed Offset. Any other offset fails with
+//     [ErrorCodeOffsetMismatch], and the error's [DetailOffset] holds the
+//     committed offset. Bytes that would extend the upload past the declared
+//     size are rejected with [ErrorCodeBadRequest], and chunks larger than the
+//     chunk limit with [ErrorCodeQuotaExceeded]; in both cases the bytes that
+//     fit are committed. If a chunk is interrupted, the registry may commit
+//     any prefix of the bytes it received.
+//  3. After a failure, [Registry.ReadUpload] reports the committed Offset, and
+//     the client resumes with the chunk starting at that offset.
+//  4. [Registry.CompleteUpload] finalizes the session once Offset equals Size;
+//     earlier calls fail with [ErrorCodeUploadIncomplete]. The registry
+//     computes the digest of the received bytes and fails with
+//     [ErrorCodeDigestMismatch] if it differs from the declared one, discarding
+//     the session. On success the archive is attached to the version exactly
+//     as by [Registry.UploadArchive], and the session is removed.
+//  5. [Registry.CancelUpload] discards a session and its bytes at any time.
+//
+// Sessions not completed by ExpiresAt are discarded and thereafter reported
+// as not found. Session IDs are opaque, URL-safe strings chosen by the
+// registry. The media type is [MediaTypeUpload].
+type Upload struct {
+	ID        string `json:"id"`        // Session identifier.
+	Namespace string `json:"namespace"` // Namespace of the target version.
+	Resource  string `json:"resource"`  // Resource of the target version.
+	Version   string `json:"version"`   // Target version string.
+	Size      int64  `json:"size"`      // Declared archive size in bytes.
+	Digest    string `json:"digest"`    // Declared archive digest.
+	Offset    int64  `json:"offset"`    // Number of bytes committed so far.
+	ExpiresAt int64  `json:"expiresAt"` // When the session is discarded if not completed.
+	CreatedAt int64  `json:"createdAt"` // When the session was started.
+	UpdatedAt int64  `json:"updatedAt"` // When the session last received bytes.
+}
+
+// Validates the upload session.
+func (u *Upload) Validate() error {
+	if err := ValidateUploadID(u.ID); err != nil {
+		return crex.Wrap(ErrInvalidUpload, fieldError("id", err))
+	}
+	if err := ValidateReference(u.Namespace, u.Resource, u.Version); err != nil {
+		return crex.Wrap(ErrInvalidUpload, err)
+	}
+	if u.Size <= 0 {
+		return crex.Wrap(ErrInvalidUpload, fieldError("size", ErrSizeInvalid))
+	}
+	if err := validateUploadDigest(u.Digest); err != nil {
+		return crex.Wrap(ErrInvalidUpload, fieldError("digest", err))
+	}
+	if u.Offset < 0 || u.Offset > u.Size {
+		return crex.Wrap(ErrInvalidUpload, fieldError("offset", ErrOffsetInvalid))
+	}
+	if err := ValidateTimestamps(u.CreatedAt, u.UpdatedAt); err != nil {
+		return crex.Wrap(ErrInvalidUpload, fieldError("updatedAt", err))
+	}
+	if err := ValidateExpiry(u.CreatedAt, &u.ExpiresAt); err != nil {
+		return crex.Wrap(ErrInvalidUpload, fieldError("expiresAt", err))
+	}
+	return nil
+}
+
+// Whether the upload has received all declared bytes.
+func (u *Upload) Complete() bool {
+	return u.Offset == u.Size
+}
+
+// Whether the session has expired at the given unix time.
+func (u *Upload) Expired(now int64) bool {
+	return now >= u.ExpiresAt
+}
+
+// Registry quotas for archive uploads.
+//
+// Zero values mean no limit. Exceeding a limit fails with
+// [ErrorCodeQuotaExceeded], and the error's [DetailLimit] holds the limit.
+type UploadLimits struct {
+	MaxSize     int64 // Maximum archive size in bytes.
+	MaxChunk    int64 // Maximum size of a single chunk in bytes.
+	MaxSessions int   // Maximum concurrent upload sessions per namespace.
+	SessionTTL  int64 // Session lifetime in seconds. Defaults to one day.
+}
+
+// Default upload session lifetime in seconds.
+const defaultSessionTTL = 24 * 60 * 60
+
+// Checks a new upload session against the limits.
+//
+// The sessions argument is the number of sessions already open in the
+// namespace.
+func (l UploadLimits) CheckStart(info UploadInfo, sessions int) error {
+	if l.MaxSize > 0 && info.Size > l.MaxSize {
+		return quotaExceeded("archive size exceeds the registry limit", l.MaxSize)
+	}
+	if l.MaxSessions > 0 && sessions >= l.MaxSessions {
+		return quotaExceeded("too many upload sessions in namespace", int64(l.MaxSessions))
+	}
+	return nil
+}
+
+// Checks a chunk size against the limits.
+func (l UploadLimits) CheckChunk(n int64) error {
+	if l.MaxChunk > 0 && n > l.MaxChunk {
+		return quotaExceeded("chunk size exceeds the registry limit", l.MaxChunk)
+	}
+	return nil
+}
+
+// Session lifetime in seconds.
+func (l UploadLimits) ttl() int64 {
+	if l.SessionTTL > 0 {
+		return l.SessionTTL
+	}
+	return defaultSessionTTL
+}
+
+// Whether an upload session ID is valid.
+//
+// IDs consist of 16 to 128 ASCII letters, digits, underscores, or hyphens.
+func ValidateUploadID(id string) error {
+	if !uploadIDPattern.MatchString(id) {
+		return ErrUploadIDInvalid
+	}
+	return nil
+}
+
+// Whether a declared upload digest is a valid SHA-256 digest.
+func validateUploadDigest(s string) error {
+	d, err := reference.ParseDigest(s)
+	if err != nil {
+		return ErrDigestInvalid
+	}
+	return ValidateBlobDigest(*d)
+}
+
+// Error returned when a quota is exceeded.
+func quotaExceeded(message string, limit int64) error {
+	return &Error{
+		Code:    ErrorCodeQuotaExceeded,
+		Message: message,
+		Details: map[string]string{DetailLimit: strconv.FormatInt(limit, 10)},
+	}
+}
+
+// Error returned when a chunk does not start at the committed offset.
+func offsetMismatch(committed int64) error {
+	return &Error{
+		Code:    ErrorCodeOffsetMismatch,
+		Message: "chunk does not start at the committed offset",
+		Details: map[string]string{DetailOffset: strconv.FormatInt(committed, 10)},
+	}
+}
+
+// Error returned for a missing or expired upload session.
+func uploadNotFound(id string) error {
+	return &Error{
+		Code:    ErrorCodeNotFound,
+		Message: "upload " + id + " not found",
+		Details: map[string]string{DetailEntity: "upload"},
+	}
+}
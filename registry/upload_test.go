@@ -0,0 +1,188 @@
+package registry
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"testing"
+)
+
+func newUploadRegistry(t *testing.T, limits UploadLimits) (Registry, *memMetadataStore, []byte, UploadInfo) {
+	t.Helper()
+	meta := &memMetadataStore{versions: map[string]*Version{
+		"1.0.0": {Namespace: "acme", Resource: "clock", String: "1.0.0", CreatedAt: 1, UpdatedAt: 1},
+	}}
+	data := buildArchive(t, map[string]string{
+		"crucible.yaml": "version: 0\nresource:\n  type: widget\n  name: acme/clock\n  version: 1.0.0\nmain: index.js\n",
+		"index.js":      "export {}",
+	})
+	sum := sha256.Sum256(data)
+	info := UploadInfo{Size: int64(len(data)), Digest: "sha256:" + hex.EncodeToString(sum[:])}
+	return NewRegistry(meta, NewMemoryBlobStore(), limits), meta, data, info
+}
+
+func TestUploadSession(t *testing.T) {
+	ctx := context.Background()
+	reg, meta, data, info := newUploadRegistry(t, UploadLimits{})
+
+	u, err := reg.StartUpload(ctx, "acme", "clock", "1.0.0", info)
+	if err != nil {
+		t.Fatalf("StartUpload failed: %v", err)
+	}
+	if err := u.Validate(); err != nil {
+		t.Fatalf("invalid session: %v", err)
+	}
+
+	half := int64(len(data) / 2)
+	if _, err := reg.UploadChunk(ctx, "acme", "clock", "1.0.0", u.ID, 0, bytes.NewReader(data[:half])); err != nil {
+		t.Fatalf("UploadChunk failed: %v", err)
+	}
+
+	// A retried chunk at a stale offset reports the committed offset.
+	_, err = reg.UploadChunk(ctx, "acme", "clock", "1.0.0", u.ID, 0, bytes.NewReader(data[:half]))
+	var apiErr *Error
+	if !errors.As(err, &apiErr) || apiErr.Code != ErrorCodeOffsetMismatch {
+		t.Fatalf("UploadChunk error = %v, want %s", err, ErrorCodeOffsetMismatch)
+	}
+	if got, want := apiErr.Details[DetailOffset], strconv.FormatInt(half, 10); got != want {
+		t.Errorf("offset detail = %s, want %s", got, want)
+	}
+
+	if _, err := reg.CompleteUpload(ctx, "acme", "clock", "1.0.0", u.ID); !errors.Is(err, ErrUploadIncomplete) {
+		t.Fatalf("CompleteUpload error = %v, want %v", err, ErrUploadIncomplete)
+	}
+
+	read, err := reg.ReadUpload(ctx, "acme", "clock", "1.0.0", u.ID)
+	if err != nil {
+		t.Fatalf("ReadUpload failed: %v", err)
+	}
+	if read.Offset != half {
+		t.Fatalf("Offset = %d, want %d", read.Offset, half)
+	}
+
+	if _, err := reg.UploadChunk(ctx, "acme", "clock", "1.0.0", u.ID, read.Offset, bytes.NewReader(data[read.Offset:])); err != nil {
+		t.Fatalf("UploadChunk failed: %v", err)
+	}
+	v, err := reg.CompleteUpload(ctx, "acme", "clock", "1.0.0", u.ID)
+	if err != nil {
+		t.Fatalf("CompleteUpload failed: %v", err)
+	}
+	if v.Digest == nil || *v.Digest != info.Digest {
+		t.Errorf("Digest = %v, want %s", v.Digest, info.Digest)
+	}
+	if len(meta.uploads) != 0 {
+		t.Error("completed session not removed")
+	}
+	if _, err := reg.ReadUpload(ctx, "acme", "clock", "1.0.0", u.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("ReadUpload after completion error = %v, want %v", err, ErrNotFound)
+	}
+}
+
+func TestUploadSessionDigestMismatch(t *testing.T) {
+	ctx := context.Background()
+	reg, _, data, info := newUploadRegistry(t, UploadLimits{})
+	info.Digest = "sha256:" + hex.EncodeToString(make([]byte, 32))
+
+	u, err := reg.StartUpload(ctx, "acme", "clock", "1.0.0", info)
+	if err != nil {
+		t.Fatalf("StartUpload failed: %v", err)
+	}
+	if _, err := reg.UploadChunk(ctx, "acme", "clock", "1.0.0", u.ID, 0, bytes.NewReader(data)); err != nil {
+		t.Fatalf("UploadChunk failed: %v", err)
+	}
+	if _, err := reg.CompleteUpload(ctx, "acme", "clock", "1.0.0", u.ID); !errors.Is(err, ErrDigestMismatch) {
+		t.Fatalf("CompleteUpload error = %v, want %v", err, ErrDigestMismatch)
+	}
+}
+
+func TestUploadSessionOverrun(t *testing.T) {
+	ctx := context.Background()
+	reg, _, data, info := newUploadRegistry(t, UploadLimits{})
+
+	u, err := reg.StartUpload(ctx, "acme", "clock", "1.0.0", info)
+	if err != nil {
+		t.Fatalf("StartUpload failed: %v", err)
+	}
+	extra := append(append([]byte{}, data...), 0)
+	if _, err := reg.UploadChunk(ctx, "acme", "clock", "1.0.0", u.ID, 0, bytes.NewReader(extra)); !errors.Is(err, ErrBadRequest) {
+		t.Fatalf("UploadChunk error = %v, want %v", err, ErrBadRequest)
+	}
+	if _, err := reg.CompleteUpload(ctx, "acme", "clock", "1.0.0", u.ID); err != nil {
+		t.Fatalf("CompleteUpload failed after committed prefix: %v", err)
+	}
+}
+
+func TestCancelUploadScope(t *testing.T) {
+	ctx := context.Background()
+	reg, meta, data, info := newUploadRegistry(t, UploadLimits{})
+
+	u, err := reg.StartUpload(ctx, "acme", "clock", "1.0.0", info)
+	if err != nil {
+		t.Fatalf("StartUpload failed: %v", err)
+	}
+	if _, err := reg.UploadChunk(ctx, "acme", "clock", "1.0.0", u.ID, 0, bytes.NewReader(data)); err != nil {
+		t.Fatalf("UploadChunk failed: %v", err)
+	}
+
+	// Cancelling under another resource leaves the session and its bytes.
+	if err := reg.CancelUpload(ctx, "acme", "calendar", "1.0.0", u.ID); err != nil {
+		t.Fatalf("CancelUpload failed: %v", err)
+	}
+	read, err := reg.ReadUpload(ctx, "acme", "clock", "1.0.0", u.ID)
+	if err != nil {
+		t.Fatalf("ReadUpload failed: %v", err)
+	}
+	if read.Offset != info.Size {
+		t.Fatalf("Offset = %d, want %d", read.Offset, info.Size)
+	}
+
+	if err := reg.CancelUpload(ctx, "acme", "clock", "1.0.0", u.ID); err != nil {
+		t.Fatalf("CancelUpload failed: %v", err)
+	}
+	if len(meta.uploads) != 0 {
+		t.Error("cancelled session not removed")
+	}
+	if _, err := reg.ReadUpload(ctx, "acme", "clock", "1.0.0", u.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("ReadUpload after cancellation error = %v, want %v", err, ErrNotFound)
+	}
+}
+
+func TestUploadLimits(t *testing.T) {
+	ctx := context.Background()
+	reg, _, data, info := newUploadRegistry(t, UploadLimits{MaxSize: 10, MaxSessions: 1})
+
+	if _, err := reg.StartUpload(ctx, "acme", "clock", "1.0.0", info); !errors.Is(err, ErrQuotaExceeded) {
+		t.Errorf("StartUpload error = %v, want %v", err, ErrQuotaExceeded)
+	}
+	if _, err := reg.UploadArchive(ctx, "acme", "clock", "1.0.0", bytes.NewReader(data)); !errors.Is(err, ErrQuotaExceeded) {
+		t.Errorf("UploadArchive error = %v, want %v", err, ErrQuotaExceeded)
+	}
+
+	small := UploadInfo{Size: 5, Digest: info.Digest}
+	if _, err := reg.StartUpload(ctx, "acme", "clock", "1.0.0", small); err != nil {
+		t.Fatalf("StartUpload failed: %v", err)
+	}
+	if _, err := reg.StartUpload(ctx, "acme", "clock", "1.0.0", small); !errors.Is(err, ErrQuotaExceeded) {
+		t.Errorf("second StartUpload error = %v, want %v", err, ErrQuotaExceeded)
+	}
+}
+
+func TestUploadInfoValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		info UploadInfo
+		want error
+	}{
+		{"zero size", UploadInfo{Digest: "sha256:" + hex.EncodeToString(make([]byte, 32))}, ErrSizeInvalid},
+		{"bad digest", UploadInfo{Size: 1, Digest: "sha256:xyz"}, ErrDigestInvalid},
+		{"md5", UploadInfo{Size: 1, Digest: "md5:" + hex.EncodeToString(make([]byte, 16))}, ErrDigestAlgorithm},
+	}
+	for _, tt := range tests {
+		if err := tt.info.Validate(); !errors.Is(err, tt.want) {
+			t.Errorf("%s: Validate() error = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
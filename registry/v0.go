@@ -0,0 +1,400 @@
+package registry
+
+// Version as sent under the v0 media type.
+//
+// The shape of [Version] before metadata, dependencies, and publication time
+// were added, still served to clients that only accept [MediaTypeVersion].
+// See [VersionToV0] and [VersionFromV0] for the conversion rules.
+type VersionV0 struct {
+	Namespace string  `json:"namespace"` // Namespace this version belongs to.
+	Resource  string  `json:"resource"`  // Resource this version belongs to.
+	String    string  `json:"string"`    // Version string (e.g., "1.0.0").
+	Archive   *string `json:"archive"`   // Download URL or null if not uploaded.
+	Size      *int64  `json:"size"`      // Archive size in bytes (null if not uploaded).
+	Digest    *string `json:"digest"`    // Archive digest (e.g., "sha256:abc...", null if not uploaded).
+	CreatedAt int64   `json:"createdAt"` // When the version was created.
+	UpdatedAt int64   `json:"updatedAt"` // When the version was last updated.
+}
+
+// Validates the v0 version.
+func (v *VersionV0) Validate() error {
+	return VersionFromV0(v).Validate()
+}
+
+// Converts a version to the v0 shape.
+//
+// Metadata, dependencies, and the publication time are dropped. The
+// remaining fields are copied unchanged, so v0 clients still see the archive
+// and can download it.
+func VersionToV0(v *Version) *VersionV0 {
+	return &VersionV0{
+		Namespace: v.Namespace,
+		Resource:  v.Resource,
+		String:    v.String,
+		Archive:   v.Archive,
+		Size:      v.Size,
+		Digest:    v.Digest,
+		CreatedAt: v.CreatedAt,
+		UpdatedAt: v.UpdatedAt,
+	}
+}
+
+// Converts a v0 version to the current shape.
+//
+// Fields the v0 shape lacks are left empty: no metadata, no dependencies, and
+// a nil publication time. Since v0 cannot express publication, the result
+// must not be relied on to tell whether the version is published.
+func VersionFromV0(v *VersionV0) *Version {
+	return &Version{
+		Namespace: v.Namespace,
+		Resource:  v.Resource,
+		String:    v.String,
+		Archive:   v.Archive,
+		Size:      v.Size,
+		Digest:    v.Digest,
+		CreatedAt: v.CreatedAt,
+		UpdatedAt: v.UpdatedAt,
+	}
+}
+
+// Version info as sent under the v0 media type.
+//
+// The shape of [VersionInfo] before metadata was added, accepted from clients
+// that only send [MediaTypeVersionInfo].
+type VersionInfoV0 struct {
+	String string `json:"string"` // Version string (e.g., "1.0.0").
+}
+
+// Validates the v0 version info.
+func (info *VersionInfoV0) Validate() error {
+	return VersionInfoFromV0(info).Validate()
+}
+
+// Converts version info to the v0 shape, dropping the metadata.
+func VersionInfoToV0(info *VersionInfo) *VersionInfoV0 {
+	return &VersionInfoV0{String: info.String}
+}
+
+// Converts v0 version info to the current shape, with empty metadata.
+func VersionInfoFromV0(info *VersionInfoV0) *VersionInfo {
+	return &VersionInfo{String: info.String}
+}
+
+// Version summary as sent under the v0 media types.
+//
+// The shape of [VersionSummary] before metadata and publication time were
+// added, listed by [VersionListV0] and [ResourceV0].
+type VersionSummaryV0 struct {
+	String    string `json:"string"`    // Version string (e.g., "1.0.0").
+	CreatedAt int64  `json:"createdAt"` // When the version was created.
+	UpdatedAt int64  `json:"updatedAt"` // When the version was last updated.
+}
+
+// Converts version summaries to the v0 shape, dropping the metadata and
+// publication times.
+func versionSummariesToV0(s []VersionSummary) []VersionSummaryV0 {
+	if s == nil {
+		return nil
+	}
+	out := make([]VersionSummaryV0, len(s))
+	for i := range s {
+		out[i] = VersionSummaryV0{String: s[i].String, CreatedAt: s[i].CreatedAt, UpdatedAt: s[i].UpdatedAt}
+	}
+	return out
+}
+
+// Converts v0 version summaries to the current shape, leaving the metadata
+// empty and the publication times nil.
+func versionSummariesFromV0(s []VersionSummaryV0) []VersionSummary {
+	if s == nil {
+		return nil
+	}
+	out := make([]VersionSummary, len(s))
+	for i := range s {
+		out[i] = VersionSummary{String: s[i].String, CreatedAt: s[i].CreatedAt, UpdatedAt: s[i].UpdatedAt}
+	}
+	return out
+}
+
+// Version list as sent under the v0 media type.
+//
+// Served to clients that only accept [MediaTypeVersionList]. See
+// [VersionListToV0] and [VersionListFromV0] for the conversion rules.
+type VersionListV0 struct {
+	Versions []VersionSummaryV0 `json:"versions"` // List of versions.
+}
+
+// Validates the v0 version list.
+func (l *VersionListV0) Validate() error {
+	return VersionListFromV0(l).Validate()
+}
+
+// Converts a version list to the v0 shape.
+//
+// Metadata and publication times are dropped from every summary.
+func VersionListToV0(l *VersionList) *VersionListV0 {
+	return &VersionListV0{Versions: versionSummariesToV0(l.Versions)}
+}
+
+// Converts a v0 version list to the current shape.
+//
+// Summaries have empty metadata and nil publication times, so, as with
+// [VersionFromV0], the result does not tell which versions are published.
+func VersionListFromV0(l *VersionListV0) *VersionList {
+	return &VersionList{Versions: versionSummariesFromV0(l.Versions)}
+}
+
+// Resource info as sent under the v0 media type.
+//
+// The shape of [ResourceInfo] before metadata was added, accepted from
+// clients that only send [MediaTypeResourceInfo].
+type ResourceInfoV0 struct {
+	Name        string `json:"name"`        // Resource name.
+	Type        string `json:"type"`        // Resource type (e.g., "widget", "service").
+	Description string `json:"description"` // Description.
+}
+
+// Validates the v0 resource info.
+func (info *ResourceInfoV0) Validate() error {
+	return ResourceInfoFromV0(info).Validate()
+}
+
+// Converts resource info to the v0 shape, dropping the metadata.
+func ResourceInfoToV0(info *ResourceInfo) *ResourceInfoV0 {
+	return &ResourceInfoV0{Name: info.Name, Type: info.Type, Description: info.Description}
+}
+
+// Converts v0 resource info to the current shape, with empty metadata.
+func ResourceInfoFromV0(info *ResourceInfoV0) *ResourceInfo {
+	return &ResourceInfo{Name: info.Name, Type: info.Type, Description: info.Description}
+}
+
+// Resource summary as sent under the v0 media types.
+//
+// The shape of [ResourceSummary] before metadata was added, listed by
+// [ResourceListV0] and [NamespaceV0].
+type ResourceSummaryV0 struct {
+	Name          string  `json:"name"`          // Resource name.
+	Type          string  `json:"type"`          // Resource type (e.g., "widget", "service").
+	Description   string  `json:"description"`   // Description.
+	LatestVersion *string `json:"latestVersion"` // Most recent version string (null if no versions).
+	VersionCount  int     `json:"versionCount"`  // Number of versions for this resource.
+	ChannelCount  int     `json:"channelCount"`  // Number of channels for this resource.
+	CreatedAt     int64   `json:"createdAt"`     // When the resource was created.
+	UpdatedAt     int64   `json:"updatedAt"`     // When the resource was last updated.
+}
+
+// Converts resource summaries to the v0 shape, dropping the metadata.
+func resourceSummariesToV0(s []ResourceSummary) []ResourceSummaryV0 {
+	if s == nil {
+		return nil
+	}
+	out := make([]ResourceSummaryV0, len(s))
+	for i, r := range s {
+		out[i] = ResourceSummaryV0{
+			Name:          r.Name,
+			Type:          r.Type,
+			Description:   r.Description,
+			LatestVersion: r.LatestVersion,
+			VersionCount:  r.VersionCount,
+			ChannelCount:  r.ChannelCount,
+			CreatedAt:     r.CreatedAt,
+			UpdatedAt:     r.UpdatedAt,
+		}
+	}
+	return out
+}
+
+// Converts v0 resource summaries to the current shape, with empty metadata.
+func resourceSummariesFromV0(s []ResourceSummaryV0) []ResourceSummary {
+	if s == nil {
+		return nil
+	}
+	out := make([]ResourceSummary, len(s))
+	for i, r := range s {
+		out[i] = ResourceSummary{
+			Name:          r.Name,
+			Type:          r.Type,
+			Description:   r.Description,
+			LatestVersion: r.LatestVersion,
+			VersionCount:  r.VersionCount,
+			ChannelCount:  r.ChannelCount,
+			CreatedAt:     r.CreatedAt,
+			UpdatedAt:     r.UpdatedAt,
+		}
+	}
+	return out
+}
+
+// Resource list as sent under the v0 media type.
+//
+// Served to clients that only accept [MediaTypeResourceList]. See
+// [ResourceListToV0] and [ResourceListFromV0] for the conversion rules.
+type ResourceListV0 struct {
+	Resources []ResourceSummaryV0 `json:"resources"` // List of resources.
+}
+
+// Validates the v0 resource list.
+func (l *ResourceListV0) Validate() error {
+	return ResourceListFromV0(l).Validate()
+}
+
+// Converts a resource list to the v0 shape, dropping the metadata of every
+// summary.
+func ResourceListToV0(l *ResourceList) *ResourceListV0 {
+	return &ResourceListV0{Resources: resourceSummariesToV0(l.Resources)}
+}
+
+// Converts a v0 resource list to the current shape, with empty metadata.
+func ResourceListFromV0(l *ResourceListV0) *ResourceList {
+	return &ResourceList{Resources: resourceSummariesFromV0(l.Resources)}
+}
+
+// Resource as sent under the v0 media type.
+//
+// The shape of [Resource] before metadata was added to it and to its version
+// summaries, still served to clients that only accept [MediaTypeResource].
+// See [ResourceToV0] and [ResourceFromV0] for the conversion rules.
+type ResourceV0 struct {
+	Namespace   string             `json:"namespace"`   // Namespace this resource belongs to.
+	Name        string             `json:"name"`        // Resource name.
+	Type        string             `json:"type"`        // Resource type (e.g., "widget", "service").
+	Description string             `json:"description"` // Description.
+	Versions    []VersionSummaryV0 `json:"versions"`    // List of versions (summary form).
+	Channels    []ChannelSummary   `json:"channels"`    // List of channels (summary form).
+	CreatedAt   int64              `json:"createdAt"`   // When the resource was created.
+	UpdatedAt   int64              `json:"updatedAt"`   // When the resource was last updated.
+}
+
+// Validates the v0 resource.
+func (r *ResourceV0) Validate() error {
+	return ResourceFromV0(r).Validate()
+}
+
+// Converts a resource to the v0 shape.
+//
+// The metadata is dropped, and the version summaries are converted as by
+// [VersionListToV0]. Channel summaries are unchanged.
+func ResourceToV0(r *Resource) *ResourceV0 {
+	return &ResourceV0{
+		Namespace:   r.Namespace,
+		Name:        r.Name,
+		Type:        r.Type,
+		Description: r.Description,
+		Versions:    versionSummariesToV0(r.Versions),
+		Channels:    r.Channels,
+		CreatedAt:   r.CreatedAt,
+		UpdatedAt:   r.UpdatedAt,
+	}
+}
+
+// Converts a v0 resource to the current shape.
+//
+// The metadata is left empty, and the version summaries are converted as by
+// [VersionListFromV0].
+func ResourceFromV0(r *ResourceV0) *Resource {
+	return &Resource{
+		Namespace:   r.Namespace,
+		Name:        r.Name,
+		Type:        r.Type,
+		Description: r.Description,
+		Versions:    versionSummariesFromV0(r.Versions),
+		Channels:    r.Channels,
+		CreatedAt:   r.CreatedAt,
+		UpdatedAt:   r.UpdatedAt,
+	}
+}
+
+// Namespace as sent under the v0 media type.
+//
+// The shape of [Namespace] before metadata was added to its resource
+// summaries, still served to clients that only accept [MediaTypeNamespace].
+// See [NamespaceToV0] and [NamespaceFromV0] for the conversion rules.
+type NamespaceV0 struct {
+	Name        string              `json:"name"`        // Namespace name.
+	Description string              `json:"description"` // Description.
+	Resources   []ResourceSummaryV0 `json:"resources"`   // List of resources (summary form).
+	CreatedAt   int64               `json:"createdAt"`   // When the namespace was created.
+	UpdatedAt   int64               `json:"updatedAt"`   // When the namespace was last updated.
+}
+
+// Validates the v0 namespace.
+func (ns *NamespaceV0) Validate() error {
+	return NamespaceFromV0(ns).Validate()
+}
+
+// Converts a namespace to the v0 shape, dropping the metadata of every
+// resource summary.
+func NamespaceToV0(ns *Namespace) *NamespaceV0 {
+	return &NamespaceV0{
+		Name:        ns.Name,
+		Description: ns.Description,
+		Resources:   resourceSummariesToV0(ns.Resources),
+		CreatedAt:   ns.CreatedAt,
+		UpdatedAt:   ns.UpdatedAt,
+	}
+}
+
+// Converts a v0 namespace to the current shape, with empty resource
+// metadata.
+func NamespaceFromV0(ns *NamespaceV0) *Namespace {
+	return &Namespace{
+		Name:        ns.Name,
+		Description: ns.Description,
+		Resources:   resourceSummariesFromV0(ns.Resources),
+		CreatedAt:   ns.CreatedAt,
+		UpdatedAt:   ns.UpdatedAt,
+	}
+}
+
+// Channel as sent under the v0 media type.
+//
+// The shape of [Channel] embedding the v0 version, still served to clients
+// that only accept [MediaTypeChannel]. See [ChannelToV0] and
+// [ChannelFromV0] for the conversion rules.
+type ChannelV0 struct {
+	Namespace   string    `json:"namespace"`   // Namespace this channel belongs to.
+	Resource    string    `json:"resource"`    // Resource this channel belongs to.
+	Name        string    `json:"name"`        // Channel name.
+	Version     VersionV0 `json:"version"`     // Full version object this channel points to.
+	Description string    `json:"description"` // Description.
+	CreatedAt   int64     `json:"createdAt"`   // When the channel was created.
+	UpdatedAt   int64     `json:"updatedAt"`   // When the channel was last updated.
+}
+
+// Validates the v0 channel.
+func (ch *ChannelV0) Validate() error {
+	return ChannelFromV0(ch).Validate()
+}
+
+// Converts a channel to the v0 shape.
+//
+// The embedded version is converted with [VersionToV0]; the other fields are
+// copied unchanged.
+func ChannelToV0(ch *Channel) *ChannelV0 {
+	return &ChannelV0{
+		Namespace:   ch.Namespace,
+		Resource:    ch.Resource,
+		Name:        ch.Name,
+		Version:     *VersionToV0(&ch.Version),
+		Description: ch.Description,
+		CreatedAt:   ch.CreatedAt,
+		UpdatedAt:   ch.UpdatedAt,
+	}
+}
+
+// Converts a v0 channel to the current shape.
+//
+// The embedded version is converted with [VersionFromV0], with the same
+// caveat about its publication status.
+func ChannelFromV0(ch *ChannelV0) *Channel {
+	return &Channel{
+		Namespace:   ch.Namespace,
+		Resource:    ch.Resource,
+		Name:        ch.Name,
+		Version:     *VersionFromV0(&ch.Version),
+		Description: ch.Description,
+		CreatedAt:   ch.CreatedAt,
+		UpdatedAt:   ch.UpdatedAt,
+	}
+}
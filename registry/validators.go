@@ -1,6 +1,7 @@
 package registry
 
 import (
+	"net/url"
 	"regexp"
 	"strings"
 
@@ -14,6 +15,28 @@ var (
 
 	// Valid digest pattern (algorithm:hex).
 	digestPattern = regexp.MustCompile(`^[a-z0-9]+:[a-f0-9]+$`)
+
+	// Valid label key prefix pattern (DNS subdomain).
+	labelPrefixPattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]*[a-z0-9])?(\.[a-z0-9]([a-z0-9-]*[a-z0-9])?)*$`)
+
+	// Valid label key name pattern.
+	labelNamePattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9._-]{0,61}[a-z0-9])?$`)
+
+	// Valid keyword pattern.
+	keywordPattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]{0,30}[a-z0-9])?$`)
+
+	// Valid SPDX license or exception identifier pattern.
+	spdxIDPattern = regexp.MustCompile(`^(DocumentRef-[A-Za-z0-9.-]+:)?[A-Za-z0-9][A-Za-z0-9.-]*\+?$`)
+)
+
+const (
+	maxLabels         = 64        // Maximum number of labels.
+	maxLabelPrefixLen = 253       // Maximum length of a label key prefix.
+	maxLabelValueLen  = 256       // Maximum length of a label value.
+	maxKeywords       = 20        // Maximum number of keywords.
+	maxReadmeLen      = 512 << 10 // Maximum readme size in bytes.
+	maxReasonLen      = 512       // Maximum channel move reason size in bytes.
+	maxManifestLen    = 1 << 20   // Maximum archive manifest size in bytes.
 )
 
 // Whether a name is valid for namespace, resource, channel names.
@@ -60,6 +83,23 @@ func ValidateTimestamps(createdAt, updatedAt int64) error {
 	return nil
 }
 
+// Whether a publication timestamp is valid.
+//
+// A nil timestamp means the version is unpublished. When set, the timestamp
+// must be a positive unix epoch and must not precede createdAt.
+func ValidatePublishedAt(createdAt int64, publishedAt *int64) error {
+	if publishedAt == nil {
+		return nil
+	}
+	if *publishedAt <= 0 {
+		return ErrTimestampInvalid
+	}
+	if *publishedAt < createdAt {
+		return ErrPublishedOrder
+	}
+	return nil
+}
+
 // Whether a resource type string is valid.
 //
 // The type must not be empty. The type is not validated against a specific
@@ -184,3 +224,194 @@ func ValidateChannelInfo(namespace, resource string, info ChannelInfo) error {
 	}
 	return ValidateVersionString(info.Version)
 }
+
+// Whether an expiry timestamp is valid.
+//
+// A nil expiry means the entity never expires. When set, the expiry must be a
+// positive unix epoch strictly after createdAt.
+func ValidateExpiry(createdAt int64, expiresAt *int64) error {
+	if expiresAt == nil {
+		return nil
+	}
+	if *expiresAt <= 0 {
+		return ErrTimestampInvalid
+	}
+	if *expiresAt <= createdAt {
+		return ErrExpiryOrder
+	}
+	return nil
+}
+
+// Whether a token's resource restriction list is valid.
+//
+// Each entry must conform to the registry naming rules (see [ValidateName])
+// and appear at most once. An empty list is valid and means the token is not
+// restricted to specific resources.
+func ValidateTokenResources(resources []string) error {
+	seen := make(map[string]bool, len(resources))
+	for _, r := range resources {
+		if err := ValidateName(r); err != nil {
+			return err
+		}
+		if seen[r] {
+			return ErrResourceDuplicate
+		}
+		seen[r] = true
+	}
+	return nil
+}
+
+// Whether a set of labels is valid.
+//
+// At most 64 labels are allowed. Keys consist of an optional prefix and a
+// name separated by a slash (e.g., "crucible.dev/tier" or "tier"). The prefix
+// must be a lowercase DNS subdomain of at most 253 characters. The name must
+// be 1 to 63 lowercase alphanumeric characters, dots, underscores, or hyphens,
+// starting and ending with an alphanumeric character. Values may be empty and
+// must not exceed 256 bytes.
+func ValidateLabels(labels map[string]string) error {
+	if len(labels) > maxLabels {
+		return ErrTooManyLabels
+	}
+	for key, value := range labels {
+		if err := ValidateLabelKey(key); err != nil {
+			return err
+		}
+		if len(value) > maxLabelValueLen {
+			return ErrLabelValueTooLong
+		}
+	}
+	return nil
+}
+
+// Whether a label key is valid.
+//
+// See [ValidateLabels] for the key format.
+func ValidateLabelKey(key string) error {
+	name := key
+	if i := strings.LastIndexByte(key, '/'); i >= 0 {
+		prefix := key[:i]
+		name = key[i+1:]
+		if len(prefix) > maxLabelPrefixLen || !labelPrefixPattern.MatchString(prefix) {
+			return ErrLabelKeyInvalid
+		}
+	}
+	if !labelNamePattern.MatchString(name) {
+		return ErrLabelKeyInvalid
+	}
+	return nil
+}
+
+// Whether a readme is valid.
+//
+// The readme must not exceed 512 KiB.
+func ValidateReadme(readme string) error {
+	if len(readme) > maxReadmeLen {
+		return ErrReadmeTooLong
+	}
+	return nil
+}
+
+// Whether a channel move reason is valid.
+//
+// The reason may be empty and must not exceed 512 bytes.
+func ValidateReason(reason string) error {
+	if len(reason) > maxReasonLen {
+		return ErrReasonTooLong
+	}
+	return nil
+}
+
+// Whether a license string is a valid SPDX license expression.
+//
+// An empty string is valid and means no license is declared. Otherwise the
+// string must be an SPDX expression built from license identifiers (optionally
+// suffixed with "+"), LicenseRef and DocumentRef references, the AND, OR, and
+// WITH operators, and balanced parentheses. Identifiers are checked for form
+// only, not against the SPDX license list.
+func ValidateLicense(license string) error {
+	if license == "" {
+		return nil
+	}
+
+	tokens := strings.Fields(strings.NewReplacer("(", " ( ", ")", " ) ").Replace(license))
+
+	depth := 0
+	operand := true // Whether the next token must be an operand.
+	for i := 0; i < len(tokens); i++ {
+		tok := tokens[i]
+		switch {
+		case operand && tok == "(":
+			depth++
+		case operand && spdxIDPattern.MatchString(tok) && !isSPDXOperator(tok):
+			operand = false
+		case !operand && tok == ")":
+			depth--
+			if depth < 0 {
+				return ErrLicenseInvalid
+			}
+		case !operand && (tok == "AND" || tok == "OR"):
+			operand = true
+		case !operand && tok == "WITH":
+			i++
+			if i == len(tokens) || isSPDXOperator(tokens[i]) || !spdxIDPattern.MatchString(tokens[i]) {
+				return ErrLicenseInvalid
+			}
+		default:
+			return ErrLicenseInvalid
+		}
+	}
+
+	if operand || depth != 0 {
+		return ErrLicenseInvalid
+	}
+	return nil
+}
+
+// Whether a token is an SPDX expression operator.
+func isSPDXOperator(tok string) bool {
+	return tok == "AND" || tok == "OR" || tok == "WITH"
+}
+
+// Whether a URL string is valid for metadata links.
+//
+// An empty string is valid and means no URL is set. Otherwise the URL must be
+// absolute, use the http or https scheme, and include a host.
+func ValidateURL(s string) error {
+	if s == "" {
+		return nil
+	}
+	u, err := url.Parse(s)
+	if err != nil {
+		return ErrURLInvalid
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return ErrURLInvalid
+	}
+	if u.Host == "" {
+		return ErrURLInvalid
+	}
+	return nil
+}
+
+// Whether a keyword list is valid.
+//
+// At most 20 keywords are allowed and each must appear once. Keywords may
+// include lowercase letters, digits, and hyphens, must start and end with an
+// alphanumeric character, and must not exceed 32 characters.
+func ValidateKeywords(keywords []string) error {
+	if len(keywords) > maxKeywords {
+		return ErrTooManyKeywords
+	}
+	seen := make(map[string]bool, len(keywords))
+	for _, k := range keywords {
+		if !keywordPattern.MatchString(k) {
+			return ErrKeywordInvalid
+		}
+		if seen[k] {
+			return ErrKeywordDuplicate
+		}
+		seen[k] = true
+	}
+	return nil
+}
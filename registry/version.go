@@ -6,18 +6,26 @@ import "github.com/cruciblehq/crex"
 //
 // Used as the request body for version creation and update operations. For
 // update requests, the version field must match the URL path parameter or
-// update context. Contains only user-modifiable fields. The media type is
-// [MediaTypeVersionInfo].
+// update context. Contains only user-modifiable fields. Metadata supplied here
+// is combined with the metadata extracted from the archive manifest on upload,
+// with the manifest taking precedence (see [Metadata.Overlay]). The media type
+// is [MediaTypeVersionInfoV1]; [MediaTypeVersionInfo] carries the older
+// [VersionInfoV0] shape.
 type VersionInfo struct {
-	String string `json:"string"` // Version string (e.g., "1.0.0").
+	String   string   `json:"string"`   // Version string (e.g., "1.0.0").
+	Metadata Metadata `json:"metadata"` // Descriptive metadata (labels, readme, license, links).
 }
 
 // Validates the version info.
 //
-// The version string must be a valid semantic version (see [ValidateVersionString]).
+// The version string must be a valid semantic version (see [ValidateVersionString])
+// and the metadata must be valid (see [Metadata.Validate]).
 func (info *VersionInfo) Validate() error {
 	if err := ValidateVersionString(info.String); err != nil {
-		return crex.Wrap(ErrInvalidVersion, err)
+		return crex.Wrap(ErrInvalidVersion, fieldError("string", err))
+	}
+	if err := info.Metadata.Validate(); err != nil {
+		return crex.Wrap(ErrInvalidVersion, fieldError("metadata", err))
 	}
 	return nil
 }
@@ -28,22 +36,35 @@ func (info *VersionInfo) Validate() error {
 // listings and version lists to keep payloads compact. Includes read-only
 // fields like publication status and timestamps.
 type VersionSummary struct {
-	String    string `json:"string"`    // Version string (e.g., "1.0.0").
-	CreatedAt int64  `json:"createdAt"` // When the version was created.
-	UpdatedAt int64  `json:"updatedAt"` // When the version was last updated.
+	String      string   `json:"string"`      // Version string (e.g., "1.0.0").
+	Metadata    Metadata `json:"metadata"`    // Descriptive metadata without readme.
+	PublishedAt *int64   `json:"publishedAt"` // When the version was published (null if unpublished).
+	CreatedAt   int64    `json:"createdAt"`   // When the version was created.
+	UpdatedAt   int64    `json:"updatedAt"`   // When the version was last updated.
 }
 
 // Validates the version summary.
 func (s *VersionSummary) Validate() error {
 	if err := ValidateVersionString(s.String); err != nil {
-		return crex.Wrap(ErrInvalidVersion, err)
+		return crex.Wrap(ErrInvalidVersion, fieldError("string", err))
+	}
+	if err := s.Metadata.Validate(); err != nil {
+		return crex.Wrap(ErrInvalidVersion, fieldError("metadata", err))
 	}
 	if err := ValidateTimestamps(s.CreatedAt, s.UpdatedAt); err != nil {
-		return crex.Wrap(ErrInvalidVersion, err)
+		return crex.Wrap(ErrInvalidVersion, fieldError("updatedAt", err))
+	}
+	if err := ValidatePublishedAt(s.CreatedAt, s.PublishedAt); err != nil {
+		return crex.Wrap(ErrInvalidVersion, fieldError("publishedAt", err))
 	}
 	return nil
 }
 
+// Whether the version has been published.
+func (s *VersionSummary) Published() bool {
+	return s.PublishedAt != nil
+}
+
 // Complete version with archive details and publication status.
 //
 // Tracks both metadata (always mutable) and archive state (immutable after
@@ -52,43 +73,66 @@ func (s *VersionSummary) Validate() error {
 // versions and contains the publication timestamp when published. Unpublished
 // versions support archive replacement for iterative development, while
 // published versions ensure immutability for stable dependency resolution.
+// The dependencies list is derived from the archive manifest on upload (see
+// [DependenciesFromManifest]) and replaced whenever the archive is.
 // Version metadata updates remain allowed even after publication. Includes
 // scoping information to identify the version's location. The media type is
-// [MediaTypeVersion].
+// [MediaTypeVersionV1]; [MediaTypeVersion] carries the older [VersionV0]
+// shape for clients that predate metadata and dependencies.
 type Version struct {
-	Namespace string  `json:"namespace"` // Namespace this version belongs to.
-	Resource  string  `json:"resource"`  // Resource this version belongs to.
-	String    string  `json:"string"`    // Version string (e.g., "1.0.0").
-	Archive   *string `json:"archive"`   // Download URL or null if not uploaded.
-	Size      *int64  `json:"size"`      // Archive size in bytes (null if not uploaded).
-	Digest    *string `json:"digest"`    // Archive digest (e.g., "sha256:abc...", null if not uploaded).
-	CreatedAt int64   `json:"createdAt"` // When the version was created.
-	UpdatedAt int64   `json:"updatedAt"` // When the version was last updated.
+	Namespace    string       `json:"namespace"`    // Namespace this version belongs to.
+	Resource     string       `json:"resource"`     // Resource this version belongs to.
+	String       string       `json:"string"`       // Version string (e.g., "1.0.0").
+	Metadata     Metadata     `json:"metadata"`     // Descriptive metadata (labels, readme, license, links).
+	Archive      *string      `json:"archive"`      // Download URL or null if not uploaded.
+	Size         *int64       `json:"size"`         // Archive size in bytes (null if not uploaded).
+	Digest       *string      `json:"digest"`       // Archive digest (e.g., "sha256:abc...", null if not uploaded).
+	Dependencies []Dependency `json:"dependencies"` // Declared dependencies (empty if not uploaded).
+	PublishedAt  *int64       `json:"publishedAt"`  // When the version was published (null if unpublished).
+	CreatedAt    int64        `json:"createdAt"`    // When the version was created.
+	UpdatedAt    int64        `json:"updatedAt"`    // When the version was last updated.
 }
 
 // Validates the version.
 func (v *Version) Validate() error {
 	if err := ValidateName(v.Namespace); err != nil {
-		return crex.Wrap(ErrInvalidVersion, err)
+		return crex.Wrap(ErrInvalidVersion, fieldError("namespace", err))
 	}
 	if err := ValidateName(v.Resource); err != nil {
-		return crex.Wrap(ErrInvalidVersion, err)
+		return crex.Wrap(ErrInvalidVersion, fieldError("resource", err))
 	}
 	if err := ValidateVersionString(v.String); err != nil {
-		return crex.Wrap(ErrInvalidVersion, err)
+		return crex.Wrap(ErrInvalidVersion, fieldError("string", err))
+	}
+	if err := v.Metadata.Validate(); err != nil {
+		return crex.Wrap(ErrInvalidVersion, fieldError("metadata", err))
 	}
 	if err := ValidateArchiveFields(v.Archive, v.Size, v.Digest); err != nil {
-		return crex.Wrap(ErrInvalidVersion, err)
+		return crex.Wrap(ErrInvalidVersion, fieldError("archive", err))
+	}
+	for i := range v.Dependencies {
+		if err := v.Dependencies[i].Validate(); err != nil {
+			return crex.Wrap(ErrInvalidVersion, fieldError(indexField("dependencies", i), err))
+		}
 	}
 	if err := ValidateTimestamps(v.CreatedAt, v.UpdatedAt); err != nil {
-		return crex.Wrap(ErrInvalidVersion, err)
+		return crex.Wrap(ErrInvalidVersion, fieldError("updatedAt", err))
+	}
+	if err := ValidatePublishedAt(v.CreatedAt, v.PublishedAt); err != nil {
+		return crex.Wrap(ErrInvalidVersion, fieldError("publishedAt", err))
 	}
 	return nil
 }
 
+// Whether the version has been published.
+func (v *Version) Published() bool {
+	return v.PublishedAt != nil
+}
+
 // Collection of versions for a resource.
 //
-// The media type is [MediaTypeVersionList].
+// The media type is [MediaTypeVersionListV1]; [MediaTypeVersionList] carries
+// the older [VersionListV0] shape.
 type VersionList struct {
 	Versions []VersionSummary `json:"versions"` // List of versions.
 }
@@ -97,7 +141,7 @@ type VersionList struct {
 func (l *VersionList) Validate() error {
 	for i := range l.Versions {
 		if err := l.Versions[i].Validate(); err != nil {
-			return crex.Wrap(ErrInvalidVersion, err)
+			return crex.Wrap(ErrInvalidVersion, fieldError(indexField("versions", i), err))
 		}
 	}
 	return nil